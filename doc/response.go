@@ -13,7 +13,7 @@ type CreateCatParam struct {
 	Body model.CreateCat `json:"body"`
 }
 
-// swagger:parameters GetCat UpdateCat DeleteCat UploadCatImage GetCatImage
+// swagger:parameters GetCat UpdateCat DeleteCat RestoreCat UploadCatImage GetCatImage CatHistory
 type CatUUIDParam struct {
 	// in:path
 	// required:true
@@ -22,33 +22,98 @@ type CatUUIDParam struct {
 
 // swagger:response getCatResponse
 type GetCatResponse struct {
+	// The version of the cat to send back in If-Match
+	// in: header
+	ETag string `json:"ETag"`
 	// The response message
 	// in: body
 	Body model.Cat `json:"body"`
 }
 
+// swagger:parameters UpdateCat DeleteCat UploadCatImage
+type IfMatchParam struct {
+	// ETag of the cat, the request fails if the cat has been changed since
+	// in:header
+	// required:false
+	IfMatch string `json:"If-Match"`
+}
+
+// swagger:parameters ListCats ListTrashedCats
+type ListCatsParam struct {
+	// The maximum number of cats in a page, from 1 to 100
+	// in:query
+	// required:false
+	Limit int `json:"limit"`
+	// The opaque cursor returned as nextCursor by the previous page
+	// in:query
+	// required:false
+	Cursor string `json:"cursor"`
+	// The status of vaccination of cats
+	// in:query
+	// required:false
+	Vaccinated bool `json:"vaccinated"`
+	// The earliest birthdate of cats, YYYY-MM-DD or RFC3339
+	// in:query
+	// required:false
+	DateBirthFrom string `json:"dateBirthFrom"`
+	// The latest birthdate of cats, YYYY-MM-DD or RFC3339
+	// in:query
+	// required:false
+	DateBirthTo string `json:"dateBirthTo"`
+	// The prefix of cats names
+	// in:query
+	// required:false
+	Name string `json:"name"`
+	// The sort order: name, -name, dateBirth or -dateBirth
+	// in:query
+	// required:false
+	Sort string `json:"sort"`
+}
+
+// swagger:response listCatsResponse
+type ListCatsResponse struct {
+	// The response message
+	// in: body
+	Body model.CatsPage `json:"body"`
+}
+
+// swagger:parameters CatHistory
+type CatHistoryParam struct {
+	// The maximum number of changes in a page, from 1 to 100
+	// in:query
+	// required:false
+	Limit int `json:"limit"`
+	// The opaque cursor returned as nextCursor by the previous page
+	// in:query
+	// required:false
+	Cursor string `json:"cursor"`
+}
+
+// swagger:response catHistoryResponse
+type CatHistoryResponse struct {
+	// The response message
+	// in: body
+	Body model.CatChangesPage `json:"body"`
+}
+
 // swagger:response updateCatResponse
 type UpdateCatResponse struct {
+	// The version of the updated cat
+	// in: header
+	ETag string `json:"ETag"`
 	// The response message
 	// in: body
 	Body model.Cat `json:"body"`
 }
 
-// A GenericError is the default error message that is generated.
-// For certain status codes there are more appropriate error structures.
+// A GenericError is the application/problem+json body of RFC 7807 returned for every failed request.
+// Details of internal errors are never included.
 //
 // swagger:response genericError
 type GenericError struct {
 	// The response message
 	// in: body
-	Body struct {
-		// a human readable version of the error
-		// required: true
-		Message string `json:"message"`
-
-		// Error An optional detailed description of the actual error. Only included if running in developer mode.
-		Error string `json:"error"`
-	}
+	Body model.Problem `json:"body"`
 }
 
 // InternalServerError is a general error indicating something went wrong internally.
@@ -66,18 +131,41 @@ type BadRequestError GenericError
 // swagger:response unsupportedMediaTypeError
 type UnsupportedMediaTypeError GenericError
 
-// A UnauthorizedError is the default error message that is generated by echo JWT middleware.
+// NotFoundError is returned when the requested object doesn't exist.
+//
+// swagger:response notFoundError
+type NotFoundError GenericError
+
+// ConflictError is returned when the object conflicts with already existing one.
+//
+// swagger:response conflictError
+type ConflictError GenericError
+
+// PreconditionFailedError is returned when the object has been changed since the version in If-Match.
+//
+// swagger:response preconditionFailedError
+type PreconditionFailedError GenericError
+
+// UnprocessableEntityError is returned when the request fails validation.
+//
+// swagger:response unprocessableEntityError
+type UnprocessableEntityError GenericError
+
+// A UnauthorizedError is returned when the token is invalid or expired or credentials are wrong.
 //
 // swagger:response unauthorizedError
-type UnauthorizedError struct {
-	// The response message
-	// in: body
-	Body struct {
-		// a human readable version of the error
-		// required: true
-		Message string `json:"message"`
-	}
-}
+type UnauthorizedError GenericError
+
+// A ForbiddenError is returned when role of the user doesn't allow the action.
+//
+// swagger:response forbiddenError
+type ForbiddenError GenericError
+
+// A TooManyRequestsError is returned when sign in is locked after too many failed attempts.
+// Retry-After header contains seconds left until the lockout ends.
+//
+// swagger:response tooManyRequestsError
+type TooManyRequestsError GenericError
 
 // swagger:parameters UploadCatImage
 type UploadCatImageParam struct {
@@ -121,6 +209,106 @@ type SignInResponse struct {
 	Body model.Tokens `json:"body"`
 }
 
+// An OIDCRedirectResponse redirects to the OpenID Connect provider.
+//
+// swagger:response oidcRedirectResponse
+type OIDCRedirectResponse struct {
+	// The authorization URL of the provider
+	// in: header
+	Location string `json:"Location"`
+}
+
+// swagger:parameters SetUserRole
+type SetUserRoleParam struct {
+	// in:path
+	// required:true
+	UserID string `json:"uuid"`
+	// in:body
+	// required:true
+	Body model.UpdateUserRole `json:"body"`
+}
+
+// swagger:parameters ForgotPassword
+type ForgotPasswordParam struct {
+	// in:body
+	// required:true
+	Body model.ForgotPassword `json:"body"`
+}
+
+// swagger:parameters ResetPassword
+type ResetPasswordParam struct {
+	// in:body
+	// required:true
+	Body model.ResetPassword `json:"body"`
+}
+
+// swagger:parameters VerifyEmail
+type VerifyEmailParam struct {
+	// in:body
+	// required:true
+	Body model.VerifyEmail `json:"body"`
+}
+
+// swagger:parameters ConfirmTwoFactor DisableTwoFactor
+type TwoFactorCodeParam struct {
+	// in:body
+	// required:true
+	Body model.TwoFactorCode `json:"body"`
+}
+
+// swagger:parameters OIDCCallback
+type OIDCCallbackParam struct {
+	// The authorization code issued by the provider
+	// in:query
+	// required:true
+	Code string `json:"code"`
+	// The state the login was started with
+	// in:query
+	// required:true
+	State string `json:"state"`
+}
+
+// swagger:parameters VerifyTwoFactor
+type VerifyTwoFactorParam struct {
+	// in:body
+	// required:true
+	Body model.VerifyTwoFactor `json:"body"`
+}
+
+// An EnrollTwoFactorResponse returns new TOTP secret of the user.
+//
+// swagger:response enrollTwoFactorResponse
+type EnrollTwoFactorResponse struct {
+	// The response message
+	// in: body
+	Body model.TwoFactorEnrollment `json:"body"`
+}
+
+// A RecoveryCodesResponse returns recovery codes of enabled two-factor authentication.
+//
+// swagger:response recoveryCodesResponse
+type RecoveryCodesResponse struct {
+	// The response message
+	// in: body
+	Body model.RecoveryCodes `json:"body"`
+}
+
+// swagger:parameters UnlockUser
+type UnlockUserParam struct {
+	// in:path
+	// required:true
+	UserID string `json:"uuid"`
+}
+
+// A ListLockoutsResponse returns current lockouts of sign in to accounts.
+//
+// swagger:response listLockoutsResponse
+type ListLockoutsResponse struct {
+	// The response message
+	// in: body
+	Body []model.Lockout `json:"body"`
+}
+
 // swagger:parameters RefreshToken
 type RefreshTokenParam struct {
 	// in:body
@@ -137,6 +325,90 @@ type RefreshTokenResponse struct {
 	Body model.Tokens `json:"body"`
 }
 
+// A JWKSResponse returns public keys verifying tokens.
+//
+// swagger:response jwksResponse
+type JWKSResponse struct {
+	// in: body
+	Body model.JWKS `json:"body"`
+}
+
+// A ListSessionsResponse returns active sessions of user.
+//
+// swagger:response listSessionsResponse
+type ListSessionsResponse struct {
+	// in: body
+	Body []model.Session `json:"body"`
+}
+
+// swagger:parameters CreateAPIKey
+type CreateAPIKeyParam struct {
+	// in:body
+	// required:true
+	Body model.CreateAPIKey `json:"body"`
+}
+
+// A CreateAPIKeyResponse returns new API key, the key is shown only once.
+//
+// swagger:response createAPIKeyResponse
+type CreateAPIKeyResponse struct {
+	// in: body
+	Body model.CreatedAPIKey `json:"body"`
+}
+
+// A ListAPIKeysResponse returns API keys of user.
+//
+// swagger:response listAPIKeysResponse
+type ListAPIKeysResponse struct {
+	// in: body
+	Body []model.APIKey `json:"body"`
+}
+
+// A ProfileResponse returns the signed in user.
+//
+// swagger:response profileResponse
+type ProfileResponse struct {
+	// in: body
+	Body model.User `json:"body"`
+}
+
+// swagger:parameters UpdateProfile
+type UpdateProfileParam struct {
+	// in:body
+	// required:true
+	Body model.UpdateProfile `json:"body"`
+}
+
+// swagger:parameters ChangePassword
+type ChangePasswordParam struct {
+	// in:body
+	// required:true
+	Body model.ChangePassword `json:"body"`
+}
+
+// A ChangePasswordResponse returns a couple of tokens of new session on the device
+// the password is changed from.
+//
+// swagger:response changePasswordResponse
+type ChangePasswordResponse struct {
+	// in: body
+	Body model.Tokens `json:"body"`
+}
+
+// swagger:parameters DeleteAccount
+type DeleteAccountParam struct {
+	// in:body
+	// required:true
+	Body model.DeleteAccount `json:"body"`
+}
+
+// swagger:parameters DeleteSession DeleteAPIKey
+type SessionIDParam struct {
+	// in:path
+	// required:true
+	ID string `json:"id"`
+}
+
 // An OKResponse is returned if the request was successful.
 //
 // swagger:response okResponse
@@ -146,3 +418,42 @@ type OKResponse struct {
 		Message string `json:"message,omitempty"`
 	}
 }
+
+// swagger:parameters ListUsers
+type ListUsersParam struct {
+	// The maximum number of users in a page, from 1 to 100
+	// in:query
+	// required:false
+	Limit int `json:"limit"`
+	// The opaque cursor returned as nextCursor by the previous page
+	// in:query
+	// required:false
+	Cursor string `json:"cursor"`
+	// The part of email or name of users, case is ignored
+	// in:query
+	// required:false
+	Query string `json:"q"`
+	// The role of users: admin, editor or viewer
+	// in:query
+	// required:false
+	Role string `json:"role"`
+	// Whether users are disabled
+	// in:query
+	// required:false
+	Disabled bool `json:"disabled"`
+}
+
+// A ListUsersResponse returns a page of users.
+//
+// swagger:response listUsersResponse
+type ListUsersResponse struct {
+	// in: body
+	Body model.UsersPage `json:"body"`
+}
+
+// swagger:parameters GetUser DisableUser EnableUser ForceLogout
+type AdminUserParam struct {
+	// in:path
+	// required:true
+	UserID string `json:"uuid"`
+}
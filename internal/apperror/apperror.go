@@ -0,0 +1,87 @@
+// Package apperror represents domain errors shared by application layers
+package apperror
+
+import (
+	"errors"
+	"time"
+
+	"github.com/malkev1ch/first-task/internal/model"
+)
+
+// Kinds of domain errors. Use errors.Is to check the kind of returned error.
+var (
+	// ErrNotFound means that requested object doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means that object conflicts with already existing one.
+	ErrConflict = errors.New("conflict")
+	// ErrUnauthorized means that caller can't be authenticated.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden means that caller isn't allowed to perform the action.
+	ErrForbidden = errors.New("forbidden")
+	// ErrValidation means that input is invalid.
+	ErrValidation = errors.New("validation failed")
+	// ErrTooManyRequests means that caller has to wait before the next attempt.
+	ErrTooManyRequests = errors.New("too many requests")
+	// ErrPreconditionFailed means that object has been changed since the version caller expects.
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Error type represents domain error with human readable message.
+type Error struct {
+	// Kind is one of the kinds of domain errors.
+	Kind error
+	// Message describes the error and is safe to show to the client.
+	Message string
+	// Fields are validation errors of separate fields of the input.
+	Fields []model.FieldError
+	// RetryAfter is the time the caller has to wait before the next attempt.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// NotFound returns error of kind ErrNotFound.
+func NotFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+// Conflict returns error of kind ErrConflict.
+func Conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+// Unauthorized returns error of kind ErrUnauthorized.
+func Unauthorized(message string) error {
+	return &Error{Kind: ErrUnauthorized, Message: message}
+}
+
+// Forbidden returns error of kind ErrForbidden.
+func Forbidden(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+// Validation returns error of kind ErrValidation.
+func Validation(message string) error {
+	return &Error{Kind: ErrValidation, Message: message}
+}
+
+// ValidationFields returns error of kind ErrValidation with errors of separate fields.
+func ValidationFields(message string, fields []model.FieldError) error {
+	return &Error{Kind: ErrValidation, Message: message, Fields: fields}
+}
+
+// PreconditionFailed returns error of kind ErrPreconditionFailed.
+func PreconditionFailed(message string) error {
+	return &Error{Kind: ErrPreconditionFailed, Message: message}
+}
+
+// TooManyRequests returns error of kind ErrTooManyRequests, the caller can retry after retryAfter.
+func TooManyRequests(message string, retryAfter time.Duration) error {
+	return &Error{Kind: ErrTooManyRequests, Message: message, RetryAfter: retryAfter}
+}
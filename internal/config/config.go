@@ -1,15 +1,131 @@
 package config
 
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
 type Config struct {
-	CurrentDB           string `env:"CURRENT_DB" envDefault:"postgres"`
-	PostgresURL         string `env:"POSTGRES_URL"`
-	MongoURL            string `env:"MONGO_URL"`
-	RedisURL            string `env:"REDIS_URL"`
-	ImagePath           string `env:"IMAGE_PATH"`
-	HTTPServer          string `env:"HTTP_SERVER_ADDRESS" envDefault:"localhost:8080"`
-	JWTKey              string `env:"JWT_KEY"`
-	CatsStreamName      string `env:"CATS_STREAM_NAME" envDefault:"cats"`
-	CacheWorkersNum     int    `env:"CACHE_WORKERS_NUM" envDefault:"3"`
-	CatsStreamGroupName string `env:"CATS_CONSUMERS_GROUP_NAME" envDefault:"consumers"`
-	AuthMode            bool   `env:"AUTH_MODE" envDefault:"true"`
+	CurrentDB            string        `env:"CURRENT_DB" envDefault:"postgres"`
+	AutoMigrate          bool          `env:"AUTO_MIGRATE" envDefault:"false"`
+	PostgresURL          string        `env:"POSTGRES_URL"`
+	MongoURL             string        `env:"MONGO_URL"`
+	RedisURL             string        `env:"REDIS_URL"`
+	ImagePath            string        `env:"IMAGE_PATH"`
+	HTTPServer           string        `env:"HTTP_SERVER_ADDRESS" envDefault:"localhost:8080"`
+	JWTKeysFile          string        `env:"JWT_KEYS_FILE"`
+	JWTKeysReload        time.Duration `env:"JWT_KEYS_RELOAD_INTERVAL" envDefault:"1m"`
+	JWTIssuer            string        `env:"JWT_ISSUER" envDefault:"first-task"`
+	JWTAudience          string        `env:"JWT_AUDIENCE" envDefault:"first-task"`
+	AccessTokenTTL       time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL      time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	CatsStreamName       string        `env:"CATS_STREAM_NAME" envDefault:"cats"`
+	CacheWorkersNum      int           `env:"CACHE_WORKERS_NUM" envDefault:"3"`
+	CatsStreamGroupName  string        `env:"CATS_CONSUMERS_GROUP_NAME" envDefault:"consumers"`
+	AuthMode             bool          `env:"AUTH_MODE" envDefault:"true"`
+	MailFrom             string        `env:"MAIL_FROM" envDefault:"no-reply@first-task.local"`
+	MailDir              string        `env:"MAIL_DIR"`
+	SMTPAddress          string        `env:"SMTP_ADDRESS"`
+	SMTPUsername         string        `env:"SMTP_USERNAME"`
+	SMTPPassword         string        `env:"SMTP_PASSWORD"`
+	PasswordResetURL     string        `env:"PASSWORD_RESET_URL"`
+	PasswordResetTTL     time.Duration `env:"PASSWORD_RESET_TTL" envDefault:"1h"`
+	VerificationURL      string        `env:"EMAIL_VERIFICATION_URL"`
+	VerificationTTL      time.Duration `env:"EMAIL_VERIFICATION_TTL" envDefault:"24h"`
+	RequireVerified      bool          `env:"REQUIRE_VERIFIED_EMAIL" envDefault:"false"`
+	LoginMaxAttempts     int64         `env:"LOGIN_MAX_ATTEMPTS" envDefault:"5"`
+	LoginMaxAttemptsIP   int64         `env:"LOGIN_MAX_ATTEMPTS_PER_IP" envDefault:"20"`
+	LoginLockout         time.Duration `env:"LOGIN_LOCKOUT" envDefault:"1m"`
+	LoginMaxLockout      time.Duration `env:"LOGIN_MAX_LOCKOUT" envDefault:"1h"`
+	LoginFailureWindow   time.Duration `env:"LOGIN_FAILURE_WINDOW" envDefault:"1h"`
+	TrustedProxies       Networks      `env:"TRUSTED_PROXIES"`
+	TOTPIssuer           string        `env:"TOTP_ISSUER" envDefault:"first-task"`
+	ChallengeTokenTTL    time.Duration `env:"CHALLENGE_TOKEN_TTL" envDefault:"5m"`
+	PasswordHasher       string        `env:"PASSWORD_HASHER" envDefault:"argon2id"`
+	Argon2Memory         uint32        `env:"ARGON2_MEMORY" envDefault:"65536"`
+	Argon2Iterations     uint32        `env:"ARGON2_ITERATIONS" envDefault:"3"`
+	Argon2Parallelism    uint8         `env:"ARGON2_PARALLELISM" envDefault:"2"`
+	BcryptCost           int           `env:"BCRYPT_COST" envDefault:"10"`
+	PasswordMinLength    int           `env:"PASSWORD_MIN_LENGTH" envDefault:"10"`
+	PasswordMinClasses   int           `env:"PASSWORD_MIN_CLASSES" envDefault:"3"`
+	PasswordRejectCommon bool          `env:"PASSWORD_REJECT_COMMON" envDefault:"true"`
+	OIDCIssuer           string        `env:"OIDC_ISSUER"`
+	OIDCClientID         string        `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret     string        `env:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL      string        `env:"OIDC_REDIRECT_URL"`
+	OIDCScopes           string        `env:"OIDC_SCOPES" envDefault:"openid email profile"`
+	OIDCLoginTTL         time.Duration `env:"OIDC_LOGIN_TTL" envDefault:"10m"`
+	TrashRetention       time.Duration `env:"TRASH_RETENTION" envDefault:"720h"`
+	TrashPurgeInterval   time.Duration `env:"TRASH_PURGE_INTERVAL" envDefault:"1h"`
+}
+
+// redacted replaces secrets in the config returned by Redacted.
+const redacted = "[REDACTED]"
+
+// Redacted returns copy of the config with secrets replaced, so the config can be logged.
+func (c Config) Redacted() Config {
+	if c.SMTPPassword != "" {
+		c.SMTPPassword = redacted
+	}
+	if c.OIDCClientSecret != "" {
+		c.OIDCClientSecret = redacted
+	}
+
+	return c
+}
+
+// Networks type represents IP networks, e.g. of proxies trusted to set X-Forwarded-For.
+type Networks []*net.IPNet
+
+// UnmarshalText method parses comma separated CIDRs or IPs, IP means network of the single address.
+func (n *Networks) UnmarshalText(text []byte) error {
+	networks := make(Networks, 0)
+	for _, value := range strings.Split(string(text), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return fmt.Errorf("invalid IP %q", value)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR %q - %w", value, err)
+		}
+		networks = append(networks, network)
+	}
+	*n = networks
+
+	return nil
+}
+
+// Contains method checks whether the IP belongs to one of the networks.
+func (n Networks) Contains(ip net.IP) bool {
+	for _, network := range n {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String method returns the networks in CIDR notation, so they can be logged.
+func (n Networks) String() string {
+	values := make([]string, 0, len(n))
+	for _, network := range n {
+		values = append(values, network.String())
+	}
+
+	return strings.Join(values, ",")
 }
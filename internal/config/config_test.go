@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedacted(t *testing.T) {
+	cfg := Config{
+		SMTPUsername: "mailer", SMTPPassword: "qwerty123",
+		OIDCClientID: "first-task", OIDCClientSecret: "secret456",
+	}
+
+	logged := fmt.Sprintf("%+v", cfg.Redacted())
+	assert.NotContains(t, logged, "qwerty123")
+	assert.NotContains(t, logged, "secret456")
+	assert.Contains(t, logged, "SMTPUsername:mailer")
+	assert.Contains(t, logged, "OIDCClientID:first-task")
+	assert.Equal(t, "qwerty123", cfg.SMTPPassword, "the config itself isn't changed")
+	// secrets which aren't set stay empty
+	assert.Empty(t, Config{}.Redacted().SMTPPassword)
+	assert.Empty(t, Config{}.Redacted().OIDCClientSecret)
+}
+
+func TestNetworks(t *testing.T) {
+	var networks Networks
+	require.NoError(t, networks.UnmarshalText([]byte("10.0.0.0/8, 192.0.2.1,2001:db8::/32,")))
+	assert.Equal(t, "10.0.0.0/8,192.0.2.1/32,2001:db8::/32", networks.String())
+	assert.True(t, networks.Contains(net.ParseIP("10.1.2.3")))
+	assert.True(t, networks.Contains(net.ParseIP("192.0.2.1")))
+	assert.True(t, networks.Contains(net.ParseIP("2001:db8::1")))
+	assert.False(t, networks.Contains(net.ParseIP("192.0.2.2")))
+	assert.False(t, networks.Contains(nil))
+
+	require.NoError(t, networks.UnmarshalText(nil))
+	assert.Empty(t, networks)
+	for _, value := range []string{"qwerty", "10.0.0.0/33", "192.0.2.1/"} {
+		assert.Error(t, networks.UnmarshalText([]byte(value)), value)
+	}
+}
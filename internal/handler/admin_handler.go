@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultUsersLimit = 20
+	maxUsersLimit     = 100
+)
+
+//	swagger:route GET /admin/users admin ListUsers
+//
+//	List users.
+//
+//	Returns a page of users sorted by email and filtered by the query parameters.
+//	Only admins can list users.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: listUsersResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) ListUsers(ctx echo.Context) error {
+	input, err := parseListUsers(ctx)
+	if err != nil {
+		logrus.Error("handler: invalid query parameters - ", err)
+		return err
+	}
+
+	page, err := h.Services.ListUsers(ctx.Request().Context(), input)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, *page)
+}
+
+// parseListUsers reads search and pagination of users from query parameters.
+func parseListUsers(ctx echo.Context) (*model.ListUsers, error) {
+	input := &model.ListUsers{
+		Limit:  defaultUsersLimit,
+		Cursor: ctx.QueryParam("cursor"),
+		Query:  ctx.QueryParam("q"),
+		Role:   ctx.QueryParam("role"),
+	}
+
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxUsersLimit {
+			return nil, apperror.Validation(fmt.Sprintf("limit should be a number from 1 to %d", maxUsersLimit))
+		}
+		input.Limit = value
+	}
+
+	if disabled := ctx.QueryParam("disabled"); disabled != "" {
+		value, err := strconv.ParseBool(disabled)
+		if err != nil {
+			return nil, apperror.Validation("disabled should be true or false")
+		}
+		input.Disabled = &value
+	}
+
+	return input, nil
+}
+
+//	swagger:route GET /admin/users/{uuid} admin GetUser
+//
+//	Get user.
+//
+//	Returns the user with the given UUID. Only admins can view users.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: profileResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 500: internalServerError
+func (h *Handler) GetUser(ctx echo.Context) error {
+	user, err := h.Services.GetUser(ctx.Request().Context(), ctx.Param("uuid"))
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, user)
+}
+
+//	swagger:route POST /admin/users/{uuid}/disable admin DisableUser
+//
+//	Disable user.
+//
+//	Disables the user with the given UUID and ends all sessions of the user.
+//	Disabled users can't sign in, refresh tokens or use API keys until enabled.
+//	Admins can't disable themselves.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 409: conflictError
+//	 500: internalServerError
+func (h *Handler) DisableUser(ctx echo.Context) error {
+	if err := h.Services.DisableUser(ctx.Request().Context(), userID(ctx), ctx.Param("uuid")); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route POST /admin/users/{uuid}/enable admin EnableUser
+//
+//	Enable user.
+//
+//	Allows the disabled user with the given UUID to sign in again.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 500: internalServerError
+func (h *Handler) EnableUser(ctx echo.Context) error {
+	if err := h.Services.EnableUser(ctx.Request().Context(), ctx.Param("uuid")); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route POST /admin/users/{uuid}/logout admin ForceLogout
+//
+//	Log out user.
+//
+//	Ends all sessions of the user with the given UUID and revokes its access tokens.
+//	The user can sign in again.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 500: internalServerError
+func (h *Handler) ForceLogout(ctx echo.Context) error {
+	if err := h.Services.ForceLogout(ctx.Request().Context(), ctx.Param("uuid")); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route PUT /admin/users/{uuid}/role admin SetUserRole
+//
+//	Set role of user.
+//
+//	Assigns role to the user with the given UUID. The role gets into tokens
+//	at the next sign in or refresh of tokens. Admins can't change their own role.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 400: badRequestError
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 409: conflictError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) SetUserRole(ctx echo.Context) error {
+	id := ctx.Param("uuid")
+	var input model.UpdateUserRole
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	if err := h.Services.SetUserRole(ctx.Request().Context(), userID(ctx), id, input.Role); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route GET /admin/users/lockouts admin ListLockouts
+//
+//	List lockouts of users.
+//
+//	Returns accounts sign in to which is locked after too many failed attempts.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: listLockoutsResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 500: internalServerError
+func (h *Handler) ListLockouts(ctx echo.Context) error {
+	lockouts, err := h.Services.ListLockouts(ctx.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, lockouts)
+}
+
+//	swagger:route DELETE /admin/users/{uuid}/lockout admin UnlockUser
+//
+//	Unlock user.
+//
+//	Ends lockout of sign in to the account of the user with the given UUID and forgets
+//	its failed attempts.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 500: internalServerError
+func (h *Handler) UnlockUser(ctx echo.Context) error {
+	if err := h.Services.UnlockUser(ctx.Request().Context(), ctx.Param("uuid")); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
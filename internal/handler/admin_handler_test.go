@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/repository"
+	"github.com/malkev1ch/first-task/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminUsers(t *testing.T) {
+	cfg := newAuthConfig(true)
+	repo := repository.NewRepositoryMemory()
+	services := &service.Service{Auth: newAuthServiceWith(&cfg, repo)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	signIn := func(email string, out interface{}) int {
+		t.Helper()
+		return sendJSON(t, r, http.MethodPost, "/auth/sign-in",
+			`{"email":"`+email+`","password":"ZAQ!2wsx3edc"}`, "", out)
+	}
+
+	var adminTokens, userTokens model.Tokens
+	for _, email := range []string{"admin@gmail.com", "qwerty@gmail.com"} {
+		require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+			`{"userName":"Some name","email":"`+email+`","password":"ZAQ!2wsx3edc"}`, "", nil))
+	}
+	page, err := repo.Auth.ListUsers(context.Background(), &model.ListUsers{Limit: 10, Query: "admin"})
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	admin := page.Users[0]
+	require.NoError(t, repo.Auth.UpdateUserRole(context.Background(), admin.ID, model.RoleAdmin))
+	require.Equal(t, http.StatusOK, signIn("admin@gmail.com", &adminTokens))
+	require.Equal(t, http.StatusOK, signIn("qwerty@gmail.com", &userTokens))
+
+	// only admins manage users
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodGet, "/admin/users", "", "", nil))
+	assert.Equal(t, http.StatusForbidden, sendJSON(t, r, http.MethodGet, "/admin/users", "", userTokens.AccessToken, nil))
+
+	var first, second model.UsersPage
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/admin/users?q=GMAIL&limit=1", "",
+		adminTokens.AccessToken, &first))
+	require.Len(t, first.Users, 1)
+	assert.Equal(t, "admin@gmail.com", first.Users[0].Email)
+	require.NotEmpty(t, first.NextCursor)
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/admin/users?q=GMAIL&limit=1&cursor="+first.NextCursor, "",
+		adminTokens.AccessToken, &second))
+	require.Len(t, second.Users, 1)
+	assert.Equal(t, "qwerty@gmail.com", second.Users[0].Email)
+	assert.Empty(t, second.NextCursor)
+	user := second.Users[0]
+
+	for _, query := range []string{"limit=0", "disabled=maybe", "role=owner", "cursor=qwerty"} {
+		assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(t, r, http.MethodGet, "/admin/users?"+query, "",
+			adminTokens.AccessToken, nil), query)
+	}
+
+	var actual model.User
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/admin/users/"+user.ID, "",
+		adminTokens.AccessToken, &actual))
+	assert.Equal(t, *user, actual)
+	unknown := uuid.New().String()
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodGet, "/admin/users/"+unknown, "",
+		adminTokens.AccessToken, nil))
+
+	assert.Equal(t, http.StatusConflict, sendJSON(t, r, http.MethodPost, "/admin/users/"+admin.ID+"/disable", "",
+		adminTokens.AccessToken, nil))
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodPost, "/admin/users/"+unknown+"/disable", "",
+		adminTokens.AccessToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/admin/users/"+user.ID+"/disable", "",
+		adminTokens.AccessToken, nil))
+
+	// disabled user loses access at once and can't get it back
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodGet, "/users/me", "", userTokens.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodPost, "/auth/refresh",
+		`{"refreshToken":"`+userTokens.RefreshToken+`"}`, "", nil))
+	assert.Equal(t, http.StatusForbidden, signIn("qwerty@gmail.com", nil))
+
+	var disabled model.UsersPage
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/admin/users?disabled=true", "",
+		adminTokens.AccessToken, &disabled))
+	require.Len(t, disabled.Users, 1)
+	assert.Equal(t, user.ID, disabled.Users[0].ID)
+	assert.True(t, disabled.Users[0].Disabled)
+
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/admin/users/"+user.ID+"/enable", "",
+		adminTokens.AccessToken, nil))
+	require.Equal(t, http.StatusOK, signIn("qwerty@gmail.com", &userTokens))
+
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodPost, "/admin/users/"+unknown+"/logout", "",
+		adminTokens.AccessToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/admin/users/"+user.ID+"/logout", "",
+		adminTokens.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodGet, "/users/me", "", userTokens.AccessToken, nil))
+	// forced logout doesn't disable the user
+	assert.Equal(t, http.StatusOK, signIn("qwerty@gmail.com", nil))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(t, r, http.MethodPut, "/admin/users/"+user.ID+"/role",
+		`{"role":"owner"}`, adminTokens.AccessToken, nil))
+	assert.Equal(t, http.StatusConflict, sendJSON(t, r, http.MethodPut, "/admin/users/"+admin.ID+"/role",
+		`{"role":"viewer"}`, adminTokens.AccessToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPut, "/admin/users/"+user.ID+"/role",
+		`{"role":"viewer"}`, adminTokens.AccessToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/admin/users/"+user.ID, "",
+		adminTokens.AccessToken, &actual))
+	assert.Equal(t, model.RoleViewer, actual.Role)
+}
+
+func TestAdminUsersWithoutAuthMode(t *testing.T) {
+	cfg := newAuthConfig(false)
+	repo := repository.NewRepositoryMemory()
+	services := &service.Service{Auth: newAuthServiceWith(&cfg, repo)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+
+	var adminTokens, userTokens model.Tokens
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"admin@gmail.com","password":"ZAQ!2wsx3edc"}`, "", nil))
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &userTokens))
+	page, err := repo.Auth.ListUsers(context.Background(), &model.ListUsers{Limit: 10, Query: "admin"})
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	require.NoError(t, repo.Auth.UpdateUserRole(context.Background(), page.Users[0].ID, model.RoleAdmin))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/sign-in",
+		`{"email":"admin@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &adminTokens))
+	var claims service.JwtCustomClaims
+	_, _, err = new(jwt.Parser).ParseUnverified(userTokens.AccessToken, &claims)
+	require.NoError(t, err)
+
+	// disabled authentication of cats API doesn't open users to everyone
+	for _, route := range []struct{ method, target, body string }{
+		{http.MethodGet, "/admin/users", ""},
+		{http.MethodGet, "/admin/users/lockouts", ""},
+		{http.MethodPost, "/admin/users/" + page.Users[0].ID + "/disable", ""},
+		{http.MethodPost, "/admin/users/" + page.Users[0].ID + "/logout", ""},
+		{http.MethodPut, "/admin/users/" + claims.Subject + "/role", `{"role":"admin"}`},
+	} {
+		assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, route.method, route.target, route.body, "", nil),
+			route.target)
+		assert.Equal(t, http.StatusForbidden, sendJSON(t, r, route.method, route.target, route.body,
+			userTokens.AccessToken, nil), route.target)
+	}
+
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/admin/users/lockouts", "",
+		adminTokens.AccessToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPut, "/admin/users/"+claims.Subject+"/role",
+		`{"role":"editor"}`, adminTokens.AccessToken, nil))
+}
@@ -1,53 +1,55 @@
 package handler
 
 import (
+	"crypto/subtle"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/config"
 	"github.com/malkev1ch/first-task/internal/model"
 	"github.com/sirupsen/logrus"
 )
 
+const oidcStateCookieName = "oidc_state"
+
 //	swagger:route POST /auth/sign-up auth SignUp
 //
 //	Registration process for new user
 //
-//	Returns a couple of tokens for recently created user.
+//	Returns a couple of tokens for recently created user. The password has to satisfy
+//	the password policy, broken rules are listed in errors of the response.
 //
 //	responses:
 //	 201: signUpResponse
 //	 400: badRequestError
+//	 409: conflictError
 //	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
 //	 500: internalServerError
 func (h *Handler) SignUp(ctx echo.Context) error {
 	contentType := ctx.Request().Header.Get("Content-Type")
 	if _, ex := AllowedContentType[contentType]; !ex {
-		return ctx.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
-			Message: "invalid media type", Error: fmt.Sprintf("got - %s", contentType),
-		})
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid media type, got - %s", contentType))
 	}
 
 	var input model.CreateUser
 	if err := ctx.Bind(&input); err != nil {
 		logrus.Error("handler: invalid content of body - ", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "invalid body content", Error: err.Error(),
-		})
+		return err
 	}
 
 	if err := h.Validator.Validate(&input); err != nil {
 		logrus.Error("handler: validation failed - ", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "not enough fields in json body or wrong values of fields", Error: err.Error(),
-		})
+		return err
 	}
 
-	tokens, err := h.Services.SignUp(ctx.Request().Context(), &input)
+	tokens, err := h.Services.SignUp(ctx.Request().Context(), &input, h.device(ctx))
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "can't create user", Error: err.Error(),
-		})
+		return err
 	}
 
 	return ctx.JSON(http.StatusCreated, *tokens)
@@ -57,41 +59,38 @@ func (h *Handler) SignUp(ctx echo.Context) error {
 //
 //	Authorisation process for existed user
 //
-//	Returns a couple of tokens for existed user.
+//	Returns a couple of tokens for existed user. Sign in to the account or from the IP
+//	is locked for a while after too many failed attempts. Users with two-factor authentication
+//	get only challenge token, the couple is returned by POST /auth/2fa/verify.
 //
 //	responses:
 //	 200: signInResponse
 //	 400: badRequestError
+//	 401: unauthorizedError
 //	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
+//	 429: tooManyRequestsError
 //	 500: internalServerError
 func (h *Handler) SignIn(ctx echo.Context) error {
 	contentType := ctx.Request().Header.Get("Content-Type")
 	if _, ex := AllowedContentType[contentType]; !ex {
-		return ctx.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
-			Message: "invalid media type", Error: fmt.Sprintf("got - %s", contentType),
-		})
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid media type, got - %s", contentType))
 	}
 
 	var input model.AuthUser
 	if err := ctx.Bind(&input); err != nil {
 		logrus.Error("handler: invalid content of body - ", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "invalid body content", Error: err.Error(),
-		})
+		return err
 	}
 
 	if err := h.Validator.Validate(&input); err != nil {
 		logrus.Error("handler: validation failed - ", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "not enough fields in json body or wrong values of fields", Error: err.Error(),
-		})
+		return err
 	}
 
-	tokens, err := h.Services.SignIn(ctx.Request().Context(), &input)
+	tokens, err := h.Services.SignIn(ctx.Request().Context(), &input, h.device(ctx))
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "authorisation failed", Error: err.Error(),
-		})
+		return err
 	}
 	return ctx.JSON(http.StatusOK, *tokens)
 }
@@ -100,41 +99,647 @@ func (h *Handler) SignIn(ctx echo.Context) error {
 //
 //	Refresh a couple tokens used refresh token
 //
-//	Returns a couple tokens for existed user.
+//	Returns a couple tokens for existed user. The refresh token can be used only once,
+//	reuse of it ends the session the token belongs to.
 //
 //	responses:
 //	 200: refreshTokenResponse
 //	 400: badRequestError
+//	 401: unauthorizedError
 //	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
 //	 500: internalServerError
 func (h *Handler) RefreshToken(ctx echo.Context) error {
 	contentType := ctx.Request().Header.Get("Content-Type")
 	if _, ex := AllowedContentType[contentType]; !ex {
-		return ctx.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
-			Message: "invalid media type", Error: fmt.Sprintf("got - %s", contentType),
-		})
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid media type, got - %s", contentType))
 	}
 
 	var input model.RefreshToken
 	if err := ctx.Bind(&input); err != nil {
 		logrus.Error("handler: invalid content of body - ", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "invalid body content", Error: err.Error(),
-		})
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	tokens, err := h.Services.RefreshToken(ctx.Request().Context(), input.RefreshToken, h.device(ctx))
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, *tokens)
+}
+
+//	swagger:route POST /auth/password/forgot auth ForgotPassword
+//
+//	Request reset of forgotten password.
+//
+//	Sends email with single-use reset token to the user. The response is the same
+//	whether the email is registered or not.
+//
+//	responses:
+//	 200: okResponse
+//	 400: badRequestError
+//	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) ForgotPassword(ctx echo.Context) error {
+	contentType := ctx.Request().Header.Get("Content-Type")
+	if _, ex := AllowedContentType[contentType]; !ex {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid media type, got - %s", contentType))
+	}
+
+	var input model.ForgotPassword
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	if err := h.Services.ForgotPassword(ctx.Request().Context(), input.Email); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "If the email is registered, a reset token has been sent to it",
+	})
+}
+
+//	swagger:route POST /auth/password/reset auth ResetPassword
+//
+//	Reset password.
+//
+//	Sets a new password using the reset token sent by email. The token can be used only once,
+//	all sessions of the user are ended. The password has to satisfy the password policy.
+//
+//	responses:
+//	 200: okResponse
+//	 400: badRequestError
+//	 401: unauthorizedError
+//	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) ResetPassword(ctx echo.Context) error {
+	contentType := ctx.Request().Header.Get("Content-Type")
+	if _, ex := AllowedContentType[contentType]; !ex {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid media type, got - %s", contentType))
+	}
+
+	var input model.ResetPassword
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	if err := h.Services.ResetPassword(ctx.Request().Context(), &input); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route POST /auth/verify auth VerifyEmail
+//
+//	Verify email.
+//
+//	Marks email of the user as verified using the verification token sent to it on sign up.
+//	The token can be used only once, the state gets into tokens at the next refresh.
+//
+//	responses:
+//	 200: okResponse
+//	 400: badRequestError
+//	 401: unauthorizedError
+//	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) VerifyEmail(ctx echo.Context) error {
+	contentType := ctx.Request().Header.Get("Content-Type")
+	if _, ex := AllowedContentType[contentType]; !ex {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid media type, got - %s", contentType))
+	}
+
+	var input model.VerifyEmail
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	if err := h.Services.VerifyEmail(ctx.Request().Context(), input.Token); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route POST /auth/2fa/enroll auth EnrollTwoFactor
+//
+//	Enroll two-factor authentication.
+//
+//	Returns new TOTP secret of the user the access token belongs to. Two-factor authentication
+//	is enabled after the secret is confirmed with a code from authenticator app.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: enrollTwoFactorResponse
+//	 401: unauthorizedError
+//	 409: conflictError
+//	 500: internalServerError
+func (h *Handler) EnrollTwoFactor(ctx echo.Context) error {
+	enrollment, err := h.Services.EnrollTwoFactor(ctx.Request().Context(), userID(ctx))
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, enrollment)
+}
+
+//	swagger:route POST /auth/2fa/confirm auth ConfirmTwoFactor
+//
+//	Confirm two-factor authentication.
+//
+//	Enables two-factor authentication if the code matches the enrolled secret and returns
+//	single-use recovery codes, they are shown only once.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: recoveryCodesResponse
+//	 400: badRequestError
+//	 401: unauthorizedError
+//	 409: conflictError
+//	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) ConfirmTwoFactor(ctx echo.Context) error {
+	contentType := ctx.Request().Header.Get("Content-Type")
+	if _, ex := AllowedContentType[contentType]; !ex {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid media type, got - %s", contentType))
+	}
+
+	var input model.TwoFactorCode
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	codes, err := h.Services.ConfirmTwoFactor(ctx.Request().Context(), userID(ctx), input.Code)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, codes)
+}
+
+//	swagger:route POST /auth/2fa/disable auth DisableTwoFactor
+//
+//	Disable two-factor authentication.
+//
+//	Disables two-factor authentication of the user, a code from authenticator app
+//	or a recovery code is required.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 400: badRequestError
+//	 401: unauthorizedError
+//	 409: conflictError
+//	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) DisableTwoFactor(ctx echo.Context) error {
+	contentType := ctx.Request().Header.Get("Content-Type")
+	if _, ex := AllowedContentType[contentType]; !ex {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid media type, got - %s", contentType))
+	}
+
+	var input model.TwoFactorCode
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	if err := h.Services.DisableTwoFactor(ctx.Request().Context(), userID(ctx), input.Code); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route POST /auth/2fa/verify auth VerifyTwoFactor
+//
+//	Pass two-factor challenge of sign in.
+//
+//	Returns a couple of tokens for the challenge token of sign in and a code from authenticator
+//	app or a recovery code. Wrong codes count as failed sign in attempts.
+//
+//	responses:
+//	 200: signInResponse
+//	 400: badRequestError
+//	 401: unauthorizedError
+//	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
+//	 429: tooManyRequestsError
+//	 500: internalServerError
+func (h *Handler) VerifyTwoFactor(ctx echo.Context) error {
+	contentType := ctx.Request().Header.Get("Content-Type")
+	if _, ex := AllowedContentType[contentType]; !ex {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid media type, got - %s", contentType))
+	}
+
+	var input model.VerifyTwoFactor
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	tokens, err := h.Services.VerifyTwoFactor(ctx.Request().Context(), &input, h.device(ctx))
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, *tokens)
+}
+
+//	swagger:route POST /auth/verify/resend auth ResendVerification
+//
+//	Resend verification email.
+//
+//	Sends email with a new verification token to the user the access token belongs to.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 401: unauthorizedError
+//	 409: conflictError
+//	 500: internalServerError
+func (h *Handler) ResendVerification(ctx echo.Context) error {
+	if err := h.Services.ResendVerification(ctx.Request().Context(), userID(ctx)); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route POST /auth/logout auth Logout
+//
+//	Logout of user.
+//
+//	Ends the session of the access token and revokes the token.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 401: unauthorizedError
+//	 500: internalServerError
+func (h *Handler) Logout(ctx echo.Context) error {
+	if err := h.Services.Logout(ctx.Request().Context(), userClaims(ctx)); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route POST /auth/logout-all auth LogoutEverywhere
+//
+//	Logout of user on all devices.
+//
+//	Ends all sessions of the user and revokes all access tokens issued to the user.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 401: unauthorizedError
+//	 500: internalServerError
+func (h *Handler) LogoutEverywhere(ctx echo.Context) error {
+	if err := h.Services.LogoutEverywhere(ctx.Request().Context(), userID(ctx)); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route GET /.well-known/jwks.json auth JWKS
+//
+//	Public keys verifying tokens.
+//
+//	Returns public keys tokens are signed with in JSON Web Key Set format,
+//	the kid header of a token is the ID of its key.
+//
+//	responses:
+//	 200: jwksResponse
+func (h *Handler) JWKS(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, h.Services.JWKS())
+}
+
+//	swagger:route GET /auth/sessions auth ListSessions
+//
+//	List sessions of user.
+//
+//	Returns active sessions of the user the access token belongs to, one per signed in device.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: listSessionsResponse
+//	 401: unauthorizedError
+//	 500: internalServerError
+func (h *Handler) ListSessions(ctx echo.Context) error {
+	claims := userClaims(ctx)
+	sessions, err := h.Services.ListSessions(ctx.Request().Context(), claims.Subject)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == claims.SessionID
+	}
+
+	return ctx.JSON(http.StatusOK, sessions)
+}
+
+//	swagger:route DELETE /auth/sessions/{id} auth DeleteSession
+//
+//	End session of user.
+//
+//	Ends the session with the given UUID, its refresh token can't be used anymore.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 401: unauthorizedError
+//	 404: notFoundError
+//	 500: internalServerError
+func (h *Handler) DeleteSession(ctx echo.Context) error {
+	id := ctx.Param("id")
+	if err := h.Services.DeleteSession(ctx.Request().Context(), userID(ctx), id); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route POST /auth/api-keys auth CreateAPIKey
+//
+//	Create API key.
+//
+//	Issues API key of the user the access token belongs to. Services send the key in X-API-Key
+//	header to call cats API on behalf of the user within scopes of the key. The key is shown only once.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 201: createAPIKeyResponse
+//	 400: badRequestError
+//	 401: unauthorizedError
+//	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) CreateAPIKey(ctx echo.Context) error {
+	contentType := ctx.Request().Header.Get("Content-Type")
+	if _, ex := AllowedContentType[contentType]; !ex {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid media type, got - %s", contentType))
+	}
+
+	var input model.CreateAPIKey
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	key, err := h.Services.CreateAPIKey(ctx.Request().Context(), userID(ctx), &input)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusCreated, key)
+}
+
+//	swagger:route GET /auth/api-keys auth ListAPIKeys
+//
+//	List API keys of user.
+//
+//	Returns API keys of the user the access token belongs to including expired ones,
+//	only beginnings of the keys are shown.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: listAPIKeysResponse
+//	 401: unauthorizedError
+//	 500: internalServerError
+func (h *Handler) ListAPIKeys(ctx echo.Context) error {
+	keys, err := h.Services.ListAPIKeys(ctx.Request().Context(), userID(ctx))
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, keys)
+}
+
+//	swagger:route DELETE /auth/api-keys/{id} auth DeleteAPIKey
+//
+//	Revoke API key of user.
+//
+//	Revokes the API key with the given UUID, requests with it are rejected at once.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 401: unauthorizedError
+//	 404: notFoundError
+//	 500: internalServerError
+func (h *Handler) DeleteAPIKey(ctx echo.Context) error {
+	if err := h.Services.DeleteAPIKey(ctx.Request().Context(), userID(ctx), ctx.Param("id")); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
+
+//	swagger:route GET /auth/oidc/login auth OIDCLogin
+//
+//	Sign in with OpenID Connect provider.
+//
+//	Redirects to the provider the user signs in at, the provider redirects back
+//	to GET /auth/oidc/callback. The login is bound to the browser by a cookie.
+//
+//	responses:
+//	 302: oidcRedirectResponse
+//	 404: notFoundError
+//	 500: internalServerError
+func (h *Handler) OIDCLogin(ctx echo.Context) error {
+	authURL, state, err := h.Services.OIDCLogin(ctx.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	ctx.SetCookie(h.oidcStateCookie(ctx, state, int(h.Cfg.OIDCLoginTTL.Seconds())))
+	return ctx.Redirect(http.StatusFound, authURL)
+}
+
+//	swagger:route GET /auth/oidc/callback auth OIDCCallback
+//
+//	Finish sign in with OpenID Connect provider.
+//
+//	Returns a couple of tokens for the user linked to the identity at the provider. At the first
+//	login the identity is linked to the user with the same email verified by both or new user is created.
+//
+//	responses:
+//	 200: signInResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 409: conflictError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) OIDCCallback(ctx echo.Context) error {
+	if providerError := ctx.QueryParam("error"); providerError != "" {
+		logrus.Error("handler: OIDC provider returned error - ", providerError)
+		return apperror.Unauthorized("OIDC login failed")
 	}
 
+	input := model.OIDCCallback{
+		Code:  ctx.QueryParam("code"),
+		State: ctx.QueryParam("state"),
+	}
 	if err := h.Validator.Validate(&input); err != nil {
 		logrus.Error("handler: validation failed - ", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "not enough fields", Error: err.Error(),
-		})
+		return err
+	}
+
+	// the state has to come back to the browser the login was started in, otherwise
+	// the callback link of an attacker would sign the victim in to the attacker's account
+	cookie, err := ctx.Cookie(oidcStateCookieName)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(input.State)) != 1 {
+		logrus.Error("handler: OIDC state doesn't match the cookie")
+		return apperror.Unauthorized("invalid or expired OIDC login state")
 	}
+	ctx.SetCookie(h.oidcStateCookie(ctx, "", -1))
 
-	tokens, err := h.Services.RefreshToken(ctx.Request().Context(), input.RefreshToken)
+	tokens, err := h.Services.OIDCCallback(ctx.Request().Context(), &input, h.device(ctx))
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "failed refresh token", Error: err.Error(),
-		})
+		return err
 	}
 	return ctx.JSON(http.StatusOK, *tokens)
 }
+
+// oidcStateCookie returns cookie with the state of OIDC login, negative maxAge deletes the cookie.
+func (h *Handler) oidcStateCookie(ctx echo.Context, state string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     "/auth/oidc",
+		MaxAge:   maxAge,
+		Secure:   ctx.Scheme() == "https",
+		HttpOnly: true,
+		// the provider redirects back with top-level navigation, Lax cookies are sent with it
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// device returns the device the request is sent from.
+func (h *Handler) device(ctx echo.Context) *model.Device {
+	return &model.Device{
+		UserAgent: ctx.Request().UserAgent(),
+		IP:        clientIP(ctx.Request(), h.Cfg.TrustedProxies),
+	}
+}
+
+// clientIP returns IP of the client the request is sent from. X-Forwarded-For is read only if the request
+// comes from one of the proxies, because clients can set it to any address to get around the limit of failed
+// sign in attempts or to lock out someone else. Proxies append to the header, so the client is the last
+// address in it which isn't of a proxy.
+func clientIP(req *http.Request, proxies config.Networks) string {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+	if !proxies.Contains(net.ParseIP(ip)) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(req.Header.Values(echo.HeaderXForwardedFor), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		address := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if address == nil {
+			// the rest of the header can't be trusted
+			break
+		}
+		ip = address.String()
+		if !proxies.Contains(address) {
+			break
+		}
+	}
+
+	return ip
+}
@@ -3,19 +3,39 @@ package handler
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/apperror"
 	"github.com/malkev1ch/first-task/internal/config"
 	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/oidc/oidctest"
+	"github.com/malkev1ch/first-task/internal/repository"
 	"github.com/malkev1ch/first-task/internal/service"
 	mock_service "github.com/malkev1ch/first-task/internal/service/mocks"
+	"github.com/malkev1ch/first-task/internal/totp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// testDevice is the device httptest sends requests from.
+var testDevice = &model.Device{IP: "192.0.2.1"}
+
 func TestSignUp(t *testing.T) {
 	type mockBehavior func(s *mock_service.MockAuth, input *model.CreateUser)
 	ctx := context.Background()
@@ -30,7 +50,7 @@ func TestSignUp(t *testing.T) {
 		{
 			name: "OK",
 			mockBehavior: func(s *mock_service.MockAuth, input *model.CreateUser) {
-				s.EXPECT().SignUp(ctx, input).Return(&model.Tokens{
+				s.EXPECT().SignUp(ctx, input, testDevice).Return(&model.Tokens{
 					RefreshToken: "qwerty",
 					AccessToken:  "qwerty",
 				}, nil)
@@ -47,7 +67,7 @@ func TestSignUp(t *testing.T) {
 		{
 			name: "Service Error",
 			mockBehavior: func(s *mock_service.MockAuth, input *model.CreateUser) {
-				s.EXPECT().SignUp(ctx, input).Return(nil, errors.New("service error"))
+				s.EXPECT().SignUp(ctx, input, testDevice).Return(nil, errors.New("service error"))
 			},
 			ctx:       ctx,
 			inputBody: `{"email":"qwerty@gmail.com", "password":"ZAQ!2wsxCDE#", "userName":"Some name"}`,
@@ -59,12 +79,33 @@ func TestSignUp(t *testing.T) {
 			expectedStatusCode: http.StatusInternalServerError,
 		},
 		{
-			name:               "Invalid Password",
-			mockBehavior:       func(s *mock_service.MockAuth, input *model.CreateUser) {},
-			ctx:                ctx,
-			inputBody:          `{"email":"qwerty@gmail.com", "password":"ZAQ!", "userName":"Some name"}`,
-			inputUser:          nil,
-			expectedStatusCode: http.StatusBadRequest,
+			name: "User with given email exists",
+			mockBehavior: func(s *mock_service.MockAuth, input *model.CreateUser) {
+				s.EXPECT().SignUp(ctx, input, testDevice).Return(nil, apperror.Conflict("user with given email exists"))
+			},
+			ctx:       ctx,
+			inputBody: `{"email":"qwerty@gmail.com", "password":"ZAQ!2wsxCDE#", "userName":"Some name"}`,
+			inputUser: &model.CreateUser{
+				UserName: "Some name",
+				Email:    "qwerty@gmail.com",
+				Password: "ZAQ!2wsxCDE#",
+			},
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name: "Invalid Password",
+			mockBehavior: func(s *mock_service.MockAuth, input *model.CreateUser) {
+				s.EXPECT().SignUp(ctx, input, testDevice).Return(nil, apperror.ValidationFields("validation failed",
+					[]model.FieldError{{Field: "password", Message: "must be at least 10 characters long"}}))
+			},
+			ctx:       ctx,
+			inputBody: `{"email":"qwerty@gmail.com", "password":"ZAQ!", "userName":"Some name"}`,
+			inputUser: &model.CreateUser{
+				UserName: "Some name",
+				Email:    "qwerty@gmail.com",
+				Password: "ZAQ!",
+			},
+			expectedStatusCode: http.StatusUnprocessableEntity,
 		},
 		{
 			name:               "Invalid email",
@@ -72,7 +113,7 @@ func TestSignUp(t *testing.T) {
 			ctx:                ctx,
 			inputBody:          `{"email":"qwertygmail.com", "password":"ZAQ!2wsxCDE#",  "userName":"Some name"}`,
 			inputUser:          nil,
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusUnprocessableEntity,
 		},
 		{
 			name:               "Request without email",
@@ -80,7 +121,7 @@ func TestSignUp(t *testing.T) {
 			ctx:                ctx,
 			inputBody:          `{"password":"ZAQ!2wsxCDE#", "userName":"Some name"}`,
 			inputUser:          nil,
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusUnprocessableEntity,
 		},
 		{
 			name:               "Request without password",
@@ -88,7 +129,7 @@ func TestSignUp(t *testing.T) {
 			ctx:                ctx,
 			inputBody:          `{"email":"qwerty@gmail.com", "userName":"Some name"}`,
 			inputUser:          nil,
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusUnprocessableEntity,
 		},
 	}
 
@@ -137,7 +178,7 @@ func TestSignIn(t *testing.T) {
 		{
 			name: "OK",
 			mockBehavior: func(s *mock_service.MockAuth, input *model.AuthUser) {
-				s.EXPECT().SignIn(ctx, input).Return(&model.Tokens{
+				s.EXPECT().SignIn(ctx, input, testDevice).Return(&model.Tokens{
 					RefreshToken: "qwerty",
 					AccessToken:  "qwerty",
 				}, nil)
@@ -153,7 +194,7 @@ func TestSignIn(t *testing.T) {
 		{
 			name: "Service Error",
 			mockBehavior: func(s *mock_service.MockAuth, input *model.AuthUser) {
-				s.EXPECT().SignIn(ctx, input).Return(nil, errors.New("service error"))
+				s.EXPECT().SignIn(ctx, input, testDevice).Return(nil, errors.New("service error"))
 			},
 			ctx:       ctx,
 			inputBody: `{"email":"qwerty@gmail.com", "password":"ZAQ!2wsxCDE#", "userName":"Some name"}`,
@@ -164,12 +205,25 @@ func TestSignIn(t *testing.T) {
 			expectedStatusCode: http.StatusInternalServerError,
 		},
 		{
-			name:               "Invalid Password",
+			name: "Incorrect password",
+			mockBehavior: func(s *mock_service.MockAuth, input *model.AuthUser) {
+				s.EXPECT().SignIn(ctx, input, testDevice).Return(nil, apperror.Unauthorized("incorrect password"))
+			},
+			ctx:       ctx,
+			inputBody: `{"email":"qwerty@gmail.com", "password":"ZAQ!2wsxCDE#", "userName":"Some name"}`,
+			inputUser: &model.AuthUser{
+				Email:    "qwerty@gmail.com",
+				Password: "ZAQ!2wsxCDE#",
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Too long password",
 			mockBehavior:       func(s *mock_service.MockAuth, input *model.AuthUser) {},
 			ctx:                ctx,
-			inputBody:          `{"email":"qwerty@gmail.com", "password":"ZAQ!", "userName":"Some name"}`,
+			inputBody:          `{"email":"qwerty@gmail.com", "password":"` + strings.Repeat("ZAQ!", 33) + `"}`,
 			inputUser:          nil,
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusUnprocessableEntity,
 		},
 		{
 			name:               "Invalid email",
@@ -177,7 +231,7 @@ func TestSignIn(t *testing.T) {
 			ctx:                ctx,
 			inputBody:          `{"email":"qwertygmail.com", "password":"ZAQ!2wsxCDE#",  "userName":"Some name"}`,
 			inputUser:          nil,
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusUnprocessableEntity,
 		},
 		{
 			name:               "Request without email",
@@ -185,7 +239,7 @@ func TestSignIn(t *testing.T) {
 			ctx:                ctx,
 			inputBody:          `{"password":"ZAQ!2wsxCDE#", "userName":"Some name"}`,
 			inputUser:          nil,
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusUnprocessableEntity,
 		},
 		{
 			name:               "Request without password",
@@ -193,7 +247,7 @@ func TestSignIn(t *testing.T) {
 			ctx:                ctx,
 			inputBody:          `{"email":"qwerty@gmail.com", "userName":"Some name"}`,
 			inputUser:          nil,
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusUnprocessableEntity,
 		},
 	}
 
@@ -242,7 +296,7 @@ func TestRefresh(t *testing.T) {
 		{
 			name: "OK",
 			mockBehavior: func(s *mock_service.MockAuth, input string) {
-				s.EXPECT().RefreshToken(ctx, input).Return(&model.Tokens{
+				s.EXPECT().RefreshToken(ctx, input, testDevice).Return(&model.Tokens{
 					AccessToken:  "qwerty",
 					RefreshToken: "qwerty",
 				}, nil)
@@ -255,7 +309,7 @@ func TestRefresh(t *testing.T) {
 		{
 			name: "Service Error",
 			mockBehavior: func(s *mock_service.MockAuth, input string) {
-				s.EXPECT().RefreshToken(ctx, input).Return(nil, errors.New("service error"))
+				s.EXPECT().RefreshToken(ctx, input, testDevice).Return(nil, errors.New("service error"))
 			},
 			ctx:                ctx,
 			inputBody:          `{"refreshToken":"qwerty"}`,
@@ -268,7 +322,7 @@ func TestRefresh(t *testing.T) {
 			ctx:                ctx,
 			inputBody:          `{"accessToken":"qwerty"}`,
 			inputService:       "qwerty",
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusUnprocessableEntity,
 		},
 	}
 
@@ -302,3 +356,862 @@ func TestRefresh(t *testing.T) {
 		})
 	}
 }
+
+func TestListSessions(t *testing.T) {
+	userID := "1c219a3f-a959-4395-81f0-4e735040ed61"
+	sessionID := "2b1d8e0a-5f3c-4a7e-9d6b-8c0f1e2d3a4b"
+	otherSessionID := "7f3c2a1b-0e9d-4c8b-a7f6-5e4d3c2b1a09"
+	testTable := []struct {
+		name               string
+		authorization      bool
+		mockBehavior       func(s *mock_service.MockAuth)
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:          "OK",
+			authorization: true,
+			mockBehavior: func(s *mock_service.MockAuth) {
+				s.EXPECT().ListSessions(context.Background(), userID).Return([]*model.Session{
+					{ID: sessionID, UserID: userID, IP: "192.0.2.1"},
+					{ID: otherSessionID, UserID: userID, IP: "192.0.2.2"},
+				}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `[{"id":"` + sessionID + `","userAgent":"","ip":"192.0.2.1","createdAt":"0001-01-01T00:00:00Z",` +
+				`"lastUsedAt":"0001-01-01T00:00:00Z","expiresAt":"0001-01-01T00:00:00Z","current":true},` +
+				`{"id":"` + otherSessionID + `","userAgent":"","ip":"192.0.2.2","createdAt":"0001-01-01T00:00:00Z",` +
+				`"lastUsedAt":"0001-01-01T00:00:00Z","expiresAt":"0001-01-01T00:00:00Z","current":false}]`,
+		},
+		{
+			name:               "Without access token",
+			mockBehavior:       func(s *mock_service.MockAuth) {},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Init dependencies
+			c := gomock.NewController(t)
+			mockAuth := mock_service.NewMockAuth(c)
+			testCase.mockBehavior(mockAuth)
+			// sessions need access token even if auth mode is off
+			cfg := newAuthConfig(false)
+			authenticateWith(mockAuth, &cfg)
+			services := &service.Service{Auth: mockAuth}
+			handlers := NewHandler(services, &cfg, NewValidator())
+
+			// Init server
+			r := InitRouter(handlers, &cfg)
+
+			// Test request
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/auth/sessions", nil)
+			if testCase.authorization {
+				tokens := newTokens(t, &cfg, userID, model.RoleViewer, sessionID)
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+			}
+
+			// Execute the request
+			r.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			if testCase.expectedBody != "" {
+				assert.JSONEq(t, testCase.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteSession(t *testing.T) {
+	userID := "1c219a3f-a959-4395-81f0-4e735040ed61"
+	sessionID := "2b1d8e0a-5f3c-4a7e-9d6b-8c0f1e2d3a4b"
+	testTable := []struct {
+		name               string
+		mockBehavior       func(s *mock_service.MockAuth)
+		expectedStatusCode int
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(s *mock_service.MockAuth) {
+				s.EXPECT().DeleteSession(context.Background(), userID, sessionID).Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "Session of another user",
+			mockBehavior: func(s *mock_service.MockAuth) {
+				s.EXPECT().DeleteSession(context.Background(), userID, sessionID).
+					Return(apperror.NotFound("session with given UUID doesn't exist"))
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Init dependencies
+			c := gomock.NewController(t)
+			mockAuth := mock_service.NewMockAuth(c)
+			testCase.mockBehavior(mockAuth)
+			cfg := newAuthConfig(true)
+			authenticateWith(mockAuth, &cfg)
+			services := &service.Service{Auth: mockAuth}
+			handlers := NewHandler(services, &cfg, NewValidator())
+
+			// Init server
+			r := InitRouter(handlers, &cfg)
+
+			// Test request
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/auth/sessions/"+sessionID, nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+newAccessToken(t, &cfg, userID, model.RoleViewer))
+
+			// Execute the request
+			r.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+// TestRefreshTokenReuse runs the flow of sessions against auth service with in-memory repository.
+func TestRefreshTokenReuse(t *testing.T) {
+	cfg := newAuthConfig(true)
+	services := &service.Service{Auth: newAuthService(&cfg)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+
+	send := func(method, target, body, accessToken string, out interface{}) int {
+		t.Helper()
+		return sendJSON(t, r, method, target, body, accessToken, out)
+	}
+	refresh := func(refreshToken string, out *model.Tokens) int {
+		t.Helper()
+		return send(http.MethodPost, "/auth/refresh", `{"refreshToken":"`+refreshToken+`"}`, "", out)
+	}
+
+	var laptop, phone model.Tokens
+	require.Equal(t, http.StatusCreated, send(http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &laptop))
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/auth/sign-in",
+		`{"email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &phone))
+
+	var sessions []model.Session
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/auth/sessions", "", phone.AccessToken, &sessions))
+	require.Len(t, sessions, 2)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+
+	// refresh tokens of the laptop are rotated, the stolen old one revokes the laptop session
+	var rotated model.Tokens
+	require.Equal(t, http.StatusOK, refresh(laptop.RefreshToken, &rotated))
+	assert.Equal(t, http.StatusUnauthorized, refresh(laptop.RefreshToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, refresh(rotated.RefreshToken, nil))
+
+	// the phone session isn't affected
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/auth/sessions", "", phone.AccessToken, &sessions))
+	require.Len(t, sessions, 1)
+	assert.True(t, sessions[0].Current)
+
+	// the ended session can't be refreshed
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, "/auth/sessions/"+sessions[0].ID, "", phone.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, refresh(phone.RefreshToken, nil))
+}
+
+// TestLogout runs the flow of logout against auth service with in-memory repository.
+func TestLogout(t *testing.T) {
+	cfg := newAuthConfig(true)
+	services := &service.Service{Auth: newAuthService(&cfg)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	send := func(method, target, accessToken string, out interface{}) int {
+		t.Helper()
+		return sendJSON(t, r, method, target, "", accessToken, out)
+	}
+	signIn := func() model.Tokens {
+		t.Helper()
+		var tokens model.Tokens
+		require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/sign-in",
+			`{"email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+		return tokens
+	}
+
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", nil))
+	laptop, phone, tablet := signIn(), signIn(), signIn()
+
+	// logout revokes access token and session of the laptop only
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/auth/logout", laptop.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/auth/sessions", laptop.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodPost, "/auth/refresh",
+		`{"refreshToken":"`+laptop.RefreshToken+`"}`, "", nil))
+	var sessions []model.Session
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/auth/sessions", phone.AccessToken, &sessions))
+	// sessions of sign up, the phone and the tablet
+	assert.Len(t, sessions, 3)
+
+	// logout everywhere revokes access tokens and sessions of all devices
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/auth/logout-all", phone.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/auth/sessions", phone.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/auth/sessions", tablet.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodPost, "/auth/refresh",
+		`{"refreshToken":"`+tablet.RefreshToken+`"}`, "", nil))
+
+	// the user can sign in again
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/auth/sessions", signIn().AccessToken, &sessions))
+	assert.Len(t, sessions, 1)
+}
+
+func TestPasswordReset(t *testing.T) {
+	cfg := newAuthConfig(true)
+	cfg.MailDir = t.TempDir()
+	services := &service.Service{Auth: newAuthService(&cfg)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	forgot := func(email string) {
+		t.Helper()
+		require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/password/forgot",
+			`{"email":"`+email+`"}`, "", nil))
+	}
+	reset := func(token, password string) int {
+		t.Helper()
+		return sendJSON(t, r, http.MethodPost, "/auth/password/reset",
+			`{"token":"`+token+`","password":"`+password+`"}`, "", nil)
+	}
+	signIn := func(password string) int {
+		t.Helper()
+		return sendJSON(t, r, http.MethodPost, "/auth/sign-in",
+			`{"email":"qwerty@gmail.com","password":"`+password+`"}`, "", nil)
+	}
+
+	var tokens model.Tokens
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+
+	// unknown email gets the same response, but no email is sent
+	forgot("unknown@gmail.com")
+	assert.Empty(t, readEmails(t, cfg.MailDir, "Password reset"))
+
+	forgot("qwerty@gmail.com")
+	emails := readEmails(t, cfg.MailDir, "Password reset")
+	require.Len(t, emails, 1)
+	assert.Contains(t, emails[0], "To: qwerty@gmail.com\r\n")
+	token := emailToken(t, emails[0], "Reset token")
+
+	assert.Equal(t, http.StatusUnauthorized, reset("invalid", "1qaz@WSX3edc"))
+	assert.Equal(t, http.StatusUnprocessableEntity, reset(token, "short"))
+
+	require.Equal(t, http.StatusOK, reset(token, "1qaz@WSX3edc"))
+	assert.Equal(t, http.StatusUnauthorized, reset(token, "2wsx#EDC4rfv"), "reset token is single-use")
+
+	// sessions and access tokens issued with the old password are revoked
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodGet, "/auth/sessions", "", tokens.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodPost, "/auth/refresh",
+		`{"refreshToken":"`+tokens.RefreshToken+`"}`, "", nil))
+	assert.Equal(t, http.StatusUnauthorized, signIn("ZAQ!2wsx3edc"))
+	assert.Equal(t, http.StatusOK, signIn("1qaz@WSX3edc"))
+}
+
+func TestEmailVerification(t *testing.T) {
+	cfg := newAuthConfig(true)
+	cfg.MailDir = t.TempDir()
+	cfg.RequireVerified = true
+	c := gomock.NewController(t)
+	defer c.Finish()
+	mockCat := mock_service.NewMockCat(c)
+	mockCat.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	services := &service.Service{Auth: newAuthService(&cfg), Cat: mockCat}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	verify := func(token string) int {
+		t.Helper()
+		return sendJSON(t, r, http.MethodPost, "/auth/verify", `{"token":"`+token+`"}`, "", nil)
+	}
+	deleteCat := func(accessToken string) int {
+		t.Helper()
+		return sendJSON(t, r, http.MethodDelete, "/cats/"+uuid.New().String(), "", accessToken, nil)
+	}
+
+	var tokens model.Tokens
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+	emails := readEmails(t, cfg.MailDir, "Email verification")
+	require.Len(t, emails, 1)
+	assert.Contains(t, emails[0], "To: qwerty@gmail.com\r\n")
+	first := emailToken(t, emails[0], "Verification token")
+
+	// unverified user can't change cats
+	assert.Equal(t, http.StatusForbidden, deleteCat(tokens.AccessToken))
+
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/verify/resend", "", tokens.AccessToken, nil))
+	emails = readEmails(t, cfg.MailDir, "Email verification")
+	require.Len(t, emails, 2)
+
+	assert.Equal(t, http.StatusUnauthorized, verify("invalid"))
+	require.Equal(t, http.StatusOK, verify(first))
+	// every token of the user is used up by verification
+	for _, email := range emails {
+		assert.Equal(t, http.StatusUnauthorized, verify(emailToken(t, email, "Verification token")))
+	}
+	assert.Equal(t, http.StatusConflict, sendJSON(t, r, http.MethodPost, "/auth/verify/resend", "", tokens.AccessToken, nil))
+
+	// the state gets into tokens at refresh
+	assert.Equal(t, http.StatusForbidden, deleteCat(tokens.AccessToken))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/refresh",
+		`{"refreshToken":"`+tokens.RefreshToken+`"}`, "", &tokens))
+	assert.Equal(t, http.StatusOK, deleteCat(tokens.AccessToken))
+}
+
+func TestSignInLockout(t *testing.T) {
+	cfg := newAuthConfig(true)
+	services := &service.Service{Auth: newAuthService(&cfg)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	signIn := func(email, password string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/sign-in",
+			bytes.NewBufferString(`{"email":"`+email+`","password":"`+password+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	var tokens model.Tokens
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+	var claims service.JwtCustomClaims
+	_, _, err := new(jwt.Parser).ParseUnverified(tokens.AccessToken, &claims)
+	require.NoError(t, err)
+
+	// unknown email and wrong password can't be told apart
+	unknown := signIn("unknown@gmail.com", "ZAQ!2wsx3edc")
+	wrong := signIn("qwerty@gmail.com", "wrong password")
+	assert.Equal(t, http.StatusUnauthorized, unknown.Code)
+	assert.Equal(t, http.StatusUnauthorized, wrong.Code)
+	assert.JSONEq(t, unknown.Body.String(), wrong.Body.String())
+
+	assert.Equal(t, http.StatusUnauthorized, signIn("qwerty@gmail.com", "wrong password").Code)
+	assert.Equal(t, http.StatusUnauthorized, signIn("qwerty@gmail.com", "wrong password").Code)
+	// correct password doesn't help while the account is locked
+	locked := signIn("qwerty@gmail.com", "ZAQ!2wsx3edc")
+	assert.Equal(t, http.StatusTooManyRequests, locked.Code)
+	assert.Equal(t, "60", locked.Header().Get("Retry-After"))
+
+	adminToken := newAccessToken(t, &cfg, uuid.New().String(), model.RoleAdmin)
+	editorToken := newAccessToken(t, &cfg, uuid.New().String(), model.RoleEditor)
+	var lockouts []model.Lockout
+	assert.Equal(t, http.StatusForbidden, sendJSON(t, r, http.MethodGet, "/admin/users/lockouts", "", editorToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/admin/users/lockouts", "", adminToken, &lockouts))
+	require.Len(t, lockouts, 1)
+	assert.Equal(t, "qwerty@gmail.com", lockouts[0].Email)
+	assert.Equal(t, int64(3), lockouts[0].Failures)
+
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodDelete,
+		"/admin/users/"+uuid.New().String()+"/lockout", "", adminToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodDelete,
+		"/admin/users/"+claims.Subject+"/lockout", "", adminToken, nil))
+	assert.Equal(t, http.StatusOK, signIn("qwerty@gmail.com", "ZAQ!2wsx3edc").Code)
+}
+
+func TestDevice(t *testing.T) {
+	testTable := []struct {
+		name       string
+		proxies    string
+		forwarded  []string
+		expectedIP string
+	}{
+		{
+			name:       "No proxies",
+			forwarded:  []string{"203.0.113.1"},
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "Request isn't from proxy",
+			proxies:    "10.0.0.0/8",
+			forwarded:  []string{"203.0.113.1"},
+			expectedIP: "192.0.2.1",
+		},
+		{
+			name:       "Request from proxy",
+			proxies:    "192.0.2.1",
+			forwarded:  []string{"203.0.113.1"},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name:       "Address set by client is skipped",
+			proxies:    "192.0.2.1",
+			forwarded:  []string{"198.51.100.1, 203.0.113.1"},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name:       "Chain of proxies",
+			proxies:    "192.0.2.0/24, 10.0.0.0/8",
+			forwarded:  []string{"198.51.100.1, 203.0.113.1", "10.0.0.2"},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name:       "Only proxies",
+			proxies:    "192.0.2.0/24, 10.0.0.0/8",
+			forwarded:  []string{"10.0.0.2"},
+			expectedIP: "10.0.0.2",
+		},
+		{
+			name:       "Malformed address",
+			proxies:    "192.0.2.0/24, 10.0.0.0/8",
+			forwarded:  []string{"198.51.100.1, qwerty, 10.0.0.2"},
+			expectedIP: "10.0.0.2",
+		},
+		{
+			name:       "Without header",
+			proxies:    "192.0.2.1",
+			expectedIP: "192.0.2.1",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			cfg := config.Config{}
+			require.NoError(t, cfg.TrustedProxies.UnmarshalText([]byte(testCase.proxies)))
+			h := NewHandler(&service.Service{}, &cfg, NewValidator())
+			req := httptest.NewRequest(http.MethodPost, "/auth/sign-in", nil)
+			req.Header.Set("User-Agent", "Mozilla/5.0")
+			for _, forwarded := range testCase.forwarded {
+				req.Header.Add(echo.HeaderXForwardedFor, forwarded)
+			}
+			// X-Real-IP is never trusted
+			req.Header.Set(echo.HeaderXRealIP, "198.51.100.2")
+			ctx := echo.New().NewContext(req, httptest.NewRecorder())
+
+			assert.Equal(t, &model.Device{UserAgent: "Mozilla/5.0", IP: testCase.expectedIP}, h.device(ctx))
+		})
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	cfg := newAuthConfig(true)
+	services := &service.Service{Auth: newAuthService(&cfg)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	signUp := func(password string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/sign-up", bytes.NewBufferString(
+			`{"userName":"Some name","email":"qwerty@gmail.com","password":"`+password+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	testTable := []struct {
+		name     string
+		password string
+		messages []string
+	}{
+		{
+			name:     "Too short",
+			password: "ZAQ!2wsx",
+			messages: []string{"must be at least 10 characters long"},
+		},
+		{
+			name:     "Too few character classes",
+			password: "zaq2wsx3edc",
+			messages: []string{"must contain at least 3 of lower case letters, " +
+				"upper case letters, digits and other characters"},
+		},
+		{
+			name:     "Common",
+			password: "Password123!",
+			messages: []string{"is too common"},
+		},
+		{
+			name:     "Short and common",
+			password: "qwerty",
+			messages: []string{"must be at least 10 characters long", "must contain at least 3 of lower case " +
+				"letters, upper case letters, digits and other characters", "is too common"},
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			w := signUp(testCase.password)
+			require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			var problem model.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			messages := make([]string, 0, len(problem.Errors))
+			for _, fieldError := range problem.Errors {
+				assert.Equal(t, "password", fieldError.Field)
+				messages = append(messages, fieldError.Message)
+			}
+			assert.Equal(t, testCase.messages, messages)
+		})
+	}
+
+	assert.Equal(t, http.StatusCreated, signUp("ZAQ!2wsx3edc").Code)
+}
+
+func TestPasswordRehash(t *testing.T) {
+	cfg := newAuthConfig(true)
+	repo := repository.NewRepositoryMemory()
+	services := &service.Service{Auth: newAuthServiceWith(&cfg, repo)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	signIn := func(password string) int {
+		t.Helper()
+		return sendJSON(t, r, http.MethodPost, "/auth/sign-in",
+			`{"email":"qwerty@gmail.com","password":"`+password+`"}`, "", nil)
+	}
+	hashOf := func() string {
+		t.Helper()
+		_, hash, err := repo.Auth.GetUserHashedPassword(context.Background(), "qwerty@gmail.com")
+		require.NoError(t, err)
+		return hash
+	}
+
+	// the user signed up while passwords were hashed with bcrypt
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("ZAQ!2wsx"), bcrypt.MinCost)
+	require.NoError(t, err)
+	require.NoError(t, repo.Auth.CreateUser(context.Background(), &repository.CreateUserInput{
+		ID: uuid.New().String(), UserName: "Some name", Email: "qwerty@gmail.com",
+		Password: string(bcryptHash), Role: model.RoleEditor,
+	}))
+
+	// failed sign in doesn't change the hash
+	assert.Equal(t, http.StatusUnauthorized, signIn("wrong password"))
+	assert.Equal(t, string(bcryptHash), hashOf())
+
+	// old password is accepted even if it breaks the current policy, and its hash is upgraded
+	require.Equal(t, http.StatusOK, signIn("ZAQ!2wsx"))
+	argon2idHash := hashOf()
+	assert.True(t, strings.HasPrefix(argon2idHash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	require.Equal(t, http.StatusOK, signIn("ZAQ!2wsx"))
+	assert.Equal(t, argon2idHash, hashOf(), "hash of the current hasher isn't rehashed")
+
+	// hash with old parameters is upgraded too
+	cfg.Argon2Iterations = 2
+	services.Auth = newAuthServiceWith(&cfg, repo)
+	r = InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	require.Equal(t, http.StatusOK, signIn("ZAQ!2wsx"))
+	assert.True(t, strings.HasPrefix(hashOf(), "$argon2id$v=19$m=1024,t=2,p=1$"))
+}
+
+func TestTwoFactor(t *testing.T) {
+	cfg := newAuthConfig(true)
+	services := &service.Service{Auth: newAuthService(&cfg)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	signIn := func() *model.Tokens {
+		t.Helper()
+		var tokens model.Tokens
+		require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/sign-in",
+			`{"email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+		return &tokens
+	}
+	verify := func(challengeToken, code string, out *model.Tokens) int {
+		t.Helper()
+		return sendJSON(t, r, http.MethodPost, "/auth/2fa/verify",
+			`{"challengeToken":"`+challengeToken+`","code":"`+code+`"}`, "", out)
+	}
+	// code returns TOTP code of the secret for the time step after the one the test starts at,
+	// so crossing step boundary during the test doesn't change codes
+	step := totp.Step(time.Now())
+	code := func(secret string, after int64) string {
+		t.Helper()
+		code, err := totp.Code(secret, step+after)
+		require.NoError(t, err)
+		return code
+	}
+
+	var tokens model.Tokens
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+	accessToken := tokens.AccessToken
+
+	var enrollment model.TwoFactorEnrollment
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/2fa/enroll", "", accessToken, &enrollment))
+	assert.Contains(t, enrollment.URI, "otpauth://totp/first-task:qwerty@gmail.com?")
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	// two-factor authentication isn't enabled until it is confirmed
+	assert.NotEmpty(t, signIn().AccessToken)
+
+	var recovery model.RecoveryCodes
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodPost, "/auth/2fa/confirm",
+		`{"code":"000000"}`, accessToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/2fa/confirm",
+		`{"code":"`+code(enrollment.Secret, 0)+`"}`, accessToken, &recovery))
+	require.Len(t, recovery.RecoveryCodes, 10)
+	assert.Equal(t, http.StatusConflict, sendJSON(t, r, http.MethodPost, "/auth/2fa/enroll", "", accessToken, nil))
+
+	challenge := signIn()
+	assert.Empty(t, challenge.AccessToken)
+	assert.Empty(t, challenge.RefreshToken)
+	require.NotEmpty(t, challenge.ChallengeToken)
+	// challenge token doesn't authenticate requests
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodGet, "/auth/sessions", "", challenge.ChallengeToken, nil))
+
+	assert.Equal(t, http.StatusUnauthorized, verify(challenge.ChallengeToken, "000000", nil))
+	// the code confirming enrollment is used up
+	assert.Equal(t, http.StatusUnauthorized, verify(challenge.ChallengeToken, code(enrollment.Secret, 0), nil))
+	require.Equal(t, http.StatusOK, verify(challenge.ChallengeToken, code(enrollment.Secret, 1), &tokens))
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.Empty(t, tokens.ChallengeToken)
+	assert.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/auth/sessions", "", tokens.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, verify(challenge.ChallengeToken, recovery.RecoveryCodes[0], nil))
+
+	// recovery codes are single-use and accepted in any case
+	challenge = signIn()
+	require.Equal(t, http.StatusOK, verify(challenge.ChallengeToken, strings.ToUpper(recovery.RecoveryCodes[0]), &tokens))
+	challenge = signIn()
+	assert.Equal(t, http.StatusUnauthorized, verify(challenge.ChallengeToken, recovery.RecoveryCodes[0], nil))
+
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodPost, "/auth/2fa/disable",
+		`{"code":"`+recovery.RecoveryCodes[0]+`"}`, accessToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/2fa/disable",
+		`{"code":"`+recovery.RecoveryCodes[1]+`"}`, accessToken, nil))
+	assert.NotEmpty(t, signIn().AccessToken)
+}
+
+func TestAPIKeys(t *testing.T) {
+	cfg := newAuthConfig(true)
+	c := gomock.NewController(t)
+	defer c.Finish()
+	mockCat := mock_service.NewMockCat(c)
+	mockCat.EXPECT().List(gomock.Any(), gomock.Any()).Return(&model.CatsPage{Cats: []*model.Cat{}}, nil).AnyTimes()
+	mockCat.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	services := &service.Service{Auth: newAuthService(&cfg), Cat: mockCat}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	// withKey sends the request to cats API authenticated by the API key
+	withKey := func(method, target, key string) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("X-API-Key", key)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	createKey := func(accessToken, body string) *model.CreatedAPIKey {
+		t.Helper()
+		var key model.CreatedAPIKey
+		require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/api-keys", body, accessToken, &key))
+		return &key
+	}
+
+	var tokens model.Tokens
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+	readKey := createKey(tokens.AccessToken, `{"name":"nightly report","scopes":["cats:read"]}`)
+	writeKey := createKey(tokens.AccessToken, `{"name":"nightly import","scopes":["cats:write"],"expiresAt":"`+
+		time.Now().Add(time.Hour).Format(time.RFC3339)+`"}`)
+	assert.True(t, strings.HasPrefix(readKey.Key, readKey.Prefix))
+	assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(t, r, http.MethodPost, "/auth/api-keys",
+		`{"name":"expired","scopes":["cats:read"],"expiresAt":"2022-04-01T00:00:00Z"}`, tokens.AccessToken, nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(t, r, http.MethodPost, "/auth/api-keys",
+		`{"name":"admin","scopes":["users:write"]}`, tokens.AccessToken, nil))
+
+	catPath := "/cats/" + uuid.New().String()
+	assert.Equal(t, http.StatusOK, withKey(http.MethodGet, "/cats", readKey.Key))
+	assert.Equal(t, http.StatusForbidden, withKey(http.MethodDelete, catPath, readKey.Key))
+	assert.Equal(t, http.StatusOK, withKey(http.MethodGet, "/cats", writeKey.Key))
+	assert.Equal(t, http.StatusOK, withKey(http.MethodDelete, catPath, writeKey.Key))
+	assert.Equal(t, http.StatusUnauthorized, withKey(http.MethodGet, "/cats", "ftk_unknown"))
+	// API keys give access to cats API only
+	assert.Equal(t, http.StatusUnauthorized, withKey(http.MethodGet, "/auth/api-keys", readKey.Key))
+
+	var keys []model.APIKey
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/auth/api-keys", "", tokens.AccessToken, &keys))
+	require.Len(t, keys, 2)
+	assert.Equal(t, readKey.ID, keys[0].ID)
+	assert.Equal(t, []string{model.ScopeCatsRead}, keys[0].Scopes)
+	assert.NotNil(t, keys[0].LastUsedAt)
+	assert.NotNil(t, keys[1].ExpiresAt)
+
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodDelete, "/auth/api-keys/"+readKey.ID, "", tokens.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, withKey(http.MethodGet, "/cats", readKey.Key))
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodDelete, "/auth/api-keys/"+readKey.ID, "", tokens.AccessToken, nil))
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "first-task", "secret")
+	cfg := newAuthConfig(true)
+	cfg.MailDir = t.TempDir()
+	cfg.OIDCIssuer = issuer.URL
+	cfg.OIDCClientID = issuer.ClientID
+	cfg.OIDCClientSecret = issuer.ClientSecret
+	cfg.OIDCRedirectURL = "http://example.com/auth/oidc/callback"
+	cfg.OIDCScopes = "openid email profile"
+	cfg.OIDCLoginTTL = time.Minute
+	repo := repository.NewRepositoryMemory()
+	services := &service.Service{Auth: newAuthServiceWith(&cfg, repo)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	// startLogin starts login in a browser and returns the state cookie and the callback URL of the provider
+	startLogin := func() (*http.Cookie, string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+		require.Equal(t, http.StatusFound, w.Code)
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.True(t, cookies[0].HttpOnly)
+		return cookies[0], issuer.Authorize(t, w.Header().Get(echo.HeaderLocation)).RequestURI()
+	}
+	callback := func(cookie *http.Cookie, target string, out interface{}) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code < http.StatusBadRequest {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), out))
+		}
+		return w.Code
+	}
+	login := func(out interface{}) int {
+		t.Helper()
+		cookie, target := startLogin()
+		return callback(cookie, target, out)
+	}
+	me := func(tokens *model.Tokens) *model.User {
+		t.Helper()
+		var user model.User
+		require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/users/me", "", tokens.AccessToken, &user))
+		return &user
+	}
+
+	// the first login creates the user, next ones sign in to it
+	issuer.SignIn(&oidctest.User{Subject: "1001", Email: "new@gmail.com", EmailVerified: true, Name: "New name"})
+	var first, second model.Tokens
+	require.Equal(t, http.StatusOK, login(&first))
+	user := me(&first)
+	assert.Equal(t, "new@gmail.com", user.Email)
+	assert.Equal(t, "New name", user.UserName)
+	assert.True(t, user.EmailVerified)
+	require.Equal(t, http.StatusOK, login(&second))
+	assert.Equal(t, user.ID, me(&second).ID)
+	// users created by the provider have no password to sign in with
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodPost, "/auth/sign-in",
+		`{"email":"new@gmail.com","password":"ZAQ!2wsx3edc"}`, "", nil))
+
+	// the callback is accepted once and only in the browser the login was started in
+	cookie, target := startLogin()
+	otherCookie, _ := startLogin()
+	assert.Equal(t, http.StatusUnauthorized, callback(nil, target, nil))
+	assert.Equal(t, http.StatusUnauthorized, callback(otherCookie, target, nil))
+	require.Equal(t, http.StatusOK, callback(cookie, target, nil))
+	assert.Equal(t, http.StatusUnauthorized, callback(cookie, target, nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, callback(cookie, "/auth/oidc/callback?state="+cookie.Value, nil))
+
+	issuer.SignIn(nil)
+	assert.Equal(t, http.StatusUnauthorized, login(nil))
+
+	// accounts with password are linked only by emails verified by both the provider and the account
+	var tokens model.Tokens
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+	issuer.SignIn(&oidctest.User{Subject: "1002", Email: "qwerty@gmail.com", Name: "Other name"})
+	assert.Equal(t, http.StatusConflict, login(nil))
+	issuer.SignIn(&oidctest.User{Subject: "1002", Email: "qwerty@gmail.com", EmailVerified: true, Name: "Other name"})
+	assert.Equal(t, http.StatusConflict, login(nil))
+	emails := readEmails(t, cfg.MailDir, "Email verification")
+	require.Len(t, emails, 1)
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/verify",
+		`{"token":"`+emailToken(t, emails[0], "Verification token")+`"}`, "", nil))
+	var linked model.Tokens
+	require.Equal(t, http.StatusOK, login(&linked))
+	assert.Equal(t, me(&tokens).ID, me(&linked).ID)
+	assert.Equal(t, "Some name", me(&linked).UserName)
+
+	// the provider doesn't replace two-factor authentication of the account
+	var enrollment model.TwoFactorEnrollment
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/2fa/enroll", "",
+		linked.AccessToken, &enrollment))
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/2fa/confirm",
+		`{"code":"`+code+`"}`, linked.AccessToken, nil))
+	var challenge model.Tokens
+	require.Equal(t, http.StatusOK, login(&challenge))
+	assert.Empty(t, challenge.AccessToken)
+	assert.Empty(t, challenge.RefreshToken)
+	require.NotEmpty(t, challenge.ChallengeToken)
+	code, err = totp.Code(enrollment.Secret, totp.Step(time.Now())+1)
+	require.NoError(t, err)
+	var verified model.Tokens
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/2fa/verify",
+		`{"challengeToken":"`+challenge.ChallengeToken+`","code":"`+code+`"}`, "", &verified))
+	assert.Equal(t, me(&tokens).ID, me(&verified).ID)
+
+	require.NoError(t, repo.Auth.SetUserDisabled(context.Background(), me(&linked).ID, true))
+	assert.Equal(t, http.StatusForbidden, login(nil))
+
+	// login is unavailable without configured provider
+	cfg.OIDCIssuer = ""
+	r = InitRouter(NewHandler(&service.Service{Auth: newAuthServiceWith(&cfg, repo)}, &cfg, NewValidator()), &cfg)
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodGet, "/auth/oidc/login", "", "", nil))
+}
+
+// readEmails returns emails with the subject written into the directory by file mailer.
+func readEmails(t *testing.T, dir, subject string) []string {
+	t.Helper()
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var emails []string
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		require.NoError(t, err)
+		if strings.Contains(string(data), "\r\nSubject: "+subject+"\r\n") {
+			emails = append(emails, string(data))
+		}
+	}
+
+	return emails
+}
+
+// emailToken returns token following the label in the email.
+func emailToken(t *testing.T, email, label string) string {
+	t.Helper()
+	match := regexp.MustCompile(label + `: (\S+)`).FindStringSubmatch(email)
+	require.NotNil(t, match, "email has no %s", label)
+
+	return match[1]
+}
+
+// sendJSON sends the request with JSON body to the router and decodes successful JSON response into out.
+func sendJSON(t *testing.T, r *echo.Echo, method, target, body, accessToken string, out interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if accessToken != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+accessToken)
+	}
+	r.ServeHTTP(w, req)
+	if out != nil && w.Code < http.StatusBadRequest {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), out))
+	}
+
+	return w.Code
+}
+
+func TestJWKS(t *testing.T) {
+	cfg := newAuthConfig(true)
+	services := &service.Service{Auth: newAuthService(&cfg)}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+
+	var jwks model.JWKS
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/.well-known/jwks.json", "", "", &jwks))
+	require.Len(t, jwks.Keys, 1)
+	jwk := jwks.Keys[0]
+	assert.Equal(t, "OKP", jwk.Kty)
+	assert.Equal(t, "Ed25519", jwk.Crv)
+	assert.Equal(t, "EdDSA", jwk.Alg)
+	assert.Equal(t, "test-key", jwk.Kid)
+
+	// tokens are verified with the published key only
+	public, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	require.NoError(t, err)
+	token, err := jwt.Parse(newAccessToken(t, &cfg, uuid.New().String(), model.RoleEditor),
+		func(token *jwt.Token) (interface{}, error) {
+			assert.Equal(t, jwk.Kid, token.Header["kid"])
+			return ed25519.PublicKey(public), nil
+		})
+	require.NoError(t, err)
+	assert.True(t, token.Valid)
+}
@@ -5,10 +5,13 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/apperror"
 	"github.com/malkev1ch/first-task/internal/model"
 	"github.com/sirupsen/logrus"
 )
@@ -21,38 +24,37 @@ import (
 //
 //	Security:
 //	 AdminAuth:
+//	 APIKeyAuth:
 //
 //	responses:
 //	 201: okResponse
 //	 400: badRequestError
 //	 401: unauthorizedError
+//	 403: forbiddenError
+//	 409: conflictError
 //	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
 //	 500: internalServerError
 func (h *Handler) CreateCat(ctx echo.Context) error {
 	var input model.CreateCat
 	if err := ctx.Bind(&input); err != nil {
 		logrus.Error("handler: validation failed - ", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "invalid content of body", Error: err.Error(),
-		})
+		return err
 	}
 
 	if err := h.Validator.Validate(&input); err != nil {
 		logrus.Error("handler: validation failed - ", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "not enough fields in json body", Error: err.Error(),
-		})
+		return err
 	}
 
 	id, err := h.Services.Create(ctx.Request().Context(), &model.Cat{
 		Name:       input.Name,
 		DateBirth:  input.DateBirth,
 		Vaccinated: input.Vaccinated,
+		OwnerID:    userID(ctx),
 	})
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "can't create cat", Error: err.Error(),
-		})
+		return err
 	}
 
 	return ctx.JSON(http.StatusCreated, OKResponse{
@@ -64,24 +66,26 @@ func (h *Handler) CreateCat(ctx echo.Context) error {
 //
 //	Get cat by UUID.
 //
-//	Returns a cat with the given UUID.
+//	Returns a cat with the given UUID, ETag header contains the version of the cat.
 //
 //	Security:
 //	 AdminAuth:
+//	 APIKeyAuth:
 //
 //	responses:
 //	 200: getCatResponse
 //	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
 //	 500: internalServerError
 func (h *Handler) GetCat(ctx echo.Context) error {
 	id := ctx.Param("uuid")
-	cat, err := h.Services.Get(ctx.Request().Context(), id)
+	cat, err := h.Services.Get(ctx.Request().Context(), catsOwner(ctx), id)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "can't get cat", Error: err.Error(),
-		})
+		return err
 	}
 
+	setETag(ctx, cat)
 	return ctx.JSON(http.StatusOK, *cat)
 }
 
@@ -89,59 +93,73 @@ func (h *Handler) GetCat(ctx echo.Context) error {
 //
 //	Update cat.
 //
-//	Update a cat with the given UUID.
+//	Update a cat with the given UUID. If-Match header with ETag of the cat makes the update fail
+//	if the cat has been changed since.
 //
 //	Security:
 //	 AdminAuth:
+//	 APIKeyAuth:
 //
 //	responses:
 //	 200: updateCatResponse
 //	 400: badRequestError
 //	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 412: preconditionFailedError
 //	 415: unsupportedMediaTypeError
+//	 422: unprocessableEntityError
 //	 500: internalServerError
 func (h *Handler) UpdateCat(ctx echo.Context) error {
 	id := ctx.Param("uuid")
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		return err
+	}
 	var input model.UpdateCat
 	if err := ctx.Bind(&input); err != nil {
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "invalid path parameter", Error: err.Error(),
-		})
+		return err
 	}
 	if err := h.Validator.ValidateUpdateCat(&input); err != nil {
 		logrus.Error("handler: not enough fields in json body - ", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "not enough fields in json body", Error: err.Error(),
-		})
+		return err
 	}
 
-	cat, err := h.Services.Update(ctx.Request().Context(), id, &input)
+	cat, err := h.Services.Update(ctx.Request().Context(), userID(ctx), catsOwner(ctx), id, version, &input)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "can't update cat", Error: err.Error(),
-		})
+		return err
 	}
 
+	setETag(ctx, cat)
 	return ctx.JSON(http.StatusOK, *cat)
 }
 
 //	swagger:route DELETE /cats/{uuid} cats DeleteCat
 //
-//	Remove cat from storage.
+//	Move cat to trash.
+//
+//	The cat can be restored until it's purged after the retention period. If-Match header
+//	with ETag of the cat makes the deletion fail if the cat has been changed since.
 //
 //	Security:
 //	 AdminAuth:
+//	 APIKeyAuth:
 //
 //	Responses:
 //	 200: okResponse
 //	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 412: preconditionFailedError
 //	 500: internalServerError
 func (h *Handler) DeleteCat(ctx echo.Context) error {
 	id := ctx.Param("uuid")
-	if err := h.Services.Delete(ctx.Request().Context(), id); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "can't delete cat", Error: err.Error(),
-		})
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if err := h.Services.Delete(ctx.Request().Context(), userID(ctx), catsOwner(ctx), id, version); err != nil {
+		return err
 	}
 
 	return ctx.JSON(http.StatusOK, OKResponse{
@@ -149,38 +167,68 @@ func (h *Handler) DeleteCat(ctx echo.Context) error {
 	})
 }
 
+//	swagger:route POST /cats/{uuid}/restore cats RestoreCat
+//
+//	Restore cat from trash.
+//
+//	Security:
+//	 AdminAuth:
+//	 APIKeyAuth:
+//
+//	responses:
+//	 200: getCatResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 500: internalServerError
+func (h *Handler) RestoreCat(ctx echo.Context) error {
+	id := ctx.Param("uuid")
+	cat, err := h.Services.Restore(ctx.Request().Context(), userID(ctx), catsOwner(ctx), id)
+	if err != nil {
+		return err
+	}
+
+	setETag(ctx, cat)
+	return ctx.JSON(http.StatusOK, *cat)
+}
+
 //	swagger:route POST /cats/{uuid}/image cats UploadCatImage
 //
-// 	Set or update cats image.
+// 	Set or update cats image. If-Match header with ETag of the cat makes the upload fail
+//	if the cat has been changed since.
 //
 // 	consumes:
 //   - multipart/form-data
 //
 //	Security:
 //	 AdminAuth:
+//	 APIKeyAuth:
 //
 // 	Responses:
 // 	 200: okResponse
 // 	 400: badRequestError
 //	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 412: preconditionFailedError
 //	 415: unsupportedMediaTypeError
 // 	 500: internalServerError
 func (h *Handler) UploadCatImage(ctx echo.Context) error {
 	id := ctx.Param("uuid")
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		return err
+	}
 	file, err := ctx.FormFile("image")
 	if err != nil {
 		logrus.Errorf("handler: can't parse form file - %e", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "can't parse form file", Error: err.Error(),
-		})
+		return echo.NewHTTPError(http.StatusBadRequest, "can't parse form file")
 	}
 
 	src, err := file.Open()
 	if err != nil {
 		logrus.Errorf("handler: can't open file - %e", err)
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "can't open file", Error: err.Error(),
-		})
+		return fmt.Errorf("handler: can't open file - %w", err)
 	}
 	defer src.Close()
 
@@ -188,19 +236,24 @@ func (h *Handler) UploadCatImage(ctx echo.Context) error {
 	dst, err := os.Create(filename)
 	if err != nil {
 		logrus.Errorf("handler: can't create file locally - %e", err)
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "can't create file locally", Error: err.Error(),
-		})
+		return fmt.Errorf("handler: can't create file locally - %w", err)
 	}
-	defer dst.Close()
+	saved := false
+	defer func() {
+		dst.Close()
+		// the file is never served if the cat doesn't get it, e.g. if the cat has been changed
+		if !saved {
+			if err := os.Remove(filename); err != nil {
+				logrus.Errorf("handler: can't remove file - %e", err)
+			}
+		}
+	}()
 
 	buffer := make([]byte, file.Size)
 
 	if _, err = src.Read(buffer); err != nil {
 		logrus.Errorf("handler: can't read file - %e", err)
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "can't read file", Error: err.Error(),
-		})
+		return fmt.Errorf("handler: can't read file - %w", err)
 	}
 
 	contentType := http.DetectContentType(buffer)
@@ -208,29 +261,50 @@ func (h *Handler) UploadCatImage(ctx echo.Context) error {
 	// Validate File Type
 	if _, ex := imageTypes[contentType]; !ex {
 		logrus.Errorf("invalid file type")
-		return ctx.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
-			Message: "image should be .jpg or .png or .webp", Error: "invalid file type",
-		})
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "image should be .jpg or .png or .webp")
 	}
 
 	if _, err = io.Copy(dst, src); err != nil {
 		logrus.Errorf("handler: can't copy file - %e", err)
-		return ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Message: "can't copy file", Error: err.Error(),
-		})
+		return echo.NewHTTPError(http.StatusBadRequest, "can't copy file")
 	}
 
-	if err := h.Services.UploadImage(ctx.Request().Context(), id, filename); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "can't update cats image path", Error: err.Error(),
-		})
+	err = h.Services.UploadImage(ctx.Request().Context(), userID(ctx), catsOwner(ctx), id, version, filename)
+	if err != nil {
+		return err
 	}
+	saved = true
 
 	return ctx.JSON(http.StatusOK, OKResponse{
 		Message: filename,
 	})
 }
 
+// setETag sets ETag header of the response to the version of the cat.
+func setETag(ctx echo.Context, cat *model.Cat) {
+	ctx.Response().Header().Set("ETag", strconv.Quote(strconv.FormatInt(cat.Version, 10)))
+}
+
+// ifMatchVersion returns version of the cat If-Match header expects, it returns zero version
+// if the header is missing or "*". Only a single ETag of the cat is accepted, other values
+// can never match.
+func ifMatchVersion(ctx echo.Context) (int64, error) {
+	header := strings.TrimSpace(ctx.Request().Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	if len(header) > 2 && header[0] == '"' && header[len(header)-1] == '"' {
+		version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+		if err == nil && version > 0 {
+			return version, nil
+		}
+	}
+
+	logrus.Error("handler: If-Match doesn't match ETag of any cat - ", header)
+	return 0, apperror.PreconditionFailed("If-Match should be ETag of the cat")
+}
+
 func (h *Handler) generateFileName(filename string) string {
 	resultedFilename := fmt.Sprintf("%s%s.%s", h.Cfg.ImagePath, uuid.New().String(), getFileExtension(filename))
 	return resultedFilename
@@ -252,17 +326,191 @@ func getFileExtension(filename string) string {
 //
 //	Security:
 //	 AdminAuth:
+//	 APIKeyAuth:
 //
 // 	Responses:
 //	 200: okResponse
 func (h *Handler) GetCatImage(ctx echo.Context) error {
 	id := ctx.Param("uuid")
-	cat, err := h.Services.Get(ctx.Request().Context(), id)
+	cat, err := h.Services.Get(ctx.Request().Context(), catsOwner(ctx), id)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Message: "can't update cats image path", Error: err.Error(),
-		})
+		return err
 	}
 
 	return ctx.File(cat.ImagePath)
 }
+
+const (
+	defaultCatsLimit = 20
+	maxCatsLimit     = 100
+)
+
+//	swagger:route GET /cats cats ListCats
+//
+//	List cats.
+//
+//	Returns a page of cats filtered and sorted by the query parameters.
+//
+//	Security:
+//	 AdminAuth:
+//	 APIKeyAuth:
+//
+//	responses:
+//	 200: listCatsResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) ListCats(ctx echo.Context) error {
+	input, err := parseListCats(ctx)
+	if err != nil {
+		logrus.Error("handler: invalid query parameters - ", err)
+		return err
+	}
+
+	input.OwnerID = catsOwner(ctx)
+	page, err := h.Services.List(ctx.Request().Context(), input)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, *page)
+}
+
+//	swagger:route GET /cats/trash cats ListTrashedCats
+//
+//	List cats in trash.
+//
+//	Returns a page of cats in trash filtered and sorted by the query parameters.
+//
+//	Security:
+//	 AdminAuth:
+//	 APIKeyAuth:
+//
+//	responses:
+//	 200: listCatsResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) ListTrashedCats(ctx echo.Context) error {
+	input, err := parseListCats(ctx)
+	if err != nil {
+		logrus.Error("handler: invalid query parameters - ", err)
+		return err
+	}
+
+	input.OwnerID = catsOwner(ctx)
+	input.Deleted = true
+	page, err := h.Services.List(ctx.Request().Context(), input)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, *page)
+}
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+//	swagger:route GET /cats/{uuid}/history cats CatHistory
+//
+//	Get history of cat.
+//
+//	Returns a page of changes of the cat with the user who made them, the latest changes go first.
+//	The history is kept after the cat is deleted.
+//
+//	Security:
+//	 AdminAuth:
+//	 APIKeyAuth:
+//
+//	responses:
+//	 200: catHistoryResponse
+//	 401: unauthorizedError
+//	 403: forbiddenError
+//	 404: notFoundError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) CatHistory(ctx echo.Context) error {
+	input := &model.ListCatChanges{
+		CatID:   ctx.Param("uuid"),
+		OwnerID: catsOwner(ctx),
+		Limit:   defaultHistoryLimit,
+		Cursor:  ctx.QueryParam("cursor"),
+	}
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxHistoryLimit {
+			logrus.Error("handler: invalid query parameters - ", err)
+			return apperror.Validation(fmt.Sprintf("limit should be a number from 1 to %d", maxHistoryLimit))
+		}
+		input.Limit = value
+	}
+
+	page, err := h.Services.History(ctx.Request().Context(), input)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, *page)
+}
+
+// parseListCats reads filtering, sorting and pagination of cats from query parameters.
+func parseListCats(ctx echo.Context) (*model.ListCats, error) {
+	input := &model.ListCats{
+		Limit:      defaultCatsLimit,
+		Cursor:     ctx.QueryParam("cursor"),
+		NamePrefix: ctx.QueryParam("name"),
+		SortBy:     model.CatsSortByName,
+	}
+
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxCatsLimit {
+			return nil, apperror.Validation(fmt.Sprintf("limit should be a number from 1 to %d", maxCatsLimit))
+		}
+		input.Limit = value
+	}
+
+	if vaccinated := ctx.QueryParam("vaccinated"); vaccinated != "" {
+		value, err := strconv.ParseBool(vaccinated)
+		if err != nil {
+			return nil, apperror.Validation("vaccinated should be true or false")
+		}
+		input.Vaccinated = &value
+	}
+
+	for param, dst := range map[string]**time.Time{
+		"dateBirthFrom": &input.DateBirthFrom,
+		"dateBirthTo":   &input.DateBirthTo,
+	} {
+		if value := ctx.QueryParam(param); value != "" {
+			date, err := parseDate(value)
+			if err != nil {
+				return nil, apperror.Validation(fmt.Sprintf("%s should be a date in format YYYY-MM-DD or RFC3339", param))
+			}
+			*dst = &date
+		}
+	}
+
+	if sort := ctx.QueryParam("sort"); sort != "" {
+		input.SortDesc = strings.HasPrefix(sort, "-")
+		input.SortBy = strings.TrimPrefix(sort, "-")
+		if input.SortBy != model.CatsSortByName && input.SortBy != model.CatsSortByDateBirth {
+			return nil, apperror.Validation(fmt.Sprintf("sort should be one of %s, -%s, %s, -%s", model.CatsSortByName,
+				model.CatsSortByName, model.CatsSortByDateBirth, model.CatsSortByDateBirth))
+		}
+	}
+
+	return input, nil
+}
+
+// parseDate parses date in format YYYY-MM-DD or RFC3339.
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,631 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/malkev1ch/first-task/internal/keys"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/password"
+	"github.com/malkev1ch/first-task/internal/rediscache"
+	"github.com/malkev1ch/first-task/internal/repository"
+	mock_repository "github.com/malkev1ch/first-task/internal/repository/mocks"
+	"github.com/malkev1ch/first-task/internal/service"
+	mock_service "github.com/malkev1ch/first-task/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCats(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockCat, input *model.ListCats)
+	ctx := context.Background()
+	vaccinated := true
+	dateBirthFrom := time.Date(2018, 9, 22, 0, 0, 0, 0, time.UTC)
+	testTable := []struct {
+		name               string
+		ctx                context.Context
+		query              string
+		inputList          *model.ListCats
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(s *mock_service.MockCat, input *model.ListCats) {
+				s.EXPECT().List(ctx, input).Return(&model.CatsPage{Cats: []*model.Cat{}}, nil)
+			},
+			ctx:   ctx,
+			query: "",
+			inputList: &model.ListCats{
+				Limit:  defaultCatsLimit,
+				SortBy: model.CatsSortByName,
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "OK with filters",
+			mockBehavior: func(s *mock_service.MockCat, input *model.ListCats) {
+				s.EXPECT().List(ctx, input).Return(&model.CatsPage{Cats: []*model.Cat{}}, nil)
+			},
+			ctx:   ctx,
+			query: "?limit=5&cursor=qwerty&vaccinated=true&dateBirthFrom=2018-09-22&name=Some&sort=-dateBirth",
+			inputList: &model.ListCats{
+				Limit:         5,
+				Cursor:        "qwerty",
+				Vaccinated:    &vaccinated,
+				DateBirthFrom: &dateBirthFrom,
+				NamePrefix:    "Some",
+				SortBy:        model.CatsSortByDateBirth,
+				SortDesc:      true,
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "Service Error",
+			mockBehavior: func(s *mock_service.MockCat, input *model.ListCats) {
+				s.EXPECT().List(ctx, input).Return(nil, errors.New("service error"))
+			},
+			ctx:   ctx,
+			query: "",
+			inputList: &model.ListCats{
+				Limit:  defaultCatsLimit,
+				SortBy: model.CatsSortByName,
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "Invalid limit",
+			mockBehavior:       func(s *mock_service.MockCat, input *model.ListCats) {},
+			ctx:                ctx,
+			query:              "?limit=1000",
+			inputList:          nil,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "Invalid date",
+			mockBehavior:       func(s *mock_service.MockCat, input *model.ListCats) {},
+			ctx:                ctx,
+			query:              "?dateBirthTo=yesterday",
+			inputList:          nil,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "Invalid sort",
+			mockBehavior:       func(s *mock_service.MockCat, input *model.ListCats) {},
+			ctx:                ctx,
+			query:              "?sort=vaccinated",
+			inputList:          nil,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Init dependencies
+			c := gomock.NewController(t)
+			mockCat := mock_service.NewMockCat(c)
+			testCase.mockBehavior(mockCat, testCase.inputList)
+			services := &service.Service{Cat: mockCat}
+			cfg := config.Config{}
+			validator := NewValidator()
+			handlers := NewHandler(services, &cfg, validator)
+
+			// Init server
+			r := InitRouter(handlers, &cfg)
+
+			// Test request
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest("GET", "/cats"+testCase.query, nil)
+
+			// Execute the request
+			r.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+func TestGetCat(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockCat, ownerID, id string)
+	ctx := context.Background()
+	testTable := []struct {
+		name               string
+		ctx                context.Context
+		userID             string
+		role               string
+		ownerID            string
+		catID              string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(s *mock_service.MockCat, ownerID, id string) {
+				s.EXPECT().Get(ctx, ownerID, id).Return(&model.Cat{ID: id}, nil)
+			},
+			ctx:                ctx,
+			catID:              "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "OK with owner",
+			mockBehavior: func(s *mock_service.MockCat, ownerID, id string) {
+				s.EXPECT().Get(ctx, ownerID, id).Return(&model.Cat{ID: id, OwnerID: ownerID}, nil)
+			},
+			ctx:                ctx,
+			userID:             "1c219a3f-a959-4395-81f0-4e735040ed61",
+			role:               model.RoleViewer,
+			ownerID:            "1c219a3f-a959-4395-81f0-4e735040ed61",
+			catID:              "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "OK for admin",
+			mockBehavior: func(s *mock_service.MockCat, ownerID, id string) {
+				s.EXPECT().Get(ctx, ownerID, id).Return(&model.Cat{ID: id, OwnerID: uuid.New().String()}, nil)
+			},
+			ctx:                ctx,
+			userID:             "1c219a3f-a959-4395-81f0-4e735040ed61",
+			role:               model.RoleAdmin,
+			ownerID:            "",
+			catID:              "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "Cat doesn't exist",
+			mockBehavior: func(s *mock_service.MockCat, ownerID, id string) {
+				s.EXPECT().Get(ctx, ownerID, id).Return(nil, apperror.NotFound("cat with given UUID doesn't exist"))
+			},
+			ctx:                ctx,
+			userID:             "1c219a3f-a959-4395-81f0-4e735040ed61",
+			role:               model.RoleEditor,
+			ownerID:            "1c219a3f-a959-4395-81f0-4e735040ed61",
+			catID:              "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "No role",
+			mockBehavior:       func(s *mock_service.MockCat, ownerID, id string) {},
+			ctx:                ctx,
+			userID:             "1c219a3f-a959-4395-81f0-4e735040ed61",
+			catID:              "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "Service Error",
+			mockBehavior: func(s *mock_service.MockCat, ownerID, id string) {
+				s.EXPECT().Get(ctx, ownerID, id).Return(nil, errors.New("service error"))
+			},
+			ctx:                ctx,
+			catID:              "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Init dependencies
+			c := gomock.NewController(t)
+			mockCat := mock_service.NewMockCat(c)
+			testCase.mockBehavior(mockCat, testCase.ownerID, testCase.catID)
+			cfg := newAuthConfig(testCase.userID != "")
+			services := &service.Service{Cat: mockCat, Auth: newAuthService(&cfg)}
+			validator := NewValidator()
+			handlers := NewHandler(services, &cfg, validator)
+
+			// Init server
+			r := InitRouter(handlers, &cfg)
+
+			// Test request
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest("GET", "/cats/"+testCase.catID, nil)
+			if testCase.userID != "" {
+				req.Header.Set(echo.HeaderAuthorization,
+					"Bearer "+newAccessToken(t, &cfg, testCase.userID, testCase.role))
+			}
+
+			// Execute the request
+			r.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+func TestCatHistory(t *testing.T) {
+	cfg := newAuthConfig(true)
+	repo := repository.NewRepositoryMemory()
+	services := &service.Service{
+		Cat:  service.NewCatService(repo, &rediscache.Cache{Cat: noCatCache{}}),
+		Auth: newAuthServiceWith(&cfg, repo),
+	}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	signUp := func(email string) (*model.Tokens, string) {
+		t.Helper()
+		var tokens model.Tokens
+		require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+			`{"userName":"Some name","email":"`+email+`","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+		var user model.User
+		require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/users/me", "", tokens.AccessToken, &user))
+		return &tokens, user.ID
+	}
+	history := func(tokens *model.Tokens, target string) *model.CatChangesPage {
+		t.Helper()
+		var page model.CatChangesPage
+		require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, target, "", tokens.AccessToken, &page))
+		return &page
+	}
+
+	owner, ownerID := signUp("qwerty@gmail.com")
+	stranger, _ := signUp("stranger@gmail.com")
+	_, adminID := signUp("admin@gmail.com")
+	require.NoError(t, repo.Auth.UpdateUserRole(context.Background(), adminID, model.RoleAdmin))
+	var admin model.Tokens
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/auth/sign-in",
+		`{"email":"admin@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &admin))
+
+	var created OKResponse
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/cats/",
+		`{"name":"Some name","dateBirth":"2018-09-22T00:00:00Z","vaccinated":false}`, owner.AccessToken, &created))
+	target := "/cats/" + created.Message
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPut, target, `{"vaccinated":true}`, owner.AccessToken, nil))
+	// updates changing nothing aren't recorded
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPut, target, `{"vaccinated":true}`, owner.AccessToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPut, target, `{"name":"Other name"}`, admin.AccessToken, nil))
+
+	page := history(owner, target+"/history")
+	require.Len(t, page.Changes, 3)
+	assert.Empty(t, page.NextCursor)
+	renamed, vaccinated, create := page.Changes[0], page.Changes[1], page.Changes[2]
+	assert.Equal(t, model.CatOperationUpdate, renamed.Operation)
+	assert.Equal(t, adminID, renamed.ActorID)
+	assert.Equal(t, ownerID, renamed.OwnerID)
+	assert.Equal(t, []string{"name"}, renamed.Fields)
+	assert.Equal(t, "Some name", renamed.Before.Name)
+	assert.Equal(t, "Other name", renamed.After.Name)
+	assert.Equal(t, ownerID, vaccinated.ActorID)
+	assert.Equal(t, []string{"vaccinated"}, vaccinated.Fields)
+	assert.Equal(t, model.CatOperationCreate, create.Operation)
+	assert.Equal(t, ownerID, create.ActorID)
+	assert.Equal(t, []string{"name", "dateBirth", "ownerId"}, create.Fields)
+	assert.Nil(t, create.Before)
+
+	first := history(owner, target+"/history?limit=2")
+	require.Len(t, first.Changes, 2)
+	require.NotEmpty(t, first.NextCursor)
+	second := history(owner, target+"/history?limit=2&cursor="+first.NextCursor)
+	require.Len(t, second.Changes, 1)
+	assert.Equal(t, create.ID, second.Changes[0].ID)
+
+	// the history is kept after the cat is deleted, but only for the owner and admins
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodDelete, target, "", owner.AccessToken, nil))
+	page = history(&admin, target+"/history")
+	require.Len(t, page.Changes, 4)
+	assert.Equal(t, model.CatOperationDelete, page.Changes[0].Operation)
+	assert.Equal(t, []string{"deletedAt"}, page.Changes[0].Fields)
+	assert.Equal(t, "Other name", page.Changes[0].Before.Name)
+	assert.Nil(t, page.Changes[0].Before.DeletedAt)
+	assert.NotNil(t, page.Changes[0].After.DeletedAt)
+	assert.Len(t, history(owner, target+"/history").Changes, 4)
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodGet, target+"/history", "", stranger.AccessToken, nil))
+
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodGet, "/cats/"+uuid.New().String()+"/history", "",
+		owner.AccessToken, nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(t, r, http.MethodGet, target+"/history?limit=0", "",
+		owner.AccessToken, nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(t, r, http.MethodGet, target+"/history?cursor=qwerty", "",
+		owner.AccessToken, nil))
+}
+
+func TestCatTrash(t *testing.T) {
+	cfg := newAuthConfig(true)
+	repo := repository.NewRepositoryMemory()
+	services := &service.Service{
+		Cat:  service.NewCatService(repo, &rediscache.Cache{Cat: noCatCache{}}),
+		Auth: newAuthServiceWith(&cfg, repo),
+	}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	signUp := func(email string) *model.Tokens {
+		t.Helper()
+		var tokens model.Tokens
+		require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+			`{"userName":"Some name","email":"`+email+`","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+		return &tokens
+	}
+	trash := func(tokens *model.Tokens) *model.CatsPage {
+		t.Helper()
+		var page model.CatsPage
+		require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/cats/trash", "", tokens.AccessToken, &page))
+		return &page
+	}
+
+	owner := signUp("qwerty@gmail.com")
+	stranger := signUp("stranger@gmail.com")
+	var created OKResponse
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/cats/",
+		`{"name":"Some name","dateBirth":"2018-09-22T00:00:00Z","vaccinated":false}`, owner.AccessToken, &created))
+	target := "/cats/" + created.Message
+	image := filepath.Join(t.TempDir(), "image.webp")
+	require.NoError(t, os.WriteFile(image, []byte("image"), 0o600))
+	require.NoError(t, services.UploadImage(context.Background(), "", "", created.Message, 0, image))
+
+	assert.Empty(t, trash(owner).Cats)
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodPost, target+"/restore", "", owner.AccessToken, nil))
+
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodDelete, target, "", owner.AccessToken, nil))
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodGet, target, "", owner.AccessToken, nil))
+	var cats model.CatsPage
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/cats", "", owner.AccessToken, &cats))
+	assert.Empty(t, cats.Cats)
+	page := trash(owner)
+	require.Len(t, page.Cats, 1)
+	assert.Equal(t, created.Message, page.Cats[0].ID)
+	assert.NotNil(t, page.Cats[0].DeletedAt)
+	assert.Empty(t, trash(stranger).Cats)
+
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodPost, target+"/restore", "", stranger.AccessToken, nil))
+	var restored model.Cat
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, target+"/restore", "", owner.AccessToken, &restored))
+	assert.Equal(t, created.Message, restored.ID)
+	assert.Nil(t, restored.DeletedAt)
+	assert.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, target, "", owner.AccessToken, nil))
+	assert.Empty(t, trash(owner).Cats)
+
+	// cats are purged only after the retention period
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodDelete, target, "", owner.AccessToken, nil))
+	purged, err := services.PurgeTrash(context.Background(), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+	purged, err = services.PurgeTrash(context.Background(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Empty(t, trash(owner).Cats)
+	assert.NoFileExists(t, image)
+	assert.Equal(t, http.StatusNotFound, sendJSON(t, r, http.MethodPost, target+"/restore", "", owner.AccessToken, nil))
+
+	var history model.CatChangesPage
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, target+"/history", "", owner.AccessToken, &history))
+	require.NotEmpty(t, history.Changes)
+	purge := history.Changes[0]
+	assert.Equal(t, model.CatOperationPurge, purge.Operation)
+	assert.Empty(t, purge.ActorID)
+	assert.Nil(t, purge.After)
+	assert.Equal(t, model.CatOperationRestore, history.Changes[2].Operation)
+	assert.Equal(t, []string{"deletedAt"}, history.Changes[2].Fields)
+}
+
+func TestPurgeTrashHistoryFailure(t *testing.T) {
+	c := gomock.NewController(t)
+	repo := mock_repository.NewMockCat(c)
+	dir := t.TempDir()
+	cats := make([]*model.Cat, 0)
+	for _, name := range []string{"first.webp", "second.webp"} {
+		image := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(image, []byte("image"), 0o600))
+		cats = append(cats, &model.Cat{ID: uuid.New().String(), Name: "Some name", ImagePath: image})
+	}
+	repo.EXPECT().PurgeCats(gomock.Any(), gomock.Any()).Return(cats, nil)
+	repo.EXPECT().AddCatChange(gomock.Any(), gomock.Any()).Return(errors.New("repository error")).Times(len(cats))
+	services := service.NewCatService(&repository.Repository{Cat: repo}, &rediscache.Cache{Cat: noCatCache{}})
+
+	// purged cats are gone even if their purges aren't recorded
+	purged, err := services.PurgeTrash(context.Background(), time.Now())
+	assert.Error(t, err)
+	assert.Equal(t, len(cats), purged)
+	for _, cat := range cats {
+		assert.NoFileExists(t, cat.ImagePath)
+	}
+}
+
+func TestUpdateCatRetries(t *testing.T) {
+	c := gomock.NewController(t)
+	repo := mock_repository.NewMockCat(c)
+	id := uuid.New().String()
+	name := "Other name"
+	input := &model.UpdateCat{Name: &name}
+	services := service.NewCatService(&repository.Repository{Cat: repo}, &rediscache.Cache{Cat: noCatCache{}})
+
+	// the cat changed by others every time is given up on after a few attempts
+	repo.EXPECT().Get(gomock.Any(), "", id).Return(&model.Cat{ID: id, Version: 1}, nil).Times(3)
+	repo.EXPECT().Update(gomock.Any(), "", id, int64(1), input).
+		Return(nil, apperror.PreconditionFailed("cat with given UUID has been changed, get it again")).Times(3)
+	_, err := services.Update(context.Background(), "", "", id, 0, input)
+	assert.ErrorIs(t, err, apperror.ErrPreconditionFailed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = services.Update(ctx, "", "", id, 0, input)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCatETag(t *testing.T) {
+	cfg := newAuthConfig(true)
+	cfg.ImagePath = t.TempDir() + string(os.PathSeparator)
+	repo := repository.NewRepositoryMemory()
+	services := &service.Service{
+		Cat:  service.NewCatService(repo, &rediscache.Cache{Cat: noCatCache{}}),
+		Auth: newAuthServiceWith(&cfg, repo),
+	}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	var tokens model.Tokens
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+	var created OKResponse
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/cats/",
+		`{"name":"Some name","dateBirth":"2018-09-22T00:00:00Z","vaccinated":false}`, tokens.AccessToken, &created))
+	target := "/cats/" + created.Message
+	send := func(method, body, ifMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodGet, "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	w = send(http.MethodPut, `{"vaccinated":true}`, `"1"`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	var cat model.Cat
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cat))
+	assert.Equal(t, int64(2), cat.Version)
+	assert.True(t, cat.Vaccinated)
+
+	// the other client still has the first version
+	w = send(http.MethodPut, `{"name":"Other name"}`, `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, MIMEApplicationProblemJSON, w.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodDelete, "", `"1"`).Code)
+	for _, ifMatch := range []string{`2`, `W/"2"`, `"2", "3"`, `"0"`, `"qwerty"`, `""`} {
+		assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodPut, `{"name":"Other name"}`, ifMatch).Code,
+			"If-Match %s", ifMatch)
+	}
+	w = send(http.MethodGet, "", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cat))
+	assert.Equal(t, "Some name", cat.Name)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// requests without If-Match or with "*" aren't checked
+	w = send(http.MethodPut, `{"name":"Other name"}`, "*")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	w = send(http.MethodPut, `{"name":"Another name"}`, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	// stale upload leaves no file behind
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, err := form.CreateFormFile("image", "image.png")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("\x89PNG\r\n\x1a\nimage"))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, target+"/image", body)
+	req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+	req.Header.Set("If-Match", `"3"`)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	files, err := os.ReadDir(cfg.ImagePath)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, "", `"4"`).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "", `"4"`).Code)
+}
+
+// noCatCache is the cache of cats which never has the cat, so every read goes to the repository.
+type noCatCache struct{}
+
+func (noCatCache) Get(ctx context.Context, id string) (*model.Cat, bool) { return nil, false }
+func (noCatCache) Set(ctx context.Context, input *model.Cat) error       { return nil }
+func (noCatCache) Update(ctx context.Context, input *model.Cat) error    { return nil }
+func (noCatCache) Delete(ctx context.Context, id string) error           { return nil }
+
+// fakeCatCache is the cache of cats kept in memory.
+type fakeCatCache struct {
+	cats map[string]model.Cat
+}
+
+func (c *fakeCatCache) Get(ctx context.Context, id string) (*model.Cat, bool) {
+	cat, ok := c.cats[id]
+	return &cat, ok
+}
+
+func (c *fakeCatCache) Set(ctx context.Context, input *model.Cat) error {
+	c.cats[input.ID] = *input
+	return nil
+}
+
+func (c *fakeCatCache) Update(ctx context.Context, input *model.Cat) error {
+	return c.Set(ctx, input)
+}
+
+func (c *fakeCatCache) Delete(ctx context.Context, id string) error {
+	delete(c.cats, id)
+	return nil
+}
+
+// testKeys are keys tokens are signed with in tests.
+var testKeys = newTestKeys("test-key")
+
+// newTestKeys returns provider of a generated key with the given ID.
+func newTestKeys(id string) *keys.Set {
+	key, err := keys.GenerateEd25519(id, time.Now())
+	if err != nil {
+		panic(err)
+	}
+
+	return keys.NewSet(key)
+}
+
+// newAuthConfig returns config of the application with the authentication mode.
+func newAuthConfig(authMode bool) config.Config {
+	return config.Config{
+		AuthMode:          authMode,
+		JWTIssuer:         "first-task",
+		JWTAudience:       "first-task",
+		AccessTokenTTL:    time.Minute,
+		RefreshTokenTTL:   time.Hour,
+		MailFrom:          "no-reply@first-task.local",
+		PasswordResetTTL:  time.Hour,
+		VerificationTTL:   time.Hour,
+		TOTPIssuer:        "first-task",
+		ChallengeTokenTTL: time.Minute,
+		// cheap parameters of argon2id, so tests are fast
+		PasswordHasher:       password.AlgorithmArgon2id,
+		Argon2Memory:         1024,
+		Argon2Iterations:     1,
+		Argon2Parallelism:    1,
+		PasswordMinLength:    10,
+		PasswordMinClasses:   3,
+		PasswordRejectCommon: true,
+	}
+}
+
+// newTokens returns token pair of the session of the user with verified email
+// issued with the config and test keys.
+func newTokens(t *testing.T, cfg *config.Config, id, role, sessionID string) *model.Tokens {
+	tokens, err := service.NewTokenManager(cfg, testKeys).Generate(&model.User{
+		ID: id, Email: "qwerty@outlook.com", Role: role, EmailVerified: true,
+	}, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tokens
+}
+
+// newAccessToken returns access token of the user issued with the config.
+func newAccessToken(t *testing.T, cfg *config.Config, id, role string) string {
+	return newTokens(t, cfg, id, role, uuid.New().String()).AccessToken
+}
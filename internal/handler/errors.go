@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MIMEApplicationProblemJSON is media type of error responses.
+	MIMEApplicationProblemJSON = "application/problem+json"
+
+	problemTypePrefix = "urn:first-task:problem:"
+)
+
+// Stable error codes of domain errors.
+const (
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeValidationFailed   = "validation_failed"
+	CodeTooManyRequests    = "too_many_requests"
+	CodePreconditionFailed = "precondition_failed"
+	CodeInternalError      = "internal_error"
+)
+
+// errorStatusCode maps domain error to HTTP status code.
+func errorStatusCode(err error) int {
+	switch {
+	case errors.Is(err, apperror.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperror.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperror.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, apperror.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apperror.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, apperror.ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, apperror.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorCode maps domain error to stable machine-readable code.
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, apperror.ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, apperror.ErrConflict):
+		return CodeConflict
+	case errors.Is(err, apperror.ErrUnauthorized):
+		return CodeUnauthorized
+	case errors.Is(err, apperror.ErrForbidden):
+		return CodeForbidden
+	case errors.Is(err, apperror.ErrValidation):
+		return CodeValidationFailed
+	case errors.Is(err, apperror.ErrTooManyRequests):
+		return CodeTooManyRequests
+	case errors.Is(err, apperror.ErrPreconditionFailed):
+		return CodePreconditionFailed
+	default:
+		return CodeInternalError
+	}
+}
+
+// statusCode returns machine-readable code for the HTTP status, e.g. unsupported_media_type.
+func statusCode(status int) string {
+	if status == http.StatusInternalServerError {
+		return CodeInternalError
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// newProblem converts error returned by handler or middleware to RFC 7807 problem.
+// Details of unexpected errors aren't exposed to the client.
+func newProblem(err error, instance string) *model.Problem {
+	problem := &model.Problem{
+		Status:   http.StatusInternalServerError,
+		Code:     CodeInternalError,
+		Instance: instance,
+	}
+
+	var appErr *apperror.Error
+	var httpErr *echo.HTTPError
+	switch {
+	case errors.As(err, &appErr):
+		problem.Status = errorStatusCode(appErr)
+		problem.Code = errorCode(appErr)
+		problem.Detail = appErr.Message
+		problem.Errors = appErr.Fields
+	case errors.As(err, &httpErr):
+		problem.Status = httpErr.Code
+		problem.Code = statusCode(httpErr.Code)
+		if httpErr.Code != http.StatusInternalServerError {
+			problem.Detail = fmt.Sprintf("%v", httpErr.Message)
+		}
+	}
+
+	problem.Type = problemTypePrefix + problem.Code
+	problem.Title = http.StatusText(problem.Status)
+	return problem
+}
+
+// HTTPErrorHandler replies to failed requests with application/problem+json body.
+func HTTPErrorHandler(err error, ctx echo.Context) {
+	if ctx.Response().Committed {
+		return
+	}
+
+	problem := newProblem(err, ctx.Request().URL.Path)
+	if problem.Status >= http.StatusInternalServerError {
+		logrus.Error("handler: request failed - ", err)
+	}
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) && appErr.RetryAfter > 0 {
+		// whole seconds, rounded up so the client doesn't retry too early
+		seconds := (appErr.RetryAfter + time.Second - 1) / time.Second
+		ctx.Response().Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+	}
+
+	if ctx.Request().Method == http.MethodHead {
+		err = ctx.NoContent(problem.Status)
+	} else {
+		var body []byte
+		if body, err = json.Marshal(problem); err == nil {
+			err = ctx.Blob(problem.Status, MIMEApplicationProblemJSON, body)
+		}
+	}
+	if err != nil {
+		logrus.Error("handler: can't send error response - ", err)
+	}
+}
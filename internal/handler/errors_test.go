@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	testTable := []struct {
+		name               string
+		err                error
+		expectedProblem    model.Problem
+		expectedRetryAfter string
+	}{
+		{
+			name: "Not found",
+			err:  fmt.Errorf("service: can't get cat - %w", apperror.NotFound("cat with given UUID doesn't exist")),
+			expectedProblem: model.Problem{
+				Type:     "urn:first-task:problem:not_found",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   "cat with given UUID doesn't exist",
+				Instance: "/cats/qwerty",
+				Code:     CodeNotFound,
+			},
+		},
+		{
+			name: "Validation failed",
+			err: apperror.ValidationFields("wrong values of fields", []model.FieldError{
+				{Field: "email", Message: "must be a valid email"},
+			}),
+			expectedProblem: model.Problem{
+				Type:     "urn:first-task:problem:validation_failed",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "wrong values of fields",
+				Instance: "/cats/qwerty",
+				Code:     CodeValidationFailed,
+				Errors:   []model.FieldError{{Field: "email", Message: "must be a valid email"}},
+			},
+		},
+		{
+			name: "Too many requests",
+			err:  apperror.TooManyRequests("too many failed sign in attempts", 90*time.Second+time.Millisecond),
+			expectedProblem: model.Problem{
+				Type:     "urn:first-task:problem:too_many_requests",
+				Title:    "Too Many Requests",
+				Status:   http.StatusTooManyRequests,
+				Detail:   "too many failed sign in attempts",
+				Instance: "/cats/qwerty",
+				Code:     CodeTooManyRequests,
+			},
+			expectedRetryAfter: "91",
+		},
+		{
+			name: "Precondition failed",
+			err:  apperror.PreconditionFailed("cat with given UUID has been changed, get it again"),
+			expectedProblem: model.Problem{
+				Type:     "urn:first-task:problem:precondition_failed",
+				Title:    "Precondition Failed",
+				Status:   http.StatusPreconditionFailed,
+				Detail:   "cat with given UUID has been changed, get it again",
+				Instance: "/cats/qwerty",
+				Code:     CodePreconditionFailed,
+			},
+		},
+		{
+			name: "Echo error",
+			err:  echo.ErrUnsupportedMediaType,
+			expectedProblem: model.Problem{
+				Type:     "urn:first-task:problem:unsupported_media_type",
+				Title:    "Unsupported Media Type",
+				Status:   http.StatusUnsupportedMediaType,
+				Detail:   "Unsupported Media Type",
+				Instance: "/cats/qwerty",
+				Code:     "unsupported_media_type",
+			},
+		},
+		{
+			name: "Internal error",
+			err:  errors.New("mongo repository: can't get cat - connection refused"),
+			expectedProblem: model.Problem{
+				Type:     "urn:first-task:problem:internal_error",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Instance: "/cats/qwerty",
+				Code:     CodeInternalError,
+			},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			router := echo.New()
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/cats/qwerty", nil)
+
+			HTTPErrorHandler(testCase.err, router.NewContext(req, w))
+
+			var problem model.Problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			assert.Equal(t, testCase.expectedProblem.Status, w.Code)
+			assert.Equal(t, MIMEApplicationProblemJSON, w.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, testCase.expectedProblem, problem)
+			assert.Equal(t, testCase.expectedRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
@@ -4,14 +4,10 @@ import (
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/malkev1ch/first-task/internal/model"
 	"github.com/malkev1ch/first-task/internal/service"
 )
 
-type ErrorResponse struct {
-	Message string `json:"message"`
-	Error   string `json:"error"`
-}
-
 type OKResponse struct {
 	Message string `json:"message"`
 }
@@ -44,36 +40,90 @@ func NewHandler(services *service.Service, cfg *config.Config, validator *Valida
 
 func InitRouter(handlers *Handler, cfg *config.Config) *echo.Echo {
 	router := echo.New()
+	router.HTTPErrorHandler = HTTPErrorHandler
 	router.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
 		AllowHeaders: []string{"*"},
 		AllowMethods: []string{"*"},
+		// clients read versions of cats from ETag to send them back in If-Match
+		ExposeHeaders: []string{"ETag"},
 	}))
 
+	router.GET("/.well-known/jwks.json", handlers.JWKS)
+
+	authMiddleware := handlers.jwtAuth
+
 	auth := router.Group("/auth")
 	{
 		auth.POST("/sign-up", handlers.SignUp)
 		auth.POST("/sign-in", handlers.SignIn)
 		auth.POST("/refresh", handlers.RefreshToken)
+		auth.POST("/password/forgot", handlers.ForgotPassword)
+		auth.POST("/password/reset", handlers.ResetPassword)
+		auth.POST("/verify", handlers.VerifyEmail)
+		auth.POST("/2fa/verify", handlers.VerifyTwoFactor)
+		auth.GET("/oidc/login", handlers.OIDCLogin)
+		auth.GET("/oidc/callback", handlers.OIDCCallback)
+		// logout and sessions belong to the user of access token, so they need it regardless of auth mode
+		auth.POST("/logout", handlers.Logout, authMiddleware)
+		auth.POST("/logout-all", handlers.LogoutEverywhere, authMiddleware)
+		auth.GET("/sessions", handlers.ListSessions, authMiddleware)
+		auth.DELETE("/sessions/:id", handlers.DeleteSession, authMiddleware)
+		auth.POST("/verify/resend", handlers.ResendVerification, authMiddleware)
+		auth.POST("/2fa/enroll", handlers.EnrollTwoFactor, authMiddleware)
+		auth.POST("/2fa/confirm", handlers.ConfirmTwoFactor, authMiddleware)
+		auth.POST("/2fa/disable", handlers.DisableTwoFactor, authMiddleware)
+		auth.POST("/api-keys", handlers.CreateAPIKey, authMiddleware)
+		auth.GET("/api-keys", handlers.ListAPIKeys, authMiddleware)
+		auth.DELETE("/api-keys/:id", handlers.DeleteAPIKey, authMiddleware)
+	}
+
+	// profile belongs to the user of access token, so it needs the token regardless of auth mode
+	me := router.Group("/users/me", authMiddleware)
+	{
+		me.GET("", handlers.GetProfile)
+		me.PATCH("", handlers.UpdateProfile)
+		me.POST("/password", handlers.ChangePassword)
+		me.DELETE("", handlers.DeleteAccount)
 	}
 
 	cat := router.Group("/cats")
 
 	if cfg.AuthMode {
-		configJWTMiddleware := middleware.JWTConfig{
-			Claims:     &service.JwtCustomClaims{},
-			SigningKey: []byte(cfg.JWTKey),
-		}
-		cat.Use(middleware.JWTWithConfig(configJWTMiddleware))
+		// batch jobs call cats API with API keys instead of signing in
+		cat.Use(handlers.jwtOrAPIKeyAuth)
+	}
+
+	read := handlers.requireRole(model.RoleAdmin, model.RoleEditor, model.RoleViewer)
+	write := handlers.requireRole(model.RoleAdmin, model.RoleEditor)
+	verified := handlers.requireVerifiedEmail
+	readScope := handlers.requireScope(model.ScopeCatsRead, model.ScopeCatsWrite)
+	writeScope := handlers.requireScope(model.ScopeCatsWrite)
+	{
+		cat.GET("", handlers.ListCats, read, readScope)
+		cat.GET("/", handlers.ListCats, read, readScope)
+		cat.GET("/trash", handlers.ListTrashedCats, read, readScope)
+		cat.GET("/:uuid", handlers.GetCat, read, readScope)
+		cat.POST("/", handlers.CreateCat, write, writeScope, verified)
+		cat.PUT("/:uuid", handlers.UpdateCat, write, writeScope, verified)
+		cat.DELETE("/:uuid", handlers.DeleteCat, write, writeScope, verified)
+		cat.POST("/:uuid/restore", handlers.RestoreCat, write, writeScope, verified)
+		cat.POST("/:uuid/image", handlers.UploadCatImage, write, writeScope, verified)
+		cat.GET("/:uuid/image", handlers.GetCatImage, read, readScope)
+		cat.GET("/:uuid/history", handlers.CatHistory, read, readScope)
 	}
 
+	// users are managed only by admins, so the group needs the token and the role regardless of auth mode
+	admin := router.Group("/admin/users", authMiddleware, handlers.requireAdmin)
 	{
-		cat.GET("/:uuid", handlers.GetCat)
-		cat.POST("/", handlers.CreateCat)
-		cat.PUT("/:uuid", handlers.UpdateCat)
-		cat.DELETE("/:uuid", handlers.DeleteCat)
-		cat.POST("/:uuid/image", handlers.UploadCatImage)
-		cat.GET("/:uuid/image", handlers.GetCatImage)
+		admin.GET("", handlers.ListUsers)
+		admin.GET("/lockouts", handlers.ListLockouts)
+		admin.GET("/:uuid", handlers.GetUser)
+		admin.POST("/:uuid/disable", handlers.DisableUser)
+		admin.POST("/:uuid/enable", handlers.EnableUser)
+		admin.POST("/:uuid/logout", handlers.ForceLogout)
+		admin.PUT("/:uuid/role", handlers.SetUserRole)
+		admin.DELETE("/:uuid/lockout", handlers.UnlockUser)
 	}
 	return router
 }
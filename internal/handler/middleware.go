@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+// userContextKey is the key of claims of access token or API key in echo context.
+const userContextKey = "user"
+
+// apiKeyHeader is the header API keys are sent in.
+const apiKeyHeader = "X-API-Key"
+
+// jwtAuth authenticates the request by access token from Authorization header
+// and puts claims of the token into context. Refresh and revoked tokens are rejected.
+func (h *Handler) jwtAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		header := ctx.Request().Header.Get(echo.HeaderAuthorization)
+		tokenString := strings.TrimPrefix(header, "Bearer ")
+		if header == "" || tokenString == header {
+			logrus.Error("handler: missing access token")
+			return apperror.Unauthorized("missing or malformed access token")
+		}
+
+		claims, err := h.Services.Authenticate(ctx.Request().Context(), tokenString)
+		if err != nil {
+			return err
+		}
+		ctx.Set(userContextKey, claims)
+
+		return next(ctx)
+	}
+}
+
+// jwtOrAPIKeyAuth authenticates the request by API key from X-API-Key header if the header is set,
+// otherwise it authenticates the request by access token the same way jwtAuth does.
+func (h *Handler) jwtOrAPIKeyAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	jwtAuth := h.jwtAuth(next)
+	return func(ctx echo.Context) error {
+		key := ctx.Request().Header.Get(apiKeyHeader)
+		if key == "" {
+			return jwtAuth(ctx)
+		}
+
+		claims, err := h.Services.AuthenticateAPIKey(ctx.Request().Context(), key)
+		if err != nil {
+			return err
+		}
+		ctx.Set(userContextKey, claims)
+
+		return next(ctx)
+	}
+}
+
+// requireScope allows the request authenticated by API key only if the key has one of the scopes.
+// Requests authenticated by access token and requests without authentication aren't limited.
+func (h *Handler) requireScope(scopes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			claims := userClaims(ctx)
+			if claims == nil || claims.TokenType != service.TokenTypeAPIKey {
+				return next(ctx)
+			}
+			for _, scope := range scopes {
+				for _, keyScope := range claims.Scopes {
+					if keyScope == scope {
+						return next(ctx)
+					}
+				}
+			}
+
+			logrus.Error("handler: scopes of API key don't allow the action")
+			return apperror.Forbidden("scopes of API key don't allow the action")
+		}
+	}
+}
+
+// requireRole allows the request only for users with one of the roles.
+// Every request is allowed if authentication is disabled.
+func (h *Handler) requireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if !h.Cfg.AuthMode {
+				return next(ctx)
+			}
+			if claims := userClaims(ctx); claims != nil {
+				for _, role := range roles {
+					if claims.Role == role {
+						return next(ctx)
+					}
+				}
+			}
+
+			logrus.Error("handler: role of the user doesn't allow the action")
+			return apperror.Forbidden("role of the user doesn't allow the action")
+		}
+	}
+}
+
+// requireAdmin allows the request only for admins. Unlike requireRole, it doesn't allow every request
+// if authentication is disabled, so it has to follow jwtAuth.
+func (h *Handler) requireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if claims := userClaims(ctx); claims != nil && claims.Role == model.RoleAdmin {
+			return next(ctx)
+		}
+
+		logrus.Error("handler: role of the user doesn't allow the action")
+		return apperror.Forbidden("role of the user doesn't allow the action")
+	}
+}
+
+// requireVerifiedEmail allows the request only for users with verified email if REQUIRE_VERIFIED_EMAIL is set.
+// Every request is allowed if authentication is disabled.
+func (h *Handler) requireVerifiedEmail(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if !h.Cfg.AuthMode || !h.Cfg.RequireVerified {
+			return next(ctx)
+		}
+		if claims := userClaims(ctx); claims != nil && claims.EmailVerified {
+			return next(ctx)
+		}
+
+		logrus.Error("handler: email of the user isn't verified")
+		return apperror.Forbidden("email of the user isn't verified, verify it and refresh tokens")
+	}
+}
+
+// userClaims returns claims of access token or API key put into context by jwtAuth or jwtOrAPIKeyAuth.
+// It returns nil if authentication is disabled.
+func userClaims(ctx echo.Context) *service.JwtCustomClaims {
+	claims, _ := ctx.Get(userContextKey).(*service.JwtCustomClaims)
+	return claims
+}
+
+// userID returns UUID of the authenticated user, empty if authentication is disabled.
+func userID(ctx echo.Context) string {
+	if claims := userClaims(ctx); claims != nil {
+		return claims.Subject
+	}
+
+	return ""
+}
+
+// catsOwner returns owner whose cats the user can access, empty means cats of all users.
+// Admins can access cats of all users.
+func catsOwner(ctx echo.Context) string {
+	if claims := userClaims(ctx); claims != nil && claims.Role != model.RoleAdmin {
+		return claims.Subject
+	}
+
+	return ""
+}
@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"context"
+	"crypto/ed25519"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/malkev1ch/first-task/internal/keys"
+	"github.com/malkev1ch/first-task/internal/mail"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/password"
+	"github.com/malkev1ch/first-task/internal/rediscache"
+	"github.com/malkev1ch/first-task/internal/repository"
+	"github.com/malkev1ch/first-task/internal/service"
+	mock_service "github.com/malkev1ch/first-task/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTAuth(t *testing.T) {
+	userID := "1c219a3f-a959-4395-81f0-4e735040ed61"
+	catID := "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4"
+	cfg := newAuthConfig(true)
+	tokens := newTokens(t, &cfg, userID, model.RoleEditor, uuid.New().String())
+
+	otherIssuerCfg := cfg
+	otherIssuerCfg.JWTIssuer = "other-service"
+	otherAudienceCfg := cfg
+	otherAudienceCfg.JWTAudience = "other-service"
+	// signs tokens with the given keys
+	signWith := func(provider keys.Provider) string {
+		tokens, err := service.NewTokenManager(&cfg, provider).Generate(&model.User{
+			ID: userID, Email: "qwerty@outlook.com", Role: model.RoleEditor,
+		}, uuid.New().String())
+		require.NoError(t, err)
+		return tokens.AccessToken
+	}
+	// token signed with the public key as HMAC secret, kid points to the public key
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, &service.JwtCustomClaims{
+		TokenType: service.TokenTypeAccess,
+		SessionID: uuid.New().String(),
+		StandardClaims: jwt.StandardClaims{
+			Subject:   userID,
+			Issuer:    cfg.JWTIssuer,
+			Audience:  cfg.JWTAudience,
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+	})
+	hmacToken.Header["kid"] = "test-key"
+	testKey, err := testKeys.VerificationKey("test-key")
+	require.NoError(t, err)
+	hmacTokenString, err := hmacToken.SignedString([]byte(testKey.Public().(ed25519.PublicKey)))
+	require.NoError(t, err)
+	expiredCfg := cfg
+	expiredCfg.AccessTokenTTL = -time.Minute
+
+	testTable := []struct {
+		name               string
+		authorization      string
+		expectedStatusCode int
+	}{
+		{
+			name:               "OK",
+			authorization:      "Bearer " + tokens.AccessToken,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Refresh token",
+			authorization:      "Bearer " + tokens.RefreshToken,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Missing token",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Not bearer",
+			authorization:      tokens.AccessToken,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Other issuer",
+			authorization:      "Bearer " + newAccessToken(t, &otherIssuerCfg, userID, model.RoleEditor),
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Other audience",
+			authorization:      "Bearer " + newAccessToken(t, &otherAudienceCfg, userID, model.RoleEditor),
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Other key with the same ID",
+			authorization:      "Bearer " + signWith(newTestKeys("test-key")),
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Unknown key",
+			authorization:      "Bearer " + signWith(newTestKeys("unknown-key")),
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "HMAC with public key",
+			authorization:      "Bearer " + hmacTokenString,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Expired token",
+			authorization:      "Bearer " + newAccessToken(t, &expiredCfg, userID, model.RoleEditor),
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Init dependencies
+			c := gomock.NewController(t)
+			mockCat := mock_service.NewMockCat(c)
+			if testCase.expectedStatusCode == http.StatusOK {
+				mockCat.EXPECT().Get(context.Background(), userID, catID).Return(&model.Cat{ID: catID, OwnerID: userID}, nil)
+			}
+			services := &service.Service{Cat: mockCat, Auth: newAuthService(&cfg)}
+			handlers := NewHandler(services, &cfg, NewValidator())
+
+			// Init server
+			r := InitRouter(handlers, &cfg)
+
+			// Test request
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/cats/"+catID, nil)
+			if testCase.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, testCase.authorization)
+			}
+
+			// Execute the request
+			r.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+// fakeDenylist keeps revoked tokens in memory the same way redis denylist does.
+type fakeDenylist struct {
+	mutex  sync.Mutex
+	tokens map[string]bool
+	users  map[string]time.Time
+}
+
+func (d *fakeDenylist) RevokeToken(ctx context.Context, id string, expiresAt time.Time) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.tokens[id] = true
+	return nil
+}
+
+func (d *fakeDenylist) RevokeUserTokens(ctx context.Context, userID string, revokedAt time.Time, ttl time.Duration) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.users[userID] = revokedAt
+	return nil
+}
+
+func (d *fakeDenylist) IsRevoked(ctx context.Context, id, userID string, issuedAt time.Time) (bool, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	revokedAt, ok := d.users[userID]
+	return d.tokens[id] || ok && issuedAt.UnixMicro() < revokedAt.UnixMicro(), nil
+}
+
+// fakeLoginLimiter locks accounts for a minute after maxAttempts failed sign in attempts in a row.
+type fakeLoginLimiter struct {
+	mutex       sync.Mutex
+	maxAttempts int64
+	failures    map[string]int64
+	lockouts    map[string]*model.Lockout
+}
+
+func newFakeLoginLimiter(maxAttempts int64) *fakeLoginLimiter {
+	return &fakeLoginLimiter{
+		maxAttempts: maxAttempts,
+		failures:    make(map[string]int64),
+		lockouts:    make(map[string]*model.Lockout),
+	}
+}
+
+func (l *fakeLoginLimiter) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if lockout, ok := l.lockouts[email]; ok {
+		return time.Until(lockout.LockedUntil), nil
+	}
+	return 0, nil
+}
+
+func (l *fakeLoginLimiter) RecordFailure(ctx context.Context, email, ip string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.failures[email]++
+	if l.failures[email] >= l.maxAttempts {
+		now := time.Now().UTC()
+		l.lockouts[email] = &model.Lockout{
+			Email: email, Failures: l.failures[email], LockedAt: now, LockedUntil: now.Add(time.Minute),
+		}
+	}
+	return nil
+}
+
+func (l *fakeLoginLimiter) Reset(ctx context.Context, email string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.failures, email)
+	delete(l.lockouts, email)
+	return nil
+}
+
+func (l *fakeLoginLimiter) Lockouts(ctx context.Context) ([]*model.Lockout, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	lockouts := make([]*model.Lockout, 0, len(l.lockouts))
+	for _, lockout := range l.lockouts {
+		lockouts = append(lockouts, lockout)
+	}
+	return lockouts, nil
+}
+
+func (l *fakeLoginLimiter) Unlock(ctx context.Context, email string) error {
+	return l.Reset(ctx, email)
+}
+
+// newAuthService returns auth service with in-memory repository, denylist and limiter
+// locking accounts after 3 failed sign in attempts, emails are written into MailDir of the config.
+func newAuthService(cfg *config.Config) *service.AuthService {
+	return newAuthServiceWith(cfg, repository.NewRepositoryMemory())
+}
+
+// newAuthServiceWith returns auth service like newAuthService, but with the given repository.
+func newAuthServiceWith(cfg *config.Config, repo *repository.Repository) *service.AuthService {
+	return newAuthServiceWithCache(cfg, repo, noCatCache{})
+}
+
+// newAuthServiceWithCache returns auth service like newAuthServiceWith, but with the given cache of cats.
+func newAuthServiceWithCache(cfg *config.Config, repo *repository.Repository,
+	cats rediscache.Cat) *service.AuthService {
+	hasher, err := password.NewHasher(cfg)
+	if err != nil {
+		panic(err)
+	}
+	denylist := &fakeDenylist{tokens: make(map[string]bool), users: make(map[string]time.Time)}
+	return service.NewAuthService(repo, service.NewTokenManager(cfg, testKeys), hasher, cats, denylist,
+		newFakeLoginLimiter(3), mail.NewFileMailer(cfg.MailFrom, cfg.MailDir), cfg)
+}
+
+// authenticateWith makes the mock authenticate requests by tokens issued with the config.
+func authenticateWith(s *mock_service.MockAuth, cfg *config.Config) {
+	s.EXPECT().Authenticate(gomock.Any(), gomock.Any()).DoAndReturn(newAuthService(cfg).Authenticate).AnyTimes()
+}
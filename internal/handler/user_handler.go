@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+//	swagger:route GET /users/me users GetProfile
+//
+//	Get profile of user.
+//
+//	Returns the signed in user.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: profileResponse
+//	 401: unauthorizedError
+//	 404: notFoundError
+//	 500: internalServerError
+func (h *Handler) GetProfile(ctx echo.Context) error {
+	user, err := h.Services.GetUser(ctx.Request().Context(), userID(ctx))
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, user)
+}
+
+//	swagger:route PATCH /users/me users UpdateProfile
+//
+//	Update profile of user.
+//
+//	Changes name of the signed in user. The name gets into tokens at the next
+//	sign in or refresh of tokens.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: profileResponse
+//	 400: badRequestError
+//	 401: unauthorizedError
+//	 404: notFoundError
+//	 422: unprocessableEntityError
+//	 500: internalServerError
+func (h *Handler) UpdateProfile(ctx echo.Context) error {
+	var input model.UpdateProfile
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	user, err := h.Services.UpdateProfile(ctx.Request().Context(), userID(ctx), &input)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, user)
+}
+
+//	swagger:route POST /users/me/password users ChangePassword
+//
+//	Change password of user.
+//
+//	Replaces password of the signed in user, the current password is required.
+//	The new password has to satisfy the password policy. All sessions of the user
+//	are ended and a new one is started on the device the request is sent from.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: changePasswordResponse
+//	 400: badRequestError
+//	 401: unauthorizedError
+//	 409: conflictError
+//	 422: unprocessableEntityError
+//	 429: tooManyRequestsError
+//	 500: internalServerError
+func (h *Handler) ChangePassword(ctx echo.Context) error {
+	var input model.ChangePassword
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	tokens, err := h.Services.ChangePassword(ctx.Request().Context(), userID(ctx), &input, h.device(ctx))
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, tokens)
+}
+
+//	swagger:route DELETE /users/me users DeleteAccount
+//
+//	Delete account of user.
+//
+//	Deletes the signed in user with all credentials and sessions, the password is required.
+//	Cats of the user are kept without owner, so only admins can manage them,
+//	and the user is removed from history of cats.
+//
+//	Security:
+//	 AdminAuth:
+//
+//	responses:
+//	 200: okResponse
+//	 400: badRequestError
+//	 401: unauthorizedError
+//	 422: unprocessableEntityError
+//	 429: tooManyRequestsError
+//	 500: internalServerError
+func (h *Handler) DeleteAccount(ctx echo.Context) error {
+	var input model.DeleteAccount
+	if err := ctx.Bind(&input); err != nil {
+		logrus.Error("handler: invalid content of body - ", err)
+		return err
+	}
+
+	if err := h.Validator.Validate(&input); err != nil {
+		logrus.Error("handler: validation failed - ", err)
+		return err
+	}
+
+	if err := h.Services.DeleteAccount(ctx.Request().Context(), userID(ctx), &input, h.device(ctx)); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, OKResponse{
+		Message: "OK",
+	})
+}
@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/rediscache"
+	"github.com/malkev1ch/first-task/internal/repository"
+	"github.com/malkev1ch/first-task/internal/service"
+	mock_service "github.com/malkev1ch/first-task/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetUserRole(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAuth, id, role string)
+	ctx := context.Background()
+	adminID := "1c219a3f-a959-4395-81f0-4e735040ed61"
+	testTable := []struct {
+		name               string
+		ctx                context.Context
+		role               string
+		userID             string
+		inputBody          string
+		inputRole          string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(s *mock_service.MockAuth, id, role string) {
+				s.EXPECT().SetUserRole(ctx, adminID, id, role).Return(nil)
+			},
+			ctx:                ctx,
+			role:               model.RoleAdmin,
+			userID:             "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			inputBody:          `{"role": "viewer"}`,
+			inputRole:          model.RoleViewer,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "User doesn't exist",
+			mockBehavior: func(s *mock_service.MockAuth, id, role string) {
+				s.EXPECT().SetUserRole(ctx, adminID, id, role).Return(apperror.NotFound("user with given UUID doesn't exist"))
+			},
+			ctx:                ctx,
+			role:               model.RoleAdmin,
+			userID:             "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			inputBody:          `{"role": "viewer"}`,
+			inputRole:          model.RoleViewer,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "Service Error",
+			mockBehavior: func(s *mock_service.MockAuth, id, role string) {
+				s.EXPECT().SetUserRole(ctx, adminID, id, role).Return(errors.New("service error"))
+			},
+			ctx:                ctx,
+			role:               model.RoleAdmin,
+			userID:             "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			inputBody:          `{"role": "viewer"}`,
+			inputRole:          model.RoleViewer,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "Unknown role",
+			mockBehavior:       func(s *mock_service.MockAuth, id, role string) {},
+			ctx:                ctx,
+			role:               model.RoleAdmin,
+			userID:             "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			inputBody:          `{"role": "owner"}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:               "Not admin",
+			mockBehavior:       func(s *mock_service.MockAuth, id, role string) {},
+			ctx:                ctx,
+			role:               model.RoleEditor,
+			userID:             "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			inputBody:          `{"role": "admin"}`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Init dependencies
+			c := gomock.NewController(t)
+			mockAuth := mock_service.NewMockAuth(c)
+			testCase.mockBehavior(mockAuth, testCase.userID, testCase.inputRole)
+			cfg := newAuthConfig(true)
+			authenticateWith(mockAuth, &cfg)
+			services := &service.Service{Auth: mockAuth}
+			validator := NewValidator()
+			handlers := NewHandler(services, &cfg, validator)
+
+			// Init server
+			r := InitRouter(handlers, &cfg)
+
+			// Test request
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest("PUT", "/admin/users/"+testCase.userID+"/role",
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization,
+				"Bearer "+newAccessToken(t, &cfg, adminID, testCase.role))
+
+			// Execute the request
+			r.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+func TestCatsRoles(t *testing.T) {
+	testTable := []struct {
+		name               string
+		role               string
+		method             string
+		expectedStatusCode int
+	}{
+		{
+			name:               "Viewer can't create cat",
+			role:               model.RoleViewer,
+			method:             http.MethodPost,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "Viewer can't delete cat",
+			role:               model.RoleViewer,
+			method:             http.MethodDelete,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "Editor deletes own cat",
+			role:               model.RoleEditor,
+			method:             http.MethodDelete,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Admin deletes cat of other user",
+			role:               model.RoleAdmin,
+			method:             http.MethodDelete,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Init dependencies
+			c := gomock.NewController(t)
+			mockCat := mock_service.NewMockCat(c)
+			userID := "1c219a3f-a959-4395-81f0-4e735040ed61"
+			catID := "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4"
+			if testCase.expectedStatusCode == http.StatusOK {
+				ownerID := userID
+				if testCase.role == model.RoleAdmin {
+					ownerID = ""
+				}
+				mockCat.EXPECT().Delete(context.Background(), userID, ownerID, catID, int64(0)).Return(nil)
+			}
+			cfg := newAuthConfig(true)
+			services := &service.Service{Cat: mockCat, Auth: newAuthService(&cfg)}
+			validator := NewValidator()
+			handlers := NewHandler(services, &cfg, validator)
+
+			// Init server
+			r := InitRouter(handlers, &cfg)
+
+			// Test request
+			w := httptest.NewRecorder()
+
+			target := "/cats/" + catID
+			if testCase.method == http.MethodPost {
+				target = "/cats/"
+			}
+			req := httptest.NewRequest(testCase.method, target, bytes.NewBufferString(`{}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+newAccessToken(t, &cfg, userID, testCase.role))
+
+			// Execute the request
+			r.ServeHTTP(w, req)
+
+			// Assert
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+		})
+	}
+}
+
+func TestProfile(t *testing.T) {
+	cfg := newAuthConfig(true)
+	repo := repository.NewRepositoryMemory()
+	cache := &fakeCatCache{cats: make(map[string]model.Cat)}
+	services := &service.Service{
+		Cat:  service.NewCatService(repo, &rediscache.Cache{Cat: cache}),
+		Auth: newAuthServiceWithCache(&cfg, repo, cache),
+	}
+	r := InitRouter(NewHandler(services, &cfg, NewValidator()), &cfg)
+	signIn := func(password string, out interface{}) int {
+		t.Helper()
+		return sendJSON(t, r, http.MethodPost, "/auth/sign-in",
+			`{"email":"qwerty@gmail.com","password":"`+password+`"}`, "", out)
+	}
+
+	var tokens, otherTokens model.Tokens
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", &tokens))
+	require.Equal(t, http.StatusOK, signIn("ZAQ!2wsx3edc", &otherTokens))
+
+	var user model.User
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodGet, "/users/me", "", "", nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/users/me", "", tokens.AccessToken, &user))
+	assert.Equal(t, "Some name", user.UserName)
+	assert.Equal(t, "qwerty@gmail.com", user.Email)
+	assert.Equal(t, model.RoleEditor, user.Role)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(t, r, http.MethodPatch, "/users/me",
+		`{"userName":""}`, tokens.AccessToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPatch, "/users/me",
+		`{"userName":"Another name"}`, tokens.AccessToken, &user))
+	assert.Equal(t, "Another name", user.UserName)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(t, r, http.MethodPost, "/users/me/password",
+		`{"currentPassword":"wrong password","newPassword":"1qaz@WSX3edc"}`, tokens.AccessToken, nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(t, r, http.MethodPost, "/users/me/password",
+		`{"currentPassword":"ZAQ!2wsx3edc","newPassword":"qwerty"}`, tokens.AccessToken, nil))
+	var newTokens model.Tokens
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodPost, "/users/me/password",
+		`{"currentPassword":"ZAQ!2wsx3edc","newPassword":"1qaz@WSX3edc"}`, tokens.AccessToken, &newTokens))
+
+	// sessions with the old password are ended, the device changing the password gets a new one
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodGet, "/users/me", "", tokens.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodPost, "/auth/refresh",
+		`{"refreshToken":"`+otherTokens.RefreshToken+`"}`, "", nil))
+	assert.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodGet, "/users/me", "", newTokens.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, signIn("ZAQ!2wsx3edc", nil))
+	assert.Equal(t, http.StatusOK, signIn("1qaz@WSX3edc", nil))
+
+	var created OKResponse
+	require.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/cats/",
+		`{"name":"Some name","dateBirth":"2018-09-22T00:00:00Z","vaccinated":false}`, newTokens.AccessToken, &created))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, sendJSON(t, r, http.MethodDelete, "/users/me",
+		`{"password":"ZAQ!2wsx3edc"}`, newTokens.AccessToken, nil))
+	require.Equal(t, http.StatusOK, sendJSON(t, r, http.MethodDelete, "/users/me",
+		`{"password":"1qaz@WSX3edc"}`, newTokens.AccessToken, nil))
+
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, r, http.MethodGet, "/users/me", "", newTokens.AccessToken, nil))
+	assert.Equal(t, http.StatusUnauthorized, signIn("1qaz@WSX3edc", nil))
+	// cats of the user are kept without owner
+	actual, err := services.Cat.Get(context.Background(), "", created.Message)
+	require.NoError(t, err)
+	assert.Empty(t, actual.OwnerID)
+	// history of the cat doesn't refer to the user anymore
+	changes, err := repo.Cat.ListCatChanges(context.Background(), &model.ListCatChanges{CatID: created.Message, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, changes.Changes, 1)
+	assert.Empty(t, changes.Changes[0].ActorID)
+	assert.Empty(t, changes.Changes[0].OwnerID)
+	assert.Empty(t, changes.Changes[0].After.OwnerID)
+	// the email can be registered again
+	assert.Equal(t, http.StatusCreated, sendJSON(t, r, http.MethodPost, "/auth/sign-up",
+		`{"userName":"Some name","email":"qwerty@gmail.com","password":"ZAQ!2wsx3edc"}`, "", nil))
+}
@@ -2,14 +2,27 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/malkev1ch/first-task/internal/apperror"
 	"github.com/malkev1ch/first-task/internal/model"
 )
 
 func NewValidator() *Validator {
+	v := validator.New()
+	// report fields by the names clients send them with
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return field.Name
+		}
+		return name
+	})
 	return &Validator{
-		validator: validator.New(),
+		validator: v,
 	}
 }
 
@@ -18,12 +31,48 @@ type Validator struct {
 }
 
 func (v *Validator) Validate(i interface{}) error {
-	return v.validator.Struct(i)
+	err := v.validator.Struct(i)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return apperror.Validation(err.Error())
+	}
+	fields := make([]model.FieldError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		fields = append(fields, model.FieldError{
+			Field:   fieldErr.Field(),
+			Message: fieldErrorMessage(fieldErr),
+		})
+	}
+	return apperror.ValidationFields("not enough fields in json body or wrong values of fields", fields)
 }
 
 func (v *Validator) ValidateUpdateCat(input *model.UpdateCat) error {
 	if input.Name != nil || input.DateBirth != nil || input.Vaccinated != nil {
 		return nil
 	}
-	return errors.New("there must be at least one field in update method")
+	return apperror.Validation("there must be at least one field in update method")
+}
+
+// fieldErrorMessage explains failed validation rule of the field.
+func fieldErrorMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "gt":
+		return fmt.Sprintf("must be longer than %s characters", fieldErr.Param())
+	case "min":
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", fieldErr.Param())
+	default:
+		return fmt.Sprintf("failed on the '%s' rule", fieldErr.Tag())
+	}
 }
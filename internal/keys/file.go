@@ -0,0 +1,105 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// manifestEntry type represents key listed in manifest file.
+type manifestEntry struct {
+	// ID is put into kid header of tokens signed with the key.
+	ID string `json:"kid"`
+	// File is the path of PEM encoded private key, relative to the manifest directory.
+	File string `json:"file"`
+	// NotBefore is the time the key is used for signing from.
+	NotBefore time.Time `json:"notBefore"`
+}
+
+// FileProvider type represents provider of keys listed in JSON manifest file, e.g.
+//
+//	[
+//	  {"kid": "2022-04", "file": "2022-04.pem", "notBefore": "2022-04-01T00:00:00Z"},
+//	  {"kid": "2022-05", "file": "2022-05.pem", "notBefore": "2022-05-01T00:00:00Z"}
+//	]
+//
+// Keys are rotated by adding a key with notBefore in future and removing the key
+// that was replaced longer than lifetime of refresh tokens ago.
+type FileProvider struct {
+	*Set
+	path string
+}
+
+// NewFileProvider returns provider of keys listed in the manifest file.
+func NewFileProvider(path string) (*FileProvider, error) {
+	p := &FileProvider{Set: NewSet(), path: path}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Reload method reads the manifest file and keys again. Keys aren't changed on error.
+func (p *FileProvider) Reload() error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("keys: can't read manifest - %w", err)
+	}
+	var entries []manifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("keys: can't parse manifest - %w", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("keys: manifest %s has no keys", p.path)
+	}
+
+	keys := make([]*Key, 0, len(entries))
+	ids := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.ID == "" || ids[entry.ID] {
+			return fmt.Errorf("keys: key id %q in manifest is empty or duplicated", entry.ID)
+		}
+		ids[entry.ID] = true
+
+		file := entry.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(p.path), file)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("keys: can't read key %s - %w", entry.ID, err)
+		}
+		key, err := ParsePEM(entry.ID, data, entry.NotBefore)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	p.replace(keys)
+
+	return nil
+}
+
+// Run method reloads keys every interval until the context is done,
+// so keys are rotated without restart of the application.
+func (p *FileProvider) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Reload(); err != nil {
+				logrus.Error("keys: can't reload keys, the previous ones are kept - ", err)
+			}
+		}
+	}
+}
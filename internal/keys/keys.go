@@ -0,0 +1,213 @@
+// Package keys provides asymmetric keys signing and verifying tokens.
+//
+// Every key has an ID put into kid header of tokens and time it is used for signing
+// from. Keys are published before that time, so everyone verifying tokens knows a new
+// key before the first token signed with it, and stay published after the next key
+// takes over, so tokens signed before the rotation stay valid till they expire.
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/malkev1ch/first-task/internal/model"
+)
+
+// ErrUnknownKey is returned when there is no key with the requested ID.
+var ErrUnknownKey = errors.New("keys: unknown key")
+
+// ErrNoSigningKey is returned when no key can be used for signing yet.
+var ErrNoSigningKey = errors.New("keys: no key to sign with")
+
+// Provider is the interface of storage of keys.
+type Provider interface {
+	// SigningKey returns the key new tokens are signed with.
+	SigningKey() (*Key, error)
+	// VerificationKey returns the key with the given ID.
+	VerificationKey(id string) (*Key, error)
+	// JWKS returns public keys of all keys.
+	JWKS() *model.JWKS
+}
+
+// Key type represents private key with its signing method.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   crypto.Signer
+	NotBefore time.Time
+}
+
+// NewKey returns key signing with RS256 for RSA keys and with EdDSA for Ed25519 keys.
+func NewKey(id string, private crypto.PrivateKey, notBefore time.Time) (*Key, error) {
+	key := &Key{ID: id, NotBefore: notBefore}
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private = jwt.SigningMethodRS256, private
+	case ed25519.PrivateKey:
+		key.Method, key.Private = jwt.SigningMethodEdDSA, private
+	default:
+		return nil, fmt.Errorf("keys: unsupported type %T of key %s, only RSA and Ed25519 keys are supported", private, id)
+	}
+
+	return key, nil
+}
+
+// ParsePEM returns key from PEM encoded PKCS #8 or PKCS #1 private key.
+func ParsePEM(id string, data []byte, notBefore time.Time) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("keys: key %s isn't PEM encoded", id)
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		var pkcs1Err error
+		if private, pkcs1Err = x509.ParsePKCS1PrivateKey(block.Bytes); pkcs1Err != nil {
+			return nil, fmt.Errorf("keys: can't parse key %s - %w", id, err)
+		}
+	}
+
+	return NewKey(id, private, notBefore)
+}
+
+// GenerateEd25519 returns new random Ed25519 key.
+func GenerateEd25519(id string, notBefore time.Time) (*Key, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("keys: can't generate key - %w", err)
+	}
+
+	return NewKey(id, private, notBefore)
+}
+
+// Public returns public key verifying tokens signed with the key.
+func (k *Key) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// JWK returns public key in JSON Web Key format.
+func (k *Key) JWK() model.JWK {
+	jwk := model.JWK{Use: "sig", Alg: k.Method.Alg(), Kid: k.ID}
+	switch public := k.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
+
+// ParseJWK returns public key in JSON Web Key format with its signing method, the inverse of Key.JWK.
+// It is used to verify tokens of other issuers, so only RSA and Ed25519 signing keys are accepted.
+func ParseJWK(jwk model.JWK) (crypto.PublicKey, jwt.SigningMethod, error) {
+	if jwk.Use != "" && jwk.Use != "sig" {
+		return nil, nil, fmt.Errorf("keys: key %s isn't a signing key", jwk.Kid)
+	}
+
+	switch {
+	case jwk.Kty == "RSA" && (jwk.Alg == "" || jwk.Alg == jwt.SigningMethodRS256.Alg()):
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil || len(n) == 0 {
+			return nil, nil, fmt.Errorf("keys: invalid modulus of RSA key %s", jwk.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, nil, fmt.Errorf("keys: invalid exponent of RSA key %s", jwk.Kid)
+		}
+		public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return public, jwt.SigningMethodRS256, nil
+
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519" && (jwk.Alg == "" || jwk.Alg == jwt.SigningMethodEdDSA.Alg()):
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, nil, fmt.Errorf("keys: invalid public key of Ed25519 key %s", jwk.Kid)
+		}
+		return ed25519.PublicKey(x), jwt.SigningMethodEdDSA, nil
+
+	default:
+		return nil, nil, fmt.Errorf("keys: unsupported type %s with algorithm %s of key %s", jwk.Kty, jwk.Alg, jwk.Kid)
+	}
+}
+
+// Set type represents provider of fixed keys.
+type Set struct {
+	// keys are sorted by time they are used for signing from
+	keys  []*Key
+	mutex sync.RWMutex
+}
+
+func NewSet(keys ...*Key) *Set {
+	s := &Set{}
+	s.replace(keys)
+
+	return s
+}
+
+// SigningKey method returns key that is used for signing since the latest time.
+func (s *Set) SigningKey() (*Key, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	now := time.Now()
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if !s.keys[i].NotBefore.After(now) {
+			return s.keys[i], nil
+		}
+	}
+
+	return nil, ErrNoSigningKey
+}
+
+// VerificationKey method returns key with the given ID.
+func (s *Set) VerificationKey(id string) (*Key, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, key := range s.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// JWKS method returns public keys of all keys including not used for signing yet.
+func (s *Set) JWKS() *model.JWKS {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	jwks := &model.JWKS{Keys: make([]model.JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwks.Keys = append(jwks.Keys, key.JWK())
+	}
+
+	return jwks
+}
+
+func (s *Set) replace(keys []*Key) {
+	sorted := append([]*Key(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].NotBefore.Before(sorted[j].NotBefore)
+	})
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys = sorted
+}
@@ -0,0 +1,192 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePEM writes the PEM block into file of the directory.
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+}
+
+func TestSetSigningKey(t *testing.T) {
+	now := time.Now()
+	previous, err := GenerateEd25519("previous", now.Add(-48*time.Hour))
+	require.NoError(t, err)
+	current, err := GenerateEd25519("current", now.Add(-time.Hour))
+	require.NoError(t, err)
+	next, err := GenerateEd25519("next", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	set := NewSet(next, previous, current)
+	key, err := set.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, "current", key.ID)
+
+	for _, id := range []string{"previous", "current", "next"} {
+		key, err := set.VerificationKey(id)
+		require.NoError(t, err)
+		assert.Equal(t, id, key.ID)
+	}
+	_, err = set.VerificationKey("unknown")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	jwks := set.JWKS()
+	require.Len(t, jwks.Keys, 3)
+	assert.Equal(t, "previous", jwks.Keys[0].Kid)
+	assert.Equal(t, "next", jwks.Keys[2].Kid)
+
+	_, err = NewSet(next).SigningKey()
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+}
+
+func TestParsePEM(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	writePEM(t, dir, "ed25519.pem", "PRIVATE KEY", edDER)
+	writePEM(t, dir, "invalid.pem", "PRIVATE KEY", []byte("invalid"))
+
+	testTable := []struct {
+		name          string
+		file          string
+		expectedAlg   string
+		expectedKty   string
+		expectedError bool
+	}{
+		{name: "RSA PKCS #1", file: "rsa.pem", expectedAlg: "RS256", expectedKty: "RSA"},
+		{name: "Ed25519 PKCS #8", file: "ed25519.pem", expectedAlg: "EdDSA", expectedKty: "OKP"},
+		{name: "Invalid key", file: "invalid.pem", expectedError: true},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(dir, testCase.file))
+			require.NoError(t, err)
+
+			key, err := ParsePEM("kid", data, time.Now())
+			if testCase.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedAlg, key.Method.Alg())
+			assert.Equal(t, testCase.expectedKty, key.JWK().Kty)
+
+			// tokens signed with the key are verified with its public key
+			token := jwt.New(key.Method)
+			token.Header["kid"] = key.ID
+			signed, err := token.SignedString(key.Private)
+			require.NoError(t, err)
+			_, err = jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+				return key.Public(), nil
+			})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRSAJWK(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := NewKey("rsa", private, time.Now())
+	require.NoError(t, err)
+
+	jwk := key.JWK()
+	assert.Equal(t, "RSA", jwk.Kty)
+	assert.Equal(t, "sig", jwk.Use)
+	assert.Equal(t, "RS256", jwk.Alg)
+	// the exponent 65537 is encoded as AQAB
+	assert.Equal(t, "AQAB", jwk.E)
+	assert.NotEmpty(t, jwk.N)
+}
+
+func TestParseJWK(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaKey, err := NewKey("rsa", private, time.Now())
+	require.NoError(t, err)
+	ed25519Key, err := GenerateEd25519("ed25519", time.Now())
+	require.NoError(t, err)
+
+	for _, key := range []*Key{rsaKey, ed25519Key} {
+		t.Run(key.ID, func(t *testing.T) {
+			public, method, err := ParseJWK(key.JWK())
+			require.NoError(t, err)
+			assert.Equal(t, key.Public(), public)
+			assert.Equal(t, key.Method, method)
+		})
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		jwk := rsaKey.JWK()
+		jwk.Alg = "HS256"
+		_, _, err := ParseJWK(jwk)
+		assert.Error(t, err)
+
+		jwk = ed25519Key.JWK()
+		jwk.Use = "enc"
+		_, _, err = ParseJWK(jwk)
+		assert.Error(t, err)
+	})
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"2022-04.pem", "2022-05.pem"} {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKCS8PrivateKey(private)
+		require.NoError(t, err)
+		writePEM(t, dir, name, "PRIVATE KEY", der)
+	}
+	manifest := filepath.Join(dir, "keys.json")
+	require.NoError(t, os.WriteFile(manifest, []byte(`[
+		{"kid": "2022-04", "file": "2022-04.pem", "notBefore": "2022-04-01T00:00:00Z"}
+	]`), 0o600))
+
+	provider, err := NewFileProvider(manifest)
+	require.NoError(t, err)
+	key, err := provider.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, "2022-04", key.ID)
+
+	// the next key is added and takes over signing
+	require.NoError(t, os.WriteFile(manifest, []byte(`[
+		{"kid": "2022-04", "file": "2022-04.pem", "notBefore": "2022-04-01T00:00:00Z"},
+		{"kid": "2022-05", "file": "2022-05.pem", "notBefore": "2022-05-01T00:00:00Z"}
+	]`), 0o600))
+	require.NoError(t, provider.Reload())
+	key, err = provider.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, "2022-05", key.ID)
+	assert.Len(t, provider.JWKS().Keys, 2)
+
+	// broken manifest doesn't change keys
+	require.NoError(t, os.WriteFile(manifest, []byte(`[
+		{"kid": "2022-06", "file": "2022-06.pem", "notBefore": "2022-06-01T00:00:00Z"}
+	]`), 0o600))
+	assert.Error(t, provider.Reload())
+	assert.Len(t, provider.JWKS().Keys, 2)
+
+	_, err = NewFileProvider(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
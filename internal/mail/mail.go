@@ -0,0 +1,142 @@
+// Package mail provides senders of emails to users.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Message type represents plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is the interface of sender of emails.
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// NewMailer returns SMTP mailer if SMTP_ADDRESS is set, otherwise it returns
+// mailer writing emails into MAIL_DIR for local development.
+func NewMailer(cfg *config.Config) Mailer {
+	if cfg.SMTPAddress == "" {
+		logrus.Warn("SMTP_ADDRESS isn't set, emails aren't sent and are written into MAIL_DIR or log")
+		return NewFileMailer(cfg.MailFrom, cfg.MailDir)
+	}
+
+	return NewSMTPMailer(cfg)
+}
+
+// SMTPMailer type represents sender of emails through SMTP server.
+type SMTPMailer struct {
+	address string
+	from    string
+	auth    smtp.Auth
+}
+
+func NewSMTPMailer(cfg *config.Config) *SMTPMailer {
+	m := &SMTPMailer{
+		address: cfg.SMTPAddress,
+		from:    cfg.MailFrom,
+	}
+	if cfg.SMTPUsername != "" {
+		host, _, err := net.SplitHostPort(cfg.SMTPAddress)
+		if err != nil {
+			host = cfg.SMTPAddress
+		}
+		m.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, host)
+	}
+
+	return m
+}
+
+// Send method sends the message through SMTP server. The context is checked only
+// before sending, net/smtp doesn't support cancellation.
+func (m *SMTPMailer) Send(ctx context.Context, message *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := format(m.from, message)
+	if err != nil {
+		return err
+	}
+
+	if err := smtp.SendMail(m.address, m.auth, m.from, []string{message.To}, data); err != nil {
+		logrus.Error(err, "mail: can't send email")
+		return fmt.Errorf("mail: can't send email - %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"subject": message.Subject,
+	}).Info("mail: email is sent")
+	return nil
+}
+
+// FileMailer type represents mailer writing every email into a file of the directory,
+// so emails can be read in local development and tests. Emails are written into
+// log if the directory isn't set.
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+func NewFileMailer(from, dir string) *FileMailer {
+	return &FileMailer{
+		from: from,
+		dir:  dir,
+	}
+}
+
+// Send method writes the message into new .eml file of the directory or into log.
+func (m *FileMailer) Send(ctx context.Context, message *Message) error {
+	data, err := format(m.from, message)
+	if err != nil {
+		return err
+	}
+
+	if m.dir == "" {
+		logrus.Infof("mail: email isn't sent\n%s", data)
+		return nil
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), uuid.New().String())
+	if err := os.WriteFile(filepath.Join(m.dir, name), data, 0o600); err != nil {
+		logrus.Error(err, "mail: can't write email")
+		return fmt.Errorf("mail: can't write email - %w", err)
+	}
+
+	return nil
+}
+
+// format returns the message in RFC 5322 format.
+func format(from string, message *Message) ([]byte, error) {
+	// line breaks in headers would allow to inject other headers and recipients
+	if strings.ContainsAny(message.To+message.Subject, "\r\n") {
+		return nil, errors.New("mail: headers of email contain line break")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer("no-reply@first-task.local", dir)
+
+	require.NoError(t, mailer.Send(context.Background(), &Message{
+		To:      "qwerty@gmail.com",
+		Subject: "Password reset",
+		Body:    "first line\nsecond line",
+	}))
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, ".eml", filepath.Ext(files[0].Name()))
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	email := string(data)
+	assert.Contains(t, email, "From: no-reply@first-task.local\r\n")
+	assert.Contains(t, email, "To: qwerty@gmail.com\r\n")
+	assert.Contains(t, email, "Subject: Password reset\r\n")
+	assert.Contains(t, email, "\r\n\r\nfirst line\r\nsecond line")
+}
+
+func TestHeaderInjection(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer("no-reply@first-task.local", dir)
+
+	testTable := []struct {
+		name    string
+		message *Message
+	}{
+		{
+			name:    "Line break in recipient",
+			message: &Message{To: "qwerty@gmail.com\r\nBcc: victim@gmail.com", Subject: "Password reset"},
+		},
+		{
+			name:    "Line break in subject",
+			message: &Message{To: "qwerty@gmail.com", Subject: "Password reset\nBcc: victim@gmail.com"},
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Error(t, mailer.Send(context.Background(), testCase.message))
+		})
+	}
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,121 @@
+// Package migration applies versioned schema changes to databases.
+//
+// Every database is handled by a Driver, which knows the list of migrations
+// and keeps versions of applied ones in a schema table of the database.
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Migration type represents single versioned change of database schema.
+type Migration struct {
+	Version int
+	Name    string
+}
+
+// Status type represents state of migration in database.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Driver is the interface a database must implement to be migrated.
+type Driver interface {
+	// Migrations returns all known migrations sorted by version.
+	Migrations() []Migration
+	// Applied returns versions of applied migrations with time they were applied at.
+	Applied(ctx context.Context) (map[int]time.Time, error)
+	// Up applies migration and records its version in one step.
+	Up(ctx context.Context, migration Migration) error
+	// Down reverts migration and removes its version in one step.
+	Down(ctx context.Context, migration Migration) error
+}
+
+// Migrator type applies and reverts migrations of the driver.
+type Migrator struct {
+	driver Driver
+}
+
+func NewMigrator(driver Driver) *Migrator {
+	return &Migrator{
+		driver: driver,
+	}
+}
+
+// Up applies all pending migrations in order of versions.
+func (m *Migrator) Up(ctx context.Context) error {
+	applied, err := m.driver.Applied(ctx)
+	if err != nil {
+		return fmt.Errorf("migration: can't get applied migrations - %w", err)
+	}
+
+	for _, migration := range m.driver.Migrations() {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"version": migration.Version,
+			"name":    migration.Name,
+		}).Info("migration: apply migration")
+		if err := m.driver.Up(ctx, migration); err != nil {
+			return fmt.Errorf("migration: can't apply migration %d %s - %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Down reverts the last applied migration. It does nothing if there are no applied migrations.
+func (m *Migrator) Down(ctx context.Context) error {
+	applied, err := m.driver.Applied(ctx)
+	if err != nil {
+		return fmt.Errorf("migration: can't get applied migrations - %w", err)
+	}
+
+	migrations := m.driver.Migrations()
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"version": migration.Version,
+			"name":    migration.Name,
+		}).Info("migration: revert migration")
+		if err := m.driver.Down(ctx, migration); err != nil {
+			return fmt.Errorf("migration: can't revert migration %d %s - %w", migration.Version, migration.Name, err)
+		}
+		return nil
+	}
+
+	logrus.Info("migration: no migrations to revert")
+
+	return nil
+}
+
+// Status returns state of every known migration sorted by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.driver.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("migration: can't get applied migrations - %w", err)
+	}
+
+	migrations := m.driver.Migrations()
+	statuses := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
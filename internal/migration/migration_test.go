@@ -0,0 +1,122 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver keeps applied migrations in memory and records calls.
+type fakeDriver struct {
+	migrations []Migration
+	applied    map[int]time.Time
+	calls      []string
+	failOn     int
+}
+
+func (d *fakeDriver) Migrations() []Migration {
+	return d.migrations
+}
+
+func (d *fakeDriver) Applied(ctx context.Context) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time, len(d.applied))
+	for version, appliedAt := range d.applied {
+		applied[version] = appliedAt
+	}
+	return applied, nil
+}
+
+func (d *fakeDriver) Up(ctx context.Context, migration Migration) error {
+	if migration.Version == d.failOn {
+		return errors.New("syntax error")
+	}
+	d.calls = append(d.calls, "up "+migration.Name)
+	d.applied[migration.Version] = time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	return nil
+}
+
+func (d *fakeDriver) Down(ctx context.Context, migration Migration) error {
+	d.calls = append(d.calls, "down "+migration.Name)
+	delete(d.applied, migration.Version)
+	return nil
+}
+
+func newFakeDriver(applied ...int) *fakeDriver {
+	d := &fakeDriver{
+		migrations: []Migration{
+			{Version: 1, Name: "first"},
+			{Version: 2, Name: "second"},
+			{Version: 3, Name: "third"},
+		},
+		applied: make(map[int]time.Time),
+	}
+	for _, version := range applied {
+		d.applied[version] = time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return d
+}
+
+func TestMigratorUp(t *testing.T) {
+	driver := newFakeDriver(1)
+	migrator := NewMigrator(driver)
+
+	require.NoError(t, migrator.Up(context.Background()))
+	assert.Equal(t, []string{"up second", "up third"}, driver.calls)
+
+	require.NoError(t, migrator.Up(context.Background()))
+	assert.Equal(t, []string{"up second", "up third"}, driver.calls)
+}
+
+func TestMigratorUpStopsOnError(t *testing.T) {
+	driver := newFakeDriver()
+	driver.failOn = 2
+	migrator := NewMigrator(driver)
+
+	assert.Error(t, migrator.Up(context.Background()))
+	assert.Equal(t, []string{"up first"}, driver.calls)
+}
+
+func TestMigratorDown(t *testing.T) {
+	driver := newFakeDriver(1, 2)
+	migrator := NewMigrator(driver)
+
+	require.NoError(t, migrator.Down(context.Background()))
+	require.NoError(t, migrator.Down(context.Background()))
+	require.NoError(t, migrator.Down(context.Background()))
+	assert.Equal(t, []string{"down second", "down first"}, driver.calls)
+}
+
+func TestMigratorStatus(t *testing.T) {
+	migrator := NewMigrator(newFakeDriver(1))
+
+	statuses, err := migrator.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Status{
+		{Migration: Migration{Version: 1, Name: "first"}, Applied: true, AppliedAt: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Migration: Migration{Version: 2, Name: "second"}},
+		{Migration: Migration{Version: 3, Name: "third"}},
+	}, statuses)
+}
+
+func TestPostgresMigrations(t *testing.T) {
+	driver, err := NewPostgres(nil)
+	require.NoError(t, err)
+
+	migrations := driver.Migrations()
+	require.NotEmpty(t, migrations)
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version)
+		assert.NotEmpty(t, driver.up[migration.Version])
+		assert.NotEmpty(t, driver.down[migration.Version])
+	}
+}
+
+func TestMongoMigrations(t *testing.T) {
+	for i, migration := range NewMongo(nil).Migrations() {
+		assert.Equal(t, i+1, migration.Version)
+	}
+}
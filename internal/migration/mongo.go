@@ -0,0 +1,400 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// namespaceExistsCode is the code of mongo error returned when collection already exists.
+const namespaceExistsCode = 48
+
+// mongoMigration type represents migration of mongo database. Steps of mongo migrations
+// must be idempotent, because mongo can't apply them and store the version atomically.
+type mongoMigration struct {
+	Migration
+	up   func(ctx context.Context, db *mongo.Database) error
+	down func(ctx context.Context, db *mongo.Database) error
+}
+
+// mongoMigrations contains all migrations of mongo database sorted by version.
+var mongoMigrations = []mongoMigration{
+	{
+		Migration: Migration{Version: 1, Name: "Create_new_collections"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{"cats", "users"} {
+				if err := createCollection(ctx, db, name); err != nil {
+					return err
+				}
+			}
+			// the index has the same name as the unique constraint in postgres,
+			// the repository relies on it to tell duplicate emails from duplicate ids
+			_, err := db.Collection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "email", Value: 1}},
+				Options: options.Index().SetName("users_email_key").SetUnique(true),
+			})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range []string{"cats", "users"} {
+				if err := db.Collection(name).Drop(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Migration: Migration{Version: 2, Name: "Add_cats_owner"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("cats").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "ownerId", Value: 1}},
+				Options: options.Index().SetName("cats_owner_id_idx"),
+			})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("cats").Indexes().DropOne(ctx, "cats_owner_id_idx")
+			return err
+		},
+	},
+	{
+		Migration: Migration{Version: 3, Name: "Add_users_role"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx,
+				bson.D{{Key: "role", Value: bson.D{{Key: "$exists", Value: false}}}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "role", Value: "editor"}}}})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx, bson.D{},
+				bson.D{{Key: "$unset", Value: bson.D{{Key: "role", Value: ""}}}})
+			return err
+		},
+	},
+	{
+		Migration: Migration{Version: 4, Name: "Add_sessions"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createCollection(ctx, db, "sessions"); err != nil {
+				return err
+			}
+			_, err := db.Collection("sessions").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "userId", Value: 1}},
+				Options: options.Index().SetName("sessions_user_id_idx"),
+			})
+			if err != nil {
+				return err
+			}
+			_, err = db.Collection("users").UpdateMany(ctx, bson.D{},
+				bson.D{{Key: "$unset", Value: bson.D{{Key: "refreshToken", Value: ""}}}})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("sessions").Drop(ctx)
+		},
+	},
+	{
+		Migration: Migration{Version: 5, Name: "Add_password_resets"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createCollection(ctx, db, "password_resets"); err != nil {
+				return err
+			}
+			// expired reset tokens are removed by mongo, the repository doesn't rely on it
+			_, err := db.Collection("password_resets").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "userId", Value: 1}},
+					Options: options.Index().SetName("password_resets_user_id_idx"),
+				},
+				{
+					Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+					Options: options.Index().SetName("password_resets_expires_at_idx").SetExpireAfterSeconds(0),
+				},
+			})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("password_resets").Drop(ctx)
+		},
+	},
+	{
+		Migration: Migration{Version: 6, Name: "Add_email_verification"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			// users registered before verification existed are trusted
+			_, err := db.Collection("users").UpdateMany(ctx,
+				bson.D{{Key: "emailVerified", Value: bson.D{{Key: "$exists", Value: false}}}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "emailVerified", Value: true}}}})
+			if err != nil {
+				return err
+			}
+			if err := createCollection(ctx, db, "email_verifications"); err != nil {
+				return err
+			}
+			_, err = db.Collection("email_verifications").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "userId", Value: 1}},
+					Options: options.Index().SetName("email_verifications_user_id_idx"),
+				},
+				{
+					Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+					Options: options.Index().SetName("email_verifications_expires_at_idx").SetExpireAfterSeconds(0),
+				},
+			})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			if err := db.Collection("email_verifications").Drop(ctx); err != nil {
+				return err
+			}
+			_, err := db.Collection("users").UpdateMany(ctx, bson.D{},
+				bson.D{{Key: "$unset", Value: bson.D{{Key: "emailVerified", Value: ""}}}})
+			return err
+		},
+	},
+	{
+		Migration: Migration{Version: 7, Name: "Add_two_factor"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			// documents are keyed by id of the user, so no index is needed
+			return createCollection(ctx, db, "two_factors")
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("two_factors").Drop(ctx)
+		},
+	},
+	{
+		Migration: Migration{Version: 8, Name: "Add_api_keys"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createCollection(ctx, db, "api_keys"); err != nil {
+				return err
+			}
+			_, err := db.Collection("api_keys").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "keyHash", Value: 1}},
+					Options: options.Index().SetName("api_keys_key_hash_key").SetUnique(true),
+				},
+				{
+					Keys:    bson.D{{Key: "userId", Value: 1}},
+					Options: options.Index().SetName("api_keys_user_id_idx"),
+				},
+			})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("api_keys").Drop(ctx)
+		},
+	},
+	{
+		Migration: Migration{Version: 9, Name: "Add_users_disabled"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx,
+				bson.D{{Key: "disabled", Value: bson.D{{Key: "$exists", Value: false}}}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "disabled", Value: false}}}})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx, bson.D{},
+				bson.D{{Key: "$unset", Value: bson.D{{Key: "disabled", Value: ""}}}})
+			return err
+		},
+	},
+	{
+		Migration: Migration{Version: 10, Name: "Add_oidc"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			for _, collection := range []string{"user_identities", "oidc_logins"} {
+				if err := createCollection(ctx, db, collection); err != nil {
+					return err
+				}
+			}
+			_, err := db.Collection("user_identities").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "issuer", Value: 1}, {Key: "subject", Value: 1}},
+					Options: options.Index().SetName("user_identities_primary_key").SetUnique(true),
+				},
+				{
+					Keys:    bson.D{{Key: "userId", Value: 1}},
+					Options: options.Index().SetName("user_identities_user_id_idx"),
+				},
+			})
+			if err != nil {
+				return err
+			}
+			// abandoned logins are removed by mongo, the repository doesn't rely on it
+			_, err = db.Collection("oidc_logins").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetName("oidc_logins_expires_at_idx").SetExpireAfterSeconds(0),
+			})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			if err := db.Collection("oidc_logins").Drop(ctx); err != nil {
+				return err
+			}
+			return db.Collection("user_identities").Drop(ctx)
+		},
+	},
+	{
+		Migration: Migration{Version: 11, Name: "Add_cat_history"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createCollection(ctx, db, "cat_history"); err != nil {
+				return err
+			}
+			_, err := db.Collection("cat_history").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "catId", Value: 1}, {Key: "changedAt", Value: -1}, {Key: "_id", Value: -1}},
+				Options: options.Index().SetName("cat_history_cat_id_idx"),
+			})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return db.Collection("cat_history").Drop(ctx)
+		},
+	},
+	{
+		Migration: Migration{Version: 12, Name: "Add_cats_deleted_at"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			// only cats in trash have the field, the purge looks them up by it
+			_, err := db.Collection("cats").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys: bson.D{{Key: "deletedAt", Value: 1}},
+				Options: options.Index().SetName("cats_deleted_at_idx").
+					SetPartialFilterExpression(bson.D{{Key: "deletedAt", Value: bson.D{{Key: "$exists", Value: true}}}}),
+			})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			// cats in trash are restored like by dropping the column in postgres
+			if _, err := db.Collection("cats").UpdateMany(ctx, bson.D{},
+				bson.D{{Key: "$unset", Value: bson.D{{Key: "deletedAt", Value: ""}}}}); err != nil {
+				return err
+			}
+			_, err := db.Collection("cats").Indexes().DropOne(ctx, "cats_deleted_at_idx")
+			return err
+		},
+	},
+	{
+		Migration: Migration{Version: 13, Name: "Add_cats_version"},
+		up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("cats").UpdateMany(ctx,
+				bson.D{{Key: "version", Value: bson.D{{Key: "$exists", Value: false}}}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "version", Value: int64(1)}}}})
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("cats").UpdateMany(ctx, bson.D{},
+				bson.D{{Key: "$unset", Value: bson.D{{Key: "version", Value: ""}}}})
+			return err
+		},
+	},
+}
+
+// mongoSchemaDocument type represents document of schema_migrations collection.
+type mongoSchemaDocument struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+// Mongo type represents driver that migrates mongo database.
+type Mongo struct {
+	DB *mongo.Client
+}
+
+func NewMongo(db *mongo.Client) *Mongo {
+	return &Mongo{
+		DB: db,
+	}
+}
+
+// Migrations method returns all mongo migrations sorted by version.
+func (m *Mongo) Migrations() []Migration {
+	migrations := make([]Migration, 0, len(mongoMigrations))
+	for _, migration := range mongoMigrations {
+		migrations = append(migrations, migration.Migration)
+	}
+
+	return migrations
+}
+
+// Applied method returns versions of migrations stored in schema_migrations collection.
+func (m *Mongo) Applied(ctx context.Context) (map[int]time.Time, error) {
+	cursor, err := m.database().Collection("schema_migrations").Find(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("migration: can't get applied mongo migrations - %w", err)
+	}
+
+	var documents []mongoSchemaDocument
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, fmt.Errorf("migration: can't get applied mongo migrations - %w", err)
+	}
+	applied := make(map[int]time.Time, len(documents))
+	for _, document := range documents {
+		applied[document.Version] = document.AppliedAt
+	}
+
+	return applied, nil
+}
+
+// Up method runs migration and stores its version.
+func (m *Mongo) Up(ctx context.Context, migration Migration) error {
+	mongoMigration, err := findMongoMigration(migration.Version)
+	if err != nil {
+		return err
+	}
+	if err := mongoMigration.up(ctx, m.database()); err != nil {
+		return err
+	}
+
+	_, err = m.database().Collection("schema_migrations").InsertOne(ctx, mongoSchemaDocument{
+		Version:   migration.Version,
+		Name:      migration.Name,
+		AppliedAt: time.Now().UTC(),
+	})
+	// another instance of the application has applied the migration at the same time
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Down method reverts migration and removes its version.
+func (m *Mongo) Down(ctx context.Context, migration Migration) error {
+	mongoMigration, err := findMongoMigration(migration.Version)
+	if err != nil {
+		return err
+	}
+	if err := mongoMigration.down(ctx, m.database()); err != nil {
+		return err
+	}
+
+	_, err = m.database().Collection("schema_migrations").DeleteOne(ctx, bson.D{{Key: "_id", Value: migration.Version}})
+
+	return err
+}
+
+func (m *Mongo) database() *mongo.Database {
+	return m.DB.Database("mongo_database")
+}
+
+func findMongoMigration(version int) (*mongoMigration, error) {
+	for i := range mongoMigrations {
+		if mongoMigrations[i].Version == version {
+			return &mongoMigrations[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("migration: unknown mongo migration %d", version)
+}
+
+// createCollection creates collection if it doesn't exist.
+func createCollection(ctx context.Context, db *mongo.Database, name string) error {
+	err := db.CreateCollection(ctx, name)
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == namespaceExistsCode {
+		return nil
+	}
+
+	return err
+}
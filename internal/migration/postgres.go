@@ -0,0 +1,166 @@
+package migration
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// postgresFS contains sql files of migrations. V<version>__<name>.sql applies migration,
+// U<version>__<name>.sql reverts it.
+//
+//go:embed postgres/*.sql
+var postgresFS embed.FS
+
+var postgresFileName = regexp.MustCompile(`^([VU])(\d+)__(\w+)\.sql$`)
+
+// postgresLockID is a key of advisory lock that prevents concurrent migrations.
+const postgresLockID = 7345120983
+
+// Postgres type represents driver that migrates postgres database.
+type Postgres struct {
+	DB         *pgxpool.Pool
+	migrations []Migration
+	up         map[int]string
+	down       map[int]string
+}
+
+func NewPostgres(db *pgxpool.Pool) (*Postgres, error) {
+	p := &Postgres{
+		DB:   db,
+		up:   make(map[int]string),
+		down: make(map[int]string),
+	}
+
+	files, err := fs.Glob(postgresFS, "postgres/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("migration: can't list postgres migrations - %w", err)
+	}
+	for _, file := range files {
+		match := postgresFileName.FindStringSubmatch(file[len("postgres/"):])
+		if match == nil {
+			return nil, fmt.Errorf("migration: wrong name of postgres migration %s", file)
+		}
+		version, err := strconv.Atoi(match[2])
+		if err != nil {
+			return nil, fmt.Errorf("migration: wrong version of postgres migration %s - %w", file, err)
+		}
+		sql, err := postgresFS.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("migration: can't read postgres migration %s - %w", file, err)
+		}
+		if match[1] == "U" {
+			p.down[version] = string(sql)
+			continue
+		}
+		p.up[version] = string(sql)
+		p.migrations = append(p.migrations, Migration{Version: version, Name: match[3]})
+	}
+	for _, migration := range p.migrations {
+		if _, ok := p.down[migration.Version]; !ok {
+			return nil, fmt.Errorf("migration: postgres migration %d has no undo file", migration.Version)
+		}
+	}
+	sort.Slice(p.migrations, func(i, j int) bool {
+		return p.migrations[i].Version < p.migrations[j].Version
+	})
+
+	return p, nil
+}
+
+// Migrations method returns all postgres migrations sorted by version.
+func (p *Postgres) Migrations() []Migration {
+	return p.migrations
+}
+
+// Applied method returns versions of migrations stored in schema_migrations table.
+func (p *Postgres) Applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := p.createSchemaTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := p.DB.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("migration: can't get applied postgres migrations - %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("migration: can't get applied postgres migrations - %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("migration: can't get applied postgres migrations - %w", err)
+	}
+
+	return applied, nil
+}
+
+// Up method runs sql of migration and stores its version in one transaction.
+func (p *Postgres) Up(ctx context.Context, migration Migration) error {
+	return p.inTx(ctx, migration.Version, true, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, p.up[migration.Version]); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+			migration.Version, migration.Name)
+		return err
+	})
+}
+
+// Down method runs undo sql of migration and removes its version in one transaction.
+func (p *Postgres) Down(ctx context.Context, migration Migration) error {
+	return p.inTx(ctx, migration.Version, false, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, p.down[migration.Version]); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		return err
+	})
+}
+
+func (p *Postgres) createSchemaTable(ctx context.Context) error {
+	_, err := p.DB.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER CONSTRAINT schema_migrations_primary_key PRIMARY KEY,
+		name VARCHAR NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("migration: can't create schema_migrations table - %w", err)
+	}
+
+	return nil
+}
+
+// inTx runs fn in transaction holding the advisory lock, so concurrently started
+// instances of the application don't apply the same migration twice.
+// fn is skipped if another instance has already applied (up) or reverted the migration.
+func (p *Postgres) inTx(ctx context.Context, version int, up bool, fn func(tx pgx.Tx) error) error {
+	return p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", postgresLockID); err != nil {
+			return err
+		}
+		var done bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)",
+			version).Scan(&done); err != nil {
+			return err
+		}
+		if done == up {
+			return nil
+		}
+		return fn(tx)
+	})
+}
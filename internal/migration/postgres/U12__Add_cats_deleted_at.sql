@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS cats_deleted_at_idx;
+
+ALTER TABLE cats DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1 @@
+ALTER TABLE cats DROP COLUMN IF EXISTS version;
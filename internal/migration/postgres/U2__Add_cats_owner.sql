@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS cats_owner_id_idx;
+
+ALTER TABLE cats DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS refresh_token VARCHAR;
+
+DROP TABLE IF EXISTS sessions;
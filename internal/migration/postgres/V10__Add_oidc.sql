@@ -0,0 +1,17 @@
+CREATE TABLE user_identities (
+                      issuer VARCHAR NOT NULL,
+                      subject VARCHAR NOT NULL,
+                      user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                      email VARCHAR NOT NULL,
+                      created_at TIMESTAMPTZ NOT NULL,
+                      CONSTRAINT user_identities_primary_key PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+CREATE TABLE oidc_logins (
+                      state_hash VARCHAR CONSTRAINT oidc_logins_primary_key PRIMARY KEY,
+                      code_verifier VARCHAR NOT NULL,
+                      nonce VARCHAR NOT NULL,
+                      expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,13 @@
+CREATE TABLE cat_history (
+                      id UUID CONSTRAINT cat_history_primary_key PRIMARY KEY,
+                      cat_id UUID NOT NULL,
+                      owner_id UUID,
+                      actor_id UUID,
+                      operation VARCHAR NOT NULL,
+                      changed_at TIMESTAMPTZ NOT NULL,
+                      fields VARCHAR[] NOT NULL,
+                      before JSONB,
+                      after JSONB
+);
+
+CREATE INDEX cat_history_cat_id_idx ON cat_history (cat_id, changed_at DESC, id DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE cats ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX cats_deleted_at_idx ON cats (deleted_at) WHERE deleted_at IS NOT NULL;
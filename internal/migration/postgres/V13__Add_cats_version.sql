@@ -0,0 +1 @@
+ALTER TABLE cats ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
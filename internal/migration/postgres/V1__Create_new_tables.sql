@@ -1,4 +1,4 @@
-CREATE TABLE cats (
+CREATE TABLE IF NOT EXISTS cats (
                       id UUID CONSTRAINT cats_primary_key PRIMARY KEY ,
                       name VARCHAR NOT NULL,
                       date_birth DATE NOT NULL,
@@ -6,12 +6,10 @@ CREATE TABLE cats (
                       image_path VARCHAR
 );
 
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
                       id UUID CONSTRAINT users_primary_key PRIMARY KEY ,
                       name VARCHAR NOT NULL,
                       email VARCHAR UNIQUE NOT NULL,
                       password VARCHAR NOT NULL,
                       refresh_token VARCHAR
 );
-
-INSERT INTO cats(id, name, date_birth, vaccinated) VALUES ('9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4', 'Some name', '2018-09-22', FALSE);
\ No newline at end of file
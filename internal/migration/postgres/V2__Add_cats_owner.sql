@@ -0,0 +1,3 @@
+ALTER TABLE cats ADD COLUMN owner_id UUID;
+
+CREATE INDEX cats_owner_id_idx ON cats (owner_id);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN role VARCHAR NOT NULL DEFAULT 'editor';
@@ -0,0 +1,14 @@
+CREATE TABLE sessions (
+                      id UUID CONSTRAINT sessions_primary_key PRIMARY KEY,
+                      user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                      token_hash VARCHAR NOT NULL,
+                      user_agent VARCHAR NOT NULL,
+                      ip VARCHAR NOT NULL,
+                      created_at TIMESTAMPTZ NOT NULL,
+                      last_used_at TIMESTAMPTZ NOT NULL,
+                      expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+ALTER TABLE users DROP COLUMN IF EXISTS refresh_token;
@@ -0,0 +1,7 @@
+CREATE TABLE password_resets (
+                      token_hash VARCHAR CONSTRAINT password_resets_primary_key PRIMARY KEY,
+                      user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                      expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX password_resets_user_id_idx ON password_resets (user_id);
@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT false;
+
+-- users registered before verification existed are trusted
+UPDATE users SET email_verified = true;
+
+CREATE TABLE email_verifications (
+                      token_hash VARCHAR CONSTRAINT email_verifications_primary_key PRIMARY KEY,
+                      user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                      expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX email_verifications_user_id_idx ON email_verifications (user_id);
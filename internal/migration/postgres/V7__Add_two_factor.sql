@@ -0,0 +1,8 @@
+CREATE TABLE two_factors (
+                      user_id UUID CONSTRAINT two_factors_primary_key PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+                      secret VARCHAR NOT NULL,
+                      enabled BOOLEAN NOT NULL DEFAULT false,
+                      recovery_codes VARCHAR[] NOT NULL DEFAULT '{}',
+                      last_step BIGINT NOT NULL DEFAULT 0,
+                      created_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,13 @@
+CREATE TABLE api_keys (
+                      id UUID CONSTRAINT api_keys_primary_key PRIMARY KEY,
+                      user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                      name VARCHAR NOT NULL,
+                      prefix VARCHAR NOT NULL,
+                      key_hash VARCHAR NOT NULL CONSTRAINT api_keys_key_hash_key UNIQUE,
+                      scopes VARCHAR[] NOT NULL,
+                      created_at TIMESTAMPTZ NOT NULL,
+                      expires_at TIMESTAMPTZ,
+                      last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
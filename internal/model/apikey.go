@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// Scopes of API keys. Keys with cats:read can only read cats, keys with cats:write can change them too,
+// the role of the owner of a key limits them as well.
+const (
+	ScopeCatsRead  = "cats:read"
+	ScopeCatsWrite = "cats:write"
+)
+
+// APIKey struct represents key a user issues to services calling API on behalf of the user,
+// only hash of the key is stored.
+// swagger:model
+type APIKey struct {
+	// The UUID of a key
+	// example: 4f0c6d1e-8a2b-4c3d-9e5f-6a7b8c9d0e1f
+	ID string `json:"id" bson:"_id"`
+	// The UUID of a user who owns the key
+	UserID string `json:"-" bson:"userId"`
+	// The name of a key
+	// example: nightly import
+	Name string `json:"name" bson:"name"`
+	// The first characters of a key telling keys apart
+	// example: ftk_Xb3q
+	Prefix string `json:"prefix" bson:"prefix"`
+	// KeyHash is SHA-256 hash of the key.
+	KeyHash string `json:"-" bson:"keyHash"`
+	// The scopes of a key
+	// example: ["cats:read"]
+	Scopes []string `json:"scopes" bson:"scopes"`
+	// The time a key was created at
+	// example: 2022-04-01T12:42:31Z
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	// The time a key expires at, keys without it don't expire
+	// example: 2023-04-01T00:00:00Z
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	// The time a key was used at last
+	// example: 2022-04-02T12:42:31Z
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+}
+
+// CreateAPIKey struct represents name, scopes and expiry of a new API key
+// swagger:model
+type CreateAPIKey struct {
+	// The name of a key
+	// example: nightly import
+	// required: true
+	Name string `json:"name" validate:"required,max=100"`
+	// The scopes of a key
+	// example: ["cats:read"]
+	// required: true
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=cats:read cats:write"`
+	// The time a key expires at, keys without it don't expire
+	// example: 2023-04-01T00:00:00Z
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// CreatedAPIKey struct represents new API key, the key is shown only once
+// swagger:model
+type CreatedAPIKey struct {
+	APIKey
+	// The key sent in X-API-Key header
+	// example: ftk_Xb3qGk2m9sLw0PzR7tYuVnC4eA1dF6hJ8iK5oQ2rS3
+	Key string `json:"key"`
+}
@@ -24,6 +24,15 @@ type Cat struct {
 	// The image path of a cat
 	// example: 1c219a3f-a959-4395-81f0-4e735040ed61.webp
 	ImagePath string `json:"imagePath,omitempty" bson:"imagePath"`
+	// The UUID of a user who created the cat
+	// example: 9b3a6e5c-0c3a-4b7e-a6f6-2f1f2a0f5d3c
+	OwnerID string `json:"ownerId,omitempty" bson:"ownerId,omitempty"`
+	// The time the cat was moved to trash at, empty for cats not in trash
+	// example: 2022-04-01T12:42:31Z
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	// The version of a cat, starts with 1 and is increased by every change of the cat
+	// example: 1
+	Version int64 `json:"version" bson:"version"`
 }
 
 // CreateCat is the struct for adding a cat
@@ -59,3 +68,105 @@ type UpdateCat struct {
 	// required: true
 	Vaccinated *bool `json:"vaccinated" bson:"vaccinated" validate:""`
 }
+
+// Supported sort keys for listing cats.
+const (
+	CatsSortByName      = "name"
+	CatsSortByDateBirth = "dateBirth"
+)
+
+// ListCats is the struct for filtering and paginating cats.
+type ListCats struct {
+	// OwnerID limits cats to the ones created by the user, empty means cats of all users.
+	OwnerID string
+	// Limit is the maximum number of cats in a page.
+	Limit int
+	// Cursor is an opaque position returned as NextCursor by the previous page.
+	Cursor string
+	// Vaccinated filters cats by the status of vaccination.
+	Vaccinated *bool
+	// DateBirthFrom filters cats born on or after the date of the given time, the time of day is ignored.
+	DateBirthFrom *time.Time
+	// DateBirthTo filters cats born on or before the date of the given time, the time of day is ignored.
+	DateBirthTo *time.Time
+	// NamePrefix filters cats whose name starts with the given string.
+	NamePrefix string
+	// SortBy is one of CatsSortByName or CatsSortByDateBirth.
+	SortBy string
+	// SortDesc reverses the sort order.
+	SortDesc bool
+	// Deleted lists cats in trash instead of cats not in trash.
+	Deleted bool
+}
+
+// CatsPage is the struct for a page of cats
+// swagger:model
+type CatsPage struct {
+	// The cats of the page
+	Cats []*Cat `json:"cats"`
+	// The cursor of the next page, empty for the last page
+	// example: eyJuIjoiU29tZSBuYW1lIiwiaSI6IjYyMDQwMzdjIn0
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Operations of cat changes.
+const (
+	CatOperationCreate      = "create"
+	CatOperationUpdate      = "update"
+	CatOperationDelete      = "delete"
+	CatOperationUploadImage = "uploadImage"
+	CatOperationRestore     = "restore"
+	CatOperationPurge       = "purge"
+)
+
+// CatChange is the struct for a change of a cat in its history
+// swagger:model
+type CatChange struct {
+	// The UUID of the change
+	// example: 0b6c8d0e-5d4f-4a9b-9b1a-3c2d1e0f9a8b
+	ID string `json:"id" bson:"_id"`
+	// The UUID of the changed cat
+	// example: 6204037c-30e6-408b-8aaa-dd8219860b4b
+	CatID string `json:"catId" bson:"catId"`
+	// The UUID of the owner of the cat
+	// example: 9b3a6e5c-0c3a-4b7e-a6f6-2f1f2a0f5d3c
+	OwnerID string `json:"ownerId,omitempty" bson:"ownerId,omitempty"`
+	// The UUID of the user who changed the cat, empty for purge and if authentication is disabled
+	// example: 9b3a6e5c-0c3a-4b7e-a6f6-2f1f2a0f5d3c
+	ActorID string `json:"actorId,omitempty" bson:"actorId,omitempty"`
+	// The operation: create, update, delete, uploadImage, restore or purge
+	// example: update
+	Operation string `json:"operation" bson:"operation"`
+	// The time of the change
+	// example: 2022-04-01T12:42:31Z
+	ChangedAt time.Time `json:"changedAt" bson:"changedAt"`
+	// The names of changed fields
+	// example: ["name","vaccinated"]
+	Fields []string `json:"fields" bson:"fields"`
+	// The cat before the change, empty for created cats
+	Before *Cat `json:"before,omitempty" bson:"before,omitempty"`
+	// The cat after the change, empty for purged cats
+	After *Cat `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// ListCatChanges is the struct for paginating history of a cat.
+type ListCatChanges struct {
+	// CatID is the UUID of the cat.
+	CatID string
+	// OwnerID limits changes to the ones of cats of the user, empty means cats of all users.
+	OwnerID string
+	// Limit is the maximum number of changes in a page.
+	Limit int
+	// Cursor is an opaque position returned as NextCursor by the previous page.
+	Cursor string
+}
+
+// CatChangesPage is the struct for a page of history of a cat, the latest changes go first
+// swagger:model
+type CatChangesPage struct {
+	// The changes of the page
+	Changes []*CatChange `json:"changes"`
+	// The cursor of the next page, empty for the last page
+	// example: eyJ0IjoiMjAyMi0wNC0wMVQxMjo0MjozMVoiLCJpIjoiMGI2YzhkMGUifQ
+	NextCursor string `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,34 @@
+package model
+
+// JWK struct represents public key verifying tokens in JSON Web Key format
+// swagger:model
+type JWK struct {
+	// The type of a key, RSA or OKP
+	// example: OKP
+	Kty string `json:"kty"`
+	// The usage of a key
+	// example: sig
+	Use string `json:"use"`
+	// The signing algorithm of a key, RS256 or EdDSA
+	// example: EdDSA
+	Alg string `json:"alg"`
+	// The ID of a key put into kid header of tokens
+	// example: 2022-04
+	Kid string `json:"kid"`
+	// The modulus of RSA key
+	N string `json:"n,omitempty"`
+	// The exponent of RSA key
+	E string `json:"e,omitempty"`
+	// The curve of OKP key
+	// example: Ed25519
+	Crv string `json:"crv,omitempty"`
+	// The public key of OKP key
+	// example: 11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo
+	X string `json:"x,omitempty"`
+}
+
+// JWKS struct represents set of public keys verifying tokens
+// swagger:model
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,43 @@
+package model
+
+// Problem struct represents error response in format of RFC 7807
+// swagger:model
+type Problem struct {
+	// The URI reference identifying the problem type
+	// example: urn:first-task:problem:not_found
+	// required: true
+	Type string `json:"type"`
+	// The short summary of the problem type
+	// example: Not Found
+	// required: true
+	Title string `json:"title"`
+	// The HTTP status code
+	// example: 404
+	// required: true
+	Status int `json:"status"`
+	// The explanation specific to this occurrence of the problem
+	// example: cat with given UUID doesn't exist
+	Detail string `json:"detail,omitempty"`
+	// The URI reference of the request
+	// example: /cats/6204037c-30e6-408b-8aaa-dd8219860b4b
+	Instance string `json:"instance,omitempty"`
+	// The machine-readable error code
+	// example: not_found
+	// required: true
+	Code string `json:"code"`
+	// The validation errors of request fields
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError struct represents validation error of a request field
+// swagger:model
+type FieldError struct {
+	// The name of the field
+	// example: email
+	// required: true
+	Field string `json:"field"`
+	// The explanation of the error
+	// example: must be a valid email
+	// required: true
+	Message string `json:"message"`
+}
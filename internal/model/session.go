@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// Session struct represents signed in device of a user. Every session owns a family
+// of refresh tokens, only the last issued token of the family is valid.
+// swagger:model
+type Session struct {
+	// The UUID of a session
+	// example: 2b1d8e0a-5f3c-4a7e-9d6b-8c0f1e2d3a4b
+	ID string `json:"id" bson:"_id"`
+	// The UUID of a user who owns the session
+	// example: 9b3a6e5c-0c3a-4b7e-a6f6-2f1f2a0f5d3c
+	UserID string `json:"-" bson:"userId"`
+	// TokenHash is SHA-256 hash of the last refresh token of the session.
+	TokenHash string `json:"-" bson:"tokenHash"`
+	// The User-Agent header of a device
+	// example: Mozilla/5.0 (X11; Linux x86_64)
+	UserAgent string `json:"userAgent" bson:"userAgent"`
+	// The IP address of a device
+	// example: 192.0.2.1
+	IP string `json:"ip" bson:"ip"`
+	// The time a session was created at
+	// example: 2022-04-01T12:42:31Z
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	// The time tokens of a session were refreshed at last
+	// example: 2022-04-02T12:42:31Z
+	LastUsedAt time.Time `json:"lastUsedAt" bson:"lastUsedAt"`
+	// The time a session expires at if it isn't refreshed
+	// example: 2022-05-02T12:42:31Z
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+	// Whether a session is the session of the request
+	// example: true
+	Current bool `json:"current" bson:"-"`
+}
+
+// Device struct represents client a user signs in from.
+type Device struct {
+	UserAgent string
+	IP        string
+}
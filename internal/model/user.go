@@ -1,5 +1,62 @@
 package model
 
+import "time"
+
+// Roles of users. Viewers can only read their cats, editors can manage their cats,
+// admins can manage cats of all users and assign roles.
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// User struct represents a user without credentials
+// swagger:model
+type User struct {
+	// The UUID of a user
+	// example: 6204037c-30e6-408b-8aaa-dd8219860b4b
+	ID string `json:"id" bson:"_id"`
+	// The Name of a user
+	// example: Some name
+	UserName string `json:"userName" bson:"name"`
+	// The email of a user
+	// example: qwerty@gmail.com
+	Email string `json:"email" bson:"email"`
+	// The role of a user
+	// example: editor
+	Role string `json:"role" bson:"role"`
+	// Whether a user has confirmed the email by the link sent to it
+	// example: true
+	EmailVerified bool `json:"emailVerified" bson:"emailVerified"`
+	// Whether a user is disabled by an admin, disabled users can't sign in
+	// example: false
+	Disabled bool `json:"disabled" bson:"disabled"`
+}
+
+// ListUsers is the struct for searching and paginating users, they are sorted by email.
+type ListUsers struct {
+	// Limit is the maximum number of users in a page.
+	Limit int
+	// Cursor is an opaque position returned as NextCursor by the previous page.
+	Cursor string
+	// Query filters users whose email or name contains the given string, case is ignored.
+	Query string
+	// Role filters users by the role.
+	Role string
+	// Disabled filters users by the state of the account.
+	Disabled *bool
+}
+
+// UsersPage is the struct for a page of users
+// swagger:model
+type UsersPage struct {
+	// The users of the page
+	Users []*User `json:"users"`
+	// The cursor of the next page, empty for the last page
+	// example: eyJlIjoicXdlcnR5QGdtYWlsLmNvbSJ9
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
 // CreateUser struct represents mandatory user information for registration
 // swagger:model
 type CreateUser struct {
@@ -11,10 +68,10 @@ type CreateUser struct {
 	// example: qwerty@gmail.com
 	// required: true
 	Email string `json:"email" validate:"required,email"`
-	// The password of a user
-	// example: ZAQ!2wsx
+	// The password of a user, it has to satisfy the password policy
+	// example: ZAQ!2wsx3edc
 	// required: true
-	Password string `json:"password" validate:"required,gt=8"`
+	Password string `json:"password" validate:"required,max=128"`
 }
 
 // AuthUser struct represents mandatory user information for authorisation
@@ -25,16 +82,58 @@ type AuthUser struct {
 	// required: true
 	Email string `json:"email" validate:"required,email"`
 	// The password of a user
-	// example: ZAQ!2wsx
+	// example: ZAQ!2wsx3edc
 	// required: true
-	Password string `json:"password" validate:"required,gt=8"`
+	Password string `json:"password" validate:"required,max=128"`
 }
 
-// Tokens struct represents a couple of token
+// Tokens struct represents a couple of token. Sign in to an account with two-factor authentication
+// returns only challenge token, the couple is returned after the challenge is passed.
 // swagger:model
 type Tokens struct {
-	AccessToken  string `json:"accessToken"`
-	RefreshToken string `json:"refreshToken"`
+	AccessToken    string `json:"accessToken,omitempty"`
+	RefreshToken   string `json:"refreshToken,omitempty"`
+	ChallengeToken string `json:"challengeToken,omitempty"`
+}
+
+// UpdateUserRole struct represents a new role of a user
+// swagger:model
+type UpdateUserRole struct {
+	// The role of a user
+	// example: editor
+	// required: true
+	Role string `json:"role" validate:"required,oneof=admin editor viewer"`
+}
+
+// UpdateProfile struct represents a new name of a user
+// swagger:model
+type UpdateProfile struct {
+	// The Name of a user
+	// example: Some name
+	// required: true
+	UserName string `json:"userName" validate:"required,max=100"`
+}
+
+// ChangePassword struct represents the current password of a user and a new one
+// swagger:model
+type ChangePassword struct {
+	// The current password of a user
+	// example: ZAQ!2wsx3edc
+	// required: true
+	CurrentPassword string `json:"currentPassword" validate:"required,max=128"`
+	// The new password of a user, it has to satisfy the password policy
+	// example: 1qaz@WSX3edc
+	// required: true
+	NewPassword string `json:"newPassword" validate:"required,max=128"`
+}
+
+// DeleteAccount struct represents the password of a user confirming deletion of the account
+// swagger:model
+type DeleteAccount struct {
+	// The password of a user
+	// example: ZAQ!2wsx3edc
+	// required: true
+	Password string `json:"password" validate:"required,max=128"`
 }
 
 // RefreshToken struct represents a  refresh token
@@ -42,3 +141,140 @@ type Tokens struct {
 type RefreshToken struct {
 	RefreshToken string `json:"refreshToken" validate:"required"`
 }
+
+// ForgotPassword struct represents email of a user who forgot the password
+// swagger:model
+type ForgotPassword struct {
+	// The email of a user
+	// example: qwerty@gmail.com
+	// required: true
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPassword struct represents a new password of a user with reset token sent by email
+// swagger:model
+type ResetPassword struct {
+	// The reset token from email
+	// required: true
+	Token string `json:"token" validate:"required"`
+	// The new password of a user, it has to satisfy the password policy
+	// example: ZAQ!2wsx3edc
+	// required: true
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+// VerifyEmail struct represents verification token sent to email of a user
+// swagger:model
+type VerifyEmail struct {
+	// The verification token from email
+	// required: true
+	Token string `json:"token" validate:"required"`
+}
+
+// EmailVerification struct represents issued token verifying email of a user,
+// only hash of the token is stored.
+type EmailVerification struct {
+	TokenHash string    `bson:"_id"`
+	UserID    string    `bson:"userId"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// PasswordReset struct represents issued token resetting password of a user,
+// only hash of the token is stored.
+type PasswordReset struct {
+	TokenHash string    `bson:"_id"`
+	UserID    string    `bson:"userId"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// Lockout struct represents temporary lockout of sign in to an account after too many failed attempts
+// swagger:model
+type Lockout struct {
+	// The email of an account
+	// example: qwerty@gmail.com
+	Email string `json:"email"`
+	// The number of failed attempts in a row
+	// example: 5
+	Failures int64 `json:"failures"`
+	// The time of the last failed attempt
+	LockedAt time.Time `json:"lockedAt"`
+	// The time sign in is allowed again
+	LockedUntil time.Time `json:"lockedUntil"`
+}
+
+// TwoFactor struct represents TOTP two-factor authentication of a user. It is enabled after
+// the user confirms the secret with a code, only hashes of recovery codes are stored.
+// LastStep is the time step of the last used code, so a code can't be used twice.
+type TwoFactor struct {
+	UserID        string    `bson:"_id"`
+	Secret        string    `bson:"secret"`
+	Enabled       bool      `bson:"enabled"`
+	RecoveryCodes []string  `bson:"recoveryCodes"`
+	LastStep      int64     `bson:"lastStep"`
+	CreatedAt     time.Time `bson:"createdAt"`
+}
+
+// TwoFactorEnrollment struct represents new TOTP secret of a user to add into authenticator app
+// swagger:model
+type TwoFactorEnrollment struct {
+	// The secret encoded in base32
+	// example: JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP
+	Secret string `json:"secret"`
+	// The otpauth URI of the secret for QR code
+	// example: otpauth://totp/first-task:qwerty@gmail.com?algorithm=SHA1&digits=6&issuer=first-task&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP
+	URI string `json:"uri"`
+}
+
+// TwoFactorCode struct represents a code from authenticator app
+// swagger:model
+type TwoFactorCode struct {
+	// The code from authenticator app
+	// example: 123456
+	// required: true
+	Code string `json:"code" validate:"required"`
+}
+
+// RecoveryCodes struct represents single-use codes signing in without authenticator app
+// swagger:model
+type RecoveryCodes struct {
+	// The recovery codes, they are shown only once
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// VerifyTwoFactor struct represents challenge token returned by sign in with a code from
+// authenticator app or a recovery code
+// swagger:model
+type VerifyTwoFactor struct {
+	// The challenge token returned by sign in
+	// required: true
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	// The code from authenticator app or a recovery code
+	// example: 123456
+	// required: true
+	Code string `json:"code" validate:"required"`
+}
+
+// Identity struct represents identity of a user at OpenID Connect provider linked to the user,
+// the provider is identified by the issuer and the user by the subject.
+type Identity struct {
+	Issuer    string    `bson:"issuer"`
+	Subject   string    `bson:"subject"`
+	UserID    string    `bson:"userId"`
+	Email     string    `bson:"email"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+// OIDCLogin struct represents started login with OpenID Connect provider, only hash of the state is stored.
+// The code verifier of PKCE and the nonce are checked when the provider redirects back with the state.
+type OIDCLogin struct {
+	StateHash    string    `bson:"_id"`
+	CodeVerifier string    `bson:"codeVerifier"`
+	Nonce        string    `bson:"nonce"`
+	ExpiresAt    time.Time `bson:"expiresAt"`
+}
+
+// OIDCCallback struct represents query parameters OpenID Connect provider redirects back with.
+type OIDCCallback struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
@@ -0,0 +1,308 @@
+// Package oidc implements the client side of OpenID Connect authorization code flow with PKCE:
+// discovery of the provider, the authorization request, exchange of the code and verification
+// of the ID token.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/malkev1ch/first-task/internal/keys"
+	"github.com/malkev1ch/first-task/internal/model"
+)
+
+// ErrInvalidLogin is returned when the provider rejects the authorization code or its ID token is invalid.
+var ErrInvalidLogin = errors.New("oidc: invalid authorization code or ID token")
+
+const (
+	// keysRefreshInterval limits fetching of provider keys by tokens with unknown key IDs
+	keysRefreshInterval = time.Minute
+	requestTimeout      = 10 * time.Second
+)
+
+// Identity type represents user authenticated by the provider.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// metadata type represents the part of provider metadata used by the client.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// verificationKey type represents public key of the provider with its signing method.
+type verificationKey struct {
+	public crypto.PublicKey
+	method jwt.SigningMethod
+}
+
+// Provider type represents OpenID Connect provider the service is registered at as a client.
+// Metadata and keys of the provider are fetched at the first login, so the service starts
+// while the provider is down.
+type Provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       string
+	client       *http.Client
+
+	mutex         sync.Mutex
+	metadata      *metadata
+	keys          map[string]verificationKey
+	keysFetchedAt time.Time
+}
+
+func NewProvider(cfg *config.Config) *Provider {
+	return &Provider{
+		issuer:       strings.TrimSuffix(cfg.OIDCIssuer, "/"),
+		clientID:     cfg.OIDCClientID,
+		clientSecret: cfg.OIDCClientSecret,
+		redirectURL:  cfg.OIDCRedirectURL,
+		scopes:       cfg.OIDCScopes,
+		client:       &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// AuthCodeURL method returns URL of the provider the user signs in at. The state and the nonce
+// bind the callback and the ID token to the login, the verifier is kept secret till the code is exchanged.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	authURL, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: invalid authorization endpoint - %w", err)
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.clientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", p.scopes)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", Challenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange method exchanges the authorization code for ID token and returns the identity of the user
+// after the token is verified. ErrInvalidLogin is returned for rejected codes and invalid tokens.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.clientID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: can't make token request - %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.clientSecret != "" {
+		// credentials of client_secret_basic are form encoded first by RFC 6749
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: can't request token - %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("oidc: can't decode token response with status %d - %w", resp.StatusCode, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusBadRequest && body.Error != "":
+		return nil, fmt.Errorf("%w - %s: %s", ErrInvalidLogin, body.Error, body.ErrorDescription)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("oidc: token endpoint responded with status %d", resp.StatusCode)
+	case body.IDToken == "":
+		return nil, fmt.Errorf("%w - no ID token in token response", ErrInvalidLogin)
+	}
+
+	return p.verify(ctx, body.IDToken, nonce)
+}
+
+// verify method checks signature, lifetime, issuer, audience and nonce of ID token.
+func (p *Provider) verify(ctx context.Context, idToken, nonce string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		// the method is taken from the key, so the public key can't verify a token as HMAC secret
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v of key %s", token.Header["alg"], kid)
+		}
+		return key.public, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w - %v", ErrInvalidLogin, err)
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+	subject, _ := claims["sub"].(string)
+	azp, _ := claims["azp"].(string)
+	switch {
+	case !claims.VerifyIssuer(p.issuer, true):
+		return nil, fmt.Errorf("%w - unexpected issuer", ErrInvalidLogin)
+	case !claims.VerifyAudience(p.clientID, true) || azp != "" && azp != p.clientID:
+		return nil, fmt.Errorf("%w - unexpected audience", ErrInvalidLogin)
+	case !claims.VerifyExpiresAt(time.Now().Unix(), true):
+		return nil, fmt.Errorf("%w - no expiration time", ErrInvalidLogin)
+	case subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1:
+		return nil, fmt.Errorf("%w - unexpected nonce", ErrInvalidLogin)
+	case subject == "":
+		return nil, fmt.Errorf("%w - no subject", ErrInvalidLogin)
+	}
+
+	identity := &Identity{Issuer: p.issuer, Subject: subject}
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	if identity.Name == "" {
+		identity.Name, _ = claims["preferred_username"].(string)
+	}
+	// some providers send the flag as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	return identity, nil
+}
+
+// discover method returns metadata of the provider fetched once.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var meta metadata
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, err
+	}
+	switch {
+	case meta.Issuer != p.issuer:
+		return nil, fmt.Errorf("oidc: provider metadata is issued by %s instead of %s", meta.Issuer, p.issuer)
+	case meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "":
+		return nil, errors.New("oidc: provider metadata lacks endpoints")
+	}
+	p.metadata = &meta
+
+	return p.metadata, nil
+}
+
+// key method returns key of the provider with the given ID. Keys are fetched again for unknown IDs,
+// so keys rotated by the provider are picked up, but not more often than keysRefreshInterval.
+func (p *Provider) key(ctx context.Context, kid string) (verificationKey, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return verificationKey{}, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < keysRefreshInterval {
+		return verificationKey{}, keys.ErrUnknownKey
+	}
+
+	var jwks model.JWKS
+	if err := p.getJSON(ctx, meta.JWKSURI, &jwks); err != nil {
+		return verificationKey{}, err
+	}
+	p.keys = make(map[string]verificationKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		// providers publish encryption keys too, they are skipped
+		public, method, err := keys.ParseJWK(jwk)
+		if err == nil {
+			p.keys[jwk.Kid] = verificationKey{public: public, method: method}
+		}
+	}
+	p.keysFetchedAt = time.Now()
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return verificationKey{}, keys.ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+// getJSON method decodes JSON document at the URL into v.
+func (p *Provider) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("oidc: can't make request to %s - %w", target, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("oidc: can't request %s - %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s responded with status %d", target, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("oidc: can't decode response of %s - %w", target, err)
+	}
+
+	return nil
+}
+
+// Challenge returns S256 code challenge of PKCE verifier.
+func Challenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
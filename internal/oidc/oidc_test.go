@@ -0,0 +1,169 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/malkev1ch/first-task/internal/oidc"
+	"github.com/malkev1ch/first-task/internal/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRedirectURL = "http://localhost:8080/auth/oidc/callback"
+	testVerifier    = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+var testUser = &oidctest.User{
+	Subject:       "248289761001",
+	Email:         "qwerty@gmail.com",
+	EmailVerified: true,
+	Name:          "Some name",
+}
+
+// newProvider returns client of the issuer registered with the issuer.
+func newProvider(issuer *oidctest.Issuer) *oidc.Provider {
+	return oidc.NewProvider(&config.Config{
+		OIDCIssuer:       issuer.URL,
+		OIDCClientID:     issuer.ClientID,
+		OIDCClientSecret: issuer.ClientSecret,
+		OIDCRedirectURL:  testRedirectURL,
+		OIDCScopes:       "openid email profile",
+	})
+}
+
+// authorize returns authorization code issued to the client for the login with the nonce.
+func authorize(t *testing.T, issuer *oidctest.Issuer, provider *oidc.Provider, nonce string) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), "some state", nonce, testVerifier)
+	require.NoError(t, err)
+	callback := issuer.Authorize(t, authURL)
+	require.Empty(t, callback.Query().Get("error"))
+	assert.Equal(t, "some state", callback.Query().Get("state"))
+
+	return callback.Query().Get("code")
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "first-task", "secret")
+	authURL, err := newProvider(issuer).AuthCodeURL(context.Background(), "some state", "some nonce", testVerifier)
+	require.NoError(t, err)
+
+	callback := issuer.Authorize(t, authURL)
+	assert.Equal(t, "some state", callback.Query().Get("state"))
+	assert.Equal(t, "access_denied", callback.Query().Get("error"))
+	assert.Equal(t, "localhost:8080", callback.Host)
+	// the challenge of the verifier from RFC 7636
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", oidc.Challenge(testVerifier))
+}
+
+func TestExchange(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "first-task", "secret")
+	issuer.SignIn(testUser)
+	provider := newProvider(issuer)
+
+	code := authorize(t, issuer, provider, "some nonce")
+	identity, err := provider.Exchange(context.Background(), code, testVerifier, "some nonce")
+	require.NoError(t, err)
+	assert.Equal(t, &oidc.Identity{
+		Issuer:        issuer.URL,
+		Subject:       testUser.Subject,
+		Email:         testUser.Email,
+		EmailVerified: true,
+		Name:          testUser.Name,
+	}, identity)
+
+	// codes are used once
+	_, err = provider.Exchange(context.Background(), code, testVerifier, "some nonce")
+	assert.ErrorIs(t, err, oidc.ErrInvalidLogin)
+}
+
+func TestExchangeInvalid(t *testing.T) {
+	testTable := []struct {
+		name     string
+		verifier string
+		nonce    string
+		tamper   func(claims jwt.MapClaims)
+	}{
+		{
+			name:     "Wrong verifier",
+			verifier: "another verifier of the same length as the real one",
+		},
+		{
+			name:  "Wrong nonce",
+			nonce: "another nonce",
+		},
+		{
+			name: "Wrong audience",
+			tamper: func(claims jwt.MapClaims) {
+				claims["aud"] = "another-client"
+			},
+		},
+		{
+			name: "Wrong authorized party",
+			tamper: func(claims jwt.MapClaims) {
+				claims["aud"] = []string{"first-task", "another-client"}
+				claims["azp"] = "another-client"
+			},
+		},
+		{
+			name: "Wrong issuer",
+			tamper: func(claims jwt.MapClaims) {
+				claims["iss"] = "https://accounts.example.com"
+			},
+		},
+		{
+			name: "Expired",
+			tamper: func(claims jwt.MapClaims) {
+				claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			},
+		},
+		{
+			name: "No subject",
+			tamper: func(claims jwt.MapClaims) {
+				delete(claims, "sub")
+			},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			issuer := oidctest.NewIssuer(t, "first-task", "secret")
+			issuer.SignIn(testUser)
+			issuer.Tamper = testCase.tamper
+			provider := newProvider(issuer)
+			verifier, nonce := testVerifier, "some nonce"
+			if testCase.verifier != "" {
+				verifier = testCase.verifier
+			}
+			if testCase.nonce != "" {
+				nonce = testCase.nonce
+			}
+
+			code := authorize(t, issuer, provider, "some nonce")
+			_, err := provider.Exchange(context.Background(), code, verifier, nonce)
+			assert.ErrorIs(t, err, oidc.ErrInvalidLogin)
+		})
+	}
+}
+
+func TestUnavailableProvider(t *testing.T) {
+	issuer := oidctest.NewIssuer(t, "first-task", "secret")
+	wrongSecret := newProvider(&oidctest.Issuer{URL: issuer.URL, ClientID: "first-task", ClientSecret: "wrong"})
+	issuer.SignIn(testUser)
+	code := authorize(t, issuer, newProvider(issuer), "some nonce")
+
+	// rejected client credentials are an error of the configuration, not of the login
+	_, err := wrongSecret.Exchange(context.Background(), code, testVerifier, "some nonce")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, oidc.ErrInvalidLogin))
+
+	_, err = newProvider(&oidctest.Issuer{URL: "http://127.0.0.1:1"}).AuthCodeURL(context.Background(),
+		"some state", "some nonce", testVerifier)
+	assert.Error(t, err)
+}
@@ -0,0 +1,232 @@
+// Package oidctest provides in-process OpenID Connect provider for tests of the login flow.
+//
+// The provider approves every authorization request on behalf of the user set by SignIn
+// without any page, checks PKCE verifier and client credentials at the token endpoint
+// and signs ID tokens with RSA key published at its JWKS endpoint.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/malkev1ch/first-task/internal/keys"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/oidc"
+	"github.com/stretchr/testify/require"
+)
+
+// User type represents user of the provider put into ID tokens.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// authorization type represents issued authorization code.
+type authorization struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	user        User
+}
+
+// Issuer type represents running provider.
+type Issuer struct {
+	// URL is the issuer identifier, metadata is served under it
+	URL          string
+	ClientID     string
+	ClientSecret string
+	// Tamper, if set, changes claims of ID tokens before they are signed
+	Tamper func(claims jwt.MapClaims)
+
+	key   *keys.Key
+	mutex sync.Mutex
+	user  *User
+	codes map[string]authorization
+}
+
+// NewIssuer starts provider with the registered client, it is stopped by cleanup of the test.
+func NewIssuer(t *testing.T, clientID, clientSecret string) *Issuer {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := keys.NewKey("oidctest", private, time.Now())
+	require.NoError(t, err)
+
+	issuer := &Issuer{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]authorization),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/authorize", issuer.authorize)
+	mux.HandleFunc("/token", issuer.token)
+	mux.HandleFunc("/jwks", issuer.jwks)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	issuer.URL = server.URL
+
+	return issuer
+}
+
+// SignIn sets the user approving next authorization requests, nil makes the provider deny them.
+func (i *Issuer) SignIn(user *User) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.user = user
+}
+
+// Authorize visits the authorization URL like a browser and returns URL the provider
+// redirects back to with the code or the error.
+func (i *Issuer) Authorize(t *testing.T, authURL string) *url.URL {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	callback, err := resp.Location()
+	require.NoError(t, err)
+
+	return callback
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                i.URL,
+		"authorization_endpoint":                i.URL + "/authorize",
+		"token_endpoint":                        i.URL + "/token",
+		"jwks_uri":                              i.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{i.key.Method.Alg()},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (i *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" || query.Get("client_id") != i.ClientID {
+		http.Error(w, "unknown client or redirect URI", http.StatusBadRequest)
+		return
+	}
+
+	callback := url.Values{}
+	callback.Set("state", query.Get("state"))
+	i.mutex.Lock()
+	switch {
+	case query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" ||
+		query.Get("code_challenge") == "":
+		callback.Set("error", "invalid_request")
+	case i.user == nil:
+		callback.Set("error", "access_denied")
+	default:
+		code := randomString()
+		i.codes[code] = authorization{
+			redirectURI: redirectURI.String(),
+			challenge:   query.Get("code_challenge"),
+			nonce:       query.Get("nonce"),
+			user:        *i.user,
+		}
+		callback.Set("code", code)
+	}
+	i.mutex.Unlock()
+
+	redirectURI.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	clientID, clientSecret, _ := r.BasicAuth()
+	clientID, _ = url.QueryUnescape(clientID)
+	clientSecret, _ = url.QueryUnescape(clientSecret)
+	if clientID != i.ClientID || clientSecret != i.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// codes are used once, even by a failed exchange
+	i.mutex.Lock()
+	code, ok := i.codes[r.PostFormValue("code")]
+	delete(i.codes, r.PostFormValue("code"))
+	i.mutex.Unlock()
+	switch {
+	case !ok || code.redirectURI != r.PostFormValue("redirect_uri"):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case oidc.Challenge(r.PostFormValue("code_verifier")) != code.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "invalid_grant", "error_description": "PKCE verification failed",
+		})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            i.URL,
+		"sub":            code.user.Subject,
+		"aud":            []string{i.ClientID},
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          code.nonce,
+		"email":          code.user.Email,
+		"email_verified": code.user.EmailVerified,
+		"name":           code.user.Name,
+	}
+	if i.Tamper != nil {
+		i.Tamper(claims)
+	}
+	idToken := jwt.NewWithClaims(i.key.Method, claims)
+	idToken.Header["kid"] = i.key.ID
+	signed, err := idToken.SignedString(i.key.Private)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, model.JWKS{Keys: []model.JWK{i.key.JWK()}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
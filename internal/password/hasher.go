@@ -0,0 +1,185 @@
+// Package password provides hashing of passwords of users and the policy passwords have to satisfy.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/malkev1ch/first-task/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Names of hashing algorithms set in PASSWORD_HASHER.
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+const (
+	argon2idPrefix = "$argon2id$"
+	saltLength     = 16
+	keyLength      = 32
+)
+
+var encoding = base64.RawStdEncoding
+
+// Hasher is the interface of password hashing algorithm. Every hasher verifies hashes of all
+// supported algorithms, so the algorithm and its parameters can be changed without resetting passwords.
+type Hasher interface {
+	// Hash returns encoded hash of the password with random salt.
+	Hash(password string) (string, error)
+	// Verify checks the password against the encoded hash. needsRehash is true if the password
+	// matches, but the hash is made by other algorithm or with other parameters than the hasher uses.
+	Verify(password, hash string) (ok, needsRehash bool, err error)
+}
+
+// NewHasher returns hasher of the algorithm set in PASSWORD_HASHER with parameters from the config.
+func NewHasher(cfg *config.Config) (Hasher, error) {
+	switch cfg.PasswordHasher {
+	case AlgorithmArgon2id:
+		return NewArgon2id(Argon2idParams{
+			Memory:      cfg.Argon2Memory,
+			Iterations:  cfg.Argon2Iterations,
+			Parallelism: cfg.Argon2Parallelism,
+		})
+
+	case AlgorithmBcrypt:
+		return NewBcrypt(cfg.BcryptCost)
+
+	default:
+		return nil, fmt.Errorf("password: unknown hashing algorithm %q", cfg.PasswordHasher)
+	}
+}
+
+// Argon2idParams type represents tunable parameters of argon2id, memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// Argon2id type represents hasher of passwords with argon2id, hashes are encoded in PHC string format.
+type Argon2id struct {
+	params Argon2idParams
+}
+
+func NewArgon2id(params Argon2idParams) (*Argon2id, error) {
+	switch {
+	case params.Iterations < 1:
+		return nil, errors.New("password: argon2id needs at least 1 iteration")
+	case params.Parallelism < 1:
+		return nil, errors.New("password: argon2id needs parallelism of at least 1")
+	case params.Memory < 8*uint32(params.Parallelism):
+		return nil, errors.New("password: argon2id needs at least 8 KiB of memory per thread")
+	}
+
+	return &Argon2id{params: params}, nil
+}
+
+// Hash method returns argon2id hash of the password.
+func (h *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("password: can't generate salt - %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, keyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		encoding.EncodeToString(salt), encoding.EncodeToString(key)), nil
+}
+
+// Verify method checks the password, bcrypt hashes and argon2id hashes with other parameters need rehash.
+func (h *Argon2id) Verify(password, hash string) (bool, bool, error) {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		ok, _, err := verifyBcrypt(password, hash)
+		return ok, ok, err
+	}
+
+	ok, params, err := verifyArgon2id(password, hash)
+	return ok, ok && params != h.params, err
+}
+
+// Bcrypt type represents hasher of passwords with bcrypt.
+type Bcrypt struct {
+	cost int
+}
+
+func NewBcrypt(cost int) (*Bcrypt, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("password: bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return &Bcrypt{cost: cost}, nil
+}
+
+// Hash method returns bcrypt hash of the password.
+func (h *Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("password: can't hash password - %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// Verify method checks the password, argon2id hashes and bcrypt hashes with other cost need rehash.
+func (h *Bcrypt) Verify(password, hash string) (bool, bool, error) {
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		ok, _, err := verifyArgon2id(password, hash)
+		return ok, ok, err
+	}
+
+	ok, cost, err := verifyBcrypt(password, hash)
+	return ok, ok && cost != h.cost, err
+}
+
+// verifyBcrypt checks the password against bcrypt hash and returns cost of the hash.
+func verifyBcrypt(password, hash string) (bool, int, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	switch {
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, 0, nil
+	case err != nil:
+		return false, 0, fmt.Errorf("password: invalid bcrypt hash - %w", err)
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, 0, fmt.Errorf("password: invalid bcrypt hash - %w", err)
+	}
+
+	return true, cost, nil
+}
+
+// verifyArgon2id checks the password against argon2id hash and returns parameters of the hash.
+func verifyArgon2id(password, hash string) (bool, Argon2idParams, error) {
+	var params Argon2idParams
+	var version int
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, params, errors.New("password: invalid argon2id hash")
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, params, errors.New("password: unsupported version of argon2id hash")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return false, params, fmt.Errorf("password: invalid parameters of argon2id hash - %w", err)
+	}
+	salt, err := encoding.DecodeString(parts[4])
+	if err != nil {
+		return false, params, fmt.Errorf("password: invalid salt of argon2id hash - %w", err)
+	}
+	key, err := encoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, params, errors.New("password: invalid key of argon2id hash")
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1, params, nil
+}
@@ -0,0 +1,153 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testParams are cheap parameters of argon2id, so tests are fast.
+var testParams = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestArgon2id(t *testing.T) {
+	hasher, err := NewArgon2id(testParams)
+	require.NoError(t, err)
+	hash, err := hasher.Hash("ZAQ!2wsx3edc")
+	require.NoError(t, err)
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=1024,t=1,p=1\$[A-Za-z0-9+/]+\$[A-Za-z0-9+/]+$`, hash)
+	other, err := hasher.Hash("ZAQ!2wsx3edc")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "salt is random")
+
+	ok, needsRehash, err := hasher.Verify("ZAQ!2wsx3edc", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	ok, needsRehash, err = hasher.Verify("ZAQ!2wsx3edC", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, needsRehash)
+
+	stronger, err := NewArgon2id(Argon2idParams{Memory: 2048, Iterations: 2, Parallelism: 1})
+	require.NoError(t, err)
+	ok, needsRehash, err = stronger.Verify("ZAQ!2wsx3edc", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash, "hash with other parameters needs rehash")
+
+	_, _, err = hasher.Verify("ZAQ!2wsx3edc", "$argon2id$v=19$m=1024,t=1$c2FsdA$a2V5")
+	assert.Error(t, err)
+	_, _, err = hasher.Verify("ZAQ!2wsx3edc", "$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5")
+	assert.Error(t, err)
+}
+
+func TestBcryptUpgrade(t *testing.T) {
+	bcryptHasher, err := NewBcrypt(4)
+	require.NoError(t, err)
+	argon2idHasher, err := NewArgon2id(testParams)
+	require.NoError(t, err)
+	hash, err := bcryptHasher.Hash("ZAQ!2wsx3edc")
+	require.NoError(t, err)
+
+	ok, needsRehash, err := bcryptHasher.Verify("ZAQ!2wsx3edc", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	ok, needsRehash, err = argon2idHasher.Verify("ZAQ!2wsx3edc", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash, "bcrypt hash needs rehash with argon2id")
+
+	ok, needsRehash, err = argon2idHasher.Verify("wrong password", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, needsRehash)
+
+	costlier, err := NewBcrypt(5)
+	require.NoError(t, err)
+	_, needsRehash, err = costlier.Verify("ZAQ!2wsx3edc", hash)
+	require.NoError(t, err)
+	assert.True(t, needsRehash, "hash with other cost needs rehash")
+
+	_, _, err = argon2idHasher.Verify("ZAQ!2wsx3edc", "plain text")
+	assert.Error(t, err)
+}
+
+func TestNewHasher(t *testing.T) {
+	testTable := []struct {
+		name     string
+		cfg      config.Config
+		expected Hasher
+		wantErr  bool
+	}{
+		{
+			name: "Argon2id",
+			cfg: config.Config{PasswordHasher: AlgorithmArgon2id,
+				Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1},
+			expected: &Argon2id{params: testParams},
+		},
+		{
+			name:     "Bcrypt",
+			cfg:      config.Config{PasswordHasher: AlgorithmBcrypt, BcryptCost: 10},
+			expected: &Bcrypt{cost: 10},
+		},
+		{
+			name:    "Unknown algorithm",
+			cfg:     config.Config{PasswordHasher: "md5"},
+			wantErr: true,
+		},
+		{
+			name: "Too little memory",
+			cfg: config.Config{PasswordHasher: AlgorithmArgon2id,
+				Argon2Memory: 8, Argon2Iterations: 1, Argon2Parallelism: 2},
+			wantErr: true,
+		},
+		{
+			name:    "Too low cost",
+			cfg:     config.Config{PasswordHasher: AlgorithmBcrypt, BcryptCost: 1},
+			wantErr: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			hasher, err := NewHasher(&testCase.cfg)
+			if testCase.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, hasher)
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := NewPolicy(&config.Config{PasswordMinLength: 10, PasswordMinClasses: 3, PasswordRejectCommon: true})
+	testTable := []struct {
+		name       string
+		password   string
+		violations int
+	}{
+		{name: "OK", password: "ZAQ!2wsx3edc"},
+		{name: "OK with unicode", password: "Пароль-кота-7"},
+		{name: "Too short", password: "Zaq!2ws", violations: 1},
+		{name: "Too long", password: "Zaq!2wsx" + string(make([]byte, MaxLength)), violations: 1},
+		{name: "Too few classes", password: "zaq2wsx3edc4", violations: 1},
+		{name: "Common", password: "PASSWORD123!", violations: 1},
+		{name: "Short and common", password: "qwerty", violations: 3},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Len(t, policy.Check(testCase.password), testCase.violations)
+		})
+	}
+
+	lax := &Policy{}
+	assert.Empty(t, lax.Check("qwerty"))
+}
@@ -0,0 +1,97 @@
+package password
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/malkev1ch/first-task/internal/config"
+)
+
+// MaxLength is the maximum length of passwords, so hashing of huge passwords doesn't exhaust the service.
+const MaxLength = 128
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords is the set of widely used passwords in lower case.
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
+// Policy type represents the rules passwords of users have to satisfy.
+type Policy struct {
+	// MinLength is the minimum number of characters of a password
+	MinLength int
+	// MinClasses is the minimum number of character classes of a password,
+	// the classes are lower case letters, upper case letters, digits and other characters
+	MinClasses int
+	// RejectCommon forbids passwords from the bundled list of common passwords
+	RejectCommon bool
+}
+
+// NewPolicy returns policy of passwords set in the config.
+func NewPolicy(cfg *config.Config) *Policy {
+	return &Policy{
+		MinLength:    cfg.PasswordMinLength,
+		MinClasses:   cfg.PasswordMinClasses,
+		RejectCommon: cfg.PasswordRejectCommon,
+	}
+}
+
+// Check method returns the rules the password breaks, it returns nothing if the password satisfies the policy.
+func (p *Policy) Check(password string) []string {
+	var violations []string
+	length := len([]rune(password))
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if length > MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", MaxLength))
+	}
+	if classes := characterClasses(password); classes < p.MinClasses {
+		violations = append(violations, fmt.Sprintf("must contain at least %d of lower case letters, "+
+			"upper case letters, digits and other characters", p.MinClasses))
+	}
+	if p.RejectCommon && IsCommon(password) {
+		violations = append(violations, "is too common")
+	}
+
+	return violations
+}
+
+// IsCommon returns true if the password is in the bundled list of common passwords, case is ignored.
+func IsCommon(password string) bool {
+	_, ok := commonPasswords[strings.ToLower(password)]
+	return ok
+}
+
+// characterClasses returns the number of character classes the password contains.
+func characterClasses(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+
+	return lower + upper + digit + other
+}
+
+// parseCommonPasswords returns set of passwords of the list, one password in line.
+func parseCommonPasswords(list string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, line := range strings.Split(list, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			passwords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+
+	return passwords
+}
@@ -0,0 +1,85 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// Prefixes of keys of revoked tokens and users, they keep the keys apart from cats.
+const (
+	revokedTokenPrefix = "denylist:token:"
+	revokedUserPrefix  = "denylist:user:"
+)
+
+// TokenDenylist type represents redis storage of revoked access tokens.
+// Every key lives as long as the tokens it revokes are valid.
+type TokenDenylist struct {
+	redisClient *redis.Client
+}
+
+func NewTokenDenylist(redisClient *redis.Client) *TokenDenylist {
+	return &TokenDenylist{
+		redisClient: redisClient,
+	}
+}
+
+// RevokeToken method revokes the token with the given id until it expires.
+func (d *TokenDenylist) RevokeToken(ctx context.Context, id string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := d.redisClient.Set(ctx, revokedTokenPrefix+id, 1, ttl).Err(); err != nil {
+		logrus.Error(err, "redis: error occurred while revoking token")
+		return fmt.Errorf("redis: error occurred while revoking token - %w", err)
+	}
+
+	logrus.Infof("redis: revoked token %s", id)
+	return nil
+}
+
+// RevokeUserTokens method revokes all tokens of the user issued before revokedAt, the time is kept in microseconds.
+// ttl is the lifetime of tokens, after it all revoked tokens are expired.
+func (d *TokenDenylist) RevokeUserTokens(ctx context.Context, userID string, revokedAt time.Time, ttl time.Duration) error {
+	err := d.redisClient.Set(ctx, revokedUserPrefix+userID, revokedAt.UnixMicro(), ttl).Err()
+	if err != nil {
+		logrus.Error(err, "redis: error occurred while revoking tokens of user")
+		return fmt.Errorf("redis: error occurred while revoking tokens of user - %w", err)
+	}
+
+	logrus.Infof("redis: revoked tokens of user %s", userID)
+	return nil
+}
+
+// IsRevoked method checks whether the token with the given id, issued to the user
+// at issuedAt, is revoked by itself or together with all tokens of the user.
+func (d *TokenDenylist) IsRevoked(ctx context.Context, id, userID string, issuedAt time.Time) (bool, error) {
+	pipe := d.redisClient.Pipeline()
+	token := pipe.Exists(ctx, revokedTokenPrefix+id)
+	user := pipe.Get(ctx, revokedUserPrefix+userID)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		logrus.Error(err, "redis: error occurred while checking revoked tokens")
+		return false, fmt.Errorf("redis: error occurred while checking revoked tokens - %w", err)
+	}
+
+	if token.Val() > 0 {
+		return true, nil
+	}
+	if user.Err() != nil {
+		return false, nil
+	}
+	revokedAt, err := strconv.ParseInt(user.Val(), 10, 64)
+	if err != nil {
+		logrus.Error(err, "redis: invalid time of revoked tokens of user")
+		return false, fmt.Errorf("redis: invalid time of revoked tokens of user - %w", err)
+	}
+
+	// tokens issued after revocation aren't revoked, so the user can sign in again right after it
+	return issuedAt.UnixMicro() < revokedAt, nil
+}
@@ -0,0 +1,97 @@
+package rediscache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	server, client := newRedis(t)
+	denylist := NewTokenDenylist(client)
+	userID := "1c219a3f-a959-4395-81f0-4e735040ed61"
+	issuedAt := time.Now()
+
+	require.NoError(t, denylist.RevokeToken(ctx, "revoked", issuedAt.Add(15*time.Minute)))
+	revoked, err := denylist.IsRevoked(ctx, "revoked", userID, issuedAt)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = denylist.IsRevoked(ctx, "other", userID, issuedAt)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// expired tokens aren't kept
+	require.NoError(t, denylist.RevokeToken(ctx, "expired", issuedAt.Add(-time.Second)))
+	assert.False(t, server.Exists(revokedTokenPrefix+"expired"))
+
+	server.FastForward(15 * time.Minute)
+	revoked, err = denylist.IsRevoked(ctx, "revoked", userID, issuedAt)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	ctx := context.Background()
+	server, client := newRedis(t)
+	denylist := NewTokenDenylist(client)
+	userID := "1c219a3f-a959-4395-81f0-4e735040ed61"
+	revokedAt := time.Date(2022, 4, 1, 12, 0, 0, 123456789, time.UTC)
+
+	require.NoError(t, denylist.RevokeUserTokens(ctx, userID, revokedAt, 15*time.Minute))
+
+	testTable := []struct {
+		name     string
+		userID   string
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{
+			name:     "Issued before",
+			userID:   userID,
+			issuedAt: revokedAt.Add(-time.Microsecond),
+			revoked:  true,
+		},
+		{
+			name:     "Issued at the same microsecond",
+			userID:   userID,
+			issuedAt: revokedAt.Add(-time.Nanosecond),
+			revoked:  false,
+		},
+		{
+			name:     "Issued at",
+			userID:   userID,
+			issuedAt: revokedAt,
+			revoked:  false,
+		},
+		{
+			name:     "Issued after",
+			userID:   userID,
+			issuedAt: revokedAt.Add(time.Microsecond),
+			revoked:  false,
+		},
+		{
+			name:     "Other user",
+			userID:   "9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4",
+			issuedAt: revokedAt.Add(-time.Minute),
+			revoked:  false,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			revoked, err := denylist.IsRevoked(ctx, "token", testCase.userID, testCase.issuedAt)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.revoked, revoked)
+		})
+	}
+
+	// all revoked tokens are expired after their lifetime
+	server.FastForward(15 * time.Minute)
+	revoked, err := denylist.IsRevoked(ctx, "token", userID, revokedAt.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
@@ -0,0 +1,202 @@
+package rediscache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Prefixes of keys of failed sign in attempts and lockouts, lockoutsKey is the hash
+// of lockouts of accounts by email that admins can list.
+const (
+	accountFailuresPrefix = "login:failures:email:"
+	ipFailuresPrefix      = "login:failures:ip:"
+	accountLockPrefix     = "login:lock:email:"
+	ipLockPrefix          = "login:lock:ip:"
+	lockoutsKey           = "login:lockouts"
+)
+
+// LoginAttempts type represents redis storage of failed sign in attempts per account and per IP.
+// After maxAttempts failures in a row the account is locked for lockout, every next failure
+// doubles it up to maxLockout. Failures are forgotten after window without failed attempts.
+type LoginAttempts struct {
+	redisClient   *redis.Client
+	maxAttempts   int64
+	maxAttemptsIP int64
+	lockout       time.Duration
+	maxLockout    time.Duration
+	window        time.Duration
+}
+
+func NewLoginAttempts(cfg *config.Config, redisClient *redis.Client) *LoginAttempts {
+	return &LoginAttempts{
+		redisClient:   redisClient,
+		maxAttempts:   cfg.LoginMaxAttempts,
+		maxAttemptsIP: cfg.LoginMaxAttemptsIP,
+		lockout:       cfg.LoginLockout,
+		maxLockout:    cfg.LoginMaxLockout,
+		window:        cfg.LoginFailureWindow,
+	}
+}
+
+// Check method returns time left until lockout of the account or the IP ends, zero if neither is locked.
+func (l *LoginAttempts) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	pipe := l.redisClient.Pipeline()
+	account := pipe.PTTL(ctx, accountLockPrefix+normalizeEmail(email))
+	address := pipe.PTTL(ctx, ipLockPrefix+ip)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.Error(err, "redis: error occurred while checking lockout")
+		return 0, fmt.Errorf("redis: error occurred while checking lockout - %w", err)
+	}
+
+	// PTTL returns negative values for missing keys
+	left := account.Val()
+	if address.Val() > left {
+		left = address.Val()
+	}
+	if left < 0 {
+		return 0, nil
+	}
+
+	return left, nil
+}
+
+// RecordFailure method counts failed attempt of the account from the IP and locks them if there are too many.
+func (l *LoginAttempts) RecordFailure(ctx context.Context, email, ip string) error {
+	email = normalizeEmail(email)
+	pipe := l.redisClient.TxPipeline()
+	accountFailures := pipe.Incr(ctx, accountFailuresPrefix+email)
+	pipe.Expire(ctx, accountFailuresPrefix+email, l.window)
+	ipFailures := pipe.Incr(ctx, ipFailuresPrefix+ip)
+	pipe.Expire(ctx, ipFailuresPrefix+ip, l.window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.Error(err, "redis: error occurred while recording failed sign in")
+		return fmt.Errorf("redis: error occurred while recording failed sign in - %w", err)
+	}
+
+	now := time.Now().UTC()
+	pipe = l.redisClient.TxPipeline()
+	if failures := accountFailures.Val(); failures >= l.maxAttempts {
+		lockout := l.backoff(failures - l.maxAttempts)
+		record, err := json.Marshal(&model.Lockout{
+			Email: email, Failures: failures, LockedAt: now, LockedUntil: now.Add(lockout),
+		})
+		if err != nil {
+			return fmt.Errorf("redis: can't encode lockout - %w", err)
+		}
+		pipe.Set(ctx, accountLockPrefix+email, 1, lockout)
+		pipe.HSet(ctx, lockoutsKey, email, record)
+		logrus.WithFields(logrus.Fields{
+			"email":    email,
+			"failures": failures,
+			"lockout":  lockout,
+		}).Warn("redis: account is locked after failed sign in attempts")
+	}
+	if failures := ipFailures.Val(); failures >= l.maxAttemptsIP {
+		lockout := l.backoff(failures - l.maxAttemptsIP)
+		pipe.Set(ctx, ipLockPrefix+ip, 1, lockout)
+		logrus.WithFields(logrus.Fields{
+			"ip":       ip,
+			"failures": failures,
+			"lockout":  lockout,
+		}).Warn("redis: IP is locked after failed sign in attempts")
+	}
+	if pipe.Len() == 0 {
+		return nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.Error(err, "redis: error occurred while locking sign in")
+		return fmt.Errorf("redis: error occurred while locking sign in - %w", err)
+	}
+
+	return nil
+}
+
+// Reset method forgets failed attempts of the account after successful sign in.
+// Failures of the IP are kept, so one valid account doesn't let to guess passwords of others.
+func (l *LoginAttempts) Reset(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+	pipe := l.redisClient.TxPipeline()
+	pipe.Del(ctx, accountFailuresPrefix+email)
+	pipe.HDel(ctx, lockoutsKey, email)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.Error(err, "redis: error occurred while resetting failed sign in attempts")
+		return fmt.Errorf("redis: error occurred while resetting failed sign in attempts - %w", err)
+	}
+
+	return nil
+}
+
+// Lockouts method returns current lockouts of accounts sorted by email, ended ones are removed.
+func (l *LoginAttempts) Lockouts(ctx context.Context) ([]*model.Lockout, error) {
+	records, err := l.redisClient.HGetAll(ctx, lockoutsKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		logrus.Error(err, "redis: error occurred while getting lockouts")
+		return nil, fmt.Errorf("redis: error occurred while getting lockouts - %w", err)
+	}
+
+	now := time.Now()
+	lockouts := make([]*model.Lockout, 0, len(records))
+	var ended []string
+	for email, record := range records {
+		var lockout model.Lockout
+		if err := json.Unmarshal([]byte(record), &lockout); err != nil || !lockout.LockedUntil.After(now) {
+			ended = append(ended, email)
+			continue
+		}
+		lockouts = append(lockouts, &lockout)
+	}
+	if len(ended) > 0 {
+		if err := l.redisClient.HDel(ctx, lockoutsKey, ended...).Err(); err != nil {
+			logrus.Error(err, "redis: error occurred while removing ended lockouts")
+		}
+	}
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].Email < lockouts[j].Email
+	})
+
+	return lockouts, nil
+}
+
+// Unlock method ends lockout of the account and forgets its failed attempts.
+func (l *LoginAttempts) Unlock(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+	pipe := l.redisClient.TxPipeline()
+	pipe.Del(ctx, accountFailuresPrefix+email, accountLockPrefix+email)
+	pipe.HDel(ctx, lockoutsKey, email)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.Error(err, "redis: error occurred while unlocking account")
+		return fmt.Errorf("redis: error occurred while unlocking account - %w", err)
+	}
+
+	logrus.Infof("redis: account %s is unlocked", email)
+	return nil
+}
+
+// backoff returns lockout after the given number of failures over the limit.
+func (l *LoginAttempts) backoff(over int64) time.Duration {
+	lockout := l.lockout
+	for i := int64(0); i < over && lockout < l.maxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > l.maxLockout {
+		return l.maxLockout
+	}
+
+	return lockout
+}
+
+// normalizeEmail returns the email in the form failed attempts are counted by,
+// so changing case of the email doesn't reset the counter.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,145 @@
+package rediscache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis/v8"
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedis returns client of redis server running in memory till the end of the test.
+func newRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return server, client
+}
+
+func newLoginAttempts(t *testing.T) (*miniredis.Miniredis, *LoginAttempts) {
+	t.Helper()
+	server, client := newRedis(t)
+	cfg := config.Config{
+		LoginMaxAttempts:   3,
+		LoginMaxAttemptsIP: 5,
+		LoginLockout:       time.Minute,
+		LoginMaxLockout:    5 * time.Minute,
+		LoginFailureWindow: time.Hour,
+	}
+
+	return server, NewLoginAttempts(&cfg, client)
+}
+
+func TestLoginAttemptsLockout(t *testing.T) {
+	ctx := context.Background()
+	_, attempts := newLoginAttempts(t)
+	email, ip := "test@gmail.com", "192.0.2.1"
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, attempts.RecordFailure(ctx, email, ip))
+	}
+	left, err := attempts.Check(ctx, email, ip)
+	require.NoError(t, err)
+	assert.Zero(t, left, "account is locked before the threshold")
+
+	// every failure over the threshold doubles lockout up to the max
+	lockouts := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for _, lockout := range lockouts {
+		require.NoError(t, attempts.RecordFailure(ctx, " Test@Gmail.com", "192.0.2.2"))
+		left, err = attempts.Check(ctx, email, "192.0.2.3")
+		require.NoError(t, err)
+		assert.Equal(t, lockout, left)
+	}
+
+	locked, err := attempts.Lockouts(ctx)
+	require.NoError(t, err)
+	require.Len(t, locked, 1)
+	assert.Equal(t, email, locked[0].Email)
+	assert.Equal(t, int64(7), locked[0].Failures)
+
+	require.NoError(t, attempts.Unlock(ctx, email))
+	left, err = attempts.Check(ctx, email, "192.0.2.3")
+	require.NoError(t, err)
+	assert.Zero(t, left)
+	locked, err = attempts.Lockouts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, locked)
+}
+
+func TestLoginAttemptsReset(t *testing.T) {
+	ctx := context.Background()
+	_, attempts := newLoginAttempts(t)
+	email, ip := "test@gmail.com", "192.0.2.1"
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, attempts.RecordFailure(ctx, email, ip))
+	}
+	require.NoError(t, attempts.Reset(ctx, email))
+
+	// failures before successful sign in aren't counted
+	for i := 0; i < 2; i++ {
+		require.NoError(t, attempts.RecordFailure(ctx, email, "192.0.2.2"))
+	}
+	left, err := attempts.Check(ctx, email, "192.0.2.2")
+	require.NoError(t, err)
+	assert.Zero(t, left)
+
+	require.NoError(t, attempts.RecordFailure(ctx, email, "192.0.2.2"))
+	left, err = attempts.Check(ctx, email, "192.0.2.2")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, left)
+}
+
+func TestLoginAttemptsWindow(t *testing.T) {
+	ctx := context.Background()
+	server, attempts := newLoginAttempts(t)
+	email, ip := "test@gmail.com", "192.0.2.1"
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, attempts.RecordFailure(ctx, email, ip))
+	}
+	server.FastForward(time.Hour)
+
+	require.NoError(t, attempts.RecordFailure(ctx, email, ip))
+	left, err := attempts.Check(ctx, email, ip)
+	require.NoError(t, err)
+	assert.Zero(t, left, "failures out of the window are counted")
+}
+
+func TestLoginAttemptsIP(t *testing.T) {
+	ctx := context.Background()
+	_, attempts := newLoginAttempts(t)
+	ip := "192.0.2.1"
+
+	// each email fails less times than the threshold of accounts
+	emails := []string{"first@gmail.com", "second@gmail.com", "third@gmail.com"}
+	for i := 0; i < 5; i++ {
+		require.NoError(t, attempts.RecordFailure(ctx, emails[i%len(emails)], ip))
+	}
+	for _, email := range emails {
+		left, err := attempts.Check(ctx, email, "192.0.2.2")
+		require.NoError(t, err)
+		assert.Zero(t, left, "account is locked by failures from the IP")
+	}
+	left, err := attempts.Check(ctx, "fourth@gmail.com", ip)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, left)
+
+	// successful sign in doesn't unlock the IP
+	require.NoError(t, attempts.Reset(ctx, emails[0]))
+	left, err = attempts.Check(ctx, emails[0], ip)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, left)
+
+	lockouts, err := attempts.Lockouts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, lockouts)
+}
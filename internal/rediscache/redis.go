@@ -19,16 +19,40 @@ type Cat interface {
 	Delete(ctx context.Context, id string) error
 }
 
+// Denylist is the storage of revoked access tokens.
+type Denylist interface {
+	RevokeToken(ctx context.Context, id string, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userID string, revokedAt time.Time, ttl time.Duration) error
+	IsRevoked(ctx context.Context, id, userID string, issuedAt time.Time) (bool, error)
+}
+
+// LoginLimiter is the storage of failed sign in attempts. Accounts are identified
+// by email, so attempts are counted for unknown emails too.
+type LoginLimiter interface {
+	// Check returns time left until lockout of the account or the IP ends, zero if neither is locked.
+	Check(ctx context.Context, email, ip string) (time.Duration, error)
+	// RecordFailure counts failed attempt and locks the account or the IP if there are too many.
+	RecordFailure(ctx context.Context, email, ip string) error
+	// Reset forgets failed attempts of the account after successful sign in.
+	Reset(ctx context.Context, email string) error
+	// Lockouts returns current lockouts of accounts.
+	Lockouts(ctx context.Context) ([]*model.Lockout, error)
+	// Unlock ends lockout of the account and forgets its failed attempts.
+	Unlock(ctx context.Context, email string) error
+}
+
 type Cache struct {
 	Cat
+	Denylist
+	LoginLimiter
 }
 
 // NewCache returns new cache instance with redisdb client.
-func NewCache(redisClient *redis.Client) *Cache {
-	return &Cache{NewCatCache(redisClient, cacheTTL)}
+func NewCache(cfg *config.Config, redisClient *redis.Client) *Cache {
+	return &Cache{NewCatCache(redisClient, cacheTTL), NewTokenDenylist(redisClient), NewLoginAttempts(cfg, redisClient)}
 }
 
 // NewStreamCache returns new cache instance with redisdb client.
 func NewStreamCache(cfg *config.Config, redisClient *redis.Client) *Cache {
-	return &Cache{NewCatStreamCache(cfg, redisClient)}
+	return &Cache{NewCatStreamCache(cfg, redisClient), NewTokenDenylist(redisClient), NewLoginAttempts(cfg, redisClient)}
 }
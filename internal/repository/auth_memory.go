@@ -0,0 +1,660 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// AuthRepositoryMemory type represents in-memory behavior for authentication.
+type AuthRepositoryMemory struct {
+	users map[string]CreateUserInput
+	// emails maps users email to users id
+	emails map[string]string
+	// resets maps hash of reset token to the reset
+	resets map[string]model.PasswordReset
+	// verifications maps hash of verification token to the verification
+	verifications map[string]model.EmailVerification
+	// twoFactors maps users id to two-factor authentication of the user
+	twoFactors map[string]model.TwoFactor
+	// apiKeys maps id of API key to the key
+	apiKeys map[string]model.APIKey
+	// identities maps issuer and subject joined by identityKey to the identity
+	identities map[string]model.Identity
+	// oidcLogins maps hash of state to the login
+	oidcLogins map[string]model.OIDCLogin
+	mutex      sync.RWMutex
+}
+
+func NewAuthRepositoryMemory() *AuthRepositoryMemory {
+	return &AuthRepositoryMemory{
+		users:         make(map[string]CreateUserInput),
+		emails:        make(map[string]string),
+		resets:        make(map[string]model.PasswordReset),
+		verifications: make(map[string]model.EmailVerification),
+		twoFactors:    make(map[string]model.TwoFactor),
+		apiKeys:       make(map[string]model.APIKey),
+		identities:    make(map[string]model.Identity),
+		oidcLogins:    make(map[string]model.OIDCLogin),
+	}
+}
+
+// CreateUser method create user in memory.
+func (r *AuthRepositoryMemory) CreateUser(ctx context.Context, input *CreateUserInput) error {
+	logrus.WithFields(logrus.Fields{
+		"ID":       input.ID,
+		"userName": input.UserName,
+		"email":    input.Email,
+	}).Debugf("memory repository: create User")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ex := r.users[input.ID]; ex {
+		return apperror.Conflict("user with given UUID exists, try to create again")
+	}
+	if _, ex := r.emails[input.Email]; ex {
+		return apperror.Conflict("user with given email exists, change your email")
+	}
+	r.users[input.ID] = *input
+	r.emails[input.Email] = input.ID
+
+	return nil
+}
+
+// GetUserHashedPassword method returns user id with hashed password from memory.
+func (r *AuthRepositoryMemory) GetUserHashedPassword(ctx context.Context, email string) (string, string, error) {
+	logrus.WithFields(logrus.Fields{
+		"email": email,
+	}).Debugf("memory repository: get user id and hashed password")
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	id, ex := r.emails[email]
+	if !ex {
+		return "", "", apperror.NotFound("user with given email doesn't exist")
+	}
+
+	return id, r.users[id].Password, nil
+}
+
+// GetUser method returns user without credentials from memory.
+func (r *AuthRepositoryMemory) GetUser(ctx context.Context, id string) (*model.User, error) {
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Debugf("memory repository: get user")
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	user, ex := r.users[id]
+	if !ex {
+		return nil, apperror.NotFound("user with given UUID doesn't exist")
+	}
+
+	return toUser(&user), nil
+}
+
+// UpdateUserRole method updates role of the user.
+func (r *AuthRepositoryMemory) UpdateUserRole(ctx context.Context, id, role string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":   id,
+		"role": role,
+	}).Debugf("memory repository: update user role")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user, ex := r.users[id]
+	if !ex {
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+	user.Role = role
+	r.users[id] = user
+
+	return nil
+}
+
+// UpdateUserName method updates name of the user.
+func (r *AuthRepositoryMemory) UpdateUserName(ctx context.Context, id, userName string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":       id,
+		"userName": userName,
+	}).Debugf("memory repository: update user name")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user, ex := r.users[id]
+	if !ex {
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+	user.UserName = userName
+	r.users[id] = user
+
+	return nil
+}
+
+// SetUserDisabled method disables or enables the user.
+func (r *AuthRepositoryMemory) SetUserDisabled(ctx context.Context, id string, disabled bool) error {
+	logrus.WithFields(logrus.Fields{
+		"id":       id,
+		"disabled": disabled,
+	}).Debugf("memory repository: set user disabled")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user, ex := r.users[id]
+	if !ex {
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+	user.Disabled = disabled
+	r.users[id] = user
+
+	return nil
+}
+
+// ListUsers method returns page of users matching the input from memory.
+func (r *AuthRepositoryMemory) ListUsers(ctx context.Context, input *model.ListUsers) (*model.UsersPage, error) {
+	logrus.WithFields(logrus.Fields{
+		"Limit":    input.Limit,
+		"Cursor":   input.Cursor,
+		"Query":    input.Query,
+		"Role":     input.Role,
+		"Disabled": input.Disabled,
+	}).Debugf("memory repository: list users")
+
+	var cursor *usersCursor
+	if input.Cursor != "" {
+		var err error
+		if cursor, err = decodeUsersCursor(input.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mutex.RLock()
+	users := make([]*model.User, 0)
+	for id := range r.users {
+		user := r.users[id]
+		if matchUser(&user, input) && (cursor == nil || user.Email > cursor.Email) {
+			users = append(users, toUser(&user))
+		}
+	}
+	r.mutex.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Email < users[j].Email
+	})
+	if len(users) > input.Limit+1 {
+		users = users[:input.Limit+1]
+	}
+
+	return newUsersPage(users, input.Limit), nil
+}
+
+// DeleteUser method deletes the user with all credentials of the user from memory.
+func (r *AuthRepositoryMemory) DeleteUser(ctx context.Context, id string) error {
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Debugf("memory repository: delete user")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user, ex := r.users[id]
+	if !ex {
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+	delete(r.users, id)
+	delete(r.emails, user.Email)
+	delete(r.twoFactors, id)
+	for hash, reset := range r.resets {
+		if reset.UserID == id {
+			delete(r.resets, hash)
+		}
+	}
+	for hash, verification := range r.verifications {
+		if verification.UserID == id {
+			delete(r.verifications, hash)
+		}
+	}
+	for keyID, key := range r.apiKeys {
+		if key.UserID == id {
+			delete(r.apiKeys, keyID)
+		}
+	}
+	for key, identity := range r.identities {
+		if identity.UserID == id {
+			delete(r.identities, key)
+		}
+	}
+
+	return nil
+}
+
+// CreatePasswordReset method saves reset token of the user in memory.
+func (r *AuthRepositoryMemory) CreatePasswordReset(ctx context.Context, reset *model.PasswordReset) error {
+	logrus.WithFields(logrus.Fields{
+		"userID":    reset.UserID,
+		"expiresAt": reset.ExpiresAt,
+	}).Debugf("memory repository: create password reset")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ex := r.resets[reset.TokenHash]; ex {
+		return apperror.Conflict("password reset with given token already exists, try again")
+	}
+	r.resets[reset.TokenHash] = *reset
+
+	return nil
+}
+
+// ResetPassword method replaces password of the user with the reset token in memory.
+func (r *AuthRepositoryMemory) ResetPassword(ctx context.Context, tokenHash, password string) (string, error) {
+	logrus.Debugf("memory repository: reset password")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	reset, ex := r.resets[tokenHash]
+	if !ex || !reset.ExpiresAt.After(time.Now()) {
+		return "", apperror.NotFound("password reset with given token doesn't exist")
+	}
+	user, ex := r.users[reset.UserID]
+	if !ex {
+		return "", apperror.NotFound("password reset with given token doesn't exist")
+	}
+	user.Password = password
+	r.users[reset.UserID] = user
+	for hash, reset := range r.resets {
+		if reset.UserID == user.ID {
+			delete(r.resets, hash)
+		}
+	}
+
+	return user.ID, nil
+}
+
+// UpdatePassword method replaces hashed password of the user in memory.
+func (r *AuthRepositoryMemory) UpdatePassword(ctx context.Context, id, oldHash, newHash string) error {
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Debugf("memory repository: update password")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user, ex := r.users[id]
+	if !ex || user.Password != oldHash {
+		return apperror.NotFound("user with given UUID and password doesn't exist")
+	}
+	user.Password = newHash
+	r.users[id] = user
+
+	return nil
+}
+
+// CreateEmailVerification method saves verification token of the user in memory.
+func (r *AuthRepositoryMemory) CreateEmailVerification(ctx context.Context, verification *model.EmailVerification) error {
+	logrus.WithFields(logrus.Fields{
+		"userID":    verification.UserID,
+		"expiresAt": verification.ExpiresAt,
+	}).Debugf("memory repository: create email verification")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ex := r.verifications[verification.TokenHash]; ex {
+		return apperror.Conflict("email verification with given token already exists, try again")
+	}
+	r.verifications[verification.TokenHash] = *verification
+
+	return nil
+}
+
+// VerifyEmail method marks email of the user with the verification token as verified in memory.
+func (r *AuthRepositoryMemory) VerifyEmail(ctx context.Context, tokenHash string) (string, error) {
+	logrus.Debugf("memory repository: verify email")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	verification, ex := r.verifications[tokenHash]
+	if !ex || !verification.ExpiresAt.After(time.Now()) {
+		return "", apperror.NotFound("email verification with given token doesn't exist")
+	}
+	user, ex := r.users[verification.UserID]
+	if !ex {
+		return "", apperror.NotFound("email verification with given token doesn't exist")
+	}
+	user.EmailVerified = true
+	r.users[verification.UserID] = user
+	for hash, verification := range r.verifications {
+		if verification.UserID == user.ID {
+			delete(r.verifications, hash)
+		}
+	}
+
+	return user.ID, nil
+}
+
+// SaveTwoFactor method saves not enabled two-factor authentication of the user in memory.
+func (r *AuthRepositoryMemory) SaveTwoFactor(ctx context.Context, twoFactor *model.TwoFactor) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": twoFactor.UserID,
+	}).Debugf("memory repository: save two-factor authentication")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.twoFactors[twoFactor.UserID].Enabled {
+		return apperror.Conflict("two-factor authentication of the user is already enabled")
+	}
+	saved := *twoFactor
+	saved.RecoveryCodes = append([]string(nil), twoFactor.RecoveryCodes...)
+	r.twoFactors[twoFactor.UserID] = saved
+
+	return nil
+}
+
+// GetTwoFactor method returns two-factor authentication of the user from memory.
+func (r *AuthRepositoryMemory) GetTwoFactor(ctx context.Context, userID string) (*model.TwoFactor, error) {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("memory repository: get two-factor authentication")
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	twoFactor, ex := r.twoFactors[userID]
+	if !ex {
+		return nil, apperror.NotFound("two-factor authentication of the user doesn't exist")
+	}
+	twoFactor.RecoveryCodes = append([]string(nil), twoFactor.RecoveryCodes...)
+
+	return &twoFactor, nil
+}
+
+// EnableTwoFactor method enables two-factor authentication of the user in memory.
+func (r *AuthRepositoryMemory) EnableTwoFactor(ctx context.Context, userID string, recoveryCodes []string, step int64) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("memory repository: enable two-factor authentication")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	twoFactor, ex := r.twoFactors[userID]
+	if !ex || twoFactor.Enabled {
+		return apperror.NotFound("not enabled two-factor authentication of the user doesn't exist")
+	}
+	twoFactor.Enabled = true
+	twoFactor.RecoveryCodes = append([]string(nil), recoveryCodes...)
+	twoFactor.LastStep = step
+	r.twoFactors[userID] = twoFactor
+
+	return nil
+}
+
+// UseTwoFactorStep method marks the time step of a code of the user as used in memory.
+func (r *AuthRepositoryMemory) UseTwoFactorStep(ctx context.Context, userID string, step int64) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"step":   step,
+	}).Debugf("memory repository: use two-factor code")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	twoFactor, ex := r.twoFactors[userID]
+	if !ex || !twoFactor.Enabled {
+		return apperror.NotFound("two-factor authentication of the user doesn't exist")
+	}
+	if step <= twoFactor.LastStep {
+		return apperror.Conflict("two-factor code is already used")
+	}
+	twoFactor.LastStep = step
+	r.twoFactors[userID] = twoFactor
+
+	return nil
+}
+
+// UseRecoveryCode method removes the recovery code of the user from memory.
+func (r *AuthRepositoryMemory) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("memory repository: use recovery code")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	twoFactor, ex := r.twoFactors[userID]
+	if ex && twoFactor.Enabled {
+		for i, hash := range twoFactor.RecoveryCodes {
+			if hash == codeHash {
+				twoFactor.RecoveryCodes = append(append([]string(nil), twoFactor.RecoveryCodes[:i]...),
+					twoFactor.RecoveryCodes[i+1:]...)
+				r.twoFactors[userID] = twoFactor
+				return nil
+			}
+		}
+	}
+
+	return apperror.NotFound("recovery code of the user doesn't exist")
+}
+
+// DeleteTwoFactor method deletes two-factor authentication of the user from memory.
+func (r *AuthRepositoryMemory) DeleteTwoFactor(ctx context.Context, userID string) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("memory repository: delete two-factor authentication")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.twoFactors, userID)
+
+	return nil
+}
+
+// CreateAPIKey method saves API key of the user in memory.
+func (r *AuthRepositoryMemory) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	logrus.WithFields(logrus.Fields{
+		"id":     key.ID,
+		"userID": key.UserID,
+		"name":   key.Name,
+		"scopes": key.Scopes,
+	}).Debugf("memory repository: create API key")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for id, saved := range r.apiKeys {
+		if id == key.ID || saved.KeyHash == key.KeyHash {
+			return apperror.Conflict("API key already exists, try to create again")
+		}
+	}
+	r.apiKeys[key.ID] = copyAPIKey(key)
+
+	return nil
+}
+
+// GetAPIKey method returns not expired API key with the hash from memory.
+func (r *AuthRepositoryMemory) GetAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	logrus.Debugf("memory repository: get API key")
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, key := range r.apiKeys {
+		if key.KeyHash == keyHash && (key.ExpiresAt == nil || key.ExpiresAt.After(time.Now())) {
+			copied := copyAPIKey(&key)
+			return &copied, nil
+		}
+	}
+
+	return nil, apperror.NotFound("API key doesn't exist")
+}
+
+// ListAPIKeys method returns API keys of the user from memory.
+func (r *AuthRepositoryMemory) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("memory repository: list API keys")
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	keys := make([]*model.APIKey, 0)
+	for _, key := range r.apiKeys {
+		if key.UserID == userID {
+			copied := copyAPIKey(&key)
+			keys = append(keys, &copied)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	return keys, nil
+}
+
+// DeleteAPIKey method deletes API key of the user from memory.
+func (r *AuthRepositoryMemory) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":     id,
+		"userID": userID,
+	}).Debugf("memory repository: delete API key")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if key, ex := r.apiKeys[id]; !ex || key.UserID != userID {
+		return apperror.NotFound("API key with given UUID doesn't exist")
+	}
+	delete(r.apiKeys, id)
+
+	return nil
+}
+
+// TouchAPIKey method sets the time the API key was used at last in memory.
+func (r *AuthRepositoryMemory) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Debugf("memory repository: touch API key")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key, ex := r.apiKeys[id]
+	if !ex {
+		return apperror.NotFound("API key with given UUID doesn't exist")
+	}
+	key.LastUsedAt = &usedAt
+	r.apiKeys[id] = key
+
+	return nil
+}
+
+// CreateIdentity method links identity at OpenID Connect provider to the user in memory.
+func (r *AuthRepositoryMemory) CreateIdentity(ctx context.Context, identity *model.Identity) error {
+	logrus.WithFields(logrus.Fields{
+		"issuer":  identity.Issuer,
+		"subject": identity.Subject,
+		"userID":  identity.UserID,
+	}).Debugf("memory repository: create identity")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := identityKey(identity.Issuer, identity.Subject)
+	if _, ex := r.identities[key]; ex {
+		return apperror.Conflict("identity is linked to a user already")
+	}
+	r.identities[key] = *identity
+
+	return nil
+}
+
+// GetIdentity method returns identity at OpenID Connect provider from memory.
+func (r *AuthRepositoryMemory) GetIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error) {
+	logrus.WithFields(logrus.Fields{
+		"issuer":  issuer,
+		"subject": subject,
+	}).Debugf("memory repository: get identity")
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	identity, ex := r.identities[identityKey(issuer, subject)]
+	if !ex {
+		return nil, apperror.NotFound("identity doesn't exist")
+	}
+
+	return &identity, nil
+}
+
+// CreateOIDCLogin method saves started login with OpenID Connect provider in memory.
+func (r *AuthRepositoryMemory) CreateOIDCLogin(ctx context.Context, login *model.OIDCLogin) error {
+	logrus.WithFields(logrus.Fields{
+		"expiresAt": login.ExpiresAt,
+	}).Debugf("memory repository: create OIDC login")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ex := r.oidcLogins[login.StateHash]; ex {
+		return apperror.Conflict("OIDC login with given state already exists, try again")
+	}
+	r.oidcLogins[login.StateHash] = *login
+
+	return nil
+}
+
+// ConsumeOIDCLogin method deletes not expired login with the state hash from memory and returns it.
+func (r *AuthRepositoryMemory) ConsumeOIDCLogin(ctx context.Context, stateHash string) (*model.OIDCLogin, error) {
+	logrus.Debugf("memory repository: consume OIDC login")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	login, ex := r.oidcLogins[stateHash]
+	delete(r.oidcLogins, stateHash)
+	if !ex || !login.ExpiresAt.After(time.Now()) {
+		return nil, apperror.NotFound("OIDC login with given state doesn't exist")
+	}
+
+	return &login, nil
+}
+
+// copyAPIKey returns copy of the key not sharing scopes and times with it.
+func copyAPIKey(key *model.APIKey) model.APIKey {
+	copied := *key
+	copied.Scopes = append([]string(nil), key.Scopes...)
+	if key.ExpiresAt != nil {
+		expiresAt := *key.ExpiresAt
+		copied.ExpiresAt = &expiresAt
+	}
+	if key.LastUsedAt != nil {
+		lastUsedAt := *key.LastUsedAt
+		copied.LastUsedAt = &lastUsedAt
+	}
+
+	return copied
+}
+
+// toUser returns the user without credentials.
+func toUser(user *CreateUserInput) *model.User {
+	return &model.User{
+		ID:            user.ID,
+		UserName:      user.UserName,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		Disabled:      user.Disabled,
+	}
+}
+
+// matchUser reports whether the user satisfies filters of the input.
+func matchUser(user *CreateUserInput, input *model.ListUsers) bool {
+	query := strings.ToLower(input.Query)
+	switch {
+	case input.Role != "" && user.Role != input.Role:
+		return false
+	case input.Disabled != nil && user.Disabled != *input.Disabled:
+		return false
+	default:
+		return strings.Contains(strings.ToLower(user.Email), query) ||
+			strings.Contains(strings.ToLower(user.UserName), query)
+	}
+}
+
+// identityKey returns key of the identity in the map of identities.
+func identityKey(issuer, subject string) string {
+	return issuer + "\x00" + subject
+}
@@ -2,11 +2,25 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// AuthRepositoryMongo type represents postgres behavior for authentication.
+// usersEmailIndex is the name of unique index on users email created by migration,
+// the same as the name of the unique constraint in postgres.
+const usersEmailIndex = "users_email_key"
+
+// AuthRepositoryMongo type represents mongo behavior for authentication.
 type AuthRepositoryMongo struct {
 	DB *mongo.Client
 }
@@ -17,18 +31,723 @@ func NewAuthRepositoryMongo(db *mongo.Client) *AuthRepositoryMongo {
 	}
 }
 
+// userMongo type represents user document in mongo database.
+type userMongo struct {
+	ID            string `bson:"_id"`
+	UserName      string `bson:"name"`
+	Email         string `bson:"email"`
+	Password      string `bson:"password"`
+	Role          string `bson:"role"`
+	EmailVerified bool   `bson:"emailVerified"`
+	Disabled      bool   `bson:"disabled"`
+}
+
+// CreateUser method create user in mongo database.
 func (r AuthRepositoryMongo) CreateUser(ctx context.Context, input *CreateUserInput) error {
+	logrus.WithFields(logrus.Fields{
+		"ID":       input.ID,
+		"userName": input.UserName,
+		"email":    input.Email,
+	}).Debugf("mongo repository: create User")
+	col := r.DB.Database("mongo_database").Collection("users")
+
+	_, err := col.InsertOne(ctx, userMongo{
+		ID:            input.ID,
+		UserName:      input.UserName,
+		Email:         input.Email,
+		Password:      input.Password,
+		Role:          input.Role,
+		EmailVerified: input.EmailVerified,
+		Disabled:      input.Disabled,
+	})
+	if err != nil {
+		switch {
+		case mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), usersEmailIndex):
+			logrus.Error(err, "mongo repository: can't create User")
+			return apperror.Conflict("user with given email exists, change your email")
+
+		case mongo.IsDuplicateKeyError(err):
+			logrus.Error(err, "mongo repository: can't create User")
+			return apperror.Conflict("user with given UUID exists, try to create again")
+
+		default:
+			logrus.Error(err, "mongo repository: can't create User")
+			return fmt.Errorf("mongo repository: can't create User - %w", err)
+		}
+	}
+
 	return nil
 }
 
+// GetUserHashedPassword method returns user id with hashed password from mongo database.
 func (r AuthRepositoryMongo) GetUserHashedPassword(ctx context.Context, email string) (string, string, error) {
-	return "", "", nil
+	logrus.WithFields(logrus.Fields{
+		"email": email,
+	}).Debugf("mongo repository: get user id and hashed password")
+	col := r.DB.Database("mongo_database").Collection("users")
+
+	var user userMongo
+	if err := col.FindOne(ctx, bson.D{{Key: "email", Value: email}}).Decode(&user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logrus.Error(err, "mongo repository: user with given email doesn't exist")
+			return "", "", apperror.NotFound("user with given email doesn't exist")
+		}
+		logrus.Error(err, "mongo repository: can't get users hashed password")
+		return "", "", fmt.Errorf("mongo repository: can't get users hashed password - %w", err)
+	}
+
+	return user.ID, user.Password, nil
+}
+
+// GetUser method returns user without credentials from mongo database.
+func (r AuthRepositoryMongo) GetUser(ctx context.Context, id string) (*model.User, error) {
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Debugf("mongo repository: get user")
+	col := r.DB.Database("mongo_database").Collection("users")
+
+	var user model.User
+	if err := col.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logrus.Error(err, "mongo repository: user with given UUID doesn't exist")
+			return nil, apperror.NotFound("user with given UUID doesn't exist")
+		}
+		logrus.Error(err, "mongo repository: can't get user")
+		return nil, fmt.Errorf("mongo repository: can't get user - %w", err)
+	}
+
+	return &user, nil
+}
+
+// UpdateUserRole method updates role of the user.
+func (r AuthRepositoryMongo) UpdateUserRole(ctx context.Context, id, role string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":   id,
+		"role": role,
+	}).Debugf("mongo repository: update user role")
+	col := r.DB.Database("mongo_database").Collection("users")
+
+	result, err := col.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "role", Value: role}}},
+	})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't update user role")
+		return fmt.Errorf("mongo repository: can't update user role - %w", err)
+	}
+	if result.MatchedCount == 0 {
+		logrus.Error("mongo repository: user with given UUID doesn't exist")
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// UpdateUserName method updates name of the user.
+func (r AuthRepositoryMongo) UpdateUserName(ctx context.Context, id, userName string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":       id,
+		"userName": userName,
+	}).Debugf("mongo repository: update user name")
+	col := r.DB.Database("mongo_database").Collection("users")
+
+	result, err := col.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "name", Value: userName}}},
+	})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't update user name")
+		return fmt.Errorf("mongo repository: can't update user name - %w", err)
+	}
+	if result.MatchedCount == 0 {
+		logrus.Error("mongo repository: user with given UUID doesn't exist")
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// SetUserDisabled method disables or enables the user.
+func (r AuthRepositoryMongo) SetUserDisabled(ctx context.Context, id string, disabled bool) error {
+	logrus.WithFields(logrus.Fields{
+		"id":       id,
+		"disabled": disabled,
+	}).Debugf("mongo repository: set user disabled")
+	col := r.DB.Database("mongo_database").Collection("users")
+
+	result, err := col.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "disabled", Value: disabled}}},
+	})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't set user disabled")
+		return fmt.Errorf("mongo repository: can't set user disabled - %w", err)
+	}
+	if result.MatchedCount == 0 {
+		logrus.Error("mongo repository: user with given UUID doesn't exist")
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// ListUsers method returns page of users matching the input from mongo database.
+func (r AuthRepositoryMongo) ListUsers(ctx context.Context, input *model.ListUsers) (*model.UsersPage, error) {
+	logrus.WithFields(logrus.Fields{
+		"Limit":    input.Limit,
+		"Cursor":   input.Cursor,
+		"Query":    input.Query,
+		"Role":     input.Role,
+		"Disabled": input.Disabled,
+	}).Debugf("mongo repository: list users")
+	col := r.DB.Database("mongo_database").Collection("users")
+
+	filter := bson.D{}
+	if input.Query != "" {
+		query := bson.D{{Key: "$regex", Value: regexp.QuoteMeta(input.Query)}, {Key: "$options", Value: "i"}}
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "email", Value: query}},
+			bson.D{{Key: "name", Value: query}},
+		}})
+	}
+	if input.Role != "" {
+		filter = append(filter, bson.E{Key: "role", Value: input.Role})
+	}
+	if input.Disabled != nil {
+		filter = append(filter, bson.E{Key: "disabled", Value: *input.Disabled})
+	}
+
+	if input.Cursor != "" {
+		cursor, err := decodeUsersCursor(input.Cursor)
+		if err != nil {
+			logrus.Error(err, "mongo repository: invalid cursor")
+			return nil, err
+		}
+		filter = append(filter, bson.E{Key: "email", Value: bson.D{{Key: "$gt", Value: cursor.Email}}})
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "email", Value: 1}}).
+		SetLimit(int64(input.Limit + 1))
+	cur, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't list users")
+		return nil, fmt.Errorf("mongo repository: can't list users - %w", err)
+	}
+
+	users := make([]*model.User, 0, input.Limit+1)
+	if err := cur.All(ctx, &users); err != nil {
+		logrus.Error(err, "mongo repository: can't decode users")
+		return nil, fmt.Errorf("mongo repository: can't list users - %w", err)
+	}
+
+	return newUsersPage(users, input.Limit), nil
+}
+
+// DeleteUser method deletes the user with all credentials of the user from mongo database.
+// The user is deleted first, so credentials left by a failure can't be used.
+func (r AuthRepositoryMongo) DeleteUser(ctx context.Context, id string) error {
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Debugf("mongo repository: delete user")
+	db := r.DB.Database("mongo_database")
+
+	result, err := db.Collection("users").DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't delete user")
+		return fmt.Errorf("mongo repository: can't delete user - %w", err)
+	}
+	if result.DeletedCount == 0 {
+		logrus.Error("mongo repository: user with given UUID doesn't exist")
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+
+	if _, err := db.Collection("two_factors").DeleteOne(ctx, bson.D{{Key: "_id", Value: id}}); err != nil {
+		logrus.Error(err, "mongo repository: can't delete two-factor authentication of user")
+		return fmt.Errorf("mongo repository: can't delete two-factor authentication of user - %w", err)
+	}
+	for _, collection := range []string{"password_resets", "email_verifications", "api_keys", "user_identities"} {
+		if _, err := db.Collection(collection).DeleteMany(ctx, bson.D{{Key: "userId", Value: id}}); err != nil {
+			logrus.Error(err, "mongo repository: can't delete "+collection+" of user")
+			return fmt.Errorf("mongo repository: can't delete %s of user - %w", collection, err)
+		}
+	}
+
+	return nil
+}
+
+// CreatePasswordReset method saves reset token of the user into mongo database.
+func (r AuthRepositoryMongo) CreatePasswordReset(ctx context.Context, reset *model.PasswordReset) error {
+	logrus.WithFields(logrus.Fields{
+		"userID":    reset.UserID,
+		"expiresAt": reset.ExpiresAt,
+	}).Debugf("mongo repository: create password reset")
+	col := r.DB.Database("mongo_database").Collection("password_resets")
+
+	if _, err := col.InsertOne(ctx, reset); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			logrus.Error(err, "mongo repository: password reset with given token already exists")
+			return apperror.Conflict("password reset with given token already exists, try again")
+		}
+		logrus.Error(err, "mongo repository: can't create password reset")
+		return fmt.Errorf("mongo repository: can't create password reset - %w", err)
+	}
+
+	return nil
+}
+
+// ResetPassword method replaces password of the user with the reset token in mongo database.
+// The token is deleted before the password is replaced, so concurrent requests can't use it twice.
+func (r AuthRepositoryMongo) ResetPassword(ctx context.Context, tokenHash, password string) (string, error) {
+	logrus.Debugf("mongo repository: reset password")
+	resets := r.DB.Database("mongo_database").Collection("password_resets")
+
+	var reset model.PasswordReset
+	if err := resets.FindOneAndDelete(ctx, bson.D{
+		{Key: "_id", Value: tokenHash},
+		{Key: "expiresAt", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}).Decode(&reset); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logrus.Error(err, "mongo repository: password reset with given token doesn't exist")
+			return "", apperror.NotFound("password reset with given token doesn't exist")
+		}
+		logrus.Error(err, "mongo repository: can't reset password")
+		return "", fmt.Errorf("mongo repository: can't reset password - %w", err)
+	}
+
+	result, err := r.DB.Database("mongo_database").Collection("users").UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: reset.UserID}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "password", Value: password}}}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't reset password")
+		return "", fmt.Errorf("mongo repository: can't reset password - %w", err)
+	}
+	if result.MatchedCount == 0 {
+		logrus.Error("mongo repository: user of password reset doesn't exist")
+		return "", apperror.NotFound("password reset with given token doesn't exist")
+	}
+
+	if _, err := resets.DeleteMany(ctx, bson.D{{Key: "userId", Value: reset.UserID}}); err != nil {
+		logrus.Error(err, "mongo repository: can't delete password resets of user")
+		return "", fmt.Errorf("mongo repository: can't delete password resets of user - %w", err)
+	}
+
+	return reset.UserID, nil
+}
+
+// UpdatePassword method replaces hashed password of the user in mongo database.
+func (r AuthRepositoryMongo) UpdatePassword(ctx context.Context, id, oldHash, newHash string) error {
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Debugf("mongo repository: update password")
+	col := r.DB.Database("mongo_database").Collection("users")
+
+	result, err := col.UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: id}, {Key: "password", Value: oldHash}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "password", Value: newHash}}}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't update password")
+		return fmt.Errorf("mongo repository: can't update password - %w", err)
+	}
+	if result.MatchedCount == 0 {
+		logrus.Error("mongo repository: user with given UUID and password doesn't exist")
+		return apperror.NotFound("user with given UUID and password doesn't exist")
+	}
+
+	return nil
+}
+
+// CreateEmailVerification method saves verification token of the user into mongo database.
+func (r AuthRepositoryMongo) CreateEmailVerification(ctx context.Context, verification *model.EmailVerification) error {
+	logrus.WithFields(logrus.Fields{
+		"userID":    verification.UserID,
+		"expiresAt": verification.ExpiresAt,
+	}).Debugf("mongo repository: create email verification")
+	col := r.DB.Database("mongo_database").Collection("email_verifications")
+
+	if _, err := col.InsertOne(ctx, verification); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			logrus.Error(err, "mongo repository: email verification with given token already exists")
+			return apperror.Conflict("email verification with given token already exists, try again")
+		}
+		logrus.Error(err, "mongo repository: can't create email verification")
+		return fmt.Errorf("mongo repository: can't create email verification - %w", err)
+	}
+
+	return nil
+}
+
+// VerifyEmail method marks email of the user with the verification token as verified in mongo database.
+// The token is deleted before the email is marked, so concurrent requests can't use it twice.
+func (r AuthRepositoryMongo) VerifyEmail(ctx context.Context, tokenHash string) (string, error) {
+	logrus.Debugf("mongo repository: verify email")
+	verifications := r.DB.Database("mongo_database").Collection("email_verifications")
+
+	var verification model.EmailVerification
+	if err := verifications.FindOneAndDelete(ctx, bson.D{
+		{Key: "_id", Value: tokenHash},
+		{Key: "expiresAt", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}).Decode(&verification); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logrus.Error(err, "mongo repository: email verification with given token doesn't exist")
+			return "", apperror.NotFound("email verification with given token doesn't exist")
+		}
+		logrus.Error(err, "mongo repository: can't verify email")
+		return "", fmt.Errorf("mongo repository: can't verify email - %w", err)
+	}
+
+	result, err := r.DB.Database("mongo_database").Collection("users").UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: verification.UserID}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "emailVerified", Value: true}}}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't verify email")
+		return "", fmt.Errorf("mongo repository: can't verify email - %w", err)
+	}
+	if result.MatchedCount == 0 {
+		logrus.Error("mongo repository: user of email verification doesn't exist")
+		return "", apperror.NotFound("email verification with given token doesn't exist")
+	}
+
+	if _, err := verifications.DeleteMany(ctx, bson.D{{Key: "userId", Value: verification.UserID}}); err != nil {
+		logrus.Error(err, "mongo repository: can't delete email verifications of user")
+		return "", fmt.Errorf("mongo repository: can't delete email verifications of user - %w", err)
+	}
+
+	return verification.UserID, nil
+}
+
+// SaveTwoFactor method saves not enabled two-factor authentication of the user into mongo database.
+func (r AuthRepositoryMongo) SaveTwoFactor(ctx context.Context, twoFactor *model.TwoFactor) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": twoFactor.UserID,
+	}).Debugf("mongo repository: save two-factor authentication")
+	col := r.DB.Database("mongo_database").Collection("two_factors")
+
+	// enabled document doesn't match the filter, so upsert fails with duplicate id
+	_, err := col.ReplaceOne(ctx, bson.D{{Key: "_id", Value: twoFactor.UserID}, {Key: "enabled", Value: false}},
+		twoFactor, options.Replace().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			logrus.Error(err, "mongo repository: two-factor authentication of the user is already enabled")
+			return apperror.Conflict("two-factor authentication of the user is already enabled")
+		}
+		logrus.Error(err, "mongo repository: can't save two-factor authentication")
+		return fmt.Errorf("mongo repository: can't save two-factor authentication - %w", err)
+	}
+
+	return nil
+}
+
+// GetTwoFactor method returns two-factor authentication of the user from mongo database.
+func (r AuthRepositoryMongo) GetTwoFactor(ctx context.Context, userID string) (*model.TwoFactor, error) {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("mongo repository: get two-factor authentication")
+	col := r.DB.Database("mongo_database").Collection("two_factors")
+
+	var twoFactor model.TwoFactor
+	if err := col.FindOne(ctx, bson.D{{Key: "_id", Value: userID}}).Decode(&twoFactor); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logrus.Error(err, "mongo repository: two-factor authentication of the user doesn't exist")
+			return nil, apperror.NotFound("two-factor authentication of the user doesn't exist")
+		}
+		logrus.Error(err, "mongo repository: can't get two-factor authentication")
+		return nil, fmt.Errorf("mongo repository: can't get two-factor authentication - %w", err)
+	}
+
+	return &twoFactor, nil
+}
+
+// EnableTwoFactor method enables two-factor authentication of the user in mongo database.
+func (r AuthRepositoryMongo) EnableTwoFactor(ctx context.Context, userID string, recoveryCodes []string, step int64) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("mongo repository: enable two-factor authentication")
+	col := r.DB.Database("mongo_database").Collection("two_factors")
+
+	result, err := col.UpdateOne(ctx, bson.D{{Key: "_id", Value: userID}, {Key: "enabled", Value: false}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "enabled", Value: true},
+			{Key: "recoveryCodes", Value: recoveryCodes},
+			{Key: "lastStep", Value: step},
+		}}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't enable two-factor authentication")
+		return fmt.Errorf("mongo repository: can't enable two-factor authentication - %w", err)
+	}
+	if result.MatchedCount == 0 {
+		logrus.Error("mongo repository: not enabled two-factor authentication of the user doesn't exist")
+		return apperror.NotFound("not enabled two-factor authentication of the user doesn't exist")
+	}
+
+	return nil
+}
+
+// UseTwoFactorStep method marks the time step of a code of the user as used in mongo database.
+// The step is compared and stored by the same update, so concurrent requests can't use a code twice.
+func (r AuthRepositoryMongo) UseTwoFactorStep(ctx context.Context, userID string, step int64) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"step":   step,
+	}).Debugf("mongo repository: use two-factor code")
+	col := r.DB.Database("mongo_database").Collection("two_factors")
+
+	result, err := col.UpdateOne(ctx, bson.D{
+		{Key: "_id", Value: userID},
+		{Key: "enabled", Value: true},
+		{Key: "lastStep", Value: bson.D{{Key: "$lt", Value: step}}},
+	}, bson.D{{Key: "$set", Value: bson.D{{Key: "lastStep", Value: step}}}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't use two-factor code")
+		return fmt.Errorf("mongo repository: can't use two-factor code - %w", err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	count, err := col.CountDocuments(ctx, bson.D{{Key: "_id", Value: userID}, {Key: "enabled", Value: true}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't use two-factor code")
+		return fmt.Errorf("mongo repository: can't use two-factor code - %w", err)
+	}
+	if count == 0 {
+		logrus.Error("mongo repository: two-factor authentication of the user doesn't exist")
+		return apperror.NotFound("two-factor authentication of the user doesn't exist")
+	}
+
+	logrus.Error("mongo repository: two-factor code is already used")
+	return apperror.Conflict("two-factor code is already used")
+}
+
+// UseRecoveryCode method removes the recovery code of the user from mongo database.
+func (r AuthRepositoryMongo) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("mongo repository: use recovery code")
+	col := r.DB.Database("mongo_database").Collection("two_factors")
+
+	result, err := col.UpdateOne(ctx, bson.D{
+		{Key: "_id", Value: userID},
+		{Key: "enabled", Value: true},
+		{Key: "recoveryCodes", Value: codeHash},
+	}, bson.D{{Key: "$pull", Value: bson.D{{Key: "recoveryCodes", Value: codeHash}}}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't use recovery code")
+		return fmt.Errorf("mongo repository: can't use recovery code - %w", err)
+	}
+	if result.MatchedCount == 0 {
+		logrus.Error("mongo repository: recovery code of the user doesn't exist")
+		return apperror.NotFound("recovery code of the user doesn't exist")
+	}
+
+	return nil
+}
+
+// DeleteTwoFactor method deletes two-factor authentication of the user from mongo database.
+func (r AuthRepositoryMongo) DeleteTwoFactor(ctx context.Context, userID string) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("mongo repository: delete two-factor authentication")
+	col := r.DB.Database("mongo_database").Collection("two_factors")
+
+	if _, err := col.DeleteOne(ctx, bson.D{{Key: "_id", Value: userID}}); err != nil {
+		logrus.Error(err, "mongo repository: can't delete two-factor authentication")
+		return fmt.Errorf("mongo repository: can't delete two-factor authentication - %w", err)
+	}
+
+	return nil
+}
+
+// CreateAPIKey method saves API key of the user into mongo database.
+func (r AuthRepositoryMongo) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	logrus.WithFields(logrus.Fields{
+		"id":     key.ID,
+		"userID": key.UserID,
+		"name":   key.Name,
+		"scopes": key.Scopes,
+	}).Debugf("mongo repository: create API key")
+	col := r.DB.Database("mongo_database").Collection("api_keys")
+
+	if _, err := col.InsertOne(ctx, key); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			logrus.Error(err, "mongo repository: API key already exists")
+			return apperror.Conflict("API key already exists, try to create again")
+		}
+		logrus.Error(err, "mongo repository: can't create API key")
+		return fmt.Errorf("mongo repository: can't create API key - %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKey method returns not expired API key with the hash from mongo database.
+func (r AuthRepositoryMongo) GetAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	logrus.Debugf("mongo repository: get API key")
+	col := r.DB.Database("mongo_database").Collection("api_keys")
+
+	// null matches keys without expiry as well
+	var key model.APIKey
+	if err := col.FindOne(ctx, bson.D{
+		{Key: "keyHash", Value: keyHash},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "expiresAt", Value: nil}},
+			bson.D{{Key: "expiresAt", Value: bson.D{{Key: "$gt", Value: time.Now()}}}},
+		}},
+	}).Decode(&key); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logrus.Error(err, "mongo repository: API key doesn't exist")
+			return nil, apperror.NotFound("API key doesn't exist")
+		}
+		logrus.Error(err, "mongo repository: can't get API key")
+		return nil, fmt.Errorf("mongo repository: can't get API key - %w", err)
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys method returns API keys of the user from mongo database.
+func (r AuthRepositoryMongo) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("mongo repository: list API keys")
+	col := r.DB.Database("mongo_database").Collection("api_keys")
+
+	cur, err := col.Find(ctx, bson.D{{Key: "userId", Value: userID}},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't list API keys")
+		return nil, fmt.Errorf("mongo repository: can't list API keys - %w", err)
+	}
+
+	keys := make([]*model.APIKey, 0)
+	if err := cur.All(ctx, &keys); err != nil {
+		logrus.Error(err, "mongo repository: can't list API keys")
+		return nil, fmt.Errorf("mongo repository: can't list API keys - %w", err)
+	}
+
+	return keys, nil
 }
 
-func (r AuthRepositoryMongo) UpdateUserRefreshToken(ctx context.Context, refreshToken, id string) error {
+// DeleteAPIKey method deletes API key of the user from mongo database.
+func (r AuthRepositoryMongo) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":     id,
+		"userID": userID,
+	}).Debugf("mongo repository: delete API key")
+	col := r.DB.Database("mongo_database").Collection("api_keys")
+
+	result, err := col.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}, {Key: "userId", Value: userID}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't delete API key")
+		return fmt.Errorf("mongo repository: can't delete API key - %w", err)
+	}
+	if result.DeletedCount == 0 {
+		logrus.Error("mongo repository: API key with given UUID doesn't exist")
+		return apperror.NotFound("API key with given UUID doesn't exist")
+	}
+
 	return nil
 }
 
-func (r AuthRepositoryMongo) GetUserRefreshToken(ctx context.Context, id string) (string, error) {
-	return "", nil
+// TouchAPIKey method sets the time the API key was used at last in mongo database.
+func (r AuthRepositoryMongo) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Debugf("mongo repository: touch API key")
+	col := r.DB.Database("mongo_database").Collection("api_keys")
+
+	result, err := col.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "lastUsedAt", Value: usedAt}}}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't touch API key")
+		return fmt.Errorf("mongo repository: can't touch API key - %w", err)
+	}
+	if result.MatchedCount == 0 {
+		logrus.Error("mongo repository: API key with given UUID doesn't exist")
+		return apperror.NotFound("API key with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// CreateIdentity method links identity at OpenID Connect provider to the user in mongo database.
+func (r AuthRepositoryMongo) CreateIdentity(ctx context.Context, identity *model.Identity) error {
+	logrus.WithFields(logrus.Fields{
+		"issuer":  identity.Issuer,
+		"subject": identity.Subject,
+		"userID":  identity.UserID,
+	}).Debugf("mongo repository: create identity")
+	col := r.DB.Database("mongo_database").Collection("user_identities")
+
+	if _, err := col.InsertOne(ctx, identity); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			logrus.Error(err, "mongo repository: identity is linked already")
+			return apperror.Conflict("identity is linked to a user already")
+		}
+		logrus.Error(err, "mongo repository: can't create identity")
+		return fmt.Errorf("mongo repository: can't create identity - %w", err)
+	}
+
+	return nil
+}
+
+// GetIdentity method returns identity at OpenID Connect provider from mongo database.
+func (r AuthRepositoryMongo) GetIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error) {
+	logrus.WithFields(logrus.Fields{
+		"issuer":  issuer,
+		"subject": subject,
+	}).Debugf("mongo repository: get identity")
+	col := r.DB.Database("mongo_database").Collection("user_identities")
+
+	var identity model.Identity
+	if err := col.FindOne(ctx, bson.D{
+		{Key: "issuer", Value: issuer},
+		{Key: "subject", Value: subject},
+	}).Decode(&identity); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logrus.Error(err, "mongo repository: identity doesn't exist")
+			return nil, apperror.NotFound("identity doesn't exist")
+		}
+		logrus.Error(err, "mongo repository: can't get identity")
+		return nil, fmt.Errorf("mongo repository: can't get identity - %w", err)
+	}
+
+	return &identity, nil
+}
+
+// CreateOIDCLogin method saves started login with OpenID Connect provider into mongo database.
+func (r AuthRepositoryMongo) CreateOIDCLogin(ctx context.Context, login *model.OIDCLogin) error {
+	logrus.WithFields(logrus.Fields{
+		"expiresAt": login.ExpiresAt,
+	}).Debugf("mongo repository: create OIDC login")
+	col := r.DB.Database("mongo_database").Collection("oidc_logins")
+
+	if _, err := col.InsertOne(ctx, login); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			logrus.Error(err, "mongo repository: OIDC login with given state already exists")
+			return apperror.Conflict("OIDC login with given state already exists, try again")
+		}
+		logrus.Error(err, "mongo repository: can't create OIDC login")
+		return fmt.Errorf("mongo repository: can't create OIDC login - %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeOIDCLogin method deletes not expired login with the state hash from mongo database and returns it.
+func (r AuthRepositoryMongo) ConsumeOIDCLogin(ctx context.Context, stateHash string) (*model.OIDCLogin, error) {
+	logrus.Debugf("mongo repository: consume OIDC login")
+	col := r.DB.Database("mongo_database").Collection("oidc_logins")
+
+	var login model.OIDCLogin
+	if err := col.FindOneAndDelete(ctx, bson.D{
+		{Key: "_id", Value: stateHash},
+		{Key: "expiresAt", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}).Decode(&login); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logrus.Error(err, "mongo repository: OIDC login with given state doesn't exist")
+			return nil, apperror.NotFound("OIDC login with given state doesn't exist")
+		}
+		logrus.Error(err, "mongo repository: can't consume OIDC login")
+		return nil, fmt.Errorf("mongo repository: can't consume OIDC login - %w", err)
+	}
+
+	return &login, nil
 }
@@ -3,9 +3,15 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
 
 	"github.com/sirupsen/logrus"
 )
@@ -24,26 +30,25 @@ func NewAuthRepository(db *pgxpool.Pool) *AuthRepository {
 // CreateUser method create user in postgres database.
 func (r AuthRepository) CreateUser(ctx context.Context, input *CreateUserInput) error {
 	logrus.WithFields(logrus.Fields{
-		"ID":           input.ID,
-		"userName":     input.UserName,
-		"email":        input.Email,
-		"password":     input.Password,
-		"refreshToken": input.RefreshToken,
+		"ID":       input.ID,
+		"userName": input.UserName,
+		"email":    input.Email,
+		"password": input.Password,
+		"role":     input.Role,
 	}).Info("postgres repository: create User")
-	_, err := r.DB.Exec(ctx, `INSERT INTO USERS (id, name, email, password, refresh_token) VALUES($1, $2, $3, $4, $5)`,
-		input.ID, input.UserName, input.Email, input.Password, input.RefreshToken)
+	_, err := r.DB.Exec(ctx, `INSERT INTO USERS (id, name, email, password, role, email_verified, disabled)
+		VALUES($1, $2, $3, $4, $5, $6, $7)`,
+		input.ID, input.UserName, input.Email, input.Password, input.Role, input.EmailVerified, input.Disabled)
 	if err != nil {
+		var pgErr *pgconn.PgError
 		switch {
-		case strings.Contains(err.Error(), "duplicate key"):
-			switch {
-			case strings.Contains(err.Error(), "users_email_key"):
-				logrus.Error(err, "postgres repository: can't create User")
-				return errors.New("user with given email exists, change your email")
+		case errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode && pgErr.ConstraintName == "users_email_key":
+			logrus.Error(err, "postgres repository: can't create User")
+			return apperror.Conflict("user with given email exists, change your email")
 
-			case strings.Contains(err.Error(), "users_primary_key"):
-				logrus.Error(err, "postgres repository: can't create User")
-				return errors.New("user with given UUID exists, try to create again")
-			}
+		case errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode:
+			logrus.Error(err, "postgres repository: can't create User")
+			return apperror.Conflict("user with given UUID exists, try to create again")
 
 		default:
 			logrus.Error(err, "postgres repository: can't create User")
@@ -63,9 +68,9 @@ func (r AuthRepository) GetUserHashedPassword(ctx context.Context, email string)
 	if err := r.DB.QueryRow(ctx, `SELECT id, password FROM users WHERE email = $1`,
 		email).Scan(&id, &password); err != nil {
 		switch {
-		case strings.Contains(err.Error(), "no rows in result set"):
+		case errors.Is(err, pgx.ErrNoRows):
 			logrus.Error(err, "postgres repository: user with given email doesn't exist")
-			return "", "", errors.New("user with given email doesn't exist")
+			return "", "", apperror.NotFound("user with given email doesn't exist")
 
 		default:
 			logrus.Error(err, "postgres repository: can't get users hashed password")
@@ -76,46 +81,651 @@ func (r AuthRepository) GetUserHashedPassword(ctx context.Context, email string)
 	return id, password, nil
 }
 
-// GetUserRefreshToken method returns users stored refresh token.
-func (r AuthRepository) GetUserRefreshToken(ctx context.Context, id string) (string, error) {
+// userColumns are the columns of table users scanned into object User.
+const userColumns = "id, name, email, role, email_verified, disabled"
+
+// GetUser method returns user without credentials from postgres database.
+func (r AuthRepository) GetUser(ctx context.Context, id string) (*model.User, error) {
 	logrus.WithFields(logrus.Fields{
 		"id": id,
-	}).Info("postgres repository: get user refresh token by his id")
-	var refreshTokenString string
-	if err := r.DB.QueryRow(ctx, `SELECT refresh_token FROM users WHERE id = $1`,
-		id).Scan(&refreshTokenString); err != nil {
+	}).Info("postgres repository: get user")
+	var user model.User
+	if err := r.DB.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`,
+		id).Scan(&user.ID, &user.UserName, &user.Email, &user.Role, &user.EmailVerified, &user.Disabled); err != nil {
 		switch {
-		case strings.Contains(err.Error(), "no rows in result set"):
+		case errors.Is(err, pgx.ErrNoRows):
 			logrus.Error(err, "postgres repository: user with given UUID doesn't exist")
-			return "", errors.New("user with given UUID doesn't exist")
+			return nil, apperror.NotFound("user with given UUID doesn't exist")
+
+		default:
+			logrus.Error(err, "postgres repository: can't get user")
+			return nil, errors.New("can't get user")
+		}
+	}
+
+	return &user, nil
+}
+
+// UpdateUserRole method updates role of the user.
+func (r AuthRepository) UpdateUserRole(ctx context.Context, id, role string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":   id,
+		"role": role,
+	}).Info("postgres repository: update user role")
+	result, err := r.DB.Exec(ctx, `UPDATE users SET role = $1 WHERE id = $2`, role, id)
+	if err != nil {
+		logrus.Error(err, "postgres repository: can't update user role")
+		return errors.New("can't update user role")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: user with given UUID doesn't exist")
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// UpdateUserName method updates name of the user.
+func (r AuthRepository) UpdateUserName(ctx context.Context, id, userName string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":       id,
+		"userName": userName,
+	}).Info("postgres repository: update user name")
+	result, err := r.DB.Exec(ctx, `UPDATE users SET name = $1 WHERE id = $2`, userName, id)
+	if err != nil {
+		logrus.Error("postgres repository: can't update user name - ", err)
+		return errors.New("can't update user name")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: user with given UUID doesn't exist")
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// SetUserDisabled method disables or enables the user.
+func (r AuthRepository) SetUserDisabled(ctx context.Context, id string, disabled bool) error {
+	logrus.WithFields(logrus.Fields{
+		"id":       id,
+		"disabled": disabled,
+	}).Info("postgres repository: set user disabled")
+	result, err := r.DB.Exec(ctx, `UPDATE users SET disabled = $1 WHERE id = $2`, disabled, id)
+	if err != nil {
+		logrus.Error("postgres repository: can't set user disabled - ", err)
+		return errors.New("can't set user disabled")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: user with given UUID doesn't exist")
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// ListUsers method returns page of users matching the input from postgres database.
+func (r AuthRepository) ListUsers(ctx context.Context, input *model.ListUsers) (*model.UsersPage, error) {
+	logrus.WithFields(logrus.Fields{
+		"Limit":    input.Limit,
+		"Cursor":   input.Cursor,
+		"Query":    input.Query,
+		"Role":     input.Role,
+		"Disabled": input.Disabled,
+	}).Info("postgres repository: list users")
+
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	argID := 1
+
+	if input.Query != "" {
+		conditions = append(conditions, fmt.Sprintf("(email ILIKE $%d OR name ILIKE $%d)", argID, argID))
+		args = append(args, "%"+escapeLike(input.Query)+"%")
+		argID++
+	}
+
+	if input.Role != "" {
+		conditions = append(conditions, fmt.Sprintf("role = $%d", argID))
+		args = append(args, input.Role)
+		argID++
+	}
+
+	if input.Disabled != nil {
+		conditions = append(conditions, fmt.Sprintf("disabled = $%d", argID))
+		args = append(args, *input.Disabled)
+		argID++
+	}
+
+	if input.Cursor != "" {
+		cursor, err := decodeUsersCursor(input.Cursor)
+		if err != nil {
+			logrus.Error("postgres repository: invalid cursor - ", err)
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf(`email COLLATE "C" > $%d`, argID))
+		args = append(args, cursor.Email)
+		argID++
+	}
+
+	whereQuery := ""
+	if len(conditions) > 0 {
+		whereQuery = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	// emails are compared bytewise to keep the same order as in mongo database
+	listUsersQuery := fmt.Sprintf(`SELECT %s FROM users %s ORDER BY email COLLATE "C" LIMIT $%d`,
+		userColumns, whereQuery, argID)
+	args = append(args, input.Limit+1)
+
+	rows, err := r.DB.Query(ctx, listUsersQuery, args...)
+	if err != nil {
+		logrus.Error("postgres repository: can't list users - ", err)
+		return nil, errors.New("can't list users")
+	}
+	defer rows.Close()
+
+	users := make([]*model.User, 0, input.Limit+1)
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(&user.ID, &user.UserName, &user.Email, &user.Role, &user.EmailVerified, &user.Disabled); err != nil {
+			logrus.Error("postgres repository: can't scan user - ", err)
+			return nil, errors.New("can't list users")
+		}
+		users = append(users, &user)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.Error("postgres repository: can't list users - ", err)
+		return nil, errors.New("can't list users")
+	}
+
+	return newUsersPage(users, input.Limit), nil
+}
+
+// DeleteUser method deletes the user from postgres database, credentials of the user
+// are deleted by foreign keys.
+func (r AuthRepository) DeleteUser(ctx context.Context, id string) error {
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Info("postgres repository: delete user")
+	result, err := r.DB.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		logrus.Error("postgres repository: can't delete user - ", err)
+		return errors.New("can't delete user")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: user with given UUID doesn't exist")
+		return apperror.NotFound("user with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// CreatePasswordReset method saves reset token of the user into postgres database.
+func (r AuthRepository) CreatePasswordReset(ctx context.Context, reset *model.PasswordReset) error {
+	logrus.WithFields(logrus.Fields{
+		"userID":    reset.UserID,
+		"expiresAt": reset.ExpiresAt,
+	}).Info("postgres repository: create password reset")
+	_, err := r.DB.Exec(ctx, `INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`,
+		reset.TokenHash, reset.UserID, reset.ExpiresAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			logrus.Error("postgres repository: password reset with given token already exists - ", err)
+			return apperror.Conflict("password reset with given token already exists, try again")
+
+		default:
+			logrus.Error("postgres repository: can't create password reset - ", err)
+			return errors.New("can't create password reset")
+		}
+	}
+
+	return nil
+}
+
+// ResetPassword method replaces password of the user with the reset token in postgres database.
+// Reset tokens are deleted by the same statement, so concurrent requests can't use the token twice.
+func (r AuthRepository) ResetPassword(ctx context.Context, tokenHash, password string) (string, error) {
+	logrus.Info("postgres repository: reset password")
+	var id string
+	if err := r.DB.QueryRow(ctx, `WITH reset AS (
+			DELETE FROM password_resets WHERE user_id = (
+				SELECT user_id FROM password_resets WHERE token_hash = $1 AND expires_at > now())
+			RETURNING user_id, token_hash)
+		UPDATE users SET password = $2 FROM reset
+		WHERE users.id = reset.user_id AND reset.token_hash = $1
+		RETURNING users.id`, tokenHash, password).Scan(&id); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logrus.Error("postgres repository: password reset with given token doesn't exist - ", err)
+			return "", apperror.NotFound("password reset with given token doesn't exist")
 
 		default:
-			logrus.Error(err, "postgres repository: can't get users refresh token")
-			return "", errors.New("can't get users refresh token")
+			logrus.Error("postgres repository: can't reset password - ", err)
+			return "", errors.New("can't reset password")
 		}
 	}
 
-	return refreshTokenString, nil
+	return id, nil
 }
 
-// UpdateUserRefreshToken method updates user refresh token.
-func (r AuthRepository) UpdateUserRefreshToken(ctx context.Context, id, refreshToken string) error {
+// UpdatePassword method replaces hashed password of the user in postgres database.
+func (r AuthRepository) UpdatePassword(ctx context.Context, id, oldHash, newHash string) error {
 	logrus.WithFields(logrus.Fields{
-		"id":           id,
-		"refreshToken": refreshToken,
-	}).Info("postgres repository: update refresh token")
-	if _, err := r.DB.Exec(ctx, `UPDATE users SET refresh_token = $1 WHERE id = $2`,
-		refreshToken, id); err != nil {
+		"id": id,
+	}).Info("postgres repository: update password")
+	result, err := r.DB.Exec(ctx, `UPDATE users SET password = $3 WHERE id = $1 AND password = $2`,
+		id, oldHash, newHash)
+	if err != nil {
+		logrus.Error("postgres repository: can't update password - ", err)
+		return errors.New("can't update password")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: user with given UUID and password doesn't exist")
+		return apperror.NotFound("user with given UUID and password doesn't exist")
+	}
+
+	return nil
+}
+
+// CreateEmailVerification method saves verification token of the user into postgres database.
+func (r AuthRepository) CreateEmailVerification(ctx context.Context, verification *model.EmailVerification) error {
+	logrus.WithFields(logrus.Fields{
+		"userID":    verification.UserID,
+		"expiresAt": verification.ExpiresAt,
+	}).Info("postgres repository: create email verification")
+	_, err := r.DB.Exec(ctx, `INSERT INTO email_verifications (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`,
+		verification.TokenHash, verification.UserID, verification.ExpiresAt)
+	if err != nil {
 		switch {
-		case strings.Contains(err.Error(), "no rows in result set"):
-			logrus.Error(err, "postgres repository: user with given UUID doesn't exist")
-			return errors.New("user with given UUID doesn't exist")
+		case isUniqueViolation(err):
+			logrus.Error("postgres repository: email verification with given token already exists - ", err)
+			return apperror.Conflict("email verification with given token already exists, try again")
+
+		default:
+			logrus.Error("postgres repository: can't create email verification - ", err)
+			return errors.New("can't create email verification")
+		}
+	}
+
+	return nil
+}
+
+// VerifyEmail method marks email of the user with the verification token as verified in postgres database.
+// Verification tokens are deleted by the same statement, so concurrent requests can't use the token twice.
+func (r AuthRepository) VerifyEmail(ctx context.Context, tokenHash string) (string, error) {
+	logrus.Info("postgres repository: verify email")
+	var id string
+	if err := r.DB.QueryRow(ctx, `WITH verification AS (
+			DELETE FROM email_verifications WHERE user_id = (
+				SELECT user_id FROM email_verifications WHERE token_hash = $1 AND expires_at > now())
+			RETURNING user_id, token_hash)
+		UPDATE users SET email_verified = true FROM verification
+		WHERE users.id = verification.user_id AND verification.token_hash = $1
+		RETURNING users.id`, tokenHash).Scan(&id); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logrus.Error("postgres repository: email verification with given token doesn't exist - ", err)
+			return "", apperror.NotFound("email verification with given token doesn't exist")
 
 		default:
-			logrus.Error(err, "postgres repository: can't update refresh token")
-			return errors.New("can't update refresh token")
+			logrus.Error("postgres repository: can't verify email - ", err)
+			return "", errors.New("can't verify email")
 		}
 	}
 
+	return id, nil
+}
+
+// SaveTwoFactor method saves not enabled two-factor authentication of the user into postgres database.
+func (r AuthRepository) SaveTwoFactor(ctx context.Context, twoFactor *model.TwoFactor) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": twoFactor.UserID,
+	}).Info("postgres repository: save two-factor authentication")
+	recoveryCodes := twoFactor.RecoveryCodes
+	if recoveryCodes == nil {
+		recoveryCodes = []string{}
+	}
+	result, err := r.DB.Exec(ctx, `INSERT INTO two_factors (user_id, secret, enabled, recovery_codes, last_step, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, enabled = excluded.enabled,
+			recovery_codes = excluded.recovery_codes, last_step = excluded.last_step, created_at = excluded.created_at
+		WHERE NOT two_factors.enabled`,
+		twoFactor.UserID, twoFactor.Secret, twoFactor.Enabled, recoveryCodes, twoFactor.LastStep, twoFactor.CreatedAt)
+	if err != nil {
+		logrus.Error("postgres repository: can't save two-factor authentication - ", err)
+		return errors.New("can't save two-factor authentication")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: two-factor authentication of the user is already enabled")
+		return apperror.Conflict("two-factor authentication of the user is already enabled")
+	}
+
 	return nil
 }
+
+// GetTwoFactor method returns two-factor authentication of the user from postgres database.
+func (r AuthRepository) GetTwoFactor(ctx context.Context, userID string) (*model.TwoFactor, error) {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("postgres repository: get two-factor authentication")
+	var twoFactor model.TwoFactor
+	if err := r.DB.QueryRow(ctx, `SELECT user_id, secret, enabled, recovery_codes, last_step, created_at
+		FROM two_factors WHERE user_id = $1`, userID).Scan(&twoFactor.UserID, &twoFactor.Secret,
+		&twoFactor.Enabled, &twoFactor.RecoveryCodes, &twoFactor.LastStep, &twoFactor.CreatedAt); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logrus.Error("postgres repository: two-factor authentication of the user doesn't exist - ", err)
+			return nil, apperror.NotFound("two-factor authentication of the user doesn't exist")
+
+		default:
+			logrus.Error("postgres repository: can't get two-factor authentication - ", err)
+			return nil, errors.New("can't get two-factor authentication")
+		}
+	}
+
+	return &twoFactor, nil
+}
+
+// EnableTwoFactor method enables two-factor authentication of the user in postgres database.
+func (r AuthRepository) EnableTwoFactor(ctx context.Context, userID string, recoveryCodes []string, step int64) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("postgres repository: enable two-factor authentication")
+	result, err := r.DB.Exec(ctx, `UPDATE two_factors SET enabled = true, recovery_codes = $2, last_step = $3
+		WHERE user_id = $1 AND NOT enabled`, userID, recoveryCodes, step)
+	if err != nil {
+		logrus.Error("postgres repository: can't enable two-factor authentication - ", err)
+		return errors.New("can't enable two-factor authentication")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: not enabled two-factor authentication of the user doesn't exist")
+		return apperror.NotFound("not enabled two-factor authentication of the user doesn't exist")
+	}
+
+	return nil
+}
+
+// UseTwoFactorStep method marks the time step of a code of the user as used in postgres database.
+// The step is compared and stored by the same statement, so concurrent requests can't use a code twice.
+func (r AuthRepository) UseTwoFactorStep(ctx context.Context, userID string, step int64) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+		"step":   step,
+	}).Info("postgres repository: use two-factor code")
+	result, err := r.DB.Exec(ctx, `UPDATE two_factors SET last_step = $2
+		WHERE user_id = $1 AND enabled AND last_step < $2`, userID, step)
+	if err != nil {
+		logrus.Error("postgres repository: can't use two-factor code - ", err)
+		return errors.New("can't use two-factor code")
+	}
+	if result.RowsAffected() > 0 {
+		return nil
+	}
+
+	var enabled bool
+	if err := r.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM two_factors WHERE user_id = $1 AND enabled)`,
+		userID).Scan(&enabled); err != nil {
+		logrus.Error("postgres repository: can't use two-factor code - ", err)
+		return errors.New("can't use two-factor code")
+	}
+	if !enabled {
+		logrus.Error("postgres repository: two-factor authentication of the user doesn't exist")
+		return apperror.NotFound("two-factor authentication of the user doesn't exist")
+	}
+
+	logrus.Error("postgres repository: two-factor code is already used")
+	return apperror.Conflict("two-factor code is already used")
+}
+
+// UseRecoveryCode method removes the recovery code of the user from postgres database.
+func (r AuthRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("postgres repository: use recovery code")
+	result, err := r.DB.Exec(ctx, `UPDATE two_factors SET recovery_codes = array_remove(recovery_codes, $2)
+		WHERE user_id = $1 AND enabled AND $2 = ANY (recovery_codes)`, userID, codeHash)
+	if err != nil {
+		logrus.Error("postgres repository: can't use recovery code - ", err)
+		return errors.New("can't use recovery code")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: recovery code of the user doesn't exist")
+		return apperror.NotFound("recovery code of the user doesn't exist")
+	}
+
+	return nil
+}
+
+// DeleteTwoFactor method deletes two-factor authentication of the user from postgres database.
+func (r AuthRepository) DeleteTwoFactor(ctx context.Context, userID string) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("postgres repository: delete two-factor authentication")
+	if _, err := r.DB.Exec(ctx, `DELETE FROM two_factors WHERE user_id = $1`, userID); err != nil {
+		logrus.Error("postgres repository: can't delete two-factor authentication - ", err)
+		return errors.New("can't delete two-factor authentication")
+	}
+
+	return nil
+}
+
+// apiKeyColumns are columns of table api_keys in the order scanAPIKey reads them.
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at"
+
+// CreateAPIKey method saves API key of the user into postgres database.
+func (r AuthRepository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	logrus.WithFields(logrus.Fields{
+		"id":     key.ID,
+		"userID": key.UserID,
+		"name":   key.Name,
+		"scopes": key.Scopes,
+	}).Info("postgres repository: create API key")
+	_, err := r.DB.Exec(ctx, "INSERT INTO api_keys ("+apiKeyColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.CreatedAt, key.ExpiresAt, key.LastUsedAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			logrus.Error("postgres repository: API key already exists - ", err)
+			return apperror.Conflict("API key already exists, try to create again")
+
+		default:
+			logrus.Error("postgres repository: can't create API key - ", err)
+			return errors.New("can't create API key")
+		}
+	}
+
+	return nil
+}
+
+// GetAPIKey method returns not expired API key with the hash from postgres database.
+func (r AuthRepository) GetAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	logrus.Info("postgres repository: get API key")
+	key, err := scanAPIKey(r.DB.QueryRow(ctx, "SELECT "+apiKeyColumns+` FROM api_keys
+		WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > now())`, keyHash))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logrus.Error("postgres repository: API key doesn't exist - ", err)
+			return nil, apperror.NotFound("API key doesn't exist")
+
+		default:
+			logrus.Error("postgres repository: can't get API key - ", err)
+			return nil, errors.New("can't get API key")
+		}
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys method returns API keys of the user from postgres database.
+func (r AuthRepository) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("postgres repository: list API keys")
+	rows, err := r.DB.Query(ctx, "SELECT "+apiKeyColumns+` FROM api_keys
+		WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		logrus.Error("postgres repository: can't list API keys - ", err)
+		return nil, errors.New("can't list API keys")
+	}
+	defer rows.Close()
+
+	keys := make([]*model.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			logrus.Error("postgres repository: can't list API keys - ", err)
+			return nil, errors.New("can't list API keys")
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.Error("postgres repository: can't list API keys - ", err)
+		return nil, errors.New("can't list API keys")
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey method deletes API key of the user from postgres database.
+func (r AuthRepository) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":     id,
+		"userID": userID,
+	}).Info("postgres repository: delete API key")
+	result, err := r.DB.Exec(ctx, "DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		logrus.Error("postgres repository: can't delete API key - ", err)
+		return errors.New("can't delete API key")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: API key with given UUID doesn't exist")
+		return apperror.NotFound("API key with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// TouchAPIKey method sets the time the API key was used at last in postgres database.
+func (r AuthRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	logrus.WithFields(logrus.Fields{
+		"id": id,
+	}).Info("postgres repository: touch API key")
+	result, err := r.DB.Exec(ctx, "UPDATE api_keys SET last_used_at = $1 WHERE id = $2", usedAt, id)
+	if err != nil {
+		logrus.Error("postgres repository: can't touch API key - ", err)
+		return errors.New("can't touch API key")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: API key with given UUID doesn't exist")
+		return apperror.NotFound("API key with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// CreateIdentity method links identity at OpenID Connect provider to the user in postgres database.
+func (r AuthRepository) CreateIdentity(ctx context.Context, identity *model.Identity) error {
+	logrus.WithFields(logrus.Fields{
+		"issuer":  identity.Issuer,
+		"subject": identity.Subject,
+		"userID":  identity.UserID,
+	}).Info("postgres repository: create identity")
+	_, err := r.DB.Exec(ctx, `INSERT INTO user_identities (issuer, subject, user_id, email, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		identity.Issuer, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			logrus.Error("postgres repository: identity is linked already - ", err)
+			return apperror.Conflict("identity is linked to a user already")
+
+		default:
+			logrus.Error("postgres repository: can't create identity - ", err)
+			return errors.New("can't create identity")
+		}
+	}
+
+	return nil
+}
+
+// GetIdentity method returns identity at OpenID Connect provider from postgres database.
+func (r AuthRepository) GetIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error) {
+	logrus.WithFields(logrus.Fields{
+		"issuer":  issuer,
+		"subject": subject,
+	}).Info("postgres repository: get identity")
+	var identity model.Identity
+	if err := r.DB.QueryRow(ctx, `SELECT issuer, subject, user_id, email, created_at FROM user_identities
+		WHERE issuer = $1 AND subject = $2`, issuer, subject).Scan(&identity.Issuer, &identity.Subject,
+		&identity.UserID, &identity.Email, &identity.CreatedAt); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logrus.Error("postgres repository: identity doesn't exist - ", err)
+			return nil, apperror.NotFound("identity doesn't exist")
+
+		default:
+			logrus.Error("postgres repository: can't get identity - ", err)
+			return nil, errors.New("can't get identity")
+		}
+	}
+
+	return &identity, nil
+}
+
+// CreateOIDCLogin method saves started login with OpenID Connect provider into postgres database.
+func (r AuthRepository) CreateOIDCLogin(ctx context.Context, login *model.OIDCLogin) error {
+	logrus.WithFields(logrus.Fields{
+		"expiresAt": login.ExpiresAt,
+	}).Info("postgres repository: create OIDC login")
+	_, err := r.DB.Exec(ctx, `INSERT INTO oidc_logins (state_hash, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, $4)`, login.StateHash, login.CodeVerifier, login.Nonce, login.ExpiresAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			logrus.Error("postgres repository: OIDC login with given state already exists - ", err)
+			return apperror.Conflict("OIDC login with given state already exists, try again")
+
+		default:
+			logrus.Error("postgres repository: can't create OIDC login - ", err)
+			return errors.New("can't create OIDC login")
+		}
+	}
+
+	return nil
+}
+
+// ConsumeOIDCLogin method deletes not expired login with the state hash from postgres database and returns it.
+func (r AuthRepository) ConsumeOIDCLogin(ctx context.Context, stateHash string) (*model.OIDCLogin, error) {
+	logrus.Info("postgres repository: consume OIDC login")
+	var login model.OIDCLogin
+	if err := r.DB.QueryRow(ctx, `DELETE FROM oidc_logins WHERE state_hash = $1
+		RETURNING state_hash, code_verifier, nonce, expires_at`, stateHash).Scan(&login.StateHash,
+		&login.CodeVerifier, &login.Nonce, &login.ExpiresAt); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logrus.Error("postgres repository: OIDC login with given state doesn't exist - ", err)
+			return nil, apperror.NotFound("OIDC login with given state doesn't exist")
+
+		default:
+			logrus.Error("postgres repository: can't consume OIDC login - ", err)
+			return nil, errors.New("can't consume OIDC login")
+		}
+	}
+	// expired login is deleted too, it can't be used anyway
+	if !login.ExpiresAt.After(time.Now()) {
+		logrus.Error("postgres repository: OIDC login with given state is expired")
+		return nil, apperror.NotFound("OIDC login with given state doesn't exist")
+	}
+
+	return &login, nil
+}
+
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &key.Scopes,
+		&key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
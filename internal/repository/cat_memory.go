@@ -0,0 +1,455 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// CatRepositoryMemory type represents in-memory object cat structure and behavior.
+type CatRepositoryMemory struct {
+	cats    map[string]model.Cat
+	changes []model.CatChange
+	mutex   sync.RWMutex
+}
+
+func NewCatRepositoryMemory() *CatRepositoryMemory {
+	return &CatRepositoryMemory{
+		cats: make(map[string]model.Cat),
+	}
+}
+
+// Create method saves object Cat in memory.
+func (r *CatRepositoryMemory) Create(ctx context.Context, input *model.Cat) error {
+	logrus.WithFields(logrus.Fields{
+		"id":         input.ID,
+		"Name":       input.Name,
+		"DateBirth":  input.DateBirth,
+		"Vaccinated": input.Vaccinated,
+		"OwnerID":    input.OwnerID,
+	}).Debugf("memory repository: create cat")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ex := r.cats[input.ID]; ex {
+		return apperror.Conflict("cat with given UUID already exists, try to create again")
+	}
+	cat := *input
+	cat.ImagePath = ""
+	cat.DeletedAt = nil
+	cat.Version = 1
+	r.cats[input.ID] = cat
+
+	return nil
+}
+
+// Get method returns object Cat from memory with selection by id.
+func (r *CatRepositoryMemory) Get(ctx context.Context, ownerID, id string) (*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"ID":      id,
+		"OwnerID": ownerID,
+	}).Debugf("memory repository: get cat")
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	cat, ex := r.find(ownerID, id)
+	if !ex {
+		return nil, apperror.NotFound("cat with given UUID doesn't exist")
+	}
+
+	return &cat, nil
+}
+
+// Update method updates object Cat in memory with selection by id and returns object Cat.
+func (r *CatRepositoryMemory) Update(ctx context.Context, ownerID, id string, version int64,
+	input *model.UpdateCat) (*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"Name":       input.Name,
+		"DateBirth":  input.DateBirth,
+		"Vaccinated": input.Vaccinated,
+		"OwnerID":    ownerID,
+		"Version":    version,
+	}).Debugf("memory repository: update cat")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cat, ex := r.find(ownerID, id)
+	if !ex {
+		return nil, apperror.NotFound("cat with given UUID doesn't exists")
+	}
+	if err := checkVersion(&cat, version); err != nil {
+		return nil, err
+	}
+	if input.Name == nil && input.DateBirth == nil && input.Vaccinated == nil {
+		return &cat, nil
+	}
+	if input.Name != nil {
+		cat.Name = *input.Name
+	}
+	if input.DateBirth != nil {
+		cat.DateBirth = *input.DateBirth
+	}
+	if input.Vaccinated != nil {
+		cat.Vaccinated = *input.Vaccinated
+	}
+	cat.Version++
+	r.cats[id] = cat
+
+	return &cat, nil
+}
+
+// Delete method moves object Cat in memory to trash with selection by id.
+func (r *CatRepositoryMemory) Delete(ctx context.Context, ownerID, id string, version int64, deletedAt time.Time) error {
+	logrus.WithFields(logrus.Fields{
+		"ID":        id,
+		"OwnerID":   ownerID,
+		"Version":   version,
+		"DeletedAt": deletedAt,
+	}).Debugf("memory repository: delete cat")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cat, ex := r.find(ownerID, id)
+	if !ex {
+		return apperror.NotFound("cat with given UUID doesn't exist")
+	}
+	if err := checkVersion(&cat, version); err != nil {
+		return err
+	}
+	cat.DeletedAt = &deletedAt
+	cat.Version++
+	r.cats[id] = cat
+
+	return nil
+}
+
+// GetTrashed method returns object Cat in trash from memory with selection by id.
+func (r *CatRepositoryMemory) GetTrashed(ctx context.Context, ownerID, id string) (*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"ID":      id,
+		"OwnerID": ownerID,
+	}).Debugf("memory repository: get trashed cat")
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	cat, ex := r.findTrashed(ownerID, id)
+	if !ex {
+		return nil, apperror.NotFound("cat with given UUID isn't in trash")
+	}
+
+	return &cat, nil
+}
+
+// Restore method moves object Cat in memory out of trash with selection by id and returns object Cat.
+func (r *CatRepositoryMemory) Restore(ctx context.Context, ownerID, id string) (*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"ID":      id,
+		"OwnerID": ownerID,
+	}).Debugf("memory repository: restore cat")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cat, ex := r.findTrashed(ownerID, id)
+	if !ex {
+		return nil, apperror.NotFound("cat with given UUID isn't in trash")
+	}
+	cat.DeletedAt = nil
+	cat.Version++
+	r.cats[id] = cat
+
+	return &cat, nil
+}
+
+// PurgeCats method deletes objects Cat moved to trash before deletedBefore from memory and returns them.
+func (r *CatRepositoryMemory) PurgeCats(ctx context.Context, deletedBefore time.Time) ([]*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"DeletedBefore": deletedBefore,
+	}).Debugf("memory repository: purge cats")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cats := make([]*model.Cat, 0)
+	for id := range r.cats {
+		cat := r.cats[id]
+		if cat.DeletedAt != nil && cat.DeletedAt.Before(deletedBefore) {
+			cats = append(cats, &cat)
+			delete(r.cats, id)
+		}
+	}
+
+	return cats, nil
+}
+
+// DisownCats method leaves cats of the owner in memory without owner and returns UUIDs of the cats.
+func (r *CatRepositoryMemory) DisownCats(ctx context.Context, ownerID string) ([]string, error) {
+	logrus.WithFields(logrus.Fields{
+		"OwnerID": ownerID,
+	}).Debugf("memory repository: disown cats")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ids := make([]string, 0)
+	for id, cat := range r.cats {
+		if cat.OwnerID == ownerID {
+			cat.OwnerID = ""
+			r.cats[id] = cat
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// UploadImage method updates image path object Cat in memory with selection by id and returns object Cat.
+func (r *CatRepositoryMemory) UploadImage(ctx context.Context, ownerID, id string, version int64,
+	path string) (*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"ID":      id,
+		"OwnerID": ownerID,
+		"Version": version,
+	}).Debugf("memory repository: update cats image path")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cat, ex := r.find(ownerID, id)
+	if !ex {
+		return nil, apperror.NotFound("cat with given UUID doesn't exist")
+	}
+	if err := checkVersion(&cat, version); err != nil {
+		return nil, err
+	}
+	cat.ImagePath = path
+	cat.Version++
+	r.cats[id] = cat
+
+	return &cat, nil
+}
+
+// List method returns page of objects Cat from memory filtered and sorted according to the input.
+func (r *CatRepositoryMemory) List(ctx context.Context, input *model.ListCats) (*model.CatsPage, error) {
+	logrus.WithFields(logrus.Fields{
+		"OwnerID":    input.OwnerID,
+		"Limit":      input.Limit,
+		"Cursor":     input.Cursor,
+		"Vaccinated": input.Vaccinated,
+		"NamePrefix": input.NamePrefix,
+		"SortBy":     input.SortBy,
+		"SortDesc":   input.SortDesc,
+		"Deleted":    input.Deleted,
+	}).Debugf("memory repository: list cats")
+
+	sortBy := catsSortBy(input)
+	var cursor *catsCursor
+	if input.Cursor != "" {
+		var err error
+		if cursor, err = decodeCatsCursor(input.Cursor, sortBy); err != nil {
+			return nil, err
+		}
+	}
+
+	// compare returns negative number if cat a goes before cat b in ascending order
+	compare := func(a, b *model.Cat) int {
+		switch {
+		case sortBy == model.CatsSortByDateBirth && !a.DateBirth.Equal(b.DateBirth):
+			if a.DateBirth.Before(b.DateBirth) {
+				return -1
+			}
+			return 1
+		case sortBy == model.CatsSortByName && a.Name != b.Name:
+			return strings.Compare(a.Name, b.Name)
+		default:
+			return strings.Compare(a.ID, b.ID)
+		}
+	}
+	if input.SortDesc {
+		ascending := compare
+		compare = func(a, b *model.Cat) int {
+			return -ascending(a, b)
+		}
+	}
+
+	r.mutex.RLock()
+	cats := make([]*model.Cat, 0)
+	for id := range r.cats {
+		cat := r.cats[id]
+		if matchCat(&cat, input) &&
+			(cursor == nil || compare(&cat, &model.Cat{ID: cursor.ID, Name: cursor.Name, DateBirth: cursor.DateBirth}) > 0) {
+			cats = append(cats, &cat)
+		}
+	}
+	r.mutex.RUnlock()
+
+	sort.Slice(cats, func(i, j int) bool {
+		return compare(cats[i], cats[j]) < 0
+	})
+	if len(cats) > input.Limit+1 {
+		cats = cats[:input.Limit+1]
+	}
+
+	return newCatsPage(cats, input.Limit, sortBy), nil
+}
+
+// AddCatChange method appends the change of a cat to history in memory.
+func (r *CatRepositoryMemory) AddCatChange(ctx context.Context, change *model.CatChange) error {
+	logrus.WithFields(logrus.Fields{
+		"ID":        change.ID,
+		"CatID":     change.CatID,
+		"ActorID":   change.ActorID,
+		"Operation": change.Operation,
+		"Fields":    change.Fields,
+	}).Debugf("memory repository: add cat change")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.changes = append(r.changes, copyCatChange(change))
+
+	return nil
+}
+
+// ListCatChanges method returns page of changes of the cat from memory.
+func (r *CatRepositoryMemory) ListCatChanges(ctx context.Context, input *model.ListCatChanges) (*model.CatChangesPage, error) {
+	logrus.WithFields(logrus.Fields{
+		"CatID":   input.CatID,
+		"OwnerID": input.OwnerID,
+		"Limit":   input.Limit,
+		"Cursor":  input.Cursor,
+	}).Debugf("memory repository: list cat changes")
+
+	var cursor *catChangesCursor
+	if input.Cursor != "" {
+		var err error
+		if cursor, err = decodeCatChangesCursor(input.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// later reports whether change a goes before change b, the latest changes go first
+	later := func(a, b *model.CatChange) bool {
+		if !a.ChangedAt.Equal(b.ChangedAt) {
+			return a.ChangedAt.After(b.ChangedAt)
+		}
+		return a.ID > b.ID
+	}
+
+	r.mutex.RLock()
+	changes := make([]*model.CatChange, 0)
+	for i := range r.changes {
+		change := &r.changes[i]
+		if change.CatID != input.CatID || (input.OwnerID != "" && change.OwnerID != input.OwnerID) ||
+			(cursor != nil && !later(&model.CatChange{ChangedAt: cursor.ChangedAt, ID: cursor.ID}, change)) {
+			continue
+		}
+		copied := copyCatChange(change)
+		changes = append(changes, &copied)
+	}
+	r.mutex.RUnlock()
+
+	sort.Slice(changes, func(i, j int) bool {
+		return later(changes[i], changes[j])
+	})
+	if len(changes) > input.Limit+1 {
+		changes = changes[:input.Limit+1]
+	}
+
+	return newCatChangesPage(changes, input.Limit), nil
+}
+
+// AnonymizeCatChanges method removes the user from changes of cats in memory.
+func (r *CatRepositoryMemory) AnonymizeCatChanges(ctx context.Context, userID string) error {
+	logrus.WithFields(logrus.Fields{
+		"UserID": userID,
+	}).Debugf("memory repository: anonymize cat changes")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.changes {
+		change := &r.changes[i]
+		if change.ActorID == userID {
+			change.ActorID = ""
+		}
+		if change.OwnerID == userID {
+			change.OwnerID = ""
+		}
+		if change.Before != nil && change.Before.OwnerID == userID {
+			change.Before.OwnerID = ""
+		}
+		if change.After != nil && change.After.OwnerID == userID {
+			change.After.OwnerID = ""
+		}
+	}
+
+	return nil
+}
+
+// copyCatChange returns copy of the change sharing no memory with it.
+func copyCatChange(change *model.CatChange) model.CatChange {
+	copied := *change
+	copied.Fields = append([]string{}, change.Fields...)
+	if change.Before != nil {
+		before := *change.Before
+		copied.Before = &before
+	}
+	if change.After != nil {
+		after := *change.After
+		copied.After = &after
+	}
+
+	return copied
+}
+
+// find returns cat with given id, it reports false for cats of other owners and cats in trash.
+// The caller must hold the mutex.
+func (r *CatRepositoryMemory) find(ownerID, id string) (model.Cat, bool) {
+	cat, ex := r.cats[id]
+	if !ex || cat.DeletedAt != nil || (ownerID != "" && cat.OwnerID != ownerID) {
+		return model.Cat{}, false
+	}
+
+	return cat, true
+}
+
+// findTrashed returns cat in trash with given id, it reports false for cats of other owners.
+// The caller must hold the mutex.
+func (r *CatRepositoryMemory) findTrashed(ownerID, id string) (model.Cat, bool) {
+	cat, ex := r.cats[id]
+	if !ex || cat.DeletedAt == nil || (ownerID != "" && cat.OwnerID != ownerID) {
+		return model.Cat{}, false
+	}
+
+	return cat, true
+}
+
+// checkVersion returns precondition failed error if the cat doesn't have the version,
+// zero version matches any cat.
+func checkVersion(cat *model.Cat, version int64) error {
+	if version != 0 && cat.Version != version {
+		return apperror.PreconditionFailed("cat with given UUID has been changed, get it again")
+	}
+
+	return nil
+}
+
+// matchCat reports whether the cat satisfies filters of the input.
+func matchCat(cat *model.Cat, input *model.ListCats) bool {
+	from, end := catsBirthRange(input)
+	switch {
+	case (cat.DeletedAt != nil) != input.Deleted:
+		return false
+	case input.OwnerID != "" && cat.OwnerID != input.OwnerID:
+		return false
+	case input.Vaccinated != nil && cat.Vaccinated != *input.Vaccinated:
+		return false
+	case from != nil && cat.DateBirth.Before(*from):
+		return false
+	case end != nil && !cat.DateBirth.Before(*end):
+		return false
+	default:
+		return strings.HasPrefix(cat.Name, input.NamePrefix)
+	}
+}
@@ -2,10 +2,15 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/malkev1ch/first-task/internal/apperror"
 	"github.com/malkev1ch/first-task/internal/model"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
@@ -28,16 +33,26 @@ func (r CatRepositoryMongo) Create(ctx context.Context, input *model.Cat) error
 		"Name":       input.Name,
 		"DateBirth":  input.DateBirth,
 		"Vaccinated": input.Vaccinated,
+		"OwnerID":    input.OwnerID,
 	}).Debugf("mongo repository: create cat")
 	col := r.DB.Database("mongo_database").Collection("cats")
 
-	_, err := col.InsertOne(ctx, bson.D{
+	document := bson.D{
 		{Key: "_id", Value: input.ID},
 		{Key: "name", Value: input.Name},
 		{Key: "dateBirth", Value: input.DateBirth},
 		{Key: "vaccinated", Value: input.Vaccinated},
-	})
+		{Key: "version", Value: int64(1)},
+	}
+	if input.OwnerID != "" {
+		document = append(document, bson.E{Key: "ownerId", Value: input.OwnerID})
+	}
+	_, err := col.InsertOne(ctx, document)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			logrus.Error(err, "mongo repository: cat with given UUID already exists")
+			return apperror.Conflict("cat with given UUID already exists, try to create again")
+		}
 		logrus.Error(err, "mongo repository: Error occurred while inserting new row in table cats")
 		return fmt.Errorf("mongo repository: can't create cat - %w", err)
 	}
@@ -47,87 +62,409 @@ func (r CatRepositoryMongo) Create(ctx context.Context, input *model.Cat) error
 
 // Get method returns object Cat from mongo database
 // with selection by id.
-func (r CatRepositoryMongo) Get(ctx context.Context, id string) (*model.Cat, error) {
+func (r CatRepositoryMongo) Get(ctx context.Context, ownerID, id string) (*model.Cat, error) {
 	logrus.WithFields(logrus.Fields{
-		"ID": id,
+		"ID":      id,
+		"OwnerID": ownerID,
 	}).Debugf("mongo repository: get cat")
 	col := r.DB.Database("mongo_database").Collection("cats")
-	var cat model.Cat
-	err := col.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&cat)
-	if err != nil {
-		logrus.Error(err, "mongo repository: Error occurred while selecting row from table cats")
-		return nil, fmt.Errorf("mongo repository: can't get cat - %w", err)
-	}
-	return &cat, nil
+	return r.findCat(ctx, col, catFilter(ownerID, id))
 }
 
 // Update method updates object Cat from mongo database
 // with selection by id.
-func (r CatRepositoryMongo) Update(ctx context.Context, id string, input *model.UpdateCat) (*model.Cat, error) {
+func (r CatRepositoryMongo) Update(ctx context.Context, ownerID, id string, version int64,
+	input *model.UpdateCat) (*model.Cat, error) {
 	logrus.WithFields(logrus.Fields{
 		"Name":       input.Name,
 		"DateBirth":  input.DateBirth,
 		"Vaccinated": input.Vaccinated,
+		"OwnerID":    ownerID,
+		"Version":    version,
 	}).Debugf("mongo repository: update cat")
 
 	col := r.DB.Database("mongo_database").Collection("cats")
 
-	_, err := col.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, bson.D{
-		{Key: "$set", Value: bson.D{
-			{Key: "name", Value: input.Name},
-			{Key: "dateBirth", Value: input.DateBirth},
-			{Key: "vaccinated", Value: input.Vaccinated},
-		}},
-	})
-	if err != nil {
-		logrus.Error(err, "mongo repository: Error occurred while updating row from table cats")
-		return nil, fmt.Errorf("mongo repository: can't update cat - %w", err)
+	setValues := bson.D{}
+	if input.Name != nil {
+		setValues = append(setValues, bson.E{Key: "name", Value: *input.Name})
 	}
-	var cat model.Cat
-	if err := col.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&cat); err != nil {
-		logrus.Error(err, "mongo repository: Error occurred while selecting row from table cats")
-		return nil, fmt.Errorf("mongo repository: can't get cat - %w", err)
+	if input.DateBirth != nil {
+		setValues = append(setValues, bson.E{Key: "dateBirth", Value: *input.DateBirth})
+	}
+	if input.Vaccinated != nil {
+		setValues = append(setValues, bson.E{Key: "vaccinated", Value: *input.Vaccinated})
+	}
+	if len(setValues) == 0 {
+		cat, err := r.findCat(ctx, col, catFilter(ownerID, id))
+		if err != nil {
+			return nil, err
+		}
+		if version != 0 && cat.Version != version {
+			logrus.Error("mongo repository: version of cat is stale")
+			return nil, apperror.PreconditionFailed("cat with given UUID has been changed, get it again")
+		}
+		return cat, nil
 	}
 
-	return &cat, nil
+	return r.updateCat(ctx, col, ownerID, id, version, bson.D{
+		{Key: "$set", Value: setValues},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	})
 }
 
-// Delete method deletes object Cat from mongo database
+// Delete method moves object Cat in mongo database to trash
 // with selection by id.
-func (r CatRepositoryMongo) Delete(ctx context.Context, id string) error {
+func (r CatRepositoryMongo) Delete(ctx context.Context, ownerID, id string, version int64, deletedAt time.Time) error {
 	logrus.WithFields(logrus.Fields{
-		"ID": id,
+		"ID":        id,
+		"OwnerID":   ownerID,
+		"Version":   version,
+		"DeletedAt": deletedAt,
 	}).Debugf("mongo repository: delete cat")
 	col := r.DB.Database("mongo_database").Collection("cats")
-	_, err := col.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	_, err := r.updateCat(ctx, col, ownerID, id, version, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "deletedAt", Value: deletedAt}}},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	})
+	return err
+}
+
+// GetTrashed method returns object Cat in trash from mongo database
+// with selection by id.
+func (r CatRepositoryMongo) GetTrashed(ctx context.Context, ownerID, id string) (*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"ID":      id,
+		"OwnerID": ownerID,
+	}).Debugf("mongo repository: get trashed cat")
+	col := r.DB.Database("mongo_database").Collection("cats")
+	return r.findCat(ctx, col, trashedCatFilter(ownerID, id))
+}
+
+// Restore method moves object Cat in mongo database out of trash
+// with selection by id and returns object Cat.
+func (r CatRepositoryMongo) Restore(ctx context.Context, ownerID, id string) (*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"ID":      id,
+		"OwnerID": ownerID,
+	}).Debugf("mongo repository: restore cat")
+	col := r.DB.Database("mongo_database").Collection("cats")
+	var cat model.Cat
+	err := col.FindOneAndUpdate(ctx, trashedCatFilter(ownerID, id), bson.D{
+		{Key: "$unset", Value: bson.D{{Key: "deletedAt", Value: ""}}},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&cat)
 	if err != nil {
-		logrus.Error(err, "Error occurred while deleting row from table cats")
-		return fmt.Errorf("mongodb repository: can't delete cat - %w", err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logrus.Error(err, "mongo repository: cat with given UUID isn't in trash")
+			return nil, apperror.NotFound("cat with given UUID isn't in trash")
+		}
+		logrus.Error(err, "mongo repository: Error occurred while restoring row from table cats")
+		return nil, fmt.Errorf("mongo repository: can't restore cat - %w", err)
+	}
+
+	return &cat, nil
+}
+
+// PurgeCats method deletes objects Cat moved to trash before deletedBefore
+// from mongo database and returns them.
+func (r CatRepositoryMongo) PurgeCats(ctx context.Context, deletedBefore time.Time) ([]*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"DeletedBefore": deletedBefore,
+	}).Debugf("mongo repository: purge cats")
+	col := r.DB.Database("mongo_database").Collection("cats")
+	filter := bson.D{{Key: "deletedAt", Value: bson.D{{Key: "$lt", Value: deletedBefore}}}}
+
+	// cats are deleted one by one to return exactly the deleted ones if a cat is restored meanwhile
+	cats := make([]*model.Cat, 0)
+	for {
+		var cat model.Cat
+		err := col.FindOneAndDelete(ctx, filter).Decode(&cat)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return cats, nil
+		}
+		if err != nil {
+			logrus.Error(err, "mongo repository: Error occurred while deleting rows from table cats")
+			return nil, fmt.Errorf("mongo repository: can't purge cats - %w", err)
+		}
+		cats = append(cats, &cat)
+	}
+}
+
+// DisownCats method leaves cats of the owner in mongo database without owner
+// and returns UUIDs of the cats.
+func (r CatRepositoryMongo) DisownCats(ctx context.Context, ownerID string) ([]string, error) {
+	logrus.WithFields(logrus.Fields{
+		"OwnerID": ownerID,
+	}).Debugf("mongo repository: disown cats")
+	col := r.DB.Database("mongo_database").Collection("cats")
+	filter := bson.D{{Key: "ownerId", Value: ownerID}}
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: "ownerId", Value: ""}}}}
+	opts := options.FindOneAndUpdate().SetProjection(bson.D{{Key: "_id", Value: 1}})
+
+	// cats are disowned one by one to return exactly the disowned ones if a cat is created meanwhile
+	ids := make([]string, 0)
+	for {
+		var cat model.Cat
+		err := col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&cat)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ids, nil
+		}
+		if err != nil {
+			logrus.Error(err, "mongo repository: can't disown cats")
+			return nil, fmt.Errorf("mongo repository: can't disown cats - %w", err)
+		}
+		ids = append(ids, cat.ID)
 	}
-	return nil
 }
 
 // UploadImage method updates image path object Cat from mongo database
 // with selection by id.
-func (r CatRepositoryMongo) UploadImage(ctx context.Context, id string, path string) (*model.Cat, error) {
+func (r CatRepositoryMongo) UploadImage(ctx context.Context, ownerID, id string, version int64,
+	path string) (*model.Cat, error) {
 	logrus.WithFields(logrus.Fields{
-		"ID": id,
+		"ID":      id,
+		"OwnerID": ownerID,
+		"Version": version,
 	}).Debugf("mongo repository: update cats image path")
 	col := r.DB.Database("mongo_database").Collection("cats")
-	_, err := col.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, bson.D{
+	return r.updateCat(ctx, col, ownerID, id, version, bson.D{
 		{Key: "$set", Value: bson.D{
 			{Key: "imagePath", Value: path},
 		}},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
 	})
-	if err != nil {
-		logrus.Error(err, "mongo repository: Error occurred while updating image path table cats")
-		return nil, fmt.Errorf("mongo repository: can't update cats image path - %w", err)
+}
+
+// updateCat applies the update to the cat not in trash if it has the version and returns the updated cat.
+// Zero version updates the cat whatever version it has.
+func (r CatRepositoryMongo) updateCat(ctx context.Context, col *mongo.Collection, ownerID, id string, version int64,
+	update bson.D) (*model.Cat, error) {
+	filter := catFilter(ownerID, id)
+	if version != 0 {
+		filter = append(filter, bson.E{Key: "version", Value: version})
 	}
+
 	var cat model.Cat
-	if err := col.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&cat); err != nil {
+	err := col.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&cat)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments) && version != 0:
+		// the cat is looked up once more to tell stale version from missing cat
+		if _, err := r.findCat(ctx, col, catFilter(ownerID, id)); err != nil {
+			return nil, err
+		}
+		logrus.Error("mongo repository: version of cat is stale")
+		return nil, apperror.PreconditionFailed("cat with given UUID has been changed, get it again")
+
+	case errors.Is(err, mongo.ErrNoDocuments):
+		logrus.Error(err, "mongo repository: cat with given UUID doesn't exist")
+		return nil, apperror.NotFound("cat with given UUID doesn't exist")
+
+	case err != nil:
+		logrus.Error(err, "mongo repository: Error occurred while updating row from table cats")
+		return nil, fmt.Errorf("mongo repository: can't update cat - %w", err)
+	}
+
+	return &cat, nil
+}
+
+// catFilter returns filter selecting cat not in trash by id, limited to the owner if it isn't empty.
+func catFilter(ownerID, id string) bson.D {
+	// null matches documents without the field
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "deletedAt", Value: nil}}
+	if ownerID != "" {
+		filter = append(filter, bson.E{Key: "ownerId", Value: ownerID})
+	}
+
+	return filter
+}
+
+// trashedCatFilter returns filter selecting cat in trash by id, limited to the owner if it isn't empty.
+func trashedCatFilter(ownerID, id string) bson.D {
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "deletedAt", Value: bson.D{{Key: "$ne", Value: nil}}}}
+	if ownerID != "" {
+		filter = append(filter, bson.E{Key: "ownerId", Value: ownerID})
+	}
+
+	return filter
+}
+
+// findCat returns object Cat from the collection with selection by filter.
+func (r CatRepositoryMongo) findCat(ctx context.Context, col *mongo.Collection, filter bson.D) (*model.Cat, error) {
+	var cat model.Cat
+	if err := col.FindOne(ctx, filter).Decode(&cat); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logrus.Error(err, "mongo repository: cat with given UUID doesn't exist")
+			return nil, apperror.NotFound("cat with given UUID doesn't exist")
+		}
 		logrus.Error(err, "mongo repository: Error occurred while selecting row from table cats")
 		return nil, fmt.Errorf("mongo repository: can't get cat - %w", err)
 	}
 
 	return &cat, nil
 }
+
+// List method returns page of objects Cat from mongo database
+// filtered and sorted according to the input.
+func (r CatRepositoryMongo) List(ctx context.Context, input *model.ListCats) (*model.CatsPage, error) {
+	logrus.WithFields(logrus.Fields{
+		"OwnerID":    input.OwnerID,
+		"Limit":      input.Limit,
+		"Cursor":     input.Cursor,
+		"Vaccinated": input.Vaccinated,
+		"NamePrefix": input.NamePrefix,
+		"SortBy":     input.SortBy,
+		"SortDesc":   input.SortDesc,
+		"Deleted":    input.Deleted,
+	}).Debugf("mongo repository: list cats")
+	col := r.DB.Database("mongo_database").Collection("cats")
+
+	sortBy := catsSortBy(input)
+	direction, comparison := 1, "$gt"
+	if input.SortDesc {
+		direction, comparison = -1, "$lt"
+	}
+
+	filter := bson.D{{Key: "deletedAt", Value: nil}}
+	if input.Deleted {
+		filter = bson.D{{Key: "deletedAt", Value: bson.D{{Key: "$ne", Value: nil}}}}
+	}
+	if input.OwnerID != "" {
+		filter = append(filter, bson.E{Key: "ownerId", Value: input.OwnerID})
+	}
+	if input.Vaccinated != nil {
+		filter = append(filter, bson.E{Key: "vaccinated", Value: *input.Vaccinated})
+	}
+
+	dateBirth := bson.D{}
+	from, end := catsBirthRange(input)
+	if from != nil {
+		dateBirth = append(dateBirth, bson.E{Key: "$gte", Value: *from})
+	}
+	if end != nil {
+		dateBirth = append(dateBirth, bson.E{Key: "$lt", Value: *end})
+	}
+	if len(dateBirth) > 0 {
+		filter = append(filter, bson.E{Key: "dateBirth", Value: dateBirth})
+	}
+
+	if input.NamePrefix != "" {
+		filter = append(filter, bson.E{Key: "name", Value: bson.D{
+			{Key: "$regex", Value: "^" + regexp.QuoteMeta(input.NamePrefix)},
+		}})
+	}
+
+	if input.Cursor != "" {
+		cursor, err := decodeCatsCursor(input.Cursor, sortBy)
+		if err != nil {
+			logrus.Error(err, "mongo repository: invalid cursor")
+			return nil, err
+		}
+		var value interface{} = cursor.Name
+		if sortBy == model.CatsSortByDateBirth {
+			value = cursor.DateBirth
+		}
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: sortBy, Value: bson.D{{Key: comparison, Value: value}}}},
+			bson.D{{Key: sortBy, Value: value}, {Key: "_id", Value: bson.D{{Key: comparison, Value: cursor.ID}}}},
+		}})
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: sortBy, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(input.Limit + 1))
+	cur, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		logrus.Error(err, "mongo repository: Error occurred while selecting rows from table cats")
+		return nil, fmt.Errorf("mongo repository: can't list cats - %w", err)
+	}
+
+	cats := make([]*model.Cat, 0, input.Limit+1)
+	if err := cur.All(ctx, &cats); err != nil {
+		logrus.Error(err, "mongo repository: Error occurred while decoding rows from table cats")
+		return nil, fmt.Errorf("mongo repository: can't list cats - %w", err)
+	}
+
+	return newCatsPage(cats, input.Limit, sortBy), nil
+}
+
+// AddCatChange method appends the change of a cat to collection cat_history of mongo database.
+func (r CatRepositoryMongo) AddCatChange(ctx context.Context, change *model.CatChange) error {
+	logrus.WithFields(logrus.Fields{
+		"ID":        change.ID,
+		"CatID":     change.CatID,
+		"ActorID":   change.ActorID,
+		"Operation": change.Operation,
+		"Fields":    change.Fields,
+	}).Debugf("mongo repository: add cat change")
+	col := r.DB.Database("mongo_database").Collection("cat_history")
+	if _, err := col.InsertOne(ctx, change); err != nil {
+		logrus.Error(err, "mongo repository: can't insert cat change")
+		return fmt.Errorf("mongo repository: can't add cat change - %w", err)
+	}
+
+	return nil
+}
+
+// ListCatChanges method returns page of changes of the cat from mongo database.
+func (r CatRepositoryMongo) ListCatChanges(ctx context.Context, input *model.ListCatChanges) (*model.CatChangesPage, error) {
+	logrus.WithFields(logrus.Fields{
+		"CatID":   input.CatID,
+		"OwnerID": input.OwnerID,
+		"Limit":   input.Limit,
+		"Cursor":  input.Cursor,
+	}).Debugf("mongo repository: list cat changes")
+	col := r.DB.Database("mongo_database").Collection("cat_history")
+
+	filter := bson.D{{Key: "catId", Value: input.CatID}}
+	if input.OwnerID != "" {
+		filter = append(filter, bson.E{Key: "ownerId", Value: input.OwnerID})
+	}
+	if input.Cursor != "" {
+		cursor, err := decodeCatChangesCursor(input.Cursor)
+		if err != nil {
+			logrus.Error(err, "mongo repository: invalid cursor")
+			return nil, err
+		}
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "changedAt", Value: bson.D{{Key: "$lt", Value: cursor.ChangedAt}}}},
+			bson.D{{Key: "changedAt", Value: cursor.ChangedAt}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: cursor.ID}}}},
+		}})
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "changedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(input.Limit + 1))
+	cur, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't list cat changes")
+		return nil, fmt.Errorf("mongo repository: can't list cat changes - %w", err)
+	}
+
+	changes := make([]*model.CatChange, 0, input.Limit+1)
+	if err := cur.All(ctx, &changes); err != nil {
+		logrus.Error(err, "mongo repository: can't decode cat changes")
+		return nil, fmt.Errorf("mongo repository: can't list cat changes - %w", err)
+	}
+
+	return newCatChangesPage(changes, input.Limit), nil
+}
+
+// AnonymizeCatChanges method removes the user from changes of cats in collection cat_history of mongo database.
+func (r CatRepositoryMongo) AnonymizeCatChanges(ctx context.Context, userID string) error {
+	logrus.WithFields(logrus.Fields{
+		"UserID": userID,
+	}).Debugf("mongo repository: anonymize cat changes")
+	col := r.DB.Database("mongo_database").Collection("cat_history")
+	for _, key := range []string{"actorId", "ownerId", "before.ownerId", "after.ownerId"} {
+		filter := bson.D{{Key: key, Value: userID}}
+		update := bson.D{{Key: "$unset", Value: bson.D{{Key: key, Value: ""}}}}
+		if _, err := col.UpdateMany(ctx, filter, update); err != nil {
+			logrus.Error(err, "mongo repository: can't anonymize cat changes")
+			return fmt.Errorf("mongo repository: can't anonymize cat changes - %w", err)
+		}
+	}
+
+	return nil
+}
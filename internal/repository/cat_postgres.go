@@ -3,15 +3,26 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/malkev1ch/first-task/internal/apperror"
 	"github.com/malkev1ch/first-task/internal/model"
 	"github.com/sirupsen/logrus"
 )
 
+// uniqueViolationCode is postgres error code of unique constraint violation.
+const uniqueViolationCode = "23505"
+
+// catColumns are columns of table cats in the order scanCat reads them.
+const catColumns = "id, name, date_birth, vaccinated, image_path, owner_id, deleted_at, version"
+
 // CatRepository type represents postgres object cat structure and behavior.
 type CatRepository struct {
 	DB *pgxpool.Pool
@@ -30,15 +41,17 @@ func (r CatRepository) Create(ctx context.Context, input *model.Cat) error {
 		"Name":       input.Name,
 		"DateBirth":  input.DateBirth,
 		"Vaccinated": input.Vaccinated,
+		"OwnerID":    input.OwnerID,
 	}).Info("postgres repository: create cat")
 
-	insertCatQuery := "INSERT INTO cats(id, name, date_birth, vaccinated) VALUES ($1, $2, $3, $4)"
+	insertCatQuery := "INSERT INTO cats(id, name, date_birth, vaccinated, owner_id) VALUES ($1, $2, $3, $4, $5)"
 
-	if _, err := r.DB.Exec(ctx, insertCatQuery, input.ID, input.Name, input.DateBirth, input.Vaccinated); err != nil {
+	if _, err := r.DB.Exec(ctx, insertCatQuery, input.ID, input.Name, input.DateBirth, input.Vaccinated,
+		sql.NullString{String: input.OwnerID, Valid: input.OwnerID != ""}); err != nil {
 		switch {
-		case strings.Contains(err.Error(), "duplicate key"):
+		case isUniqueViolation(err):
 			logrus.Error("postgres repository: cat with given UUID already exists - ", err)
-			return errors.New("cat with given UUID already exists, try to create again")
+			return apperror.Conflict("cat with given UUID already exists, try to create again")
 
 		default:
 			logrus.Error("postgres repository: Error occurred while inserting new row in table cats - ", err)
@@ -51,38 +64,39 @@ func (r CatRepository) Create(ctx context.Context, input *model.Cat) error {
 
 // Get method returns object Cat from postgres database
 // with selection by id.
-func (r CatRepository) Get(ctx context.Context, id string) (*model.Cat, error) {
+func (r CatRepository) Get(ctx context.Context, ownerID, id string) (*model.Cat, error) {
 	logrus.WithFields(logrus.Fields{
-		"ID": id,
+		"ID":      id,
+		"OwnerID": ownerID,
 	}).Info("postgres repository: get cat")
-	getCatQuery := "SELECT id, name, date_birth, vaccinated, image_path FROM cats WHERE id = $1"
-	var cat model.Cat
-	imageNull := sql.NullString{}
-	if err := r.DB.QueryRow(ctx, getCatQuery, id).Scan(&cat.ID, &cat.Name, &cat.DateBirth, &cat.Vaccinated,
-		&imageNull); err != nil {
+	args := []interface{}{id}
+	getCatQuery := "SELECT " + catColumns + " FROM cats WHERE id = $1 AND deleted_at IS NULL" +
+		ownerCondition(ownerID, &args)
+	cat, err := scanCat(r.DB.QueryRow(ctx, getCatQuery, args...))
+	if err != nil {
 		switch {
-		case strings.Contains(err.Error(), "no rows in result set"):
+		case errors.Is(err, pgx.ErrNoRows):
 			logrus.Error("postgres repository: cat with UUID email doesn't exist - ", err)
-			return nil, errors.New("cat with given UUID doesn't exist")
+			return nil, apperror.NotFound("cat with given UUID doesn't exist")
 
 		default:
 			logrus.Error("postgres repository: Error occurred while selecting row from table cats - ", err)
 			return nil, errors.New("can't get cat")
 		}
 	}
-	if imageNull.Valid {
-		cat.ImagePath = imageNull.String
-	}
-	return &cat, nil
+	return cat, nil
 }
 
 // Update method updates object Cat from postgres database
 // with selection by id and returns object Cat.
-func (r CatRepository) Update(ctx context.Context, id string, input *model.UpdateCat) (*model.Cat, error) {
+func (r CatRepository) Update(ctx context.Context, ownerID, id string, version int64,
+	input *model.UpdateCat) (*model.Cat, error) {
 	logrus.WithFields(logrus.Fields{
 		"Name":       input.Name,
 		"DateBirth":  input.DateBirth,
 		"Vaccinated": input.Vaccinated,
+		"OwnerID":    ownerID,
+		"Version":    version,
 	}).Info("postgres repository: update cat")
 
 	setValues := make([]string, 0)
@@ -107,67 +121,527 @@ func (r CatRepository) Update(ctx context.Context, id string, input *model.Updat
 		argID++
 	}
 
+	if len(setValues) == 0 {
+		cat, err := r.Get(ctx, ownerID, id)
+		if err != nil {
+			return nil, err
+		}
+		if version != 0 && cat.Version != version {
+			logrus.Error("postgres repository: version of cat is stale")
+			return nil, apperror.PreconditionFailed("cat with given UUID has been changed, get it again")
+		}
+		return cat, nil
+	}
+
+	setValues = append(setValues, "version = version + 1")
 	setQuery := strings.Join(setValues, ", ")
-	updateCatQuery := fmt.Sprintf("UPDATE cats SET %s WHERE id = $%d RETURNING id, name, date_birth,"+
-		" vaccinated, image_path;", setQuery, argID)
 	args = append(args, id)
+	updateCatQuery := fmt.Sprintf("UPDATE cats SET %s WHERE id = $%d AND deleted_at IS NULL%s%s RETURNING %s;",
+		setQuery, argID, ownerCondition(ownerID, &args), versionCondition(version, &args), catColumns)
 
-	var cat model.Cat
-	imageNull := sql.NullString{}
-
-	if err := r.DB.QueryRow(ctx, updateCatQuery, args...).Scan(&cat.ID, &cat.Name, &cat.DateBirth, &cat.Vaccinated,
-		&imageNull); err != nil {
+	cat, err := scanCat(r.DB.QueryRow(ctx, updateCatQuery, args...))
+	if err != nil {
 		switch {
-		case strings.Contains(err.Error(), "no rows in result set"):
+		case errors.Is(err, pgx.ErrNoRows):
 			logrus.Error("postgres repository: at with given UUID doesn't exists - ", err)
-			return nil, errors.New("cat with given UUID doesn't exists")
+			return nil, r.unchangedCatError(ctx, ownerID, id, version)
 		default:
 			logrus.Error("postgres repository: Error occurred while updating row from table cats - ", err)
 			return nil, errors.New("can't update cat")
 		}
 	}
 
-	if imageNull.Valid {
-		cat.ImagePath = imageNull.String
-	}
-
-	return &cat, nil
+	return cat, nil
 }
 
-// Delete method deletes object Cat from postgres database
+// Delete method moves object Cat in postgres database to trash
 // with selection by id.
-func (r CatRepository) Delete(ctx context.Context, id string) error {
+func (r CatRepository) Delete(ctx context.Context, ownerID, id string, version int64, deletedAt time.Time) error {
 	logrus.WithFields(logrus.Fields{
-		"ID": id,
+		"ID":        id,
+		"OwnerID":   ownerID,
+		"Version":   version,
+		"DeletedAt": deletedAt,
 	}).Info("repository: delete cat")
-	deleteCatQuery := "DELETE FROM cats WHERE id = $1"
-	_, err := r.DB.Exec(ctx, deleteCatQuery, id)
+	args := []interface{}{id, deletedAt}
+	deleteCatQuery := "UPDATE cats SET deleted_at = $2, version = version + 1 WHERE id = $1 AND deleted_at IS NULL" +
+		ownerCondition(ownerID, &args) + versionCondition(version, &args)
+	result, err := r.DB.Exec(ctx, deleteCatQuery, args...)
 	if err != nil {
 		logrus.Error("postgres repository: Error occurred while deleting row from table cats - ", err)
 		return errors.New("can't delete cat")
 	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: cat with given UUID doesn't exist")
+		return r.unchangedCatError(ctx, ownerID, id, version)
+	}
 
 	return nil
 }
 
+// GetTrashed method returns object Cat in trash from postgres database
+// with selection by id.
+func (r CatRepository) GetTrashed(ctx context.Context, ownerID, id string) (*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"ID":      id,
+		"OwnerID": ownerID,
+	}).Info("postgres repository: get trashed cat")
+	args := []interface{}{id}
+	getCatQuery := "SELECT " + catColumns + " FROM cats WHERE id = $1 AND deleted_at IS NOT NULL" +
+		ownerCondition(ownerID, &args)
+	cat, err := scanCat(r.DB.QueryRow(ctx, getCatQuery, args...))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logrus.Error("postgres repository: cat with given UUID isn't in trash - ", err)
+			return nil, apperror.NotFound("cat with given UUID isn't in trash")
+
+		default:
+			logrus.Error("postgres repository: Error occurred while selecting row from table cats - ", err)
+			return nil, errors.New("can't get cat")
+		}
+	}
+	return cat, nil
+}
+
+// Restore method moves object Cat in postgres database out of trash
+// with selection by id and returns object Cat.
+func (r CatRepository) Restore(ctx context.Context, ownerID, id string) (*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"ID":      id,
+		"OwnerID": ownerID,
+	}).Info("postgres repository: restore cat")
+	args := []interface{}{id}
+	restoreCatQuery := "UPDATE cats SET deleted_at = NULL, version = version + 1" +
+		" WHERE id = $1 AND deleted_at IS NOT NULL" + ownerCondition(ownerID, &args) + " RETURNING " + catColumns + ";"
+	cat, err := scanCat(r.DB.QueryRow(ctx, restoreCatQuery, args...))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logrus.Error("postgres repository: cat with given UUID isn't in trash - ", err)
+			return nil, apperror.NotFound("cat with given UUID isn't in trash")
+
+		default:
+			logrus.Error("postgres repository: Error occurred while restoring row from table cats - ", err)
+			return nil, errors.New("can't restore cat")
+		}
+	}
+	return cat, nil
+}
+
+// PurgeCats method deletes objects Cat moved to trash before deletedBefore
+// from postgres database and returns them.
+func (r CatRepository) PurgeCats(ctx context.Context, deletedBefore time.Time) ([]*model.Cat, error) {
+	logrus.WithFields(logrus.Fields{
+		"DeletedBefore": deletedBefore,
+	}).Info("postgres repository: purge cats")
+	rows, err := r.DB.Query(ctx, "DELETE FROM cats WHERE deleted_at < $1 RETURNING "+catColumns, deletedBefore)
+	if err != nil {
+		logrus.Error("postgres repository: Error occurred while deleting rows from table cats - ", err)
+		return nil, errors.New("can't purge cats")
+	}
+	defer rows.Close()
+
+	cats := make([]*model.Cat, 0)
+	for rows.Next() {
+		cat, err := scanCat(rows)
+		if err != nil {
+			logrus.Error("postgres repository: Error occurred while scanning row from table cats - ", err)
+			return nil, errors.New("can't purge cats")
+		}
+		cats = append(cats, cat)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.Error("postgres repository: Error occurred while deleting rows from table cats - ", err)
+		return nil, errors.New("can't purge cats")
+	}
+
+	return cats, nil
+}
+
+// DisownCats method leaves cats of the owner in postgres database without owner
+// and returns UUIDs of the cats.
+func (r CatRepository) DisownCats(ctx context.Context, ownerID string) ([]string, error) {
+	logrus.WithFields(logrus.Fields{
+		"OwnerID": ownerID,
+	}).Info("postgres repository: disown cats")
+	rows, err := r.DB.Query(ctx, "UPDATE cats SET owner_id = NULL WHERE owner_id = $1 RETURNING id", ownerID)
+	if err != nil {
+		logrus.Error("postgres repository: can't disown cats - ", err)
+		return nil, errors.New("can't disown cats")
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			logrus.Error("postgres repository: Error occurred while scanning row from table cats - ", err)
+			return nil, errors.New("can't disown cats")
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.Error("postgres repository: can't disown cats - ", err)
+		return nil, errors.New("can't disown cats")
+	}
+
+	return ids, nil
+}
+
 // UploadImage method updates image path object Cat from postgres database
 // with selection by id and returns object Cat.
-func (r CatRepository) UploadImage(ctx context.Context, id string, path string) (*model.Cat, error) {
+func (r CatRepository) UploadImage(ctx context.Context, ownerID, id string, version int64,
+	path string) (*model.Cat, error) {
 	logrus.WithFields(logrus.Fields{
-		"ID": id,
+		"ID":      id,
+		"OwnerID": ownerID,
+		"Version": version,
 	}).Info("postgres repository: update cats image path")
-	UpdateImagePathCatQuery := "UPDATE cats SET image_path=$1 WHERE id = $2 RETURNING id, name, date_birth," +
-		" vaccinated, image_path;"
+	args := []interface{}{path, id}
+	UpdateImagePathCatQuery := "UPDATE cats SET image_path=$1, version = version + 1" +
+		" WHERE id = $2 AND deleted_at IS NULL" + ownerCondition(ownerID, &args) + versionCondition(version, &args) +
+		" RETURNING " + catColumns + ";"
+	cat, err := scanCat(r.DB.QueryRow(ctx, UpdateImagePathCatQuery, args...))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logrus.Error("postgres repository: cat with given UUID doesn't exist - ", err)
+			return nil, r.unchangedCatError(ctx, ownerID, id, version)
+		default:
+			logrus.Error("postgres repository: Error occurred while updating image path table cats - ", err)
+			return nil, fmt.Errorf("postgres repository: can't update cats image path - %w", err)
+		}
+	}
+
+	return cat, nil
+}
+
+// List method returns page of objects Cat from postgres database
+// filtered and sorted according to the input.
+func (r CatRepository) List(ctx context.Context, input *model.ListCats) (*model.CatsPage, error) {
+	logrus.WithFields(logrus.Fields{
+		"OwnerID":    input.OwnerID,
+		"Limit":      input.Limit,
+		"Cursor":     input.Cursor,
+		"Vaccinated": input.Vaccinated,
+		"NamePrefix": input.NamePrefix,
+		"SortBy":     input.SortBy,
+		"SortDesc":   input.SortDesc,
+		"Deleted":    input.Deleted,
+	}).Info("postgres repository: list cats")
+
+	sortBy := catsSortBy(input)
+	// names are compared bytewise to keep the same order as in mongo database
+	sortColumn := `name COLLATE "C"`
+	if sortBy == model.CatsSortByDateBirth {
+		sortColumn = "date_birth"
+	}
+	direction, comparison := "ASC", ">"
+	if input.SortDesc {
+		direction, comparison = "DESC", "<"
+	}
+
+	conditions := []string{"deleted_at IS NULL"}
+	if input.Deleted {
+		conditions = []string{"deleted_at IS NOT NULL"}
+	}
+	args := make([]interface{}, 0)
+	argID := 1
+
+	if input.OwnerID != "" {
+		conditions = append(conditions, fmt.Sprintf("owner_id = $%d", argID))
+		args = append(args, input.OwnerID)
+		argID++
+	}
+
+	if input.Vaccinated != nil {
+		conditions = append(conditions, fmt.Sprintf("vaccinated = $%d", argID))
+		args = append(args, *input.Vaccinated)
+		argID++
+	}
+
+	from, end := catsBirthRange(input)
+	if from != nil {
+		conditions = append(conditions, fmt.Sprintf("date_birth >= $%d", argID))
+		args = append(args, *from)
+		argID++
+	}
+
+	if end != nil {
+		conditions = append(conditions, fmt.Sprintf("date_birth < $%d", argID))
+		args = append(args, *end)
+		argID++
+	}
+
+	if input.NamePrefix != "" {
+		conditions = append(conditions, fmt.Sprintf("name LIKE $%d", argID))
+		args = append(args, escapeLike(input.NamePrefix)+"%")
+		argID++
+	}
+
+	if input.Cursor != "" {
+		cursor, err := decodeCatsCursor(input.Cursor, sortBy)
+		if err != nil {
+			logrus.Error("postgres repository: invalid cursor - ", err)
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)", sortColumn, comparison, argID, argID+1))
+		if sortBy == model.CatsSortByDateBirth {
+			args = append(args, cursor.DateBirth)
+		} else {
+			args = append(args, cursor.Name)
+		}
+		args = append(args, cursor.ID)
+		argID += 2
+	}
+
+	whereQuery := "WHERE " + strings.Join(conditions, " AND ")
+	listCatsQuery := fmt.Sprintf("SELECT %s FROM cats %s ORDER BY %s %s, id %s LIMIT $%d",
+		catColumns, whereQuery, sortColumn, direction, direction, argID)
+	args = append(args, input.Limit+1)
+
+	rows, err := r.DB.Query(ctx, listCatsQuery, args...)
+	if err != nil {
+		logrus.Error("postgres repository: Error occurred while selecting rows from table cats - ", err)
+		return nil, errors.New("can't list cats")
+	}
+	defer rows.Close()
+
+	cats := make([]*model.Cat, 0, input.Limit+1)
+	for rows.Next() {
+		cat, err := scanCat(rows)
+		if err != nil {
+			logrus.Error("postgres repository: Error occurred while scanning row from table cats - ", err)
+			return nil, errors.New("can't list cats")
+		}
+		cats = append(cats, cat)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.Error("postgres repository: Error occurred while selecting rows from table cats - ", err)
+		return nil, errors.New("can't list cats")
+	}
+
+	return newCatsPage(cats, input.Limit, sortBy), nil
+}
+
+// AddCatChange method appends the change of a cat to table cat_history of postgres database.
+func (r CatRepository) AddCatChange(ctx context.Context, change *model.CatChange) error {
+	logrus.WithFields(logrus.Fields{
+		"ID":        change.ID,
+		"CatID":     change.CatID,
+		"ActorID":   change.ActorID,
+		"Operation": change.Operation,
+		"Fields":    change.Fields,
+	}).Info("postgres repository: add cat change")
+	before, err := marshalCat(change.Before)
+	if err != nil {
+		logrus.Error("postgres repository: can't marshal cat - ", err)
+		return errors.New("can't add cat change")
+	}
+	after, err := marshalCat(change.After)
+	if err != nil {
+		logrus.Error("postgres repository: can't marshal cat - ", err)
+		return errors.New("can't add cat change")
+	}
+
+	insertCatChangeQuery := `INSERT INTO cat_history(id, cat_id, owner_id, actor_id, operation, changed_at, fields, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	if _, err := r.DB.Exec(ctx, insertCatChangeQuery, change.ID, change.CatID,
+		sql.NullString{String: change.OwnerID, Valid: change.OwnerID != ""},
+		sql.NullString{String: change.ActorID, Valid: change.ActorID != ""},
+		change.Operation, change.ChangedAt, change.Fields, before, after); err != nil {
+		logrus.Error("postgres repository: can't insert cat change - ", err)
+		return errors.New("can't add cat change")
+	}
+
+	return nil
+}
+
+// ListCatChanges method returns page of changes of the cat from postgres database.
+func (r CatRepository) ListCatChanges(ctx context.Context, input *model.ListCatChanges) (*model.CatChangesPage, error) {
+	logrus.WithFields(logrus.Fields{
+		"CatID":   input.CatID,
+		"OwnerID": input.OwnerID,
+		"Limit":   input.Limit,
+		"Cursor":  input.Cursor,
+	}).Info("postgres repository: list cat changes")
+
+	conditions := []string{"cat_id = $1"}
+	args := []interface{}{input.CatID}
+	argID := 2
+
+	if input.OwnerID != "" {
+		conditions = append(conditions, fmt.Sprintf("owner_id = $%d", argID))
+		args = append(args, input.OwnerID)
+		argID++
+	}
+
+	if input.Cursor != "" {
+		cursor, err := decodeCatChangesCursor(input.Cursor)
+		if err != nil {
+			logrus.Error("postgres repository: invalid cursor - ", err)
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(changed_at, id) < ($%d, $%d)", argID, argID+1))
+		args = append(args, cursor.ChangedAt, cursor.ID)
+		argID += 2
+	}
+
+	listCatChangesQuery := fmt.Sprintf(`SELECT id, cat_id, owner_id, actor_id, operation, changed_at, fields, before, after
+		FROM cat_history WHERE %s ORDER BY changed_at DESC, id DESC LIMIT $%d`, strings.Join(conditions, " AND "), argID)
+	args = append(args, input.Limit+1)
+
+	rows, err := r.DB.Query(ctx, listCatChangesQuery, args...)
+	if err != nil {
+		logrus.Error("postgres repository: can't list cat changes - ", err)
+		return nil, errors.New("can't list cat changes")
+	}
+	defer rows.Close()
+
+	changes := make([]*model.CatChange, 0, input.Limit+1)
+	for rows.Next() {
+		change, err := scanCatChange(rows)
+		if err != nil {
+			logrus.Error("postgres repository: can't scan cat change - ", err)
+			return nil, errors.New("can't list cat changes")
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.Error("postgres repository: can't list cat changes - ", err)
+		return nil, errors.New("can't list cat changes")
+	}
+
+	return newCatChangesPage(changes, input.Limit), nil
+}
+
+// AnonymizeCatChanges method removes the user from changes of cats in table cat_history of postgres database.
+func (r CatRepository) AnonymizeCatChanges(ctx context.Context, userID string) error {
+	logrus.WithFields(logrus.Fields{
+		"UserID": userID,
+	}).Info("postgres repository: anonymize cat changes")
+	anonymizeCatChangesQuery := `UPDATE cat_history SET
+		actor_id = CASE WHEN actor_id = $1 THEN NULL ELSE actor_id END,
+		owner_id = CASE WHEN owner_id = $1 THEN NULL ELSE owner_id END,
+		before = CASE WHEN before->>'ownerId' = $2 THEN before - 'ownerId' ELSE before END,
+		after = CASE WHEN after->>'ownerId' = $2 THEN after - 'ownerId' ELSE after END
+		WHERE actor_id = $1 OR owner_id = $1 OR before->>'ownerId' = $2 OR after->>'ownerId' = $2`
+	if _, err := r.DB.Exec(ctx, anonymizeCatChangesQuery, userID, userID); err != nil {
+		logrus.Error("postgres repository: can't anonymize cat changes - ", err)
+		return errors.New("can't anonymize cat changes")
+	}
+
+	return nil
+}
+
+// scanCatChange reads object CatChange from the row of table cat_history.
+func scanCatChange(row pgx.Row) (*model.CatChange, error) {
+	var change model.CatChange
+	var before, after []byte
+	ownerNull := sql.NullString{}
+	actorNull := sql.NullString{}
+	if err := row.Scan(&change.ID, &change.CatID, &ownerNull, &actorNull, &change.Operation, &change.ChangedAt,
+		&change.Fields, &before, &after); err != nil {
+		return nil, err
+	}
+	change.OwnerID = ownerNull.String
+	change.ActorID = actorNull.String
+
+	var err error
+	if change.Before, err = unmarshalCat(before); err != nil {
+		return nil, err
+	}
+	if change.After, err = unmarshalCat(after); err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// marshalCat returns JSON of the cat for JSONB column, nil cat is stored as NULL.
+func marshalCat(cat *model.Cat) ([]byte, error) {
+	if cat == nil {
+		return nil, nil
+	}
+	return json.Marshal(cat)
+}
+
+// unmarshalCat parses JSON of the cat read from JSONB column, NULL is read as nil cat.
+func unmarshalCat(b []byte) (*model.Cat, error) {
+	if b == nil {
+		return nil, nil
+	}
+	var cat model.Cat
+	if err := json.Unmarshal(b, &cat); err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+// scanCat reads object Cat from the row selected with catColumns.
+func scanCat(row pgx.Row) (*model.Cat, error) {
 	var cat model.Cat
 	imageNull := sql.NullString{}
-	if err := r.DB.QueryRow(ctx, UpdateImagePathCatQuery, path, id).Scan(&cat.ID, &cat.Name, &cat.DateBirth,
-		&cat.Vaccinated, &imageNull); err != nil {
-		logrus.Error("postgres repository: Error occurred while updating image path table cats - ", err)
-		return nil, fmt.Errorf("postgres repository: can't update cats image path - %w", err)
+	ownerNull := sql.NullString{}
+	deletedNull := sql.NullTime{}
+	if err := row.Scan(&cat.ID, &cat.Name, &cat.DateBirth, &cat.Vaccinated, &imageNull, &ownerNull,
+		&deletedNull, &cat.Version); err != nil {
+		return nil, err
 	}
-	if imageNull.Valid {
-		cat.ImagePath = imageNull.String
+	cat.ImagePath = imageNull.String
+	cat.OwnerID = ownerNull.String
+	if deletedNull.Valid {
+		deletedAt := deletedNull.Time.UTC()
+		cat.DeletedAt = &deletedAt
 	}
 
 	return &cat, nil
 }
+
+// ownerCondition returns condition limiting cats to the owner and appends owner to args.
+// It returns empty condition for empty owner.
+func ownerCondition(ownerID string, args *[]interface{}) string {
+	if ownerID == "" {
+		return ""
+	}
+	*args = append(*args, ownerID)
+
+	return fmt.Sprintf(" AND owner_id = $%d", len(*args))
+}
+
+// versionCondition returns condition limiting cats to the version and appends version to args.
+// It returns empty condition for zero version.
+func versionCondition(version int64, args *[]interface{}) string {
+	if version == 0 {
+		return ""
+	}
+	*args = append(*args, version)
+
+	return fmt.Sprintf(" AND version = $%d", len(*args))
+}
+
+// unchangedCatError returns error of the statement that changed no cat: precondition failed error
+// if the cat exists with other version than expected and not found error otherwise.
+func (r CatRepository) unchangedCatError(ctx context.Context, ownerID, id string, version int64) error {
+	if version == 0 {
+		return apperror.NotFound("cat with given UUID doesn't exist")
+	}
+	if _, err := r.Get(ctx, ownerID, id); err != nil {
+		return err
+	}
+
+	logrus.Error("postgres repository: version of cat is stale")
+	return apperror.PreconditionFailed("cat with given UUID has been changed, get it again")
+}
+
+// isUniqueViolation reports whether postgres rejected the statement because of unique constraint.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
+
+// escapeLike escapes wildcard characters of LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
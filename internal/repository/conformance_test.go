@@ -0,0 +1,20 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/malkev1ch/first-task/internal/repository"
+	"github.com/malkev1ch/first-task/internal/repository/repotest"
+)
+
+func TestPostgresConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Repository {
+		return repository.PostgresTestRepository()
+	})
+}
+
+func TestMongoConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Repository {
+		return repository.MongoTestRepository()
+	})
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+)
+
+// catsCursor type represents position of the last cat on a page.
+type catsCursor struct {
+	SortBy    string    `json:"s"`
+	Name      string    `json:"n,omitempty"`
+	DateBirth time.Time `json:"d,omitempty"`
+	ID        string    `json:"i"`
+}
+
+// encodeCatsCursor returns opaque cursor pointing after the given cat.
+func encodeCatsCursor(cat *model.Cat, sortBy string) string {
+	cursor := catsCursor{SortBy: sortBy, ID: cat.ID}
+	switch sortBy {
+	case model.CatsSortByDateBirth:
+		cursor.DateBirth = cat.DateBirth
+	default:
+		cursor.Name = cat.Name
+	}
+	// marshaling of the struct with plain fields can't fail
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCatsCursor parses cursor made by encodeCatsCursor for the same sort key.
+func decodeCatsCursor(cursor, sortBy string) (*catsCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, apperror.Validation("invalid cursor")
+	}
+	var c catsCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return nil, apperror.Validation("invalid cursor")
+	}
+	if c.SortBy != sortBy {
+		return nil, apperror.Validation("cursor doesn't match sort order")
+	}
+	return &c, nil
+}
+
+// catsSortBy returns sort key of the input with the default applied.
+func catsSortBy(input *model.ListCats) string {
+	if input.SortBy == model.CatsSortByDateBirth {
+		return model.CatsSortByDateBirth
+	}
+	return model.CatsSortByName
+}
+
+// catsBirthRange returns bounds of birthdate filters of the input, cats born on or after from and
+// before end match. Birthdate in postgres is a date, so bounds are dates too, and the whole last day
+// is before end whatever time the filter has.
+func catsBirthRange(input *model.ListCats) (from, end *time.Time) {
+	if input.DateBirthFrom != nil {
+		date := dateOf(*input.DateBirthFrom)
+		from = &date
+	}
+	if input.DateBirthTo != nil {
+		date := dateOf(*input.DateBirthTo).AddDate(0, 0, 1)
+		end = &date
+	}
+	return from, end
+}
+
+// dateOf returns midnight UTC of the date of the time in its location, the way postgres stores dates.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// newCatsPage cuts the extra cat fetched over the limit and builds next cursor from the last cat.
+func newCatsPage(cats []*model.Cat, limit int, sortBy string) *model.CatsPage {
+	page := &model.CatsPage{Cats: cats}
+	if limit > 0 && len(cats) > limit {
+		page.Cats = cats[:limit]
+		page.NextCursor = encodeCatsCursor(page.Cats[limit-1], sortBy)
+	}
+	return page
+}
+
+// usersCursor type represents position of the last user on a page, emails are unique.
+type usersCursor struct {
+	Email string `json:"e"`
+}
+
+// encodeUsersCursor returns opaque cursor pointing after the given user.
+func encodeUsersCursor(user *model.User) string {
+	// marshaling of the struct with plain fields can't fail
+	b, _ := json.Marshal(usersCursor{Email: user.Email})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeUsersCursor parses cursor made by encodeUsersCursor.
+func decodeUsersCursor(cursor string) (*usersCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, apperror.Validation("invalid cursor")
+	}
+	var c usersCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Email == "" {
+		return nil, apperror.Validation("invalid cursor")
+	}
+	return &c, nil
+}
+
+// newUsersPage cuts the extra user fetched over the limit and builds next cursor from the last user.
+func newUsersPage(users []*model.User, limit int) *model.UsersPage {
+	page := &model.UsersPage{Users: users}
+	if limit > 0 && len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = encodeUsersCursor(page.Users[limit-1])
+	}
+	return page
+}
+
+// catChangesCursor type represents position of the last change on a page.
+type catChangesCursor struct {
+	ChangedAt time.Time `json:"t"`
+	ID        string    `json:"i"`
+}
+
+// encodeCatChangesCursor returns opaque cursor pointing after the given change.
+func encodeCatChangesCursor(change *model.CatChange) string {
+	// marshaling of the struct with plain fields can't fail
+	b, _ := json.Marshal(catChangesCursor{ChangedAt: change.ChangedAt, ID: change.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCatChangesCursor parses cursor made by encodeCatChangesCursor.
+func decodeCatChangesCursor(cursor string) (*catChangesCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, apperror.Validation("invalid cursor")
+	}
+	var c catChangesCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return nil, apperror.Validation("invalid cursor")
+	}
+	return &c, nil
+}
+
+// newCatChangesPage cuts the extra change fetched over the limit and builds next cursor from the last change.
+func newCatChangesPage(changes []*model.CatChange, limit int) *model.CatChangesPage {
+	page := &model.CatChangesPage{Changes: changes}
+	if limit > 0 && len(changes) > limit {
+		page.Changes = changes[:limit]
+		page.NextCursor = encodeCatChangesCursor(page.Changes[limit-1])
+	}
+	return page
+}
@@ -0,0 +1,7 @@
+package repository
+
+// Repositories started by TestMain are exported to conformance tests of package repository_test.
+var (
+	PostgresTestRepository = func() *Repository { return repo }
+	MongoTestRepository    = func() *Repository { return mongoRepo }
+)
@@ -7,6 +7,7 @@ package mock_repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/malkev1ch/first-task/internal/model"
@@ -36,6 +37,34 @@ func (m *MockCat) EXPECT() *MockCatMockRecorder {
 	return m.recorder
 }
 
+// AddCatChange mocks base method.
+func (m *MockCat) AddCatChange(ctx context.Context, change *model.CatChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCatChange", ctx, change)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddCatChange indicates an expected call of AddCatChange.
+func (mr *MockCatMockRecorder) AddCatChange(ctx, change interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCatChange", reflect.TypeOf((*MockCat)(nil).AddCatChange), ctx, change)
+}
+
+// AnonymizeCatChanges mocks base method.
+func (m *MockCat) AnonymizeCatChanges(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnonymizeCatChanges", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AnonymizeCatChanges indicates an expected call of AnonymizeCatChanges.
+func (mr *MockCatMockRecorder) AnonymizeCatChanges(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnonymizeCatChanges", reflect.TypeOf((*MockCat)(nil).AnonymizeCatChanges), ctx, userID)
+}
+
 // Create mocks base method.
 func (m *MockCat) Create(ctx context.Context, cat *model.Cat) error {
 	m.ctrl.T.Helper()
@@ -51,62 +80,152 @@ func (mr *MockCatMockRecorder) Create(ctx, cat interface{}) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockCat) Delete(ctx context.Context, id string) error {
+func (m *MockCat) Delete(ctx context.Context, ownerID, id string, version int64, deletedAt time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, ownerID, id, version, deletedAt)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockCatMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *MockCatMockRecorder) Delete(ctx, ownerID, id, version, deletedAt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCat)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCat)(nil).Delete), ctx, ownerID, id, version, deletedAt)
+}
+
+// DisownCats mocks base method.
+func (m *MockCat) DisownCats(ctx context.Context, ownerID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisownCats", ctx, ownerID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DisownCats indicates an expected call of DisownCats.
+func (mr *MockCatMockRecorder) DisownCats(ctx, ownerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisownCats", reflect.TypeOf((*MockCat)(nil).DisownCats), ctx, ownerID)
 }
 
 // Get mocks base method.
-func (m *MockCat) Get(ctx context.Context, id string) (*model.Cat, error) {
+func (m *MockCat) Get(ctx context.Context, ownerID, id string) (*model.Cat, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret := m.ctrl.Call(m, "Get", ctx, ownerID, id)
 	ret0, _ := ret[0].(*model.Cat)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockCatMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+func (mr *MockCatMockRecorder) Get(ctx, ownerID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCat)(nil).Get), ctx, ownerID, id)
+}
+
+// GetTrashed mocks base method.
+func (m *MockCat) GetTrashed(ctx context.Context, ownerID, id string) (*model.Cat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTrashed", ctx, ownerID, id)
+	ret0, _ := ret[0].(*model.Cat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTrashed indicates an expected call of GetTrashed.
+func (mr *MockCatMockRecorder) GetTrashed(ctx, ownerID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTrashed", reflect.TypeOf((*MockCat)(nil).GetTrashed), ctx, ownerID, id)
+}
+
+// List mocks base method.
+func (m *MockCat) List(ctx context.Context, input *model.ListCats) (*model.CatsPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, input)
+	ret0, _ := ret[0].(*model.CatsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockCatMockRecorder) List(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockCat)(nil).List), ctx, input)
+}
+
+// ListCatChanges mocks base method.
+func (m *MockCat) ListCatChanges(ctx context.Context, input *model.ListCatChanges) (*model.CatChangesPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCatChanges", ctx, input)
+	ret0, _ := ret[0].(*model.CatChangesPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCatChanges indicates an expected call of ListCatChanges.
+func (mr *MockCatMockRecorder) ListCatChanges(ctx, input interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCat)(nil).Get), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCatChanges", reflect.TypeOf((*MockCat)(nil).ListCatChanges), ctx, input)
+}
+
+// PurgeCats mocks base method.
+func (m *MockCat) PurgeCats(ctx context.Context, deletedBefore time.Time) ([]*model.Cat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeCats", ctx, deletedBefore)
+	ret0, _ := ret[0].([]*model.Cat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeCats indicates an expected call of PurgeCats.
+func (mr *MockCatMockRecorder) PurgeCats(ctx, deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeCats", reflect.TypeOf((*MockCat)(nil).PurgeCats), ctx, deletedBefore)
+}
+
+// Restore mocks base method.
+func (m *MockCat) Restore(ctx context.Context, ownerID, id string) (*model.Cat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, ownerID, id)
+	ret0, _ := ret[0].(*model.Cat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockCatMockRecorder) Restore(ctx, ownerID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockCat)(nil).Restore), ctx, ownerID, id)
 }
 
 // Update mocks base method.
-func (m *MockCat) Update(ctx context.Context, id string, input *model.UpdateCat) (*model.Cat, error) {
+func (m *MockCat) Update(ctx context.Context, ownerID, id string, version int64, input *model.UpdateCat) (*model.Cat, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", ctx, id, input)
+	ret := m.ctrl.Call(m, "Update", ctx, ownerID, id, version, input)
 	ret0, _ := ret[0].(*model.Cat)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockCatMockRecorder) Update(ctx, id, input interface{}) *gomock.Call {
+func (mr *MockCatMockRecorder) Update(ctx, ownerID, id, version, input interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockCat)(nil).Update), ctx, id, input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockCat)(nil).Update), ctx, ownerID, id, version, input)
 }
 
 // UploadImage mocks base method.
-func (m *MockCat) UploadImage(ctx context.Context, id, path string) (*model.Cat, error) {
+func (m *MockCat) UploadImage(ctx context.Context, ownerID, id string, version int64, path string) (*model.Cat, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UploadImage", ctx, id, path)
+	ret := m.ctrl.Call(m, "UploadImage", ctx, ownerID, id, version, path)
 	ret0, _ := ret[0].(*model.Cat)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UploadImage indicates an expected call of UploadImage.
-func (mr *MockCatMockRecorder) UploadImage(ctx, id, path interface{}) *gomock.Call {
+func (mr *MockCatMockRecorder) UploadImage(ctx, ownerID, id, version, path interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadImage", reflect.TypeOf((*MockCat)(nil).UploadImage), ctx, id, path)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadImage", reflect.TypeOf((*MockCat)(nil).UploadImage), ctx, ownerID, id, version, path)
 }
 
 // MockAuth is a mock of Auth interface.
@@ -132,6 +251,91 @@ func (m *MockAuth) EXPECT() *MockAuthMockRecorder {
 	return m.recorder
 }
 
+// ConsumeOIDCLogin mocks base method.
+func (m *MockAuth) ConsumeOIDCLogin(ctx context.Context, stateHash string) (*model.OIDCLogin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOIDCLogin", ctx, stateHash)
+	ret0, _ := ret[0].(*model.OIDCLogin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOIDCLogin indicates an expected call of ConsumeOIDCLogin.
+func (mr *MockAuthMockRecorder) ConsumeOIDCLogin(ctx, stateHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOIDCLogin", reflect.TypeOf((*MockAuth)(nil).ConsumeOIDCLogin), ctx, stateHash)
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockAuth) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockAuthMockRecorder) CreateAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockAuth)(nil).CreateAPIKey), ctx, key)
+}
+
+// CreateEmailVerification mocks base method.
+func (m *MockAuth) CreateEmailVerification(ctx context.Context, verification *model.EmailVerification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEmailVerification", ctx, verification)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateEmailVerification indicates an expected call of CreateEmailVerification.
+func (mr *MockAuthMockRecorder) CreateEmailVerification(ctx, verification interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEmailVerification", reflect.TypeOf((*MockAuth)(nil).CreateEmailVerification), ctx, verification)
+}
+
+// CreateIdentity mocks base method.
+func (m *MockAuth) CreateIdentity(ctx context.Context, identity *model.Identity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdentity", ctx, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateIdentity indicates an expected call of CreateIdentity.
+func (mr *MockAuthMockRecorder) CreateIdentity(ctx, identity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdentity", reflect.TypeOf((*MockAuth)(nil).CreateIdentity), ctx, identity)
+}
+
+// CreateOIDCLogin mocks base method.
+func (m *MockAuth) CreateOIDCLogin(ctx context.Context, login *model.OIDCLogin) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOIDCLogin", ctx, login)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOIDCLogin indicates an expected call of CreateOIDCLogin.
+func (mr *MockAuthMockRecorder) CreateOIDCLogin(ctx, login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOIDCLogin", reflect.TypeOf((*MockAuth)(nil).CreateOIDCLogin), ctx, login)
+}
+
+// CreatePasswordReset mocks base method.
+func (m *MockAuth) CreatePasswordReset(ctx context.Context, reset *model.PasswordReset) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordReset", ctx, reset)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePasswordReset indicates an expected call of CreatePasswordReset.
+func (mr *MockAuthMockRecorder) CreatePasswordReset(ctx, reset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordReset", reflect.TypeOf((*MockAuth)(nil).CreatePasswordReset), ctx, reset)
+}
+
 // CreateUser mocks base method.
 func (m *MockAuth) CreateUser(ctx context.Context, input *repository.CreateUserInput) error {
 	m.ctrl.T.Helper()
@@ -146,6 +350,122 @@ func (mr *MockAuthMockRecorder) CreateUser(ctx, input interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockAuth)(nil).CreateUser), ctx, input)
 }
 
+// DeleteAPIKey mocks base method.
+func (m *MockAuth) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", ctx, userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey.
+func (mr *MockAuthMockRecorder) DeleteAPIKey(ctx, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockAuth)(nil).DeleteAPIKey), ctx, userID, id)
+}
+
+// DeleteTwoFactor mocks base method.
+func (m *MockAuth) DeleteTwoFactor(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTwoFactor", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTwoFactor indicates an expected call of DeleteTwoFactor.
+func (mr *MockAuthMockRecorder) DeleteTwoFactor(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTwoFactor", reflect.TypeOf((*MockAuth)(nil).DeleteTwoFactor), ctx, userID)
+}
+
+// DeleteUser mocks base method.
+func (m *MockAuth) DeleteUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockAuthMockRecorder) DeleteUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockAuth)(nil).DeleteUser), ctx, id)
+}
+
+// EnableTwoFactor mocks base method.
+func (m *MockAuth) EnableTwoFactor(ctx context.Context, userID string, recoveryCodes []string, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTwoFactor", ctx, userID, recoveryCodes, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTwoFactor indicates an expected call of EnableTwoFactor.
+func (mr *MockAuthMockRecorder) EnableTwoFactor(ctx, userID, recoveryCodes, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTwoFactor", reflect.TypeOf((*MockAuth)(nil).EnableTwoFactor), ctx, userID, recoveryCodes, step)
+}
+
+// GetAPIKey mocks base method.
+func (m *MockAuth) GetAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKey", ctx, keyHash)
+	ret0, _ := ret[0].(*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKey indicates an expected call of GetAPIKey.
+func (mr *MockAuthMockRecorder) GetAPIKey(ctx, keyHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKey", reflect.TypeOf((*MockAuth)(nil).GetAPIKey), ctx, keyHash)
+}
+
+// GetIdentity mocks base method.
+func (m *MockAuth) GetIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdentity", ctx, issuer, subject)
+	ret0, _ := ret[0].(*model.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdentity indicates an expected call of GetIdentity.
+func (mr *MockAuthMockRecorder) GetIdentity(ctx, issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdentity", reflect.TypeOf((*MockAuth)(nil).GetIdentity), ctx, issuer, subject)
+}
+
+// GetTwoFactor mocks base method.
+func (m *MockAuth) GetTwoFactor(ctx context.Context, userID string) (*model.TwoFactor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTwoFactor", ctx, userID)
+	ret0, _ := ret[0].(*model.TwoFactor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTwoFactor indicates an expected call of GetTwoFactor.
+func (mr *MockAuthMockRecorder) GetTwoFactor(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTwoFactor", reflect.TypeOf((*MockAuth)(nil).GetTwoFactor), ctx, userID)
+}
+
+// GetUser mocks base method.
+func (m *MockAuth) GetUser(ctx context.Context, id string) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", ctx, id)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockAuthMockRecorder) GetUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockAuth)(nil).GetUser), ctx, id)
+}
+
 // GetUserHashedPassword mocks base method.
 func (m *MockAuth) GetUserHashedPassword(ctx context.Context, email string) (string, string, error) {
 	m.ctrl.T.Helper()
@@ -162,31 +482,268 @@ func (mr *MockAuthMockRecorder) GetUserHashedPassword(ctx, email interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserHashedPassword", reflect.TypeOf((*MockAuth)(nil).GetUserHashedPassword), ctx, email)
 }
 
-// GetUserRefreshToken mocks base method.
-func (m *MockAuth) GetUserRefreshToken(ctx context.Context, id string) (string, error) {
+// ListAPIKeys mocks base method.
+func (m *MockAuth) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx, userID)
+	ret0, _ := ret[0].([]*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockAuthMockRecorder) ListAPIKeys(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockAuth)(nil).ListAPIKeys), ctx, userID)
+}
+
+// ListUsers mocks base method.
+func (m *MockAuth) ListUsers(ctx context.Context, input *model.ListUsers) (*model.UsersPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserRefreshToken", ctx, id)
+	ret := m.ctrl.Call(m, "ListUsers", ctx, input)
+	ret0, _ := ret[0].(*model.UsersPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockAuthMockRecorder) ListUsers(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockAuth)(nil).ListUsers), ctx, input)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAuth) ResetPassword(ctx context.Context, tokenHash, password string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, tokenHash, password)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserRefreshToken indicates an expected call of GetUserRefreshToken.
-func (mr *MockAuthMockRecorder) GetUserRefreshToken(ctx, id interface{}) *gomock.Call {
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAuthMockRecorder) ResetPassword(ctx, tokenHash, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAuth)(nil).ResetPassword), ctx, tokenHash, password)
+}
+
+// SaveTwoFactor mocks base method.
+func (m *MockAuth) SaveTwoFactor(ctx context.Context, twoFactor *model.TwoFactor) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTwoFactor", ctx, twoFactor)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTwoFactor indicates an expected call of SaveTwoFactor.
+func (mr *MockAuthMockRecorder) SaveTwoFactor(ctx, twoFactor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTwoFactor", reflect.TypeOf((*MockAuth)(nil).SaveTwoFactor), ctx, twoFactor)
+}
+
+// SetUserDisabled mocks base method.
+func (m *MockAuth) SetUserDisabled(ctx context.Context, id string, disabled bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserDisabled", ctx, id, disabled)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserDisabled indicates an expected call of SetUserDisabled.
+func (mr *MockAuthMockRecorder) SetUserDisabled(ctx, id, disabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserDisabled", reflect.TypeOf((*MockAuth)(nil).SetUserDisabled), ctx, id, disabled)
+}
+
+// TouchAPIKey mocks base method.
+func (m *MockAuth) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAPIKey", ctx, id, usedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAPIKey indicates an expected call of TouchAPIKey.
+func (mr *MockAuthMockRecorder) TouchAPIKey(ctx, id, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAPIKey", reflect.TypeOf((*MockAuth)(nil).TouchAPIKey), ctx, id, usedAt)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockAuth) UpdatePassword(ctx context.Context, id, oldHash, newHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, id, oldHash, newHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockAuthMockRecorder) UpdatePassword(ctx, id, oldHash, newHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockAuth)(nil).UpdatePassword), ctx, id, oldHash, newHash)
+}
+
+// UpdateUserName mocks base method.
+func (m *MockAuth) UpdateUserName(ctx context.Context, id, userName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserName", ctx, id, userName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserName indicates an expected call of UpdateUserName.
+func (mr *MockAuthMockRecorder) UpdateUserName(ctx, id, userName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserName", reflect.TypeOf((*MockAuth)(nil).UpdateUserName), ctx, id, userName)
+}
+
+// UpdateUserRole mocks base method.
+func (m *MockAuth) UpdateUserRole(ctx context.Context, id, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserRole", ctx, id, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserRole indicates an expected call of UpdateUserRole.
+func (mr *MockAuthMockRecorder) UpdateUserRole(ctx, id, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRole", reflect.TypeOf((*MockAuth)(nil).UpdateUserRole), ctx, id, role)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockAuth) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userID, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockAuthMockRecorder) UseRecoveryCode(ctx, userID, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockAuth)(nil).UseRecoveryCode), ctx, userID, codeHash)
+}
+
+// UseTwoFactorStep mocks base method.
+func (m *MockAuth) UseTwoFactorStep(ctx context.Context, userID string, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTwoFactorStep", ctx, userID, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseTwoFactorStep indicates an expected call of UseTwoFactorStep.
+func (mr *MockAuthMockRecorder) UseTwoFactorStep(ctx, userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTwoFactorStep", reflect.TypeOf((*MockAuth)(nil).UseTwoFactorStep), ctx, userID, step)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockAuth) VerifyEmail(ctx context.Context, tokenHash string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, tokenHash)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockAuthMockRecorder) VerifyEmail(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockAuth)(nil).VerifyEmail), ctx, tokenHash)
+}
+
+// MockSession is a mock of Session interface.
+type MockSession struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionMockRecorder
+}
+
+// MockSessionMockRecorder is the mock recorder for MockSession.
+type MockSessionMockRecorder struct {
+	mock *MockSession
+}
+
+// NewMockSession creates a new mock instance.
+func NewMockSession(ctrl *gomock.Controller) *MockSession {
+	mock := &MockSession{ctrl: ctrl}
+	mock.recorder = &MockSessionMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSession) EXPECT() *MockSessionMockRecorder {
+	return m.recorder
+}
+
+// CreateSession mocks base method.
+func (m *MockSession) CreateSession(ctx context.Context, session *model.Session) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, session)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockSessionMockRecorder) CreateSession(ctx, session interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockSession)(nil).CreateSession), ctx, session)
+}
+
+// DeleteSession mocks base method.
+func (m *MockSession) DeleteSession(ctx context.Context, userID, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSession", ctx, userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSession indicates an expected call of DeleteSession.
+func (mr *MockSessionMockRecorder) DeleteSession(ctx, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockSession)(nil).DeleteSession), ctx, userID, id)
+}
+
+// DeleteUserSessions mocks base method.
+func (m *MockSession) DeleteUserSessions(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserSessions", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserSessions indicates an expected call of DeleteUserSessions.
+func (mr *MockSessionMockRecorder) DeleteUserSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserSessions", reflect.TypeOf((*MockSession)(nil).DeleteUserSessions), ctx, userID)
+}
+
+// ListSessions mocks base method.
+func (m *MockSession) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, userID)
+	ret0, _ := ret[0].([]*model.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockSessionMockRecorder) ListSessions(ctx, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserRefreshToken", reflect.TypeOf((*MockAuth)(nil).GetUserRefreshToken), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockSession)(nil).ListSessions), ctx, userID)
 }
 
-// UpdateUserRefreshToken mocks base method.
-func (m *MockAuth) UpdateUserRefreshToken(ctx context.Context, refreshToken, id string) error {
+// RotateSession mocks base method.
+func (m *MockSession) RotateSession(ctx context.Context, oldTokenHash string, session *model.Session) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUserRefreshToken", ctx, refreshToken, id)
+	ret := m.ctrl.Call(m, "RotateSession", ctx, oldTokenHash, session)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateUserRefreshToken indicates an expected call of UpdateUserRefreshToken.
-func (mr *MockAuthMockRecorder) UpdateUserRefreshToken(ctx, refreshToken, id interface{}) *gomock.Call {
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockSessionMockRecorder) RotateSession(ctx, oldTokenHash, session interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRefreshToken", reflect.TypeOf((*MockAuth)(nil).UpdateUserRefreshToken), ctx, refreshToken, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockSession)(nil).RotateSession), ctx, oldTokenHash, session)
 }
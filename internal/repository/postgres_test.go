@@ -5,14 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/migration"
 	"github.com/malkev1ch/first-task/internal/model"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
@@ -20,8 +23,9 @@ import (
 )
 
 var (
-	db   *pgxpool.Pool
-	repo *Repository
+	db        *pgxpool.Pool
+	repo      *Repository
+	mongoRepo *Repository
 )
 
 func TestMain(m *testing.M) {
@@ -73,14 +77,51 @@ func TestMain(m *testing.M) {
 
 	log.Info("Created postgres repository successfully")
 
-	flywayPath := fmt.Sprintf("-url=jdbc:postgresql://%s/postgres", hostAndPort)
-	flywayConfFile := "-configFiles=/home/andreimalkevich/first-task/sql/dockertest/flyway.conf"
-	cmd := exec.Command("flyway", flywayPath, flywayConfFile, "migrate")
-	log.Infof("Start migration with cmd command: %s", cmd.String())
-	if err := cmd.Run(); err != nil {
-		log.Fatalf("Command finished with error: %v", err)
+	postgresDriver, err := migration.NewPostgres(db)
+	if err != nil {
+		log.Fatalf("Could not load postgres migrations: %s", err)
+	}
+	if err := migration.NewMigrator(postgresDriver).Up(context.Background()); err != nil {
+		log.Fatalf("Could not migrate postgres: %s", err)
+	}
+
+	mongoResource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "mongo",
+		Tag:        "5.0",
+		Env: []string{
+			"MONGO_INITDB_ROOT_USERNAME=admin",
+			"MONGO_INITDB_ROOT_PASSWORD=qwerty",
+		},
+	}, func(config *docker.HostConfig) {
+		// set AutoRemove to true so that stopped container goes away by itself
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		log.Fatalf("Could not start mongo: %s", err)
 	}
 
+	mongoResource.Expire(120) // Tell docker to hard kill the container in 120 seconds
+
+	mongoURL := fmt.Sprintf("mongodb://admin:qwerty@%s/", mongoResource.GetHostPort("27017/tcp"))
+	var mongoClient *mongo.Client
+	if err = pool.Retry(func() error {
+		mongoClient, err = mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURL))
+		if err != nil {
+			return err
+		}
+		return mongoClient.Ping(context.Background(), nil)
+	}); err != nil {
+		log.Fatalf("Could not connect to mongo: %s", err)
+	}
+	if err := migration.NewMigrator(migration.NewMongo(mongoClient)).Up(context.Background()); err != nil {
+		log.Fatalf("Could not migrate mongo: %s", err)
+	}
+
+	mongoRepo = NewRepositoryMongo(mongoClient)
+
+	log.Info("Created mongo repository successfully")
+
 	code := m.Run()
 
 	// You can't defer this because os.Exit doesn't care for defer
@@ -88,6 +129,10 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Could not purge resource: %s", err)
 	}
 
+	if err := pool.Purge(mongoResource); err != nil {
+		log.Fatalf("Could not purge mongo: %s", err)
+	}
+
 	os.Exit(code)
 }
 
@@ -103,11 +148,10 @@ func TestCreateUser(t *testing.T) {
 		{
 			name: "OK",
 			input: &CreateUserInput{
-				ID:           id,
-				UserName:     "Some Name",
-				Email:        "example@outlook.com",
-				Password:     "qwerty",
-				RefreshToken: "1234",
+				ID:       id,
+				UserName: "Some Name",
+				Email:    "example@outlook.com",
+				Password: "qwerty",
 			},
 			ctx:           ctx,
 			expectedError: nil,
@@ -115,11 +159,10 @@ func TestCreateUser(t *testing.T) {
 		{
 			name: "Invalid UUID",
 			input: &CreateUserInput{
-				ID:           "123",
-				UserName:     "Some Name",
-				Email:        "example@gmail.com",
-				Password:     "qwerty",
-				RefreshToken: "1234",
+				ID:       "123",
+				UserName: "Some Name",
+				Email:    "example@gmail.com",
+				Password: "qwerty",
 			},
 			ctx:           ctx,
 			expectedError: errors.New("can't create User"),
@@ -127,26 +170,24 @@ func TestCreateUser(t *testing.T) {
 		{
 			name: "User with given email exists",
 			input: &CreateUserInput{
-				ID:           uuid.New().String(),
-				UserName:     "Some Name",
-				Email:        "example@outlook.com",
-				Password:     "qwerty",
-				RefreshToken: "1234",
+				ID:       uuid.New().String(),
+				UserName: "Some Name",
+				Email:    "example@outlook.com",
+				Password: "qwerty",
 			},
 			ctx:           ctx,
-			expectedError: errors.New("user with given email exists, change your email"),
+			expectedError: apperror.Conflict("user with given email exists, change your email"),
 		},
 		{
 			name: "User with given UUID exists",
 			input: &CreateUserInput{
-				ID:           id,
-				UserName:     "Some Name",
-				Email:        "example@outlook.com",
-				Password:     "qwerty",
-				RefreshToken: "1234",
+				ID:       id,
+				UserName: "Some Name",
+				Email:    "example@outlook.com",
+				Password: "qwerty",
 			},
 			ctx:           ctx,
-			expectedError: errors.New("user with given UUID exists, try to create again"),
+			expectedError: apperror.Conflict("user with given UUID exists, try to create again"),
 		},
 	}
 	for _, testCase := range testTable {
@@ -161,11 +202,10 @@ func TestGetUserHashedPassword(t *testing.T) {
 	ctx := context.Background()
 	id := uuid.New().String()
 	err := repo.Auth.CreateUser(context.Background(), &CreateUserInput{
-		ID:           id,
-		UserName:     "Some Name",
-		Email:        "TestGetUserHashedPassword@outlook.com",
-		Password:     "qwerty",
-		RefreshToken: "1234",
+		ID:       id,
+		UserName: "Some Name",
+		Email:    "TestGetUserHashedPassword@outlook.com",
+		Password: "qwerty",
 	})
 	if err != nil {
 		t.Fail()
@@ -187,7 +227,7 @@ func TestGetUserHashedPassword(t *testing.T) {
 			name:          "User doesn't exist",
 			email:         "",
 			ctx:           ctx,
-			expectedError: errors.New("user with given email doesn't exist"),
+			expectedError: apperror.NotFound("user with given email doesn't exist"),
 		},
 	}
 	for _, testCase := range testTable {
@@ -198,92 +238,67 @@ func TestGetUserHashedPassword(t *testing.T) {
 	}
 }
 
-func TestGetUserRefreshToken(t *testing.T) {
+func TestRotateSession(t *testing.T) {
 	ctx := context.Background()
 	id := uuid.New().String()
 	err := repo.Auth.CreateUser(context.Background(), &CreateUserInput{
-		ID:           id,
-		UserName:     "Some Name",
-		Email:        "TestGetUserRefreshToken@outlook.com",
-		Password:     "qwerty",
-		RefreshToken: "1234",
+		ID:       id,
+		UserName: "Some Name",
+		Email:    "TestRotateSession@outlook.com",
+		Password: "qwerty",
+	})
+	if err != nil {
+		t.Fail()
+	}
+	sessionID := uuid.New().String()
+	now := time.Now().UTC()
+	err = repo.Session.CreateSession(ctx, &model.Session{
+		ID: sessionID, UserID: id, TokenHash: "1234",
+		CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour),
 	})
 	if err != nil {
 		t.Fail()
 	}
 
 	testTable := []struct {
-		userID        string
 		name          string
+		oldTokenHash  string
+		session       *model.Session
 		ctx           context.Context
 		expectedError error
 	}{
 		{
 			name:          "OK",
-			userID:        id,
+			oldTokenHash:  "1234",
+			session:       &model.Session{ID: sessionID, UserID: id, TokenHash: "5678", ExpiresAt: now.Add(time.Hour)},
 			ctx:           ctx,
 			expectedError: nil,
 		},
 		{
-			name:          "User doesn't exist",
-			userID:        uuid.New().String(),
+			name:          "Token has been already rotated",
+			oldTokenHash:  "1234",
+			session:       &model.Session{ID: sessionID, UserID: id, TokenHash: "5678", ExpiresAt: now.Add(time.Hour)},
 			ctx:           ctx,
-			expectedError: errors.New("user with given UUID doesn't exist"),
+			expectedError: apperror.Conflict("refresh token has been already used"),
 		},
 		{
-			name:          "Invalid UUID",
-			userID:        "",
+			name:          "Session doesn't exist",
+			oldTokenHash:  "5678",
+			session:       &model.Session{ID: uuid.New().String(), UserID: id, TokenHash: "9012"},
 			ctx:           ctx,
-			expectedError: errors.New("can't get users refresh token"),
-		},
-	}
-	for _, testCase := range testTable {
-		t.Run(testCase.name, func(t *testing.T) {
-			_, err := repo.Auth.GetUserRefreshToken(testCase.ctx, testCase.userID)
-			assert.Equal(t, testCase.expectedError, err)
-		})
-	}
-}
-
-func TestUpdateUserRefreshToken(t *testing.T) {
-	ctx := context.Background()
-	id := uuid.New().String()
-	err := repo.Auth.CreateUser(context.Background(), &CreateUserInput{
-		ID:           id,
-		UserName:     "Some Name",
-		Email:        "TestUpdateUserRefreshToken@outlook.com",
-		Password:     "qwerty",
-		RefreshToken: "1234",
-	})
-	if err != nil {
-		t.Fail()
-	}
-
-	testTable := []struct {
-		userID        string
-		refreshToken  string
-		name          string
-		ctx           context.Context
-		expectedError error
-	}{
-		{
-			name:          "OK",
-			userID:        id,
-			refreshToken:  "",
-			ctx:           ctx,
-			expectedError: nil,
+			expectedError: apperror.NotFound("session with given UUID doesn't exist"),
 		},
 		{
 			name:          "Invalid UUID",
-			userID:        "",
-			refreshToken:  "",
+			oldTokenHash:  "5678",
+			session:       &model.Session{ID: "", UserID: id, TokenHash: "9012"},
 			ctx:           ctx,
-			expectedError: errors.New("can't update refresh token"),
+			expectedError: errors.New("can't rotate session"),
 		},
 	}
 	for _, testCase := range testTable {
 		t.Run(testCase.name, func(t *testing.T) {
-			err := repo.Auth.UpdateUserRefreshToken(testCase.ctx, testCase.userID, testCase.refreshToken)
+			err := repo.Session.RotateSession(testCase.ctx, testCase.oldTokenHash, testCase.session)
 			assert.Equal(t, testCase.expectedError, err)
 		})
 	}
@@ -312,7 +327,7 @@ func TestCreateCat(t *testing.T) {
 				ID: id,
 			},
 			ctx:           ctx,
-			expectedError: errors.New("cat with given UUID already exists, try to create again"),
+			expectedError: apperror.Conflict("cat with given UUID already exists, try to create again"),
 		},
 		{
 			name:          "Invalid UUID",
@@ -354,7 +369,7 @@ func TestGetCat(t *testing.T) {
 			name:          "Cat with given UUID doesn't exist",
 			input:         uuid.New().String(),
 			ctx:           ctx,
-			expectedError: errors.New("cat with given UUID doesn't exist"),
+			expectedError: apperror.NotFound("cat with given UUID doesn't exist"),
 		},
 		{
 			name:          "Invalid UUID",
@@ -365,7 +380,7 @@ func TestGetCat(t *testing.T) {
 	}
 	for _, testCase := range testTable {
 		t.Run(testCase.name, func(t *testing.T) {
-			_, err := repo.Cat.Get(testCase.ctx, testCase.input)
+			_, err := repo.Cat.Get(testCase.ctx, "", testCase.input)
 			assert.Equal(t, testCase.expectedError, err)
 		})
 	}
@@ -410,19 +425,19 @@ func TestUpdateCat(t *testing.T) {
 			catID:         uuid.New().String(),
 			input:         testUpdateCat,
 			ctx:           ctx,
-			expectedError: errors.New("cat with given UUID doesn't exists"),
+			expectedError: apperror.NotFound("cat with given UUID doesn't exist"),
 		},
 		{
 			name:          "Invalid UUID",
 			catID:         uuid.New().String(),
 			input:         testUpdateCat,
 			ctx:           ctx,
-			expectedError: errors.New("cat with given UUID doesn't exists"),
+			expectedError: apperror.NotFound("cat with given UUID doesn't exist"),
 		},
 	}
 	for _, testCase := range testTable {
 		t.Run(testCase.name, func(t *testing.T) {
-			_, err := repo.Cat.Update(testCase.ctx, testCase.catID, testCase.input)
+			_, err := repo.Cat.Update(testCase.ctx, "", testCase.catID, 0, testCase.input)
 
 			assert.Equal(t, testCase.expectedError, err)
 		})
@@ -459,8 +474,88 @@ func TestDeleteCat(t *testing.T) {
 	}
 	for _, testCase := range testTable {
 		t.Run(testCase.name, func(t *testing.T) {
-			err := repo.Cat.Delete(testCase.ctx, testCase.catID)
+			err := repo.Cat.Delete(testCase.ctx, "", testCase.catID, 0, time.Now().UTC())
 			assert.Equal(t, testCase.expectedError, err)
 		})
 	}
 }
+
+func TestListCats(t *testing.T) {
+	ctx := context.Background()
+	prefix := uuid.New().String()
+	for i, name := range []string{"b", "a", "c"} {
+		err := repo.Cat.Create(context.Background(), &model.Cat{
+			ID:         uuid.New().String(),
+			Name:       prefix + name,
+			DateBirth:  time.Date(2018, 9, 22+i, 0, 0, 0, 0, time.UTC),
+			Vaccinated: i%2 == 0,
+		})
+		if err != nil {
+			t.Fail()
+		}
+	}
+	vaccinated := true
+
+	testTable := []struct {
+		name          string
+		input         *model.ListCats
+		ctx           context.Context
+		expectedNames []string
+		expectedError error
+	}{
+		{
+			name:          "OK",
+			input:         &model.ListCats{Limit: 10, NamePrefix: prefix},
+			ctx:           ctx,
+			expectedNames: []string{prefix + "a", prefix + "b", prefix + "c"},
+			expectedError: nil,
+		},
+		{
+			name:          "Sort by date birth desc",
+			input:         &model.ListCats{Limit: 10, NamePrefix: prefix, SortBy: model.CatsSortByDateBirth, SortDesc: true},
+			ctx:           ctx,
+			expectedNames: []string{prefix + "c", prefix + "a", prefix + "b"},
+			expectedError: nil,
+		},
+		{
+			name:          "Filter by vaccinated",
+			input:         &model.ListCats{Limit: 10, NamePrefix: prefix, Vaccinated: &vaccinated},
+			ctx:           ctx,
+			expectedNames: []string{prefix + "b", prefix + "c"},
+			expectedError: nil,
+		},
+		{
+			name:          "Invalid cursor",
+			input:         &model.ListCats{Limit: 10, Cursor: "qwerty"},
+			ctx:           ctx,
+			expectedNames: nil,
+			expectedError: apperror.Validation("invalid cursor"),
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			page, err := repo.Cat.List(testCase.ctx, testCase.input)
+			assert.Equal(t, testCase.expectedError, err)
+			if err == nil {
+				names := make([]string, 0, len(page.Cats))
+				for _, cat := range page.Cats {
+					names = append(names, cat.Name)
+				}
+				assert.Equal(t, testCase.expectedNames, names)
+			}
+		})
+	}
+
+	t.Run("Pagination", func(t *testing.T) {
+		first, err := repo.Cat.List(ctx, &model.ListCats{Limit: 2, NamePrefix: prefix})
+		assert.NoError(t, err)
+		assert.Len(t, first.Cats, 2)
+		assert.NotEmpty(t, first.NextCursor)
+
+		second, err := repo.Cat.List(ctx, &model.ListCats{Limit: 2, NamePrefix: prefix, Cursor: first.NextCursor})
+		assert.NoError(t, err)
+		assert.Len(t, second.Cats, 1)
+		assert.Equal(t, prefix+"c", second.Cats[0].Name)
+		assert.Empty(t, second.NextCursor)
+	})
+}
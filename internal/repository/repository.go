@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -12,43 +13,154 @@ import (
 //go:generate mockgen -source=repository.go -destination=mocks/repository_mock.go
 
 type CreateUserInput struct {
-	ID           string
-	UserName     string
-	Email        string
-	Password     string
-	RefreshToken string
+	ID            string
+	UserName      string
+	Email         string
+	Password      string
+	Role          string
+	EmailVerified bool
+	Disabled      bool
 }
 
+// Cat is the storage of cats. Methods taking ownerID see only cats of the owner
+// and return not found error for cats of other users, empty ownerID means any owner.
+// Every change of a cat increases its version. Methods taking version change the cat only
+// if it still has the version and return precondition failed error otherwise, zero version
+// changes the cat whatever version it has.
 type Cat interface {
+	// Create saves the cat with version 1.
 	Create(ctx context.Context, cat *model.Cat) error
-	Get(ctx context.Context, id string) (*model.Cat, error)
-	Update(ctx context.Context, id string, input *model.UpdateCat) (*model.Cat, error)
-	Delete(ctx context.Context, id string) error
-	UploadImage(ctx context.Context, id string, path string) (*model.Cat, error)
+	Get(ctx context.Context, ownerID, id string) (*model.Cat, error)
+	Update(ctx context.Context, ownerID, id string, version int64, input *model.UpdateCat) (*model.Cat, error)
+	// Delete moves the cat to trash. Cats in trash are seen only by GetTrashed, Restore, PurgeCats
+	// and List of deleted cats, other methods treat them as not existing.
+	Delete(ctx context.Context, ownerID, id string, version int64, deletedAt time.Time) error
+	UploadImage(ctx context.Context, ownerID, id string, version int64, path string) (*model.Cat, error)
+	List(ctx context.Context, input *model.ListCats) (*model.CatsPage, error)
+	// GetTrashed returns the cat in trash.
+	GetTrashed(ctx context.Context, ownerID, id string) (*model.Cat, error)
+	// Restore moves the cat out of trash and returns it.
+	Restore(ctx context.Context, ownerID, id string) (*model.Cat, error)
+	// PurgeCats deletes cats moved to trash before the time for good and returns them.
+	PurgeCats(ctx context.Context, deletedBefore time.Time) ([]*model.Cat, error)
+	// DisownCats leaves cats of the owner without owner, so only admins can manage them,
+	// and returns UUIDs of the cats.
+	DisownCats(ctx context.Context, ownerID string) ([]string, error)
+	// AddCatChange appends the change to history of cats, the history is kept after cats are deleted.
+	AddCatChange(ctx context.Context, change *model.CatChange) error
+	// ListCatChanges returns page of changes of the cat matching the input, the latest changes go first.
+	ListCatChanges(ctx context.Context, input *model.ListCatChanges) (*model.CatChangesPage, error)
+	// AnonymizeCatChanges removes the user from history of cats, the user is left out as actor
+	// and owner of changes and as owner of cats before and after them.
+	AnonymizeCatChanges(ctx context.Context, userID string) error
 }
 
 type Auth interface {
 	CreateUser(ctx context.Context, input *CreateUserInput) error
 	GetUserHashedPassword(ctx context.Context, email string) (string, string, error)
-	UpdateUserRefreshToken(ctx context.Context, refreshToken, id string) error
-	GetUserRefreshToken(ctx context.Context, id string) (string, error)
+	GetUser(ctx context.Context, id string) (*model.User, error)
+	UpdateUserRole(ctx context.Context, id, role string) error
+	UpdateUserName(ctx context.Context, id, userName string) error
+	// SetUserDisabled disables or enables the user.
+	SetUserDisabled(ctx context.Context, id string, disabled bool) error
+	// ListUsers returns page of users matching the input sorted by email.
+	ListUsers(ctx context.Context, input *model.ListUsers) (*model.UsersPage, error)
+	// DeleteUser deletes the user with reset and verification tokens, two-factor authentication,
+	// API keys and linked identities of the user. Sessions and cats of the user are left to their storages.
+	DeleteUser(ctx context.Context, id string) error
+	// CreatePasswordReset saves reset token of the user. Tokens issued earlier
+	// stay valid until they expire or one of them is used.
+	CreatePasswordReset(ctx context.Context, reset *model.PasswordReset) error
+	// ResetPassword replaces password of the user the not expired reset token with tokenHash
+	// is issued to, deletes all reset tokens of the user and returns id of the user.
+	// Unknown, used and expired tokens are treated as not existing.
+	ResetPassword(ctx context.Context, tokenHash, password string) (string, error)
+	// UpdatePassword replaces hashed password of the user if it still equals oldHash,
+	// so a password changed meanwhile isn't overwritten. Unknown users and changed
+	// passwords are treated as not existing.
+	UpdatePassword(ctx context.Context, id, oldHash, newHash string) error
+	// CreateEmailVerification saves verification token of the user. Tokens issued earlier
+	// stay valid until they expire or one of them is used.
+	CreateEmailVerification(ctx context.Context, verification *model.EmailVerification) error
+	// VerifyEmail marks email of the user the not expired verification token with tokenHash
+	// is issued to as verified, deletes all verification tokens of the user and returns id of the user.
+	// Unknown, used and expired tokens are treated as not existing.
+	VerifyEmail(ctx context.Context, tokenHash string) (string, error)
+	// SaveTwoFactor saves two-factor authentication of the user replacing not enabled one,
+	// it returns conflict error if two-factor authentication of the user is enabled.
+	SaveTwoFactor(ctx context.Context, twoFactor *model.TwoFactor) error
+	GetTwoFactor(ctx context.Context, userID string) (*model.TwoFactor, error)
+	// EnableTwoFactor enables two-factor authentication of the user with hashes of recovery codes
+	// and marks the step of the confirming code as used. It returns not found error if
+	// the user has no two-factor authentication or it is already enabled.
+	EnableTwoFactor(ctx context.Context, userID string, recoveryCodes []string, step int64) error
+	// UseTwoFactorStep marks the time step of a code of enabled two-factor authentication as used,
+	// it returns conflict error if the step isn't later than the last used one.
+	UseTwoFactorStep(ctx context.Context, userID string, step int64) error
+	// UseRecoveryCode removes hash of the recovery code of enabled two-factor authentication,
+	// it returns not found error if the user has no such code.
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	// DeleteTwoFactor deletes two-factor authentication of the user, it succeeds if the user has none.
+	DeleteTwoFactor(ctx context.Context, userID string) error
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	// GetAPIKey returns API key with keyHash. Expired keys are treated as not existing.
+	GetAPIKey(ctx context.Context, keyHash string) (*model.APIKey, error)
+	// ListAPIKeys returns API keys of the user including expired ones sorted by creation time.
+	ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, id string) error
+	// TouchAPIKey sets the time the API key was used at last.
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+	// CreateIdentity links identity at OpenID Connect provider to the user, it returns
+	// conflict error if the identity is linked already.
+	CreateIdentity(ctx context.Context, identity *model.Identity) error
+	GetIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error)
+	CreateOIDCLogin(ctx context.Context, login *model.OIDCLogin) error
+	// ConsumeOIDCLogin deletes login with stateHash and returns it, so the state can be used once.
+	// Expired logins are treated as not existing.
+	ConsumeOIDCLogin(ctx context.Context, stateHash string) (*model.OIDCLogin, error)
+}
+
+// Session is the storage of sessions of users. Methods taking userID see only
+// sessions of the user and return not found error for sessions of other users.
+type Session interface {
+	CreateSession(ctx context.Context, session *model.Session) error
+	// RotateSession replaces token hash, device and times of the session if hash of its last
+	// refresh token equals oldTokenHash, otherwise it returns conflict error.
+	// Expired sessions are treated as not existing.
+	RotateSession(ctx context.Context, oldTokenHash string, session *model.Session) error
+	// ListSessions returns not expired sessions of the user sorted by creation time.
+	ListSessions(ctx context.Context, userID string) ([]*model.Session, error)
+	DeleteSession(ctx context.Context, userID, id string) error
+	// DeleteUserSessions deletes all sessions of the user, it succeeds if the user has no sessions.
+	DeleteUserSessions(ctx context.Context, userID string) error
 }
 
 type Repository struct {
 	Cat
 	Auth
+	Session
 }
 
 func NewRepositoryPostgres(db *pgxpool.Pool) *Repository {
 	return &Repository{
-		Cat:  NewCatRepository(db),
-		Auth: NewAuthRepository(db),
+		Cat:     NewCatRepository(db),
+		Auth:    NewAuthRepository(db),
+		Session: NewSessionRepository(db),
 	}
 }
 
 func NewRepositoryMongo(db *mongo.Client) *Repository {
 	return &Repository{
-		Cat:  NewCatRepositoryMongo(db),
-		Auth: NewAuthRepositoryMongo(db),
+		Cat:     NewCatRepositoryMongo(db),
+		Auth:    NewAuthRepositoryMongo(db),
+		Session: NewSessionRepositoryMongo(db),
+	}
+}
+
+func NewRepositoryMemory() *Repository {
+	return &Repository{
+		Cat:     NewCatRepositoryMemory(),
+		Auth:    NewAuthRepositoryMemory(),
+		Session: NewSessionRepositoryMemory(),
 	}
 }
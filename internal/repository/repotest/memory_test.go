@@ -0,0 +1,18 @@
+package repotest_test
+
+import (
+	"testing"
+
+	"github.com/malkev1ch/first-task/internal/repository"
+	"github.com/malkev1ch/first-task/internal/repository/repotest"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Repository {
+		return repository.NewRepositoryMemory()
+	})
+}
+
+func TestMemoryConcurrency(t *testing.T) {
+	repotest.RunConcurrent(t, repository.NewRepositoryMemory())
+}
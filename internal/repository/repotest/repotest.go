@@ -0,0 +1,1467 @@
+// Package repotest provides conformance tests for implementations of repository.Repository.
+//
+// Every backend is expected to behave the same, so the tests run against
+// a repository returned by the factory and check only observable behavior.
+// Tests create objects with random UUIDs and emails, so the factory may
+// return the same repository for every call.
+package repotest
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns repository under test.
+type Factory func(t *testing.T) *repository.Repository
+
+// Run runs conformance tests for every method of repository.Cat, repository.Auth and repository.Session.
+func Run(t *testing.T, factory Factory) {
+	t.Run("Cat", func(t *testing.T) {
+		t.Run("Create", func(t *testing.T) { testCreateCat(t, factory(t)) })
+		t.Run("Get", func(t *testing.T) { testGetCat(t, factory(t)) })
+		t.Run("Update", func(t *testing.T) { testUpdateCat(t, factory(t)) })
+		t.Run("Delete", func(t *testing.T) { testDeleteCat(t, factory(t)) })
+		t.Run("Trash", func(t *testing.T) { testTrash(t, factory(t)) })
+		t.Run("Version", func(t *testing.T) { testCatVersion(t, factory(t)) })
+		t.Run("UploadImage", func(t *testing.T) { testUploadImage(t, factory(t)) })
+		t.Run("List", func(t *testing.T) { testListCats(t, factory(t)) })
+		t.Run("Owner", func(t *testing.T) { testCatOwner(t, factory(t)) })
+		t.Run("Disown", func(t *testing.T) { testDisownCats(t, factory(t)) })
+		t.Run("History", func(t *testing.T) { testCatHistory(t, factory(t)) })
+		t.Run("AnonymizeHistory", func(t *testing.T) { testAnonymizeCatChanges(t, factory(t)) })
+	})
+	t.Run("Auth", func(t *testing.T) {
+		t.Run("CreateUser", func(t *testing.T) { testCreateUser(t, factory(t)) })
+		t.Run("GetUserHashedPassword", func(t *testing.T) { testGetUserHashedPassword(t, factory(t)) })
+		t.Run("GetUser", func(t *testing.T) { testGetUser(t, factory(t)) })
+		t.Run("Role", func(t *testing.T) { testUserRole(t, factory(t)) })
+		t.Run("Name", func(t *testing.T) { testUserName(t, factory(t)) })
+		t.Run("Disabled", func(t *testing.T) { testUserDisabled(t, factory(t)) })
+		t.Run("ListUsers", func(t *testing.T) { testListUsers(t, factory(t)) })
+		t.Run("DeleteUser", func(t *testing.T) { testDeleteUser(t, factory(t)) })
+		t.Run("PasswordReset", func(t *testing.T) { testPasswordReset(t, factory(t)) })
+		t.Run("UpdatePassword", func(t *testing.T) { testUpdatePassword(t, factory(t)) })
+		t.Run("EmailVerification", func(t *testing.T) { testEmailVerification(t, factory(t)) })
+		t.Run("TwoFactor", func(t *testing.T) { testTwoFactor(t, factory(t)) })
+		t.Run("APIKeys", func(t *testing.T) { testAPIKeys(t, factory(t)) })
+		t.Run("Identity", func(t *testing.T) { testIdentity(t, factory(t)) })
+		t.Run("OIDCLogin", func(t *testing.T) { testOIDCLogin(t, factory(t)) })
+	})
+	t.Run("Session", func(t *testing.T) {
+		t.Run("Create", func(t *testing.T) { testCreateSession(t, factory(t)) })
+		t.Run("Rotate", func(t *testing.T) { testRotateSession(t, factory(t)) })
+		t.Run("List", func(t *testing.T) { testListSessions(t, factory(t)) })
+		t.Run("Delete", func(t *testing.T) { testDeleteSession(t, factory(t)) })
+		t.Run("DeleteUserSessions", func(t *testing.T) { testDeleteUserSessions(t, factory(t)) })
+	})
+}
+
+// date returns midnight of the day, the precision every backend keeps birthdate with.
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// newCat returns cat with random UUID.
+func newCat(name string, dateBirth time.Time, vaccinated bool) *model.Cat {
+	return &model.Cat{
+		ID:         uuid.New().String(),
+		Name:       name,
+		DateBirth:  dateBirth,
+		Vaccinated: vaccinated,
+	}
+}
+
+// createCat saves the cat and fails the test on error.
+func createCat(t *testing.T, repo *repository.Repository, cat *model.Cat) {
+	t.Helper()
+	require.NoError(t, repo.Cat.Create(context.Background(), cat))
+}
+
+// assertCat checks that cats are equal keeping in mind precision of birthdate.
+func assertCat(t *testing.T, expected, actual *model.Cat) {
+	t.Helper()
+	require.NotNil(t, actual)
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.Name, actual.Name)
+	assert.True(t, expected.DateBirth.Equal(actual.DateBirth),
+		"expected birthdate %s, actual %s", expected.DateBirth, actual.DateBirth)
+	assert.Equal(t, expected.Vaccinated, actual.Vaccinated)
+	assert.Equal(t, expected.ImagePath, actual.ImagePath)
+	assert.Equal(t, expected.OwnerID, actual.OwnerID)
+}
+
+func testCreateCat(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	cat := newCat("Some name", date(2018, 9, 22), true)
+
+	assert.NoError(t, repo.Cat.Create(ctx, cat))
+	assert.ErrorIs(t, repo.Cat.Create(ctx, cat), apperror.ErrConflict)
+}
+
+func testGetCat(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	cat := newCat("Some name", date(2018, 9, 22), true)
+	createCat(t, repo, cat)
+
+	actual, err := repo.Cat.Get(ctx, "", cat.ID)
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+
+	_, err = repo.Cat.Get(ctx, "", uuid.New().String())
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func testUpdateCat(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	cat := newCat("Some name", date(2018, 9, 22), true)
+	createCat(t, repo, cat)
+
+	name := "Another name"
+	cat.Name = name
+	actual, err := repo.Cat.Update(ctx, "", cat.ID, 0, &model.UpdateCat{Name: &name})
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+
+	vaccinated := false
+	dateBirth := date(2019, 1, 1)
+	cat.Vaccinated, cat.DateBirth = vaccinated, dateBirth
+	actual, err = repo.Cat.Update(ctx, "", cat.ID, 0, &model.UpdateCat{Vaccinated: &vaccinated, DateBirth: &dateBirth})
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+
+	actual, err = repo.Cat.Update(ctx, "", cat.ID, 0, &model.UpdateCat{})
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+
+	actual, err = repo.Cat.Get(ctx, "", cat.ID)
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+
+	_, err = repo.Cat.Update(ctx, "", uuid.New().String(), 0, &model.UpdateCat{Name: &name})
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func testDeleteCat(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	cat := newCat("Some name", date(2018, 9, 22), true)
+	createCat(t, repo, cat)
+
+	assert.NoError(t, repo.Cat.Delete(ctx, "", cat.ID, 0, time.Now().UTC()))
+
+	_, err := repo.Cat.Get(ctx, "", cat.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.ErrorIs(t, repo.Cat.Delete(ctx, "", cat.ID, 0, time.Now().UTC()), apperror.ErrNotFound)
+}
+
+func testTrash(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	prefix := uuid.New().String()
+	owner, stranger := uuid.New().String(), uuid.New().String()
+	// trashed cats are shared by the database, so the old one is deleted long before the others
+	now := time.Now().UTC().Truncate(time.Second)
+	oldDeletedAt := now.AddDate(-1, 0, 0)
+	cat := newCat(prefix+"a", date(2018, 9, 22), true)
+	cat.OwnerID = owner
+	createCat(t, repo, cat)
+	oldCat := newCat(prefix+"b", date(2018, 9, 22), true)
+	createCat(t, repo, oldCat)
+	alive := newCat(prefix+"c", date(2018, 9, 22), true)
+	createCat(t, repo, alive)
+
+	_, err := repo.Cat.GetTrashed(ctx, "", cat.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Cat.Restore(ctx, "", cat.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	assert.ErrorIs(t, repo.Cat.Delete(ctx, stranger, cat.ID, 0, now), apperror.ErrNotFound)
+	require.NoError(t, repo.Cat.Delete(ctx, owner, cat.ID, 0, now))
+	require.NoError(t, repo.Cat.Delete(ctx, "", oldCat.ID, 0, oldDeletedAt))
+
+	// cats in trash are treated as not existing
+	name := "Another name"
+	_, err = repo.Cat.Get(ctx, "", cat.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Cat.Update(ctx, "", cat.ID, 0, &model.UpdateCat{Name: &name})
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Cat.UploadImage(ctx, "", cat.ID, 0, "image.webp")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	page, err := repo.Cat.List(ctx, &model.ListCats{Limit: 10, NamePrefix: prefix})
+	assert.NoError(t, err)
+	require.Len(t, page.Cats, 1)
+	assertCat(t, alive, page.Cats[0])
+
+	page, err = repo.Cat.List(ctx, &model.ListCats{Limit: 10, NamePrefix: prefix, Deleted: true})
+	assert.NoError(t, err)
+	require.Len(t, page.Cats, 2)
+	assertCat(t, cat, page.Cats[0])
+	assertCat(t, oldCat, page.Cats[1])
+	page, err = repo.Cat.List(ctx, &model.ListCats{Limit: 10, NamePrefix: prefix, Deleted: true, OwnerID: owner})
+	assert.NoError(t, err)
+	require.Len(t, page.Cats, 1)
+	assertCat(t, cat, page.Cats[0])
+
+	_, err = repo.Cat.GetTrashed(ctx, stranger, cat.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	actual, err := repo.Cat.GetTrashed(ctx, owner, cat.ID)
+	require.NoError(t, err)
+	assertCat(t, cat, actual)
+	require.NotNil(t, actual.DeletedAt)
+	assert.True(t, now.Equal(*actual.DeletedAt), "expected deletion time %s, actual %s", now, actual.DeletedAt)
+
+	_, err = repo.Cat.Restore(ctx, stranger, cat.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	actual, err = repo.Cat.Restore(ctx, owner, cat.ID)
+	require.NoError(t, err)
+	assertCat(t, cat, actual)
+	assert.Nil(t, actual.DeletedAt)
+	actual, err = repo.Cat.Get(ctx, owner, cat.ID)
+	require.NoError(t, err)
+	assertCat(t, cat, actual)
+	assert.Nil(t, actual.DeletedAt)
+
+	require.NoError(t, repo.Cat.Delete(ctx, "", cat.ID, 0, now))
+	purged, err := repo.Cat.PurgeCats(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	ids := make([]string, 0, len(purged))
+	for _, purgedCat := range purged {
+		ids = append(ids, purgedCat.ID)
+		if purgedCat.ID == oldCat.ID {
+			assertCat(t, oldCat, purgedCat)
+		}
+	}
+	assert.Contains(t, ids, oldCat.ID)
+	assert.NotContains(t, ids, cat.ID)
+	assert.NotContains(t, ids, alive.ID)
+
+	_, err = repo.Cat.GetTrashed(ctx, "", oldCat.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Cat.GetTrashed(ctx, "", cat.ID)
+	assert.NoError(t, err)
+}
+
+func testCatVersion(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	cat := newCat("Some name", date(2018, 9, 22), true)
+	createCat(t, repo, cat)
+
+	actual, err := repo.Cat.Get(ctx, "", cat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), actual.Version)
+
+	name := "Another name"
+	cat.Name = name
+	actual, err = repo.Cat.Update(ctx, "", cat.ID, 1, &model.UpdateCat{Name: &name})
+	require.NoError(t, err)
+	assertCat(t, cat, actual)
+	assert.Equal(t, int64(2), actual.Version)
+	actual, err = repo.Cat.Update(ctx, "", cat.ID, 0, &model.UpdateCat{Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), actual.Version)
+	// updates of no fields check the version, but don't change it
+	actual, err = repo.Cat.Update(ctx, "", cat.ID, 3, &model.UpdateCat{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), actual.Version)
+
+	// stale versions change nothing
+	other := "Other name"
+	_, err = repo.Cat.Update(ctx, "", cat.ID, 2, &model.UpdateCat{Name: &other})
+	assert.ErrorIs(t, err, apperror.ErrPreconditionFailed)
+	_, err = repo.Cat.Update(ctx, "", cat.ID, 2, &model.UpdateCat{})
+	assert.ErrorIs(t, err, apperror.ErrPreconditionFailed)
+	_, err = repo.Cat.UploadImage(ctx, "", cat.ID, 2, "image.webp")
+	assert.ErrorIs(t, err, apperror.ErrPreconditionFailed)
+	assert.ErrorIs(t, repo.Cat.Delete(ctx, "", cat.ID, 2, time.Now().UTC()), apperror.ErrPreconditionFailed)
+	actual, err = repo.Cat.Get(ctx, "", cat.ID)
+	require.NoError(t, err)
+	assertCat(t, cat, actual)
+	assert.Equal(t, int64(3), actual.Version)
+
+	// unknown cats aren't reported as stale
+	_, err = repo.Cat.Update(ctx, "", uuid.New().String(), 1, &model.UpdateCat{Name: &other})
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Cat.Update(ctx, uuid.New().String(), cat.ID, 3, &model.UpdateCat{Name: &other})
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	cat.ImagePath = "image.webp"
+	actual, err = repo.Cat.UploadImage(ctx, "", cat.ID, 3, cat.ImagePath)
+	require.NoError(t, err)
+	assertCat(t, cat, actual)
+	assert.Equal(t, int64(4), actual.Version)
+
+	require.NoError(t, repo.Cat.Delete(ctx, "", cat.ID, 4, time.Now().UTC()))
+	actual, err = repo.Cat.GetTrashed(ctx, "", cat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), actual.Version)
+	assert.ErrorIs(t, repo.Cat.Delete(ctx, "", cat.ID, 5, time.Now().UTC()), apperror.ErrNotFound)
+
+	actual, err = repo.Cat.Restore(ctx, "", cat.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), actual.Version)
+}
+
+func testUploadImage(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	cat := newCat("Some name", date(2018, 9, 22), true)
+	createCat(t, repo, cat)
+
+	cat.ImagePath = "/Data/1c219a3f-a959-4395-81f0-4e735040ed61.webp"
+	actual, err := repo.Cat.UploadImage(ctx, "", cat.ID, 0, cat.ImagePath)
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+
+	actual, err = repo.Cat.Get(ctx, "", cat.ID)
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+
+	_, err = repo.Cat.UploadImage(ctx, "", uuid.New().String(), 0, cat.ImagePath)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func testListCats(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	// the prefix separates cats of the test from other cats in the repository
+	prefix := uuid.New().String()
+	b := newCat(prefix+"b", date(2018, 9, 22), true)
+	a := newCat(prefix+"a", date(2018, 9, 23), false)
+	c := newCat(prefix+"c", date(2018, 9, 24), true)
+	// the cat has the same birthdate as cat c, the tie is broken by UUID
+	d := newCat(prefix+"B", date(2018, 9, 24), false)
+	for _, cat := range []*model.Cat{b, a, c, d} {
+		createCat(t, repo, cat)
+	}
+	vaccinated := true
+	from, to := date(2018, 9, 23), date(2018, 9, 24)
+	// bounds with time of day filter by their dates
+	fromNoon, toNoon := from.Add(12*time.Hour), date(2018, 9, 22).Add(12*time.Hour)
+	byDate := []*model.Cat{b, a, c, d}
+	if d.ID < c.ID {
+		byDate = []*model.Cat{b, a, d, c}
+	}
+
+	testTable := []struct {
+		name     string
+		input    model.ListCats
+		expected []*model.Cat
+	}{
+		{
+			name:     "Sort by name",
+			input:    model.ListCats{SortBy: model.CatsSortByName},
+			expected: []*model.Cat{d, a, b, c},
+		},
+		{
+			name:     "Sort by name desc",
+			input:    model.ListCats{SortBy: model.CatsSortByName, SortDesc: true},
+			expected: []*model.Cat{c, b, a, d},
+		},
+		{
+			name:     "Sort by date birth",
+			input:    model.ListCats{SortBy: model.CatsSortByDateBirth},
+			expected: byDate,
+		},
+		{
+			name:     "Sort by date birth desc",
+			input:    model.ListCats{SortBy: model.CatsSortByDateBirth, SortDesc: true},
+			expected: []*model.Cat{byDate[3], byDate[2], byDate[1], byDate[0]},
+		},
+		{
+			name:     "Filter by vaccinated",
+			input:    model.ListCats{Vaccinated: &vaccinated},
+			expected: []*model.Cat{b, c},
+		},
+		{
+			name:     "Filter by date birth",
+			input:    model.ListCats{DateBirthFrom: &from, DateBirthTo: &to, SortBy: model.CatsSortByDateBirth},
+			expected: byDate[1:],
+		},
+		{
+			name:     "Filter by date birth with time",
+			input:    model.ListCats{DateBirthFrom: &fromNoon, SortBy: model.CatsSortByDateBirth},
+			expected: byDate[1:],
+		},
+		{
+			name:     "Filter by date birth to with time",
+			input:    model.ListCats{DateBirthTo: &toNoon, SortBy: model.CatsSortByDateBirth},
+			expected: byDate[:1],
+		},
+		{
+			name:     "Filter by name prefix",
+			input:    model.ListCats{NamePrefix: prefix + "b"},
+			expected: []*model.Cat{b},
+		},
+	}
+	for _, testCase := range testTable {
+		for _, limit := range []int{1, 2, 10} {
+			t.Run(testCase.name, func(t *testing.T) {
+				input := testCase.input
+				input.Limit = limit
+				if input.NamePrefix == "" {
+					input.NamePrefix = prefix
+				}
+
+				actual := make([]*model.Cat, 0)
+				for pages := 0; pages <= len(testCase.expected); pages++ {
+					page, err := repo.Cat.List(ctx, &input)
+					require.NoError(t, err)
+					assert.LessOrEqual(t, len(page.Cats), limit)
+					actual = append(actual, page.Cats...)
+					if page.NextCursor == "" {
+						break
+					}
+					input.Cursor = page.NextCursor
+				}
+
+				require.Len(t, actual, len(testCase.expected))
+				for i := range testCase.expected {
+					assertCat(t, testCase.expected[i], actual[i])
+				}
+			})
+		}
+	}
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		_, err := repo.Cat.List(ctx, &model.ListCats{Limit: 1, Cursor: "qwerty"})
+		assert.ErrorIs(t, err, apperror.ErrValidation)
+
+		page, err := repo.Cat.List(ctx, &model.ListCats{Limit: 1, NamePrefix: prefix})
+		require.NoError(t, err)
+		_, err = repo.Cat.List(ctx, &model.ListCats{Limit: 1, Cursor: page.NextCursor, SortBy: model.CatsSortByDateBirth})
+		assert.ErrorIs(t, err, apperror.ErrValidation)
+	})
+}
+
+func testCatOwner(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	prefix := uuid.New().String()
+	owner, stranger := uuid.New().String(), uuid.New().String()
+	cat := newCat(prefix, date(2018, 9, 22), true)
+	cat.OwnerID = owner
+	createCat(t, repo, cat)
+	createCat(t, repo, newCat(prefix, date(2018, 9, 22), true))
+
+	actual, err := repo.Cat.Get(ctx, owner, cat.ID)
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+
+	actual, err = repo.Cat.Get(ctx, "", cat.ID)
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+
+	page, err := repo.Cat.List(ctx, &model.ListCats{Limit: 10, NamePrefix: prefix, OwnerID: owner})
+	assert.NoError(t, err)
+	require.Len(t, page.Cats, 1)
+	assertCat(t, cat, page.Cats[0])
+
+	page, err = repo.Cat.List(ctx, &model.ListCats{Limit: 10, NamePrefix: prefix, OwnerID: stranger})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Cats)
+
+	name := "Another name"
+	_, err = repo.Cat.Get(ctx, stranger, cat.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Cat.Update(ctx, stranger, cat.ID, 0, &model.UpdateCat{Name: &name})
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Cat.Update(ctx, stranger, cat.ID, 0, &model.UpdateCat{})
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Cat.UploadImage(ctx, stranger, cat.ID, 0, "image.webp")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.ErrorIs(t, repo.Cat.Delete(ctx, stranger, cat.ID, 0, time.Now().UTC()), apperror.ErrNotFound)
+
+	actual, err = repo.Cat.Get(ctx, owner, cat.ID)
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+
+	cat.Name = name
+	actual, err = repo.Cat.Update(ctx, owner, cat.ID, 0, &model.UpdateCat{Name: &name})
+	assert.NoError(t, err)
+	assertCat(t, cat, actual)
+	assert.NoError(t, repo.Cat.Delete(ctx, owner, cat.ID, 0, time.Now().UTC()))
+}
+
+func testDisownCats(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	prefix := uuid.New().String()
+	owner, other := uuid.New().String(), uuid.New().String()
+	cat := newCat(prefix, date(2018, 9, 22), true)
+	cat.OwnerID = owner
+	createCat(t, repo, cat)
+	otherCat := newCat(prefix, date(2018, 9, 22), true)
+	otherCat.OwnerID = other
+	createCat(t, repo, otherCat)
+
+	ids, err := repo.Cat.DisownCats(ctx, owner)
+	require.NoError(t, err)
+	assert.Equal(t, []string{cat.ID}, ids)
+	_, err = repo.Cat.Get(ctx, owner, cat.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	actual, err := repo.Cat.Get(ctx, "", cat.ID)
+	require.NoError(t, err)
+	cat.OwnerID = ""
+	assertCat(t, cat, actual)
+	actual, err = repo.Cat.Get(ctx, other, otherCat.ID)
+	require.NoError(t, err)
+	assertCat(t, otherCat, actual)
+
+	// owner without cats is disowned without error
+	ids, err = repo.Cat.DisownCats(ctx, owner)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+// newCatChange returns change of the cat from before to after with random UUID.
+func newCatChange(operation string, changedAt time.Time, before, after *model.Cat) *model.CatChange {
+	change := &model.CatChange{
+		ID:        uuid.New().String(),
+		ActorID:   uuid.New().String(),
+		Operation: operation,
+		ChangedAt: changedAt,
+		Fields:    []string{"name"},
+		Before:    before,
+		After:     after,
+	}
+	if after != nil {
+		change.CatID, change.OwnerID = after.ID, after.OwnerID
+	} else {
+		change.CatID, change.OwnerID = before.ID, before.OwnerID
+	}
+
+	return change
+}
+
+// assertCatChange checks that changes are equal keeping in mind precision of times.
+func assertCatChange(t *testing.T, expected, actual *model.CatChange) {
+	t.Helper()
+	require.NotNil(t, actual)
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.CatID, actual.CatID)
+	assert.Equal(t, expected.OwnerID, actual.OwnerID)
+	assert.Equal(t, expected.ActorID, actual.ActorID)
+	assert.Equal(t, expected.Operation, actual.Operation)
+	assert.True(t, expected.ChangedAt.Equal(actual.ChangedAt),
+		"expected changed at %s, actual %s", expected.ChangedAt, actual.ChangedAt)
+	assert.Equal(t, expected.Fields, actual.Fields)
+	for _, cats := range [][2]*model.Cat{{expected.Before, actual.Before}, {expected.After, actual.After}} {
+		if cats[0] == nil {
+			assert.Nil(t, cats[1])
+		} else {
+			assertCat(t, cats[0], cats[1])
+		}
+	}
+}
+
+func testCatHistory(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	owner := uuid.New().String()
+	cat := newCat("Some name", date(2018, 9, 22), true)
+	cat.OwnerID = owner
+	renamed := *cat
+	renamed.Name = "Other name"
+	renamed.ImagePath = "image.webp"
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	created := newCatChange(model.CatOperationCreate, now, nil, cat)
+	updated := newCatChange(model.CatOperationUpdate, now.Add(time.Second), cat, &renamed)
+	updated.Fields = []string{"name", "imagePath"}
+	deleted := newCatChange(model.CatOperationDelete, now.Add(2*time.Second), &renamed, nil)
+	// changes made at the same time go in the order of UUIDs
+	sameTime := newCatChange(model.CatOperationUpdate, now.Add(time.Second), cat, &renamed)
+	otherCat := newCatChange(model.CatOperationCreate, now, nil, newCat("Some name", date(2018, 9, 22), true))
+	for _, change := range []*model.CatChange{updated, deleted, created, sameTime, otherCat} {
+		require.NoError(t, repo.Cat.AddCatChange(ctx, change))
+	}
+	expected := []*model.CatChange{deleted, updated, sameTime, created}
+	if sameTime.ID > updated.ID {
+		expected[1], expected[2] = sameTime, updated
+	}
+
+	actual := make([]*model.CatChange, 0)
+	cursor := ""
+	for pages := 0; pages < len(expected); pages++ {
+		page, err := repo.Cat.ListCatChanges(ctx, &model.ListCatChanges{CatID: cat.ID, Limit: 3, Cursor: cursor})
+		require.NoError(t, err)
+		actual = append(actual, page.Changes...)
+		if cursor = page.NextCursor; cursor == "" {
+			break
+		}
+	}
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		assertCatChange(t, expected[i], actual[i])
+	}
+
+	page, err := repo.Cat.ListCatChanges(ctx, &model.ListCatChanges{CatID: cat.ID, OwnerID: owner, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, page.Changes, len(expected))
+	assert.Empty(t, page.NextCursor)
+	page, err = repo.Cat.ListCatChanges(ctx, &model.ListCatChanges{CatID: cat.ID, OwnerID: uuid.New().String(), Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, page.Changes)
+
+	_, err = repo.Cat.ListCatChanges(ctx, &model.ListCatChanges{CatID: cat.ID, Limit: 10, Cursor: "qwerty"})
+	assert.ErrorIs(t, err, apperror.ErrValidation)
+}
+
+func testAnonymizeCatChanges(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user, other := uuid.New().String(), uuid.New().String()
+	cat := newCat("Some name", date(2018, 9, 22), true)
+	cat.OwnerID = user
+	renamed := *cat
+	renamed.Name = "Other name"
+	otherCat := newCat("Some name", date(2018, 9, 22), true)
+	otherCat.OwnerID = other
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	created := newCatChange(model.CatOperationCreate, now, nil, cat)
+	created.ActorID = user
+	// the change is made by an admin
+	updated := newCatChange(model.CatOperationUpdate, now.Add(time.Second), cat, &renamed)
+	otherCreated := newCatChange(model.CatOperationCreate, now, nil, otherCat)
+	otherCreated.ActorID = user
+	for _, change := range []*model.CatChange{created, updated, otherCreated} {
+		require.NoError(t, repo.Cat.AddCatChange(ctx, change))
+	}
+
+	require.NoError(t, repo.Cat.AnonymizeCatChanges(ctx, user))
+
+	disowned, disownedRenamed := *cat, renamed
+	disowned.OwnerID, disownedRenamed.OwnerID = "", ""
+	anonymousCreated, anonymousUpdated, anonymousOtherCreated := *created, *updated, *otherCreated
+	anonymousCreated.ActorID, anonymousCreated.OwnerID, anonymousCreated.After = "", "", &disowned
+	anonymousUpdated.OwnerID, anonymousUpdated.Before, anonymousUpdated.After = "", &disowned, &disownedRenamed
+	anonymousOtherCreated.ActorID = ""
+
+	page, err := repo.Cat.ListCatChanges(ctx, &model.ListCatChanges{CatID: cat.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Changes, 2)
+	assertCatChange(t, &anonymousUpdated, page.Changes[0])
+	assertCatChange(t, &anonymousCreated, page.Changes[1])
+	page, err = repo.Cat.ListCatChanges(ctx, &model.ListCatChanges{CatID: otherCat.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Changes, 1)
+	assertCatChange(t, &anonymousOtherCreated, page.Changes[0])
+}
+
+// newUser returns user with random UUID and email.
+func newUser() *repository.CreateUserInput {
+	id := uuid.New().String()
+	return &repository.CreateUserInput{
+		ID:       id,
+		UserName: "Some Name",
+		Email:    id + "@outlook.com",
+		Password: "hashed password",
+		Role:     model.RoleEditor,
+	}
+}
+
+func testCreateUser(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	assert.NoError(t, repo.Auth.CreateUser(ctx, user))
+
+	sameEmail := newUser()
+	sameEmail.Email = user.Email
+	assert.ErrorIs(t, repo.Auth.CreateUser(ctx, sameEmail), apperror.ErrConflict)
+
+	sameID := newUser()
+	sameID.ID = user.ID
+	assert.ErrorIs(t, repo.Auth.CreateUser(ctx, sameID), apperror.ErrConflict)
+}
+
+func testGetUserHashedPassword(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+
+	id, password, err := repo.Auth.GetUserHashedPassword(ctx, user.Email)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, id)
+	assert.Equal(t, user.Password, password)
+
+	_, _, err = repo.Auth.GetUserHashedPassword(ctx, newUser().Email)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func testUpdatePassword(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+
+	require.NoError(t, repo.Auth.UpdatePassword(ctx, user.ID, user.Password, "rehashed password"))
+	_, password, err := repo.Auth.GetUserHashedPassword(ctx, user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, "rehashed password", password)
+
+	// the password is changed meanwhile, so the stale hash doesn't overwrite it
+	assert.ErrorIs(t, repo.Auth.UpdatePassword(ctx, user.ID, user.Password, "stale password"), apperror.ErrNotFound)
+	_, password, err = repo.Auth.GetUserHashedPassword(ctx, user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, "rehashed password", password)
+
+	assert.ErrorIs(t, repo.Auth.UpdatePassword(ctx, uuid.New().String(), "rehashed password", "password"),
+		apperror.ErrNotFound)
+}
+
+func testGetUser(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+
+	actual, err := repo.Auth.GetUser(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &model.User{
+		ID:       user.ID,
+		UserName: user.UserName,
+		Email:    user.Email,
+		Role:     user.Role,
+	}, actual)
+
+	verified := newUser()
+	verified.EmailVerified = true
+	require.NoError(t, repo.Auth.CreateUser(ctx, verified))
+	actual, err = repo.Auth.GetUser(ctx, verified.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, actual)
+	assert.True(t, actual.EmailVerified)
+
+	_, err = repo.Auth.GetUser(ctx, uuid.New().String())
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func testUserRole(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+
+	assert.NoError(t, repo.Auth.UpdateUserRole(ctx, user.ID, model.RoleAdmin))
+	actual, err := repo.Auth.GetUser(ctx, user.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, actual)
+	assert.Equal(t, model.RoleAdmin, actual.Role)
+
+	assert.ErrorIs(t, repo.Auth.UpdateUserRole(ctx, uuid.New().String(), model.RoleAdmin), apperror.ErrNotFound)
+}
+
+func testUserName(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+
+	assert.NoError(t, repo.Auth.UpdateUserName(ctx, user.ID, "Another name"))
+	actual, err := repo.Auth.GetUser(ctx, user.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, actual)
+	assert.Equal(t, "Another name", actual.UserName)
+	assert.Equal(t, user.Email, actual.Email)
+
+	assert.ErrorIs(t, repo.Auth.UpdateUserName(ctx, uuid.New().String(), "Another name"), apperror.ErrNotFound)
+}
+
+func testUserDisabled(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+
+	actual, err := repo.Auth.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, actual.Disabled)
+
+	for _, disabled := range []bool{true, false} {
+		assert.NoError(t, repo.Auth.SetUserDisabled(ctx, user.ID, disabled))
+		actual, err = repo.Auth.GetUser(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, disabled, actual.Disabled)
+	}
+
+	assert.ErrorIs(t, repo.Auth.SetUserDisabled(ctx, uuid.New().String(), true), apperror.ErrNotFound)
+}
+
+func testListUsers(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	// the query separates users of the test from other users in the repository
+	query := uuid.New().String()
+	a, b, c := newUser(), newUser(), newUser()
+	a.Email = query + "a@outlook.com"
+	b.Email = query + "b@outlook.com"
+	b.Role = model.RoleViewer
+	b.Disabled = true
+	// the user is found by name regardless of case
+	c.UserName = "Cat Lover " + strings.ToUpper(query)
+	for _, user := range []*repository.CreateUserInput{a, b, c} {
+		require.NoError(t, repo.Auth.CreateUser(ctx, user))
+	}
+	byEmail := []*repository.CreateUserInput{a, b, c}
+	sort.Slice(byEmail, func(i, j int) bool {
+		return byEmail[i].Email < byEmail[j].Email
+	})
+	enabled := false
+
+	testTable := []struct {
+		name     string
+		input    model.ListUsers
+		expected []*repository.CreateUserInput
+	}{
+		{
+			name:     "Search",
+			input:    model.ListUsers{Query: query},
+			expected: byEmail,
+		},
+		{
+			name:     "Search ignores case",
+			input:    model.ListUsers{Query: strings.ToUpper(query + "a")},
+			expected: []*repository.CreateUserInput{a},
+		},
+		{
+			name:     "Filter by role",
+			input:    model.ListUsers{Query: query, Role: model.RoleViewer},
+			expected: []*repository.CreateUserInput{b},
+		},
+		{
+			name:     "Filter by disabled",
+			input:    model.ListUsers{Query: query, Disabled: &enabled},
+			expected: []*repository.CreateUserInput{a, c},
+		},
+	}
+	// the order of users a and c depends on the random email of user c
+	if c.Email < a.Email {
+		testTable[3].expected = []*repository.CreateUserInput{c, a}
+	}
+	for _, testCase := range testTable {
+		for _, limit := range []int{1, 2, 10} {
+			t.Run(testCase.name, func(t *testing.T) {
+				input := testCase.input
+				input.Limit = limit
+
+				actual := make([]*model.User, 0)
+				for pages := 0; pages <= len(testCase.expected); pages++ {
+					page, err := repo.Auth.ListUsers(ctx, &input)
+					require.NoError(t, err)
+					assert.LessOrEqual(t, len(page.Users), limit)
+					actual = append(actual, page.Users...)
+					if page.NextCursor == "" {
+						break
+					}
+					input.Cursor = page.NextCursor
+				}
+
+				require.Len(t, actual, len(testCase.expected))
+				for i, expected := range testCase.expected {
+					assert.Equal(t, expected.ID, actual[i].ID)
+					assert.Equal(t, expected.UserName, actual[i].UserName)
+					assert.Equal(t, expected.Email, actual[i].Email)
+					assert.Equal(t, expected.Role, actual[i].Role)
+					assert.Equal(t, expected.Disabled, actual[i].Disabled)
+				}
+			})
+		}
+	}
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		_, err := repo.Auth.ListUsers(ctx, &model.ListUsers{Limit: 1, Cursor: "qwerty"})
+		assert.ErrorIs(t, err, apperror.ErrValidation)
+	})
+}
+
+func testDeleteUser(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user, other := newUser(), newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+	require.NoError(t, repo.Auth.CreateUser(ctx, other))
+	reset, otherReset := newPasswordReset(user.ID), newPasswordReset(other.ID)
+	require.NoError(t, repo.Auth.CreatePasswordReset(ctx, reset))
+	require.NoError(t, repo.Auth.CreatePasswordReset(ctx, otherReset))
+	verification := &model.EmailVerification{
+		TokenHash: uuid.New().String(), UserID: user.ID, ExpiresAt: time.Now().UTC().Add(time.Hour),
+	}
+	require.NoError(t, repo.Auth.CreateEmailVerification(ctx, verification))
+	require.NoError(t, repo.Auth.SaveTwoFactor(ctx, &model.TwoFactor{
+		UserID: user.ID, Secret: uuid.New().String(), RecoveryCodes: []string{},
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}))
+	key := &model.APIKey{
+		ID: uuid.New().String(), UserID: user.ID, Name: "nightly import", Prefix: "ftk_test",
+		KeyHash: uuid.New().String(), Scopes: []string{model.ScopeCatsRead},
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	require.NoError(t, repo.Auth.CreateAPIKey(ctx, key))
+	identity := newIdentity(user.ID)
+	require.NoError(t, repo.Auth.CreateIdentity(ctx, identity))
+
+	require.NoError(t, repo.Auth.DeleteUser(ctx, user.ID))
+	_, err := repo.Auth.GetUser(ctx, user.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, _, err = repo.Auth.GetUserHashedPassword(ctx, user.Email)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Auth.ResetPassword(ctx, reset.TokenHash, "new password")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Auth.VerifyEmail(ctx, verification.TokenHash)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Auth.GetTwoFactor(ctx, user.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Auth.GetAPIKey(ctx, key.KeyHash)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Auth.GetIdentity(ctx, identity.Issuer, identity.Subject)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.ErrorIs(t, repo.Auth.DeleteUser(ctx, user.ID), apperror.ErrNotFound)
+
+	// the email can be registered again
+	user.ID = uuid.New().String()
+	assert.NoError(t, repo.Auth.CreateUser(ctx, user))
+
+	// data of other users is kept
+	_, err = repo.Auth.GetUser(ctx, other.ID)
+	assert.NoError(t, err)
+	_, err = repo.Auth.ResetPassword(ctx, otherReset.TokenHash, "new password")
+	assert.NoError(t, err)
+}
+
+// newPasswordReset returns reset token of the user with random hash expiring in an hour.
+func newPasswordReset(userID string) *model.PasswordReset {
+	return &model.PasswordReset{
+		TokenHash: uuid.New().String(),
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(time.Hour),
+	}
+}
+
+func testPasswordReset(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+
+	first, second := newPasswordReset(user.ID), newPasswordReset(user.ID)
+	require.NoError(t, repo.Auth.CreatePasswordReset(ctx, first))
+	require.NoError(t, repo.Auth.CreatePasswordReset(ctx, second))
+	assert.ErrorIs(t, repo.Auth.CreatePasswordReset(ctx, first), apperror.ErrConflict)
+
+	expired := newPasswordReset(user.ID)
+	expired.ExpiresAt = time.Now().UTC().Add(-time.Minute)
+	require.NoError(t, repo.Auth.CreatePasswordReset(ctx, expired))
+	_, err := repo.Auth.ResetPassword(ctx, expired.TokenHash, "expired password")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Auth.ResetPassword(ctx, uuid.New().String(), "unknown password")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	// reset token of another user doesn't change the password
+	other := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, other))
+	require.NoError(t, repo.Auth.CreatePasswordReset(ctx, newPasswordReset(other.ID)))
+
+	id, err := repo.Auth.ResetPassword(ctx, first.TokenHash, "new password")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, id)
+	_, password, err := repo.Auth.GetUserHashedPassword(ctx, user.Email)
+	assert.NoError(t, err)
+	assert.Equal(t, "new password", password)
+	_, password, err = repo.Auth.GetUserHashedPassword(ctx, other.Email)
+	assert.NoError(t, err)
+	assert.Equal(t, other.Password, password)
+
+	// the used token and other tokens of the user can't be used anymore
+	_, err = repo.Auth.ResetPassword(ctx, first.TokenHash, "newer password")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Auth.ResetPassword(ctx, second.TokenHash, "newer password")
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func testEmailVerification(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+	newVerification := func(expiresIn time.Duration) *model.EmailVerification {
+		verification := &model.EmailVerification{
+			TokenHash: uuid.New().String(),
+			UserID:    user.ID,
+			ExpiresAt: time.Now().UTC().Add(expiresIn),
+		}
+		require.NoError(t, repo.Auth.CreateEmailVerification(ctx, verification))
+		return verification
+	}
+
+	first, second, expired := newVerification(time.Hour), newVerification(time.Hour), newVerification(-time.Minute)
+	assert.ErrorIs(t, repo.Auth.CreateEmailVerification(ctx, first), apperror.ErrConflict)
+
+	_, err := repo.Auth.VerifyEmail(ctx, expired.TokenHash)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Auth.VerifyEmail(ctx, uuid.New().String())
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	actual, err := repo.Auth.GetUser(ctx, user.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, actual)
+	assert.False(t, actual.EmailVerified)
+
+	id, err := repo.Auth.VerifyEmail(ctx, first.TokenHash)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, id)
+	actual, err = repo.Auth.GetUser(ctx, user.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, actual)
+	assert.True(t, actual.EmailVerified)
+
+	// the used token and other tokens of the user can't be used anymore
+	_, err = repo.Auth.VerifyEmail(ctx, first.TokenHash)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	_, err = repo.Auth.VerifyEmail(ctx, second.TokenHash)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func testTwoFactor(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+	newTwoFactor := func() *model.TwoFactor {
+		return &model.TwoFactor{
+			UserID:        user.ID,
+			Secret:        uuid.New().String(),
+			RecoveryCodes: []string{},
+			CreatedAt:     time.Now().UTC().Truncate(time.Millisecond),
+		}
+	}
+
+	_, err := repo.Auth.GetTwoFactor(ctx, user.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.ErrorIs(t, repo.Auth.EnableTwoFactor(ctx, user.ID, []string{"first"}, 10), apperror.ErrNotFound)
+
+	// not enabled two-factor authentication is replaced by new enrollment
+	require.NoError(t, repo.Auth.SaveTwoFactor(ctx, newTwoFactor()))
+	expected := newTwoFactor()
+	require.NoError(t, repo.Auth.SaveTwoFactor(ctx, expected))
+	actual, err := repo.Auth.GetTwoFactor(ctx, user.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, actual)
+	assert.Equal(t, expected.Secret, actual.Secret)
+	assert.False(t, actual.Enabled)
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt))
+	assert.ErrorIs(t, repo.Auth.UseTwoFactorStep(ctx, user.ID, 11), apperror.ErrNotFound)
+	assert.ErrorIs(t, repo.Auth.UseRecoveryCode(ctx, user.ID, "first"), apperror.ErrNotFound)
+
+	require.NoError(t, repo.Auth.EnableTwoFactor(ctx, user.ID, []string{"first", "second"}, 10))
+	assert.ErrorIs(t, repo.Auth.EnableTwoFactor(ctx, user.ID, []string{"first"}, 10), apperror.ErrNotFound)
+	assert.ErrorIs(t, repo.Auth.SaveTwoFactor(ctx, newTwoFactor()), apperror.ErrConflict)
+	actual, err = repo.Auth.GetTwoFactor(ctx, user.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, actual)
+	assert.Equal(t, expected.Secret, actual.Secret)
+	assert.True(t, actual.Enabled)
+	assert.Equal(t, []string{"first", "second"}, actual.RecoveryCodes)
+	assert.Equal(t, int64(10), actual.LastStep)
+
+	// every step and recovery code can be used once
+	assert.ErrorIs(t, repo.Auth.UseTwoFactorStep(ctx, user.ID, 10), apperror.ErrConflict)
+	assert.NoError(t, repo.Auth.UseTwoFactorStep(ctx, user.ID, 12))
+	assert.ErrorIs(t, repo.Auth.UseTwoFactorStep(ctx, user.ID, 11), apperror.ErrConflict)
+	assert.NoError(t, repo.Auth.UseRecoveryCode(ctx, user.ID, "first"))
+	assert.ErrorIs(t, repo.Auth.UseRecoveryCode(ctx, user.ID, "first"), apperror.ErrNotFound)
+	actual, err = repo.Auth.GetTwoFactor(ctx, user.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, actual)
+	assert.Equal(t, []string{"second"}, actual.RecoveryCodes)
+	assert.Equal(t, int64(12), actual.LastStep)
+
+	require.NoError(t, repo.Auth.DeleteTwoFactor(ctx, user.ID))
+	assert.NoError(t, repo.Auth.DeleteTwoFactor(ctx, user.ID))
+	_, err = repo.Auth.GetTwoFactor(ctx, user.ID)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	assert.NoError(t, repo.Auth.SaveTwoFactor(ctx, newTwoFactor()))
+}
+
+func testAPIKeys(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user, other := newUser(), newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+	require.NoError(t, repo.Auth.CreateUser(ctx, other))
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newKey := func(userID string, createdAt time.Time, expiresAt *time.Time) *model.APIKey {
+		key := &model.APIKey{
+			ID:        uuid.New().String(),
+			UserID:    userID,
+			Name:      "nightly import",
+			Prefix:    "ftk_test",
+			KeyHash:   uuid.New().String(),
+			Scopes:    []string{model.ScopeCatsRead},
+			CreatedAt: createdAt,
+			ExpiresAt: expiresAt,
+		}
+		require.NoError(t, repo.Auth.CreateAPIKey(ctx, key))
+		return key
+	}
+
+	expiresAt, expiredAt := now.Add(time.Hour), now.Add(-time.Minute)
+	first := newKey(user.ID, now.Add(-time.Hour), nil)
+	second := newKey(user.ID, now, &expiresAt)
+	expired := newKey(user.ID, now.Add(-2*time.Hour), &expiredAt)
+	newKey(other.ID, now, nil)
+	duplicate := *first
+	duplicate.ID = uuid.New().String()
+	assert.ErrorIs(t, repo.Auth.CreateAPIKey(ctx, &duplicate), apperror.ErrConflict)
+
+	actual, err := repo.Auth.GetAPIKey(ctx, second.KeyHash)
+	assert.NoError(t, err)
+	require.NotNil(t, actual)
+	assert.Equal(t, second.ID, actual.ID)
+	assert.Equal(t, user.ID, actual.UserID)
+	assert.Equal(t, second.Scopes, actual.Scopes)
+	require.NotNil(t, actual.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*actual.ExpiresAt))
+	assert.Nil(t, actual.LastUsedAt)
+	_, err = repo.Auth.GetAPIKey(ctx, expired.KeyHash)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	actual, err = repo.Auth.GetAPIKey(ctx, first.KeyHash)
+	assert.NoError(t, err)
+	require.NotNil(t, actual)
+	assert.Nil(t, actual.ExpiresAt)
+
+	usedAt := now.Add(time.Minute)
+	require.NoError(t, repo.Auth.TouchAPIKey(ctx, first.ID, usedAt))
+	assert.ErrorIs(t, repo.Auth.TouchAPIKey(ctx, uuid.New().String(), usedAt), apperror.ErrNotFound)
+
+	keys, err := repo.Auth.ListAPIKeys(ctx, user.ID)
+	assert.NoError(t, err)
+	require.Len(t, keys, 3)
+	assert.Equal(t, expired.ID, keys[0].ID)
+	assert.Equal(t, first.ID, keys[1].ID)
+	assert.Equal(t, second.ID, keys[2].ID)
+	require.NotNil(t, keys[1].LastUsedAt)
+	assert.True(t, usedAt.Equal(*keys[1].LastUsedAt))
+
+	// keys of other users can't be deleted
+	assert.ErrorIs(t, repo.Auth.DeleteAPIKey(ctx, other.ID, first.ID), apperror.ErrNotFound)
+	require.NoError(t, repo.Auth.DeleteAPIKey(ctx, user.ID, first.ID))
+	assert.ErrorIs(t, repo.Auth.DeleteAPIKey(ctx, user.ID, first.ID), apperror.ErrNotFound)
+	_, err = repo.Auth.GetAPIKey(ctx, first.KeyHash)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+	keys, err = repo.Auth.ListAPIKeys(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+}
+
+// newSession returns session of the user with random UUID created now, the times are
+// truncated to milliseconds, the precision every backend keeps them with.
+func newIdentity(userID string) *model.Identity {
+	return &model.Identity{
+		Issuer:    "https://accounts.example.com",
+		Subject:   uuid.New().String(),
+		UserID:    userID,
+		Email:     userID + "@example.com",
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+}
+
+func testIdentity(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(ctx, user))
+
+	identity := newIdentity(user.ID)
+	require.NoError(t, repo.Auth.CreateIdentity(ctx, identity))
+	assert.ErrorIs(t, repo.Auth.CreateIdentity(ctx, identity), apperror.ErrConflict)
+	// the subject is unique only at its issuer
+	other := *identity
+	other.Issuer = "https://login.example.org"
+	require.NoError(t, repo.Auth.CreateIdentity(ctx, &other))
+
+	actual, err := repo.Auth.GetIdentity(ctx, identity.Issuer, identity.Subject)
+	require.NoError(t, err)
+	assert.Equal(t, identity.UserID, actual.UserID)
+	assert.Equal(t, identity.Email, actual.Email)
+	assert.True(t, identity.CreatedAt.Equal(actual.CreatedAt))
+
+	_, err = repo.Auth.GetIdentity(ctx, identity.Issuer, uuid.New().String())
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func testOIDCLogin(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	login := &model.OIDCLogin{
+		StateHash:    uuid.New().String(),
+		CodeVerifier: uuid.New().String(),
+		Nonce:        uuid.New().String(),
+		ExpiresAt:    time.Now().UTC().Add(time.Hour).Truncate(time.Millisecond),
+	}
+	require.NoError(t, repo.Auth.CreateOIDCLogin(ctx, login))
+	assert.ErrorIs(t, repo.Auth.CreateOIDCLogin(ctx, login), apperror.ErrConflict)
+
+	actual, err := repo.Auth.ConsumeOIDCLogin(ctx, login.StateHash)
+	require.NoError(t, err)
+	assert.Equal(t, login.CodeVerifier, actual.CodeVerifier)
+	assert.Equal(t, login.Nonce, actual.Nonce)
+	assert.True(t, login.ExpiresAt.Equal(actual.ExpiresAt))
+	// the state is used once
+	_, err = repo.Auth.ConsumeOIDCLogin(ctx, login.StateHash)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+	expired := *login
+	expired.StateHash = uuid.New().String()
+	expired.ExpiresAt = time.Now().UTC().Add(-time.Minute)
+	require.NoError(t, repo.Auth.CreateOIDCLogin(ctx, &expired))
+	_, err = repo.Auth.ConsumeOIDCLogin(ctx, expired.StateHash)
+	assert.ErrorIs(t, err, apperror.ErrNotFound)
+}
+
+func newSession(userID string) *model.Session {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	return &model.Session{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		TokenHash:  uuid.New().String(),
+		UserAgent:  "Mozilla/5.0",
+		IP:         "192.0.2.1",
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(time.Hour),
+	}
+}
+
+// createSession creates user with the session and fails the test on error.
+func createSession(t *testing.T, repo *repository.Repository) *model.Session {
+	t.Helper()
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(context.Background(), user))
+	session := newSession(user.ID)
+	require.NoError(t, repo.Session.CreateSession(context.Background(), session))
+
+	return session
+}
+
+// assertSession checks that sessions are equal keeping in mind precision of times.
+func assertSession(t *testing.T, expected, actual *model.Session) {
+	t.Helper()
+	require.NotNil(t, actual)
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.UserID, actual.UserID)
+	assert.Equal(t, expected.TokenHash, actual.TokenHash)
+	assert.Equal(t, expected.UserAgent, actual.UserAgent)
+	assert.Equal(t, expected.IP, actual.IP)
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt),
+		"expected created at %s, actual %s", expected.CreatedAt, actual.CreatedAt)
+	assert.True(t, expected.LastUsedAt.Equal(actual.LastUsedAt),
+		"expected last used at %s, actual %s", expected.LastUsedAt, actual.LastUsedAt)
+	assert.True(t, expected.ExpiresAt.Equal(actual.ExpiresAt),
+		"expected expires at %s, actual %s", expected.ExpiresAt, actual.ExpiresAt)
+}
+
+func testCreateSession(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	session := createSession(t, repo)
+
+	assert.ErrorIs(t, repo.Session.CreateSession(ctx, session), apperror.ErrConflict)
+
+	sessions, err := repo.Session.ListSessions(ctx, session.UserID)
+	assert.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assertSession(t, session, sessions[0])
+}
+
+func testRotateSession(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	session := createSession(t, repo)
+
+	oldTokenHash := session.TokenHash
+	rotated := *session
+	rotated.TokenHash = uuid.New().String()
+	rotated.UserAgent = "curl/7.81.0"
+	rotated.IP = "192.0.2.2"
+	rotated.LastUsedAt = session.LastUsedAt.Add(time.Minute)
+	rotated.ExpiresAt = session.ExpiresAt.Add(time.Minute)
+	assert.NoError(t, repo.Session.RotateSession(ctx, oldTokenHash, &rotated))
+
+	sessions, err := repo.Session.ListSessions(ctx, session.UserID)
+	assert.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assertSession(t, &rotated, sessions[0])
+
+	// the old token has been already rotated
+	reused := rotated
+	reused.TokenHash = uuid.New().String()
+	assert.ErrorIs(t, repo.Session.RotateSession(ctx, oldTokenHash, &reused), apperror.ErrConflict)
+
+	otherUser := reused
+	otherUser.UserID = uuid.New().String()
+	assert.ErrorIs(t, repo.Session.RotateSession(ctx, rotated.TokenHash, &otherUser), apperror.ErrNotFound)
+
+	unknown := newSession(session.UserID)
+	assert.ErrorIs(t, repo.Session.RotateSession(ctx, unknown.TokenHash, unknown), apperror.ErrNotFound)
+
+	expired := newSession(session.UserID)
+	expired.ExpiresAt = expired.CreatedAt.Add(-time.Minute)
+	require.NoError(t, repo.Session.CreateSession(ctx, expired))
+	assert.ErrorIs(t, repo.Session.RotateSession(ctx, expired.TokenHash, expired), apperror.ErrNotFound)
+}
+
+func testListSessions(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	first := createSession(t, repo)
+
+	second := newSession(first.UserID)
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	require.NoError(t, repo.Session.CreateSession(ctx, second))
+
+	expired := newSession(first.UserID)
+	expired.ExpiresAt = expired.CreatedAt.Add(-time.Minute)
+	require.NoError(t, repo.Session.CreateSession(ctx, expired))
+
+	// session of another user
+	createSession(t, repo)
+
+	sessions, err := repo.Session.ListSessions(ctx, first.UserID)
+	assert.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assertSession(t, first, sessions[0])
+	assertSession(t, second, sessions[1])
+
+	sessions, err = repo.Session.ListSessions(ctx, uuid.New().String())
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func testDeleteSession(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	session := createSession(t, repo)
+
+	assert.ErrorIs(t, repo.Session.DeleteSession(ctx, uuid.New().String(), session.ID), apperror.ErrNotFound)
+	assert.NoError(t, repo.Session.DeleteSession(ctx, session.UserID, session.ID))
+	assert.ErrorIs(t, repo.Session.DeleteSession(ctx, session.UserID, session.ID), apperror.ErrNotFound)
+	assert.ErrorIs(t, repo.Session.RotateSession(ctx, session.TokenHash, session), apperror.ErrNotFound)
+
+	sessions, err := repo.Session.ListSessions(ctx, session.UserID)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func testDeleteUserSessions(t *testing.T, repo *repository.Repository) {
+	ctx := context.Background()
+	session := createSession(t, repo)
+	require.NoError(t, repo.Session.CreateSession(ctx, newSession(session.UserID)))
+	other := createSession(t, repo)
+
+	assert.NoError(t, repo.Session.DeleteUserSessions(ctx, session.UserID))
+	assert.NoError(t, repo.Session.DeleteUserSessions(ctx, session.UserID))
+
+	sessions, err := repo.Session.ListSessions(ctx, session.UserID)
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	sessions, err = repo.Session.ListSessions(ctx, other.UserID)
+	assert.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assertSession(t, other, sessions[0])
+}
+
+// RunConcurrent checks that the repository can be used from many goroutines at once.
+// Run it with the race detector enabled.
+func RunConcurrent(t *testing.T, repo *repository.Repository) {
+	const goroutines = 16
+	ctx := context.Background()
+	prefix := uuid.New().String()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*10)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cat := newCat(prefix, date(2018, 9, 22), true)
+			user := newUser()
+			name := "Another name"
+			errs <- repo.Cat.Create(ctx, cat)
+			_, err := repo.Cat.Get(ctx, "", cat.ID)
+			errs <- err
+			_, err = repo.Cat.Update(ctx, "", cat.ID, 0, &model.UpdateCat{Name: &name})
+			errs <- err
+			_, err = repo.Cat.UploadImage(ctx, "", cat.ID, 0, "image.webp")
+			errs <- err
+			_, err = repo.Cat.List(ctx, &model.ListCats{Limit: 10, NamePrefix: prefix})
+			errs <- err
+			errs <- repo.Cat.Delete(ctx, "", cat.ID, 0, time.Now().UTC())
+			errs <- repo.Auth.CreateUser(ctx, user)
+			session := newSession(user.ID)
+			errs <- repo.Session.CreateSession(ctx, session)
+			_, err = repo.Session.ListSessions(ctx, user.ID)
+			errs <- err
+			errs <- repo.Session.DeleteSession(ctx, user.ID, session.ID)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	t.Run("RotateSession", func(t *testing.T) { testRotateSessionConcurrently(t, repo) })
+	t.Run("ResetPassword", func(t *testing.T) { testResetPasswordConcurrently(t, repo) })
+}
+
+// testRotateSessionConcurrently checks that only one of concurrent rotations
+// of the same refresh token succeeds, the others see reuse of the token.
+func testRotateSessionConcurrently(t *testing.T, repo *repository.Repository) {
+	const goroutines = 16
+	session := createSession(t, repo)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rotated := *session
+			rotated.TokenHash = uuid.New().String()
+			errs <- repo.Session.RotateSession(context.Background(), session.TokenHash, &rotated)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var succeeded int
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, apperror.ErrConflict)
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+// testResetPasswordConcurrently checks that only one of concurrent resets
+// with the same reset token succeeds.
+func testResetPasswordConcurrently(t *testing.T, repo *repository.Repository) {
+	const goroutines = 16
+	user := newUser()
+	require.NoError(t, repo.Auth.CreateUser(context.Background(), user))
+	reset := newPasswordReset(user.ID)
+	require.NoError(t, repo.Auth.CreatePasswordReset(context.Background(), reset))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Auth.ResetPassword(context.Background(), reset.TokenHash, uuid.New().String())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var succeeded int
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+	}
+	assert.Equal(t, 1, succeeded)
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// SessionRepositoryMemory type represents in-memory behavior for sessions of users.
+type SessionRepositoryMemory struct {
+	sessions map[string]model.Session
+	mutex    sync.RWMutex
+}
+
+func NewSessionRepositoryMemory() *SessionRepositoryMemory {
+	return &SessionRepositoryMemory{
+		sessions: make(map[string]model.Session),
+	}
+}
+
+// CreateSession method saves session in memory.
+func (r *SessionRepositoryMemory) CreateSession(ctx context.Context, session *model.Session) error {
+	logrus.WithFields(logrus.Fields{
+		"id":        session.ID,
+		"userID":    session.UserID,
+		"userAgent": session.UserAgent,
+		"ip":        session.IP,
+	}).Debugf("memory repository: create session")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ex := r.sessions[session.ID]; ex {
+		return apperror.Conflict("session with given UUID already exists, try to sign in again")
+	}
+	r.sessions[session.ID] = *session
+
+	return nil
+}
+
+// RotateSession method replaces the last refresh token of the session in memory.
+func (r *SessionRepositoryMemory) RotateSession(ctx context.Context, oldTokenHash string, session *model.Session) error {
+	logrus.WithFields(logrus.Fields{
+		"id":        session.ID,
+		"userID":    session.UserID,
+		"userAgent": session.UserAgent,
+		"ip":        session.IP,
+	}).Debugf("memory repository: rotate session")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, ex := r.find(session.UserID, session.ID)
+	if !ex {
+		return apperror.NotFound("session with given UUID doesn't exist")
+	}
+	if stored.TokenHash != oldTokenHash {
+		return apperror.Conflict("refresh token has been already used")
+	}
+	stored.TokenHash = session.TokenHash
+	stored.UserAgent = session.UserAgent
+	stored.IP = session.IP
+	stored.LastUsedAt = session.LastUsedAt
+	stored.ExpiresAt = session.ExpiresAt
+	r.sessions[session.ID] = stored
+
+	return nil
+}
+
+// ListSessions method returns not expired sessions of the user from memory.
+func (r *SessionRepositoryMemory) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("memory repository: list sessions")
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	sessions := make([]*model.Session, 0)
+	for id := range r.sessions {
+		if session, ex := r.find(userID, id); ex {
+			sessions = append(sessions, &session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+
+	return sessions, nil
+}
+
+// DeleteSession method deletes session of the user from memory.
+func (r *SessionRepositoryMemory) DeleteSession(ctx context.Context, userID, id string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":     id,
+		"userID": userID,
+	}).Debugf("memory repository: delete session")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if session, ex := r.sessions[id]; !ex || session.UserID != userID {
+		return apperror.NotFound("session with given UUID doesn't exist")
+	}
+	delete(r.sessions, id)
+
+	return nil
+}
+
+// DeleteUserSessions method deletes all sessions of the user from memory.
+func (r *SessionRepositoryMemory) DeleteUserSessions(ctx context.Context, userID string) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("memory repository: delete user sessions")
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+
+	return nil
+}
+
+// find returns not expired session of the user, callers must hold the mutex.
+func (r *SessionRepositoryMemory) find(userID, id string) (model.Session, bool) {
+	session, ex := r.sessions[id]
+	if !ex || session.UserID != userID || !session.ExpiresAt.After(time.Now()) {
+		return model.Session{}, false
+	}
+
+	return session, true
+}
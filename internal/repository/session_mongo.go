@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionRepositoryMongo type represents mongo behavior for sessions of users.
+type SessionRepositoryMongo struct {
+	DB *mongo.Client
+}
+
+func NewSessionRepositoryMongo(db *mongo.Client) *SessionRepositoryMongo {
+	return &SessionRepositoryMongo{
+		DB: db,
+	}
+}
+
+// CreateSession method saves session into mongo database.
+func (r SessionRepositoryMongo) CreateSession(ctx context.Context, session *model.Session) error {
+	logrus.WithFields(logrus.Fields{
+		"id":        session.ID,
+		"userID":    session.UserID,
+		"userAgent": session.UserAgent,
+		"ip":        session.IP,
+	}).Debugf("mongo repository: create session")
+	col := r.DB.Database("mongo_database").Collection("sessions")
+
+	if _, err := col.InsertOne(ctx, session); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			logrus.Error(err, "mongo repository: session with given UUID already exists")
+			return apperror.Conflict("session with given UUID already exists, try to sign in again")
+		}
+		logrus.Error(err, "mongo repository: can't create session")
+		return fmt.Errorf("mongo repository: can't create session - %w", err)
+	}
+
+	return nil
+}
+
+// RotateSession method replaces the last refresh token of the session in mongo database.
+func (r SessionRepositoryMongo) RotateSession(ctx context.Context, oldTokenHash string, session *model.Session) error {
+	logrus.WithFields(logrus.Fields{
+		"id":        session.ID,
+		"userID":    session.UserID,
+		"userAgent": session.UserAgent,
+		"ip":        session.IP,
+	}).Debugf("mongo repository: rotate session")
+	col := r.DB.Database("mongo_database").Collection("sessions")
+
+	filter := sessionFilter(session.UserID, session.ID)
+	result, err := col.UpdateOne(ctx, append(filter, bson.E{Key: "tokenHash", Value: oldTokenHash}), bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "tokenHash", Value: session.TokenHash},
+			{Key: "userAgent", Value: session.UserAgent},
+			{Key: "ip", Value: session.IP},
+			{Key: "lastUsedAt", Value: session.LastUsedAt},
+			{Key: "expiresAt", Value: session.ExpiresAt},
+		}},
+	})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't rotate session")
+		return fmt.Errorf("mongo repository: can't rotate session - %w", err)
+	}
+	if result.MatchedCount != 0 {
+		return nil
+	}
+
+	count, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't rotate session")
+		return fmt.Errorf("mongo repository: can't rotate session - %w", err)
+	}
+	if count != 0 {
+		logrus.Error("mongo repository: refresh token of the session has been already rotated")
+		return apperror.Conflict("refresh token has been already used")
+	}
+
+	logrus.Error("mongo repository: session with given UUID doesn't exist")
+	return apperror.NotFound("session with given UUID doesn't exist")
+}
+
+// ListSessions method returns not expired sessions of the user from mongo database.
+func (r SessionRepositoryMongo) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("mongo repository: list sessions")
+	col := r.DB.Database("mongo_database").Collection("sessions")
+
+	cur, err := col.Find(ctx, bson.D{
+		{Key: "userId", Value: userID},
+		{Key: "expiresAt", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't list sessions")
+		return nil, fmt.Errorf("mongo repository: can't list sessions - %w", err)
+	}
+
+	sessions := make([]*model.Session, 0)
+	if err := cur.All(ctx, &sessions); err != nil {
+		logrus.Error(err, "mongo repository: can't list sessions")
+		return nil, fmt.Errorf("mongo repository: can't list sessions - %w", err)
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession method deletes session of the user from mongo database.
+func (r SessionRepositoryMongo) DeleteSession(ctx context.Context, userID, id string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":     id,
+		"userID": userID,
+	}).Debugf("mongo repository: delete session")
+	col := r.DB.Database("mongo_database").Collection("sessions")
+
+	result, err := col.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}, {Key: "userId", Value: userID}})
+	if err != nil {
+		logrus.Error(err, "mongo repository: can't delete session")
+		return fmt.Errorf("mongo repository: can't delete session - %w", err)
+	}
+	if result.DeletedCount == 0 {
+		logrus.Error("mongo repository: session with given UUID doesn't exist")
+		return apperror.NotFound("session with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// DeleteUserSessions method deletes all sessions of the user from mongo database.
+func (r SessionRepositoryMongo) DeleteUserSessions(ctx context.Context, userID string) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Debugf("mongo repository: delete user sessions")
+	col := r.DB.Database("mongo_database").Collection("sessions")
+
+	if _, err := col.DeleteMany(ctx, bson.D{{Key: "userId", Value: userID}}); err != nil {
+		logrus.Error(err, "mongo repository: can't delete user sessions")
+		return fmt.Errorf("mongo repository: can't delete user sessions - %w", err)
+	}
+
+	return nil
+}
+
+// sessionFilter returns filter of not expired session of the user.
+func sessionFilter(userID, id string) bson.D {
+	return bson.D{
+		{Key: "_id", Value: id},
+		{Key: "userId", Value: userID},
+		{Key: "expiresAt", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// sessionColumns are columns of table sessions in the order scanSession reads them.
+const sessionColumns = "id, user_id, token_hash, user_agent, ip, created_at, last_used_at, expires_at"
+
+// SessionRepository type represents postgres behavior for sessions of users.
+type SessionRepository struct {
+	DB *pgxpool.Pool
+}
+
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{
+		DB: db,
+	}
+}
+
+// CreateSession method saves session into postgres database.
+func (r SessionRepository) CreateSession(ctx context.Context, session *model.Session) error {
+	logrus.WithFields(logrus.Fields{
+		"id":        session.ID,
+		"userID":    session.UserID,
+		"userAgent": session.UserAgent,
+		"ip":        session.IP,
+	}).Info("postgres repository: create session")
+	_, err := r.DB.Exec(ctx, "INSERT INTO sessions ("+sessionColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		session.ID, session.UserID, session.TokenHash, session.UserAgent, session.IP,
+		session.CreatedAt, session.LastUsedAt, session.ExpiresAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			logrus.Error("postgres repository: session with given UUID already exists - ", err)
+			return apperror.Conflict("session with given UUID already exists, try to sign in again")
+
+		default:
+			logrus.Error("postgres repository: can't create session - ", err)
+			return errors.New("can't create session")
+		}
+	}
+
+	return nil
+}
+
+// RotateSession method replaces the last refresh token of the session in postgres database.
+func (r SessionRepository) RotateSession(ctx context.Context, oldTokenHash string, session *model.Session) error {
+	logrus.WithFields(logrus.Fields{
+		"id":        session.ID,
+		"userID":    session.UserID,
+		"userAgent": session.UserAgent,
+		"ip":        session.IP,
+	}).Info("postgres repository: rotate session")
+	result, err := r.DB.Exec(ctx, `UPDATE sessions
+		SET token_hash = $1, user_agent = $2, ip = $3, last_used_at = $4, expires_at = $5
+		WHERE id = $6 AND user_id = $7 AND token_hash = $8 AND expires_at > now()`,
+		session.TokenHash, session.UserAgent, session.IP, session.LastUsedAt, session.ExpiresAt,
+		session.ID, session.UserID, oldTokenHash)
+	if err != nil {
+		logrus.Error("postgres repository: can't rotate session - ", err)
+		return errors.New("can't rotate session")
+	}
+	if result.RowsAffected() != 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := r.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM sessions
+		WHERE id = $1 AND user_id = $2 AND expires_at > now())`,
+		session.ID, session.UserID).Scan(&exists); err != nil {
+		logrus.Error("postgres repository: can't rotate session - ", err)
+		return errors.New("can't rotate session")
+	}
+	if exists {
+		logrus.Error("postgres repository: refresh token of the session has been already rotated")
+		return apperror.Conflict("refresh token has been already used")
+	}
+
+	logrus.Error("postgres repository: session with given UUID doesn't exist")
+	return apperror.NotFound("session with given UUID doesn't exist")
+}
+
+// ListSessions method returns not expired sessions of the user from postgres database.
+func (r SessionRepository) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("postgres repository: list sessions")
+	rows, err := r.DB.Query(ctx, "SELECT "+sessionColumns+` FROM sessions
+		WHERE user_id = $1 AND expires_at > now() ORDER BY created_at, id`, userID)
+	if err != nil {
+		logrus.Error("postgres repository: can't list sessions - ", err)
+		return nil, errors.New("can't list sessions")
+	}
+	defer rows.Close()
+
+	sessions := make([]*model.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			logrus.Error("postgres repository: can't list sessions - ", err)
+			return nil, errors.New("can't list sessions")
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.Error("postgres repository: can't list sessions - ", err)
+		return nil, errors.New("can't list sessions")
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession method deletes session of the user from postgres database.
+func (r SessionRepository) DeleteSession(ctx context.Context, userID, id string) error {
+	logrus.WithFields(logrus.Fields{
+		"id":     id,
+		"userID": userID,
+	}).Info("postgres repository: delete session")
+	result, err := r.DB.Exec(ctx, "DELETE FROM sessions WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		logrus.Error("postgres repository: can't delete session - ", err)
+		return errors.New("can't delete session")
+	}
+	if result.RowsAffected() == 0 {
+		logrus.Error("postgres repository: session with given UUID doesn't exist")
+		return apperror.NotFound("session with given UUID doesn't exist")
+	}
+
+	return nil
+}
+
+// DeleteUserSessions method deletes all sessions of the user from postgres database.
+func (r SessionRepository) DeleteUserSessions(ctx context.Context, userID string) error {
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("postgres repository: delete user sessions")
+	if _, err := r.DB.Exec(ctx, "DELETE FROM sessions WHERE user_id = $1", userID); err != nil {
+		logrus.Error("postgres repository: can't delete user sessions - ", err)
+		return errors.New("can't delete user sessions")
+	}
+
+	return nil
+}
+
+// scanSession reads row selected with sessionColumns.
+func scanSession(row pgx.Row) (*model.Session, error) {
+	var session model.Session
+	if err := row.Scan(&session.ID, &session.UserID, &session.TokenHash, &session.UserAgent, &session.IP,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// GetUser method returns the user without credentials.
+func (s AuthService) GetUser(ctx context.Context, id string) (*model.User, error) {
+	return s.repo.Auth.GetUser(ctx, id)
+}
+
+// UpdateProfile method changes name of the user and returns the updated user.
+// The name gets into tokens at the next sign in or refresh of tokens.
+func (s AuthService) UpdateProfile(ctx context.Context, id string, input *model.UpdateProfile) (*model.User, error) {
+	if err := s.repo.Auth.UpdateUserName(ctx, id, input.UserName); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Auth.GetUser(ctx, id)
+}
+
+// ChangePassword method replaces password of the user after the current password is checked.
+// All sessions of the user are ended, so whoever knew the old password loses access,
+// and new session is started on the device the password is changed from.
+func (s AuthService) ChangePassword(ctx context.Context, id string, input *model.ChangePassword, device *model.Device) (*model.Tokens, error) {
+	user, hash, err := s.checkCurrentPassword(ctx, id, "currentPassword", input.CurrentPassword, device)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPasswordPolicy(input.NewPassword); err != nil {
+		return nil, err
+	}
+
+	hPassword, err := s.hasher.Hash(input.NewPassword)
+	if err != nil {
+		logrus.Error(err, "service: hash password failed")
+		return nil, fmt.Errorf("service: hash password failed - %w", err)
+	}
+	if err := s.repo.Auth.UpdatePassword(ctx, id, hash, hPassword); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.Conflict("password is changed by another request, try again")
+		}
+		return nil, err
+	}
+
+	if err := s.LogoutEverywhere(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.createSession(ctx, user, device)
+}
+
+// DeleteAccount method deletes the user with all credentials and sessions after the password
+// is checked. Cats of the user are kept without owner, so only admins can manage them,
+// and the user is removed from history of cats.
+// The user is deleted last, so the request can be sent again if any step fails.
+func (s AuthService) DeleteAccount(ctx context.Context, id string, input *model.DeleteAccount, device *model.Device) error {
+	if _, _, err := s.checkCurrentPassword(ctx, id, "password", input.Password, device); err != nil {
+		return err
+	}
+
+	if err := s.LogoutEverywhere(ctx, id); err != nil {
+		return err
+	}
+	cats, err := s.repo.Cat.DisownCats(ctx, id)
+	if err != nil {
+		return err
+	}
+	// cached cats still have the owner
+	for _, catID := range cats {
+		if err := s.cats.Delete(ctx, catID); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.Cat.AnonymizeCatChanges(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.Auth.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"userID": id,
+	}).Info("service: account is deleted")
+
+	return nil
+}
+
+// checkCurrentPassword method checks password of the signed in user confirming a sensitive change
+// and returns the user with hash of the password. Wrong passwords count as failed sign in attempts,
+// so a stolen access token doesn't allow guessing the password.
+func (s AuthService) checkCurrentPassword(ctx context.Context, id, field, password string,
+	device *model.Device) (*model.User, string, error) {
+	user, err := s.repo.Auth.GetUser(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	locked, err := s.limiter.Check(ctx, user.Email, device.IP)
+	if err != nil {
+		return nil, "", err
+	}
+	if locked > 0 {
+		return nil, "", apperror.TooManyRequests("too many failed sign in attempts, try again later", locked)
+	}
+
+	_, hash, err := s.repo.Auth.GetUserHashedPassword(ctx, user.Email)
+	if err != nil {
+		return nil, "", err
+	}
+	if hash == "" {
+		// users created by OpenID Connect login have no password till they reset it
+		return nil, "", apperror.ValidationFields("validation failed", []model.FieldError{
+			{Field: field, Message: "can't be checked, the account has no password, reset it first"},
+		})
+	}
+	pass, _, err := s.hasher.Verify(password, hash)
+	if err != nil {
+		logrus.Error(err, "service: can't check password")
+		return nil, "", err
+	}
+	if !pass {
+		logrus.Error("service: incorrect password")
+		if err := s.limiter.RecordFailure(ctx, user.Email, device.IP); err != nil {
+			return nil, "", err
+		}
+		return nil, "", apperror.ValidationFields("validation failed", []model.FieldError{
+			{Field: field, Message: "is incorrect"},
+		})
+	}
+	if err := s.limiter.Reset(ctx, user.Email); err != nil {
+		return nil, "", err
+	}
+
+	return user, hash, nil
+}
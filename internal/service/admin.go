@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+)
+
+// ListUsers method returns page of users matching the input sorted by email.
+func (s AuthService) ListUsers(ctx context.Context, input *model.ListUsers) (*model.UsersPage, error) {
+	if input.Role != "" {
+		if err := checkRole(input.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.Auth.ListUsers(ctx, input)
+}
+
+// DisableUser method disables the user and ends all sessions of the user, so the user
+// loses access at once and can't sign in until enabled. Admins can't disable themselves,
+// so the last admin can't lock everyone out.
+func (s AuthService) DisableUser(ctx context.Context, adminID, id string) error {
+	if adminID == id {
+		return apperror.Conflict("you can't disable your own account")
+	}
+	if err := s.repo.Auth.SetUserDisabled(ctx, id, true); err != nil {
+		return err
+	}
+
+	return s.LogoutEverywhere(ctx, id)
+}
+
+// EnableUser method allows the disabled user to sign in again.
+func (s AuthService) EnableUser(ctx context.Context, id string) error {
+	return s.repo.Auth.SetUserDisabled(ctx, id, false)
+}
+
+// ForceLogout method ends all sessions of the user and revokes all access tokens issued to the user.
+func (s AuthService) ForceLogout(ctx context.Context, id string) error {
+	if _, err := s.repo.Auth.GetUser(ctx, id); err != nil {
+		return err
+	}
+
+	return s.LogoutEverywhere(ctx, id)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// apiKeyPrefix starts every API key, so leaked keys are easy to find by secret scanners
+	apiKeyPrefix = "ftk_"
+	// apiKeyShownLen is the length of the beginning of a key shown in the list of keys
+	apiKeyShownLen = len(apiKeyPrefix) + 8
+	// apiKeyTouchInterval is the precision of the time a key was used at last,
+	// so every request with a key doesn't write into database
+	apiKeyTouchInterval = time.Minute
+)
+
+// CreateAPIKey method issues new API key of the user, only hash of the key is stored,
+// so the key is returned only once.
+func (s AuthService) CreateAPIKey(ctx context.Context, userID string, input *model.CreateAPIKey) (*model.CreatedAPIKey, error) {
+	now := time.Now().UTC()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, apperror.ValidationFields("validation failed", []model.FieldError{
+			{Field: "expiresAt", Message: "must be in the future"},
+		})
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + token
+	apiKey := model.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    key[:apiKeyShownLen],
+		KeyHash:   hashToken(key),
+		Scopes:    input.Scopes,
+		CreatedAt: now,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.repo.Auth.CreateAPIKey(ctx, &apiKey); err != nil {
+		return nil, err
+	}
+
+	return &model.CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// ListAPIKeys method returns API keys of the user without the keys themselves.
+func (s AuthService) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	return s.repo.Auth.ListAPIKeys(ctx, userID)
+}
+
+// DeleteAPIKey method revokes API key of the user, requests with it are rejected at once.
+func (s AuthService) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	return s.repo.Auth.DeleteAPIKey(ctx, userID, id)
+}
+
+// AuthenticateAPIKey method checks API key and returns claims of the owner of the key
+// with scopes of the key. Role and email verification state are taken from the owner,
+// so changes of them apply to keys at once.
+func (s AuthService) AuthenticateAPIKey(ctx context.Context, key string) (*JwtCustomClaims, error) {
+	apiKey, err := s.repo.Auth.GetAPIKey(ctx, hashToken(key))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.Unauthorized("invalid, revoked or expired API key")
+		}
+		return nil, err
+	}
+	user, err := s.repo.Auth.GetUser(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.Unauthorized("invalid, revoked or expired API key")
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		logrus.Error("service: owner of API key is disabled")
+		return nil, apperror.Unauthorized("account is disabled")
+	}
+
+	now := time.Now().UTC()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		// the request doesn't depend on the time, so failure is only logged
+		if err := s.repo.Auth.TouchAPIKey(ctx, apiKey.ID, now); err != nil {
+			logrus.Error(err, "service: can't record usage of API key")
+		}
+	}
+
+	claims := &JwtCustomClaims{
+		Name:          user.UserName,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		TokenType:     TokenTypeAPIKey,
+		Scopes:        apiKey.Scopes,
+	}
+	claims.Id = apiKey.ID
+	claims.Subject = user.ID
+
+	return claims, nil
+}
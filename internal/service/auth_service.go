@@ -2,176 +2,524 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"net/url"
+	"sync"
 	"time"
 
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/malkev1ch/first-task/internal/mail"
+	"github.com/malkev1ch/first-task/internal/oidc"
+	"github.com/malkev1ch/first-task/internal/password"
+	"github.com/malkev1ch/first-task/internal/rediscache"
 	"github.com/malkev1ch/first-task/internal/repository"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"github.com/malkev1ch/first-task/internal/model"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
-const (
-	accessTokenExTime  = 720
-	refreshTokenExTime = 720
+// unknownUserHash is hash of random password compared with passwords of unknown emails,
+// it is computed at the first sign in with unknown email.
+var (
+	unknownUserHash     string
+	unknownUserHashOnce sync.Once
 )
 
-// JwtCustomClaims are custom claims extending default ones.
-type JwtCustomClaims struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	jwt.StandardClaims
-}
-
 type AuthService struct {
-	repo *repository.Repository
+	repo     *repository.Repository
+	tokens   *TokenManager
+	hasher   password.Hasher
+	policy   *password.Policy
+	cats     rediscache.Cat
+	denylist rediscache.Denylist
+	limiter  rediscache.LoginLimiter
+	mailer   mail.Mailer
+	// resetURL and verifyURL are pages of frontend reset and verification tokens are sent to,
+	// resetTTL and verifyTTL are lifetimes of the tokens
+	resetURL  string
+	resetTTL  time.Duration
+	verifyURL string
+	verifyTTL time.Duration
+	// totpIssuer is the name of the service shown by authenticator apps
+	totpIssuer string
+	// oidc is the OpenID Connect provider users sign in with, nil if it isn't configured
+	oidc         *oidc.Provider
+	oidcLoginTTL time.Duration
 }
 
-func NewAuthService(repo *repository.Repository) *AuthService {
-	return &AuthService{repo: repo}
+func NewAuthService(repo *repository.Repository, tokens *TokenManager, hasher password.Hasher, cats rediscache.Cat,
+	denylist rediscache.Denylist, limiter rediscache.LoginLimiter, mailer mail.Mailer, cfg *config.Config) *AuthService {
+	s := &AuthService{
+		repo:       repo,
+		tokens:     tokens,
+		hasher:     hasher,
+		policy:     password.NewPolicy(cfg),
+		cats:       cats,
+		denylist:   denylist,
+		limiter:    limiter,
+		mailer:     mailer,
+		resetURL:   cfg.PasswordResetURL,
+		resetTTL:   cfg.PasswordResetTTL,
+		verifyURL:  cfg.VerificationURL,
+		verifyTTL:  cfg.VerificationTTL,
+		totpIssuer: cfg.TOTPIssuer,
+	}
+	if cfg.OIDCIssuer != "" {
+		s.oidc = oidc.NewProvider(cfg)
+		s.oidcLoginTTL = cfg.OIDCLoginTTL
+	}
+
+	return s
 }
 
-// SignUp method hash user password and after that save user in repository.
-func (s AuthService) SignUp(ctx context.Context, input *model.CreateUser) (*model.Tokens, error) {
-	hPassword, err := s.hashPassword(input.Password)
+// SignUp method checks user password against the password policy, hash it and after that
+// save user in repository. The user is signed in on the device and gets email with link verifying the email.
+func (s AuthService) SignUp(ctx context.Context, input *model.CreateUser, device *model.Device) (*model.Tokens, error) {
+	if err := s.checkPasswordPolicy(input.Password); err != nil {
+		return nil, err
+	}
+	hPassword, err := s.hasher.Hash(input.Password)
 	if err != nil {
 		logrus.Error(err, "service: hash password failed")
 		return nil, fmt.Errorf("service: hash password failed - %w", err)
 	}
 
 	input.Password = hPassword
-	id := uuid.New().String()
-	tokens, err := s.generateToken(input.Email, id)
-	if err != nil {
-		return nil, err
+	user := &model.User{
+		ID: uuid.New().String(), UserName: input.UserName, Email: input.Email, Role: model.RoleEditor,
 	}
-
 	err = s.repo.Auth.CreateUser(ctx, &repository.CreateUserInput{
-		ID: id, UserName: input.UserName, Email: input.Email,
-		Password: input.Password, RefreshToken: tokens.RefreshToken,
+		ID: user.ID, UserName: user.UserName, Email: user.Email,
+		Password: input.Password, Role: user.Role,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return tokens, nil
+	// the user is already created, so failed email doesn't fail sign up, the email can be resent
+	if err := s.sendVerification(ctx, user); err != nil {
+		logrus.Error(err, "service: can't send verification email")
+	}
+
+	return s.createSession(ctx, user, device)
 }
 
-// SignIn Generates tokens for created user.
-func (s AuthService) SignIn(ctx context.Context, input *model.AuthUser) (*model.Tokens, error) {
+// SignIn Generates tokens for created user and starts new session on the device.
+// Unknown email and wrong password fail the same way, so accounts can't be enumerated,
+// and after too many failures the account or the IP is locked for a while. Users with
+// two-factor authentication get only challenge token, it is exchanged for tokens by VerifyTwoFactor.
+// Disabled users are refused once the password is checked.
+func (s AuthService) SignIn(ctx context.Context, input *model.AuthUser, device *model.Device) (*model.Tokens, error) {
+	locked, err := s.limiter.Check(ctx, input.Email, device.IP)
+	if err != nil {
+		return nil, err
+	}
+	if locked > 0 {
+		logrus.WithFields(logrus.Fields{
+			"ip":     device.IP,
+			"locked": locked,
+		}).Warn("service: sign in is locked")
+		return nil, apperror.TooManyRequests("too many failed sign in attempts, try again later", locked)
+	}
+
 	id, hash, err := s.repo.Auth.GetUserHashedPassword(ctx, input.Email)
+	switch {
+	case errors.Is(err, apperror.ErrNotFound) || err == nil && hash == "":
+		// password is checked anyway, so response time doesn't tell whether the email is registered,
+		// users created by OpenID Connect login have no password till they reset it
+		unknownUserHashOnce.Do(func() {
+			unknownUserHash, _ = s.hasher.Hash(uuid.New().String())
+		})
+		_, _, _ = s.hasher.Verify(input.Password, unknownUserHash)
+		logrus.Error("service: user with given email doesn't exist")
+		return nil, s.failSignIn(ctx, input.Email, device)
+
+	case err != nil:
+		return nil, err
+	}
+
+	pass, needsRehash, err := s.hasher.Verify(input.Password, hash)
 	if err != nil {
+		logrus.Error(err, "service: can't check password")
+		return nil, err
+	}
+	if !pass {
+		logrus.Error("service: incorrect password")
+		return nil, s.failSignIn(ctx, input.Email, device)
+	}
+	if needsRehash {
+		s.rehashPassword(ctx, id, input.Password, hash)
+	}
+	if err := s.limiter.Reset(ctx, input.Email); err != nil {
 		return nil, err
 	}
 
-	if pass := s.checkPasswordHash(input.Password, hash); !pass {
-		logrus.Error(err, "service: incorrect password")
-		return nil, fmt.Errorf("service: incorrect password - %w", err)
+	user, err := s.repo.Auth.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	if user.Disabled {
+		// the password is correct, so telling about it doesn't help to guess passwords
+		logrus.Error("service: user is disabled")
+		return nil, apperror.Forbidden("account is disabled")
+	}
+
+	return s.completeSignIn(ctx, user, device)
+}
+
+// completeSignIn method starts new session of the user who proved the first factor. If the user
+// has two-factor authentication enabled, only challenge token for VerifyTwoFactor is returned.
+func (s AuthService) completeSignIn(ctx context.Context, user *model.User, device *model.Device) (*model.Tokens, error) {
+	twoFactor, err := s.repo.Auth.GetTwoFactor(ctx, user.ID)
+	switch {
+	case err == nil && twoFactor.Enabled:
+		challengeToken, err := s.tokens.Challenge(user, uuid.New().String())
+		if err != nil {
+			return nil, err
+		}
+		return &model.Tokens{ChallengeToken: challengeToken}, nil
 
-	tokens, err := s.generateToken(input.Email, id)
+	case err != nil && !errors.Is(err, apperror.ErrNotFound):
+		return nil, err
+	}
+
+	return s.createSession(ctx, user, device)
+}
+
+// RefreshToken method checks refresh token for validity and if it's ok return new token pair
+// of the same session. The refresh token can be used only once, reuse of it means the token
+// has been stolen, so the whole session with all its tokens is revoked.
+func (s AuthService) RefreshToken(ctx context.Context, refreshTokenString string, device *model.Device) (*model.Tokens, error) {
+	claims, err := s.tokens.Parse(refreshTokenString, TokenTypeRefresh)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.Auth.UpdateUserRefreshToken(ctx, id, tokens.RefreshToken); err != nil {
+	user, err := s.repo.Auth.GetUser(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.Unauthorized("invalid refresh token")
+		}
+		return nil, fmt.Errorf("service: token refresh failed - %w", err)
+	}
+	if user.Disabled {
+		logrus.Error("service: user is disabled")
+		return nil, apperror.Unauthorized("account is disabled")
+	}
+
+	tokens, err := s.tokens.Generate(user, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	err = s.repo.Session.RotateSession(ctx, hashToken(refreshTokenString), &model.Session{
+		ID:         claims.SessionID,
+		UserID:     claims.Subject,
+		TokenHash:  hashToken(tokens.RefreshToken),
+		UserAgent:  device.UserAgent,
+		IP:         device.IP,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.tokens.RefreshTTL()),
+	})
+	switch {
+	case errors.Is(err, apperror.ErrConflict):
+		logrus.WithFields(logrus.Fields{
+			"userID":    claims.Subject,
+			"sessionID": claims.SessionID,
+		}).Warn("service: reuse of refresh token, session is revoked")
+		if err := s.repo.Session.DeleteSession(ctx, claims.Subject, claims.SessionID); err != nil &&
+			!errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, apperror.Unauthorized("invalid refresh token")
+
+	case errors.Is(err, apperror.ErrNotFound):
+		return nil, apperror.Unauthorized("session is expired or ended")
+
+	case err != nil:
 		return nil, err
 	}
 
 	return tokens, nil
 }
 
-// RefreshToken method checks refresh token for validity and if it's ok return new token pair.
-func (s AuthService) RefreshToken(ctx context.Context, refreshTokenString string) (*model.Tokens, error) {
-	refreshToken, err := jwt.Parse(refreshTokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_KEY")), nil
+// Authenticate method checks access token for validity and returns its claims
+// if the token isn't revoked by logout.
+func (s AuthService) Authenticate(ctx context.Context, accessTokenString string) (*JwtCustomClaims, error) {
+	claims, err := s.tokens.Parse(accessTokenString, TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.denylist.IsRevoked(ctx, claims.Id, claims.Subject, claims.issuedAt())
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		logrus.Error("service: access token is revoked")
+		return nil, apperror.Unauthorized("access token is revoked")
+	}
+
+	return claims, nil
+}
+
+// Logout method ends the session of access token and revokes the token.
+func (s AuthService) Logout(ctx context.Context, claims *JwtCustomClaims) error {
+	err := s.repo.Session.DeleteSession(ctx, claims.Subject, claims.SessionID)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return err
+	}
+
+	return s.denylist.RevokeToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0))
+}
+
+// LogoutEverywhere method ends all sessions of the user and revokes all access tokens issued to the user.
+func (s AuthService) LogoutEverywhere(ctx context.Context, userID string) error {
+	if err := s.repo.Session.DeleteUserSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	// access tokens issued before now expire within their lifetime
+	return s.denylist.RevokeUserTokens(ctx, userID, time.Now(), s.tokens.AccessTTL())
+}
+
+// JWKS method returns public keys verifying tokens.
+func (s AuthService) JWKS() *model.JWKS {
+	return s.tokens.keys.JWKS()
+}
+
+// ListSessions method returns active sessions of the user.
+func (s AuthService) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	return s.repo.Session.ListSessions(ctx, userID)
+}
+
+// DeleteSession method ends session of the user, its refresh token can't be used anymore.
+func (s AuthService) DeleteSession(ctx context.Context, userID, id string) error {
+	return s.repo.Session.DeleteSession(ctx, userID, id)
+}
+
+// ForgotPassword method sends email with single-use reset token to the user with the email.
+// It succeeds for unknown emails too, so the result doesn't tell whether the email is registered.
+func (s AuthService) ForgotPassword(ctx context.Context, email string) error {
+	id, _, err := s.repo.Auth.GetUserHashedPassword(ctx, email)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			logrus.Info("service: password reset is requested for unknown email")
+			return nil
+		}
+		return err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Auth.CreatePasswordReset(ctx, &model.PasswordReset{
+		TokenHash: hashToken(token),
+		UserID:    id,
+		ExpiresAt: time.Now().UTC().Add(s.resetTTL),
+	}); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &mail.Message{
+		To:      email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Somebody has requested reset of your password. If it was you, use the link "+
+			"below to set a new password, it expires in %s and can be used only once.\n\n%s\n\n"+
+			"Reset token: %s\n\nIf you didn't request it, just ignore this email.\n",
+			s.resetTTL, tokenLink(s.resetURL, token), token),
 	})
+}
+
+// ResetPassword method replaces password of the user the reset token is sent to.
+// All sessions of the user are ended, so whoever knew the old password loses access.
+func (s AuthService) ResetPassword(ctx context.Context, input *model.ResetPassword) error {
+	if err := s.checkPasswordPolicy(input.Password); err != nil {
+		return err
+	}
+	hPassword, err := s.hasher.Hash(input.Password)
 	if err != nil {
-		logrus.Error(err, "service: can't parse refresh token")
-		return nil, fmt.Errorf("service: can't parse refresh token - %w", err)
+		logrus.Error(err, "service: hash password failed")
+		return fmt.Errorf("service: hash password failed - %w", err)
 	}
-	if !refreshToken.Valid {
-		logrus.Info("service: expired refresh token")
-		return nil, fmt.Errorf("service: expired refresh token")
+
+	id, err := s.repo.Auth.ResetPassword(ctx, hashToken(input.Token), hPassword)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.Unauthorized("reset token is invalid, used or expired")
+		}
+		return err
 	}
-	claims := refreshToken.Claims.(jwt.MapClaims)
-	userID := claims["jti"]
-	email := claims["email"]
-	if userID == "" || email == "" {
-		logrus.Error(err, "service: error while parsing claims", userID, email)
-		return nil, fmt.Errorf("service: error while parsing claims")
+
+	return s.LogoutEverywhere(ctx, id)
+}
+
+// VerifyEmail method marks email of the user the verification token is sent to as verified.
+// The state gets into tokens at the next sign in or refresh of tokens.
+func (s AuthService) VerifyEmail(ctx context.Context, token string) error {
+	if _, err := s.repo.Auth.VerifyEmail(ctx, hashToken(token)); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.Unauthorized("verification token is invalid, used or expired")
+		}
+		return err
 	}
 
-	lastRefreshTokenStringLast, err := s.repo.GetUserRefreshToken(ctx, userID.(string))
+	return nil
+}
+
+// ResendVerification method sends email with a new verification token to the user.
+func (s AuthService) ResendVerification(ctx context.Context, userID string) error {
+	user, err := s.repo.Auth.GetUser(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("service: token refresh failed - %w", err)
+		return err
 	}
-	if refreshTokenString != lastRefreshTokenStringLast {
-		logrus.Error(err, "service: invalid refresh token")
-		return nil, fmt.Errorf("service: invalid refresh token")
+	if user.EmailVerified {
+		return apperror.Conflict("email is already verified")
 	}
 
-	tokens, err := s.generateToken(fmt.Sprintf("%v", email), fmt.Sprintf("%v", userID))
+	return s.sendVerification(ctx, user)
+}
+
+// ListLockouts method returns current lockouts of sign in to accounts.
+func (s AuthService) ListLockouts(ctx context.Context) ([]*model.Lockout, error) {
+	return s.limiter.Lockouts(ctx)
+}
+
+// UnlockUser method ends lockout of sign in to the account of the user.
+func (s AuthService) UnlockUser(ctx context.Context, id string) error {
+	user, err := s.repo.Auth.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.limiter.Unlock(ctx, user.Email)
+}
+
+// SetUserRole method assigns role to the user. The role gets into tokens
+// at the next sign in or refresh of tokens. Admins can't change their own role,
+// so the last admin can't give up managing users.
+func (s AuthService) SetUserRole(ctx context.Context, adminID, id, role string) error {
+	if err := checkRole(role); err != nil {
+		return err
+	}
+	if adminID == id {
+		return apperror.Conflict("you can't change your own role")
+	}
+
+	return s.repo.Auth.UpdateUserRole(ctx, id, role)
+}
+
+// checkRole returns validation error if the role is unknown.
+func checkRole(role string) error {
+	switch role {
+	case model.RoleAdmin, model.RoleEditor, model.RoleViewer:
+		return nil
+	default:
+		return apperror.Validation(fmt.Sprintf("role should be one of %s, %s, %s",
+			model.RoleAdmin, model.RoleEditor, model.RoleViewer))
+	}
+}
+
+// createSession method starts new session of the user on the device and returns its tokens.
+func (s AuthService) createSession(ctx context.Context, user *model.User, device *model.Device) (*model.Tokens, error) {
+	sessionID := uuid.New().String()
+	tokens, err := s.tokens.Generate(user, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.Auth.UpdateUserRefreshToken(ctx, fmt.Sprintf("%v", userID), tokens.RefreshToken); err != nil {
+	now := time.Now().UTC()
+	if err := s.repo.Session.CreateSession(ctx, &model.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		TokenHash:  hashToken(tokens.RefreshToken),
+		UserAgent:  device.UserAgent,
+		IP:         device.IP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.tokens.RefreshTTL()),
+	}); err != nil {
 		return nil, err
 	}
 
 	return tokens, nil
 }
 
-// hashPassword from string
-// bcrypt.DefaultCost = 10.
-func (s AuthService) hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+// failSignIn method records failed sign in attempt and returns the error, the same for every failure.
+func (s AuthService) failSignIn(ctx context.Context, email string, device *model.Device) error {
+	if err := s.limiter.RecordFailure(ctx, email, device.IP); err != nil {
+		return err
+	}
+
+	return apperror.Unauthorized("incorrect email or password")
 }
 
-// checkPasswordHash compare encrypt.
-func (s AuthService) checkPasswordHash(password string, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+// sendVerification method saves new verification token of the user and sends it to email of the user.
+func (s AuthService) sendVerification(ctx context.Context, user *model.User) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Auth.CreateEmailVerification(ctx, &model.EmailVerification{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(s.verifyTTL),
+	}); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Email verification",
+		Body: fmt.Sprintf("Hello, %s! Use the link below to verify your email, "+
+			"it expires in %s and can be used only once.\n\n%s\n\nVerification token: %s\n",
+			user.UserName, s.verifyTTL, tokenLink(s.verifyURL, token), token),
+	})
 }
 
-// generateToken generates new token pair and with putting email inside payload.
-func (s AuthService) generateToken(email string, id string) (*model.Tokens, error) {
-	expirationTimeAT := time.Now().Add(accessTokenExTime * time.Hour)
-	expirationTimeRT := time.Now().Add(refreshTokenExTime * time.Hour)
+// tokenLink returns link to the page of frontend with the token, or the token itself
+// if the page isn't configured.
+func tokenLink(pageURL, token string) string {
+	if pageURL == "" {
+		return token
+	}
 
-	accessTokensClaims := &JwtCustomClaims{
-		Email: email,
-		StandardClaims: jwt.StandardClaims{
-			Id:        id,
-			ExpiresAt: expirationTimeAT.Unix(),
-		},
+	return pageURL + "?token=" + url.QueryEscape(token)
+}
+
+// checkPasswordPolicy returns validation error listing the rules of the password policy the password breaks.
+func (s AuthService) checkPasswordPolicy(password string) error {
+	violations := s.policy.Check(password)
+	if len(violations) == 0 {
+		return nil
 	}
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokensClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(os.Getenv("JWT_KEY")))
-	if err != nil {
-		logrus.Error(err, "service: can't generate access token")
-		return nil, fmt.Errorf("service: can't generate access token - %w", err)
+	fields := make([]model.FieldError, 0, len(violations))
+	for _, violation := range violations {
+		fields = append(fields, model.FieldError{Field: "password", Message: violation})
 	}
 
-	refreshTokensClaims := &JwtCustomClaims{
-		Email: email,
-		StandardClaims: jwt.StandardClaims{
-			Id:        id,
-			ExpiresAt: expirationTimeRT.Unix(),
-		},
-	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokensClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(os.Getenv("JWT_KEY")))
+	return apperror.ValidationFields("validation failed", fields)
+}
+
+// rehashPassword replaces hash of the password made by other algorithm or with other parameters
+// with the hash of the current hasher. The user is already signed in, so failure is only logged
+// and the hash is upgraded at the next sign in.
+func (s AuthService) rehashPassword(ctx context.Context, id, password, oldHash string) {
+	newHash, err := s.hasher.Hash(password)
 	if err != nil {
-		logrus.Error(err, "service: can't generate refresh token")
-		return nil, fmt.Errorf("service: can't generate refresh token - %w", err)
+		logrus.Error(err, "service: can't rehash password")
+		return
 	}
-
-	return &model.Tokens{AccessToken: accessTokenString, RefreshToken: refreshTokenString}, nil
+	if err := s.repo.Auth.UpdatePassword(ctx, id, oldHash, newHash); err != nil {
+		logrus.Error(err, "service: can't save rehashed password")
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"userID": id,
+	}).Info("service: password is rehashed")
 }
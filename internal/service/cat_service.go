@@ -2,7 +2,11 @@ package service
 
 import (
 	"context"
+	"errors"
+	"os"
+	"time"
 
+	"github.com/malkev1ch/first-task/internal/apperror"
 	"github.com/malkev1ch/first-task/internal/rediscache"
 	"github.com/malkev1ch/first-task/internal/repository"
 
@@ -11,6 +15,10 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// maxWriteAttempts is how many times a change of the cat without expected version is tried
+// if the cat keeps being changed concurrently.
+const maxWriteAttempts = 3
+
 type CatService struct {
 	repo  *repository.Repository
 	redis *rediscache.Cache
@@ -20,9 +28,12 @@ func NewCatService(repo *repository.Repository, redis *rediscache.Cache) *CatSer
 	return &CatService{repo: repo, redis: redis}
 }
 
+// Create method saves the cat, the owner of the cat is recorded as the author of the change.
 func (s CatService) Create(ctx context.Context, cat *model.Cat) (string, error) {
 	id := uuid.New().String()
 	cat.ID = id
+	// repositories create cats with the first version
+	cat.Version = 1
 	if err := s.redis.Cat.Set(ctx, cat); err != nil {
 		return "", err
 	}
@@ -30,22 +41,35 @@ func (s CatService) Create(ctx context.Context, cat *model.Cat) (string, error)
 	if err := s.repo.Create(ctx, cat); err != nil {
 		return "", err
 	}
+
+	if err := s.addChange(ctx, cat.OwnerID, model.CatOperationCreate, nil, cat); err != nil {
+		return "", err
+	}
 	return id, nil
 }
 
-func (s CatService) Get(ctx context.Context, id string) (*model.Cat, error) {
+func (s CatService) Get(ctx context.Context, ownerID, id string) (*model.Cat, error) {
 	cat, ex := s.redis.Cat.Get(ctx, id)
 	if !ex {
 		logrus.Info("got cat from database")
-		return s.repo.Cat.Get(ctx, id)
+		return s.repo.Cat.Get(ctx, ownerID, id)
+	}
+	if ownerID != "" && cat.OwnerID != ownerID {
+		return nil, apperror.NotFound("cat with given UUID doesn't exist")
 	}
 
 	logrus.Info("got cat from cache")
 	return cat, nil
 }
 
-func (s CatService) Update(ctx context.Context, id string, input *model.UpdateCat) (*model.Cat, error) {
-	cat, err := s.repo.Cat.Update(ctx, id, input)
+// Update method updates the cat if it has the version and records the change made by the actor.
+func (s CatService) Update(ctx context.Context, actorID, ownerID, id string, version int64,
+	input *model.UpdateCat) (*model.Cat, error) {
+	var cat *model.Cat
+	before, err := s.writeVersion(ctx, ownerID, id, version, func(version int64) (err error) {
+		cat, err = s.repo.Cat.Update(ctx, ownerID, id, version, input)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -54,23 +78,96 @@ func (s CatService) Update(ctx context.Context, id string, input *model.UpdateCa
 		return nil, err
 	}
 
+	if err := s.addChange(ctx, actorID, model.CatOperationUpdate, before, cat); err != nil {
+		return nil, err
+	}
+
 	return cat, nil
 }
 
-func (s CatService) Delete(ctx context.Context, id string) error {
-	if err := s.redis.Cat.Delete(ctx, id); err != nil {
+// Delete method moves the cat to trash if it has the version and records the change made by the actor.
+// The cat is purged for good by PurgeTrash after the retention period.
+func (s CatService) Delete(ctx context.Context, actorID, ownerID, id string, version int64) error {
+	// the repository checks the owner, so the cat leaves cache only after it's deleted
+	deletedAt := time.Now().UTC()
+	before, err := s.writeVersion(ctx, ownerID, id, version, func(version int64) error {
+		return s.repo.Cat.Delete(ctx, ownerID, id, version, deletedAt)
+	})
+	if err != nil {
 		return err
 	}
 
-	if err := s.repo.Cat.Delete(ctx, id); err != nil {
+	if err := s.redis.Cat.Delete(ctx, id); err != nil {
 		return err
 	}
 
-	return nil
+	after := *before
+	after.DeletedAt = &deletedAt
+	after.Version++
+	return s.addChange(ctx, actorID, model.CatOperationDelete, before, &after)
+}
+
+// Restore method moves the cat out of trash and records the change made by the actor.
+func (s CatService) Restore(ctx context.Context, actorID, ownerID, id string) (*model.Cat, error) {
+	before, err := s.repo.Cat.GetTrashed(ctx, ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cat, err := s.repo.Cat.Restore(ctx, ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.redis.Cat.Set(ctx, cat); err != nil {
+		return nil, err
+	}
+
+	if err := s.addChange(ctx, actorID, model.CatOperationRestore, before, cat); err != nil {
+		return nil, err
+	}
+
+	return cat, nil
+}
+
+// PurgeTrash method deletes cats moved to trash before deletedBefore for good with their images
+// and returns the number of purged cats. Purges are recorded without actor. The cats are gone
+// once they are purged, so the number is returned even if purges can't be recorded.
+func (s CatService) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	cats, err := s.repo.Cat.PurgeCats(ctx, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cat := range cats {
+		if cat.ImagePath != "" {
+			if err := os.Remove(cat.ImagePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				// the cat is gone already, so the image is only left orphaned
+				logrus.Error("service: can't remove image of purged cat - ", err)
+			}
+		}
+	}
+
+	var historyErr error
+	for _, cat := range cats {
+		if err := s.addChange(ctx, "", model.CatOperationPurge, cat, nil); err != nil {
+			logrus.Error("service: can't record purge of cat - ", err)
+			if historyErr == nil {
+				historyErr = err
+			}
+		}
+	}
+
+	return len(cats), historyErr
 }
 
-func (s CatService) UploadImage(ctx context.Context, id, path string) error {
-	cat, err := s.repo.Cat.UploadImage(ctx, id, path)
+// UploadImage method sets image path of the cat if it has the version and records the change made by the actor.
+func (s CatService) UploadImage(ctx context.Context, actorID, ownerID, id string, version int64, path string) error {
+	var cat *model.Cat
+	before, err := s.writeVersion(ctx, ownerID, id, version, func(version int64) (err error) {
+		cat, err = s.repo.Cat.UploadImage(ctx, ownerID, id, version, path)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -79,5 +176,115 @@ func (s CatService) UploadImage(ctx context.Context, id, path string) error {
 		return err
 	}
 
-	return nil
+	return s.addChange(ctx, actorID, model.CatOperationUploadImage, before, cat)
+}
+
+// writeVersion method reads the cat and runs write of the version it has read, so the returned cat
+// is exactly the state the write replaced even if the cat is changed concurrently. The write fails
+// if the cat doesn't have the version, zero version retries the write up to maxWriteAttempts times.
+func (s CatService) writeVersion(ctx context.Context, ownerID, id string, version int64,
+	write func(version int64) error) (*model.Cat, error) {
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		before, err := s.repo.Cat.Get(ctx, ownerID, id)
+		if err != nil {
+			return nil, err
+		}
+		if version != 0 && version != before.Version {
+			return nil, apperror.PreconditionFailed("cat with given UUID has been changed, get it again")
+		}
+
+		err = write(before.Version)
+		if version == 0 && errors.Is(err, apperror.ErrPreconditionFailed) && attempt < maxWriteAttempts {
+			// the cat has been changed since it was read
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return before, nil
+	}
+}
+
+func (s CatService) List(ctx context.Context, input *model.ListCats) (*model.CatsPage, error) {
+	return s.repo.Cat.List(ctx, input)
+}
+
+// History method returns page of changes of the cat, the latest changes go first. The history is kept
+// after the cat is deleted, so the cat is looked up only if it has no changes to tell unknown cats apart.
+func (s CatService) History(ctx context.Context, input *model.ListCatChanges) (*model.CatChangesPage, error) {
+	page, err := s.repo.Cat.ListCatChanges(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Changes) == 0 && input.Cursor == "" {
+		if _, err := s.repo.Cat.Get(ctx, input.OwnerID, input.CatID); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// addChange appends the change of the cat from before to after made by the actor to the history,
+// nil before means created cat and nil after means purged cat. Changes of no fields aren't recorded.
+func (s CatService) addChange(ctx context.Context, actorID, operation string, before, after *model.Cat) error {
+	fields := changedFields(before, after)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	change := &model.CatChange{
+		ID:        uuid.New().String(),
+		ActorID:   actorID,
+		Operation: operation,
+		ChangedAt: time.Now().UTC(),
+		Fields:    fields,
+		Before:    before,
+		After:     after,
+	}
+	if after != nil {
+		change.CatID, change.OwnerID = after.ID, after.OwnerID
+	} else {
+		change.CatID, change.OwnerID = before.ID, before.OwnerID
+	}
+
+	return s.repo.Cat.AddCatChange(ctx, change)
+}
+
+// changedFields returns JSON names of fields which differ in the cats, nil cat has no fields set.
+func changedFields(before, after *model.Cat) []string {
+	if before == nil {
+		before = &model.Cat{}
+	}
+	if after == nil {
+		after = &model.Cat{}
+	}
+
+	fields := make([]string, 0)
+	if before.Name != after.Name {
+		fields = append(fields, "name")
+	}
+	if !before.DateBirth.Equal(after.DateBirth) {
+		fields = append(fields, "dateBirth")
+	}
+	if before.Vaccinated != after.Vaccinated {
+		fields = append(fields, "vaccinated")
+	}
+	if before.ImagePath != after.ImagePath {
+		fields = append(fields, "imagePath")
+	}
+	if before.OwnerID != after.OwnerID {
+		fields = append(fields, "ownerId")
+	}
+	if (before.DeletedAt == nil) != (after.DeletedAt == nil) ||
+		(before.DeletedAt != nil && !before.DeletedAt.Equal(*after.DeletedAt)) {
+		fields = append(fields, "deletedAt")
+	}
+
+	return fields
 }
@@ -7,9 +7,11 @@ package mock_service
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/malkev1ch/first-task/internal/model"
+	service "github.com/malkev1ch/first-task/internal/service"
 )
 
 // MockCat is a mock of Cat interface.
@@ -51,61 +53,121 @@ func (mr *MockCatMockRecorder) Create(ctx, cat interface{}) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockCat) Delete(ctx context.Context, id string) error {
+func (m *MockCat) Delete(ctx context.Context, actorID, ownerID, id string, version int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, actorID, ownerID, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockCatMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *MockCatMockRecorder) Delete(ctx, actorID, ownerID, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCat)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCat)(nil).Delete), ctx, actorID, ownerID, id, version)
 }
 
 // Get mocks base method.
-func (m *MockCat) Get(ctx context.Context, id string) (*model.Cat, error) {
+func (m *MockCat) Get(ctx context.Context, ownerID, id string) (*model.Cat, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret := m.ctrl.Call(m, "Get", ctx, ownerID, id)
 	ret0, _ := ret[0].(*model.Cat)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockCatMockRecorder) Get(ctx, id interface{}) *gomock.Call {
+func (mr *MockCatMockRecorder) Get(ctx, ownerID, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCat)(nil).Get), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockCat)(nil).Get), ctx, ownerID, id)
+}
+
+// History mocks base method.
+func (m *MockCat) History(ctx context.Context, input *model.ListCatChanges) (*model.CatChangesPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx, input)
+	ret0, _ := ret[0].(*model.CatChangesPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History.
+func (mr *MockCatMockRecorder) History(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockCat)(nil).History), ctx, input)
+}
+
+// List mocks base method.
+func (m *MockCat) List(ctx context.Context, input *model.ListCats) (*model.CatsPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, input)
+	ret0, _ := ret[0].(*model.CatsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockCatMockRecorder) List(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockCat)(nil).List), ctx, input)
+}
+
+// PurgeTrash mocks base method.
+func (m *MockCat) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeTrash", ctx, deletedBefore)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeTrash indicates an expected call of PurgeTrash.
+func (mr *MockCatMockRecorder) PurgeTrash(ctx, deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeTrash", reflect.TypeOf((*MockCat)(nil).PurgeTrash), ctx, deletedBefore)
+}
+
+// Restore mocks base method.
+func (m *MockCat) Restore(ctx context.Context, actorID, ownerID, id string) (*model.Cat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, actorID, ownerID, id)
+	ret0, _ := ret[0].(*model.Cat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockCatMockRecorder) Restore(ctx, actorID, ownerID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockCat)(nil).Restore), ctx, actorID, ownerID, id)
 }
 
 // Update mocks base method.
-func (m *MockCat) Update(ctx context.Context, id string, input *model.UpdateCat) (*model.Cat, error) {
+func (m *MockCat) Update(ctx context.Context, actorID, ownerID, id string, version int64, input *model.UpdateCat) (*model.Cat, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", ctx, id, input)
+	ret := m.ctrl.Call(m, "Update", ctx, actorID, ownerID, id, version, input)
 	ret0, _ := ret[0].(*model.Cat)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockCatMockRecorder) Update(ctx, id, input interface{}) *gomock.Call {
+func (mr *MockCatMockRecorder) Update(ctx, actorID, ownerID, id, version, input interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockCat)(nil).Update), ctx, id, input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockCat)(nil).Update), ctx, actorID, ownerID, id, version, input)
 }
 
 // UploadImage mocks base method.
-func (m *MockCat) UploadImage(ctx context.Context, id, path string) error {
+func (m *MockCat) UploadImage(ctx context.Context, actorID, ownerID, id string, version int64, path string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UploadImage", ctx, id, path)
+	ret := m.ctrl.Call(m, "UploadImage", ctx, actorID, ownerID, id, version, path)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UploadImage indicates an expected call of UploadImage.
-func (mr *MockCatMockRecorder) UploadImage(ctx, id, path interface{}) *gomock.Call {
+func (mr *MockCatMockRecorder) UploadImage(ctx, actorID, ownerID, id, version, path interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadImage", reflect.TypeOf((*MockCat)(nil).UploadImage), ctx, id, path)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadImage", reflect.TypeOf((*MockCat)(nil).UploadImage), ctx, actorID, ownerID, id, version, path)
 }
 
 // MockAuth is a mock of Auth interface.
@@ -131,47 +193,497 @@ func (m *MockAuth) EXPECT() *MockAuthMockRecorder {
 	return m.recorder
 }
 
+// Authenticate mocks base method.
+func (m *MockAuth) Authenticate(ctx context.Context, accessTokenString string) (*service.JwtCustomClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, accessTokenString)
+	ret0, _ := ret[0].(*service.JwtCustomClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockAuthMockRecorder) Authenticate(ctx, accessTokenString interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockAuth)(nil).Authenticate), ctx, accessTokenString)
+}
+
+// AuthenticateAPIKey mocks base method.
+func (m *MockAuth) AuthenticateAPIKey(ctx context.Context, key string) (*service.JwtCustomClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateAPIKey", ctx, key)
+	ret0, _ := ret[0].(*service.JwtCustomClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateAPIKey indicates an expected call of AuthenticateAPIKey.
+func (mr *MockAuthMockRecorder) AuthenticateAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateAPIKey", reflect.TypeOf((*MockAuth)(nil).AuthenticateAPIKey), ctx, key)
+}
+
+// ChangePassword mocks base method.
+func (m *MockAuth) ChangePassword(ctx context.Context, id string, input *model.ChangePassword, device *model.Device) (*model.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, id, input, device)
+	ret0, _ := ret[0].(*model.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockAuthMockRecorder) ChangePassword(ctx, id, input, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockAuth)(nil).ChangePassword), ctx, id, input, device)
+}
+
+// ConfirmTwoFactor mocks base method.
+func (m *MockAuth) ConfirmTwoFactor(ctx context.Context, userID, code string) (*model.RecoveryCodes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTwoFactor", ctx, userID, code)
+	ret0, _ := ret[0].(*model.RecoveryCodes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTwoFactor indicates an expected call of ConfirmTwoFactor.
+func (mr *MockAuthMockRecorder) ConfirmTwoFactor(ctx, userID, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTwoFactor", reflect.TypeOf((*MockAuth)(nil).ConfirmTwoFactor), ctx, userID, code)
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockAuth) CreateAPIKey(ctx context.Context, userID string, input *model.CreateAPIKey) (*model.CreatedAPIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, userID, input)
+	ret0, _ := ret[0].(*model.CreatedAPIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockAuthMockRecorder) CreateAPIKey(ctx, userID, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockAuth)(nil).CreateAPIKey), ctx, userID, input)
+}
+
+// DeleteAPIKey mocks base method.
+func (m *MockAuth) DeleteAPIKey(ctx context.Context, userID, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", ctx, userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey.
+func (mr *MockAuthMockRecorder) DeleteAPIKey(ctx, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockAuth)(nil).DeleteAPIKey), ctx, userID, id)
+}
+
+// DeleteAccount mocks base method.
+func (m *MockAuth) DeleteAccount(ctx context.Context, id string, input *model.DeleteAccount, device *model.Device) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccount", ctx, id, input, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount.
+func (mr *MockAuthMockRecorder) DeleteAccount(ctx, id, input, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockAuth)(nil).DeleteAccount), ctx, id, input, device)
+}
+
+// DeleteSession mocks base method.
+func (m *MockAuth) DeleteSession(ctx context.Context, userID, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSession", ctx, userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSession indicates an expected call of DeleteSession.
+func (mr *MockAuthMockRecorder) DeleteSession(ctx, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockAuth)(nil).DeleteSession), ctx, userID, id)
+}
+
+// DisableTwoFactor mocks base method.
+func (m *MockAuth) DisableTwoFactor(ctx context.Context, userID, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTwoFactor", ctx, userID, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTwoFactor indicates an expected call of DisableTwoFactor.
+func (mr *MockAuthMockRecorder) DisableTwoFactor(ctx, userID, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTwoFactor", reflect.TypeOf((*MockAuth)(nil).DisableTwoFactor), ctx, userID, code)
+}
+
+// DisableUser mocks base method.
+func (m *MockAuth) DisableUser(ctx context.Context, adminID, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableUser", ctx, adminID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableUser indicates an expected call of DisableUser.
+func (mr *MockAuthMockRecorder) DisableUser(ctx, adminID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableUser", reflect.TypeOf((*MockAuth)(nil).DisableUser), ctx, adminID, id)
+}
+
+// EnableUser mocks base method.
+func (m *MockAuth) EnableUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableUser indicates an expected call of EnableUser.
+func (mr *MockAuthMockRecorder) EnableUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableUser", reflect.TypeOf((*MockAuth)(nil).EnableUser), ctx, id)
+}
+
+// EnrollTwoFactor mocks base method.
+func (m *MockAuth) EnrollTwoFactor(ctx context.Context, userID string) (*model.TwoFactorEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTwoFactor", ctx, userID)
+	ret0, _ := ret[0].(*model.TwoFactorEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTwoFactor indicates an expected call of EnrollTwoFactor.
+func (mr *MockAuthMockRecorder) EnrollTwoFactor(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTwoFactor", reflect.TypeOf((*MockAuth)(nil).EnrollTwoFactor), ctx, userID)
+}
+
+// ForceLogout mocks base method.
+func (m *MockAuth) ForceLogout(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceLogout", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForceLogout indicates an expected call of ForceLogout.
+func (mr *MockAuthMockRecorder) ForceLogout(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceLogout", reflect.TypeOf((*MockAuth)(nil).ForceLogout), ctx, id)
+}
+
+// ForgotPassword mocks base method.
+func (m *MockAuth) ForgotPassword(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgotPassword", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForgotPassword indicates an expected call of ForgotPassword.
+func (mr *MockAuthMockRecorder) ForgotPassword(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgotPassword", reflect.TypeOf((*MockAuth)(nil).ForgotPassword), ctx, email)
+}
+
+// GetUser mocks base method.
+func (m *MockAuth) GetUser(ctx context.Context, id string) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", ctx, id)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockAuthMockRecorder) GetUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockAuth)(nil).GetUser), ctx, id)
+}
+
+// JWKS mocks base method.
+func (m *MockAuth) JWKS() *model.JWKS {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(*model.JWKS)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockAuthMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockAuth)(nil).JWKS))
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockAuth) ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx, userID)
+	ret0, _ := ret[0].([]*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockAuthMockRecorder) ListAPIKeys(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockAuth)(nil).ListAPIKeys), ctx, userID)
+}
+
+// ListLockouts mocks base method.
+func (m *MockAuth) ListLockouts(ctx context.Context) ([]*model.Lockout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLockouts", ctx)
+	ret0, _ := ret[0].([]*model.Lockout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLockouts indicates an expected call of ListLockouts.
+func (mr *MockAuthMockRecorder) ListLockouts(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLockouts", reflect.TypeOf((*MockAuth)(nil).ListLockouts), ctx)
+}
+
+// ListSessions mocks base method.
+func (m *MockAuth) ListSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", ctx, userID)
+	ret0, _ := ret[0].([]*model.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockAuthMockRecorder) ListSessions(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockAuth)(nil).ListSessions), ctx, userID)
+}
+
+// ListUsers mocks base method.
+func (m *MockAuth) ListUsers(ctx context.Context, input *model.ListUsers) (*model.UsersPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", ctx, input)
+	ret0, _ := ret[0].(*model.UsersPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockAuthMockRecorder) ListUsers(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockAuth)(nil).ListUsers), ctx, input)
+}
+
+// Logout mocks base method.
+func (m *MockAuth) Logout(ctx context.Context, claims *service.JwtCustomClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, claims)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockAuthMockRecorder) Logout(ctx, claims interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockAuth)(nil).Logout), ctx, claims)
+}
+
+// LogoutEverywhere mocks base method.
+func (m *MockAuth) LogoutEverywhere(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogoutEverywhere", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogoutEverywhere indicates an expected call of LogoutEverywhere.
+func (mr *MockAuthMockRecorder) LogoutEverywhere(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutEverywhere", reflect.TypeOf((*MockAuth)(nil).LogoutEverywhere), ctx, userID)
+}
+
+// OIDCCallback mocks base method.
+func (m *MockAuth) OIDCCallback(ctx context.Context, input *model.OIDCCallback, device *model.Device) (*model.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OIDCCallback", ctx, input, device)
+	ret0, _ := ret[0].(*model.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OIDCCallback indicates an expected call of OIDCCallback.
+func (mr *MockAuthMockRecorder) OIDCCallback(ctx, input, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OIDCCallback", reflect.TypeOf((*MockAuth)(nil).OIDCCallback), ctx, input, device)
+}
+
+// OIDCLogin mocks base method.
+func (m *MockAuth) OIDCLogin(ctx context.Context) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OIDCLogin", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// OIDCLogin indicates an expected call of OIDCLogin.
+func (mr *MockAuthMockRecorder) OIDCLogin(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OIDCLogin", reflect.TypeOf((*MockAuth)(nil).OIDCLogin), ctx)
+}
+
 // RefreshToken mocks base method.
-func (m *MockAuth) RefreshToken(ctx context.Context, refreshTokenString string) (*model.Tokens, error) {
+func (m *MockAuth) RefreshToken(ctx context.Context, refreshTokenString string, device *model.Device) (*model.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RefreshToken", ctx, refreshTokenString)
+	ret := m.ctrl.Call(m, "RefreshToken", ctx, refreshTokenString, device)
 	ret0, _ := ret[0].(*model.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RefreshToken indicates an expected call of RefreshToken.
-func (mr *MockAuthMockRecorder) RefreshToken(ctx, refreshTokenString interface{}) *gomock.Call {
+func (mr *MockAuthMockRecorder) RefreshToken(ctx, refreshTokenString, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockAuth)(nil).RefreshToken), ctx, refreshTokenString, device)
+}
+
+// ResendVerification mocks base method.
+func (m *MockAuth) ResendVerification(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendVerification", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendVerification indicates an expected call of ResendVerification.
+func (mr *MockAuthMockRecorder) ResendVerification(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerification", reflect.TypeOf((*MockAuth)(nil).ResendVerification), ctx, userID)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAuth) ResetPassword(ctx context.Context, input *model.ResetPassword) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAuthMockRecorder) ResetPassword(ctx, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAuth)(nil).ResetPassword), ctx, input)
+}
+
+// SetUserRole mocks base method.
+func (m *MockAuth) SetUserRole(ctx context.Context, adminID, id, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", ctx, adminID, id, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockAuthMockRecorder) SetUserRole(ctx, adminID, id, role interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshToken", reflect.TypeOf((*MockAuth)(nil).RefreshToken), ctx, refreshTokenString)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockAuth)(nil).SetUserRole), ctx, adminID, id, role)
 }
 
 // SignIn mocks base method.
-func (m *MockAuth) SignIn(ctx context.Context, input *model.AuthUser) (*model.Tokens, error) {
+func (m *MockAuth) SignIn(ctx context.Context, input *model.AuthUser, device *model.Device) (*model.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SignIn", ctx, input)
+	ret := m.ctrl.Call(m, "SignIn", ctx, input, device)
 	ret0, _ := ret[0].(*model.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SignIn indicates an expected call of SignIn.
-func (mr *MockAuthMockRecorder) SignIn(ctx, input interface{}) *gomock.Call {
+func (mr *MockAuthMockRecorder) SignIn(ctx, input, device interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockAuth)(nil).SignIn), ctx, input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockAuth)(nil).SignIn), ctx, input, device)
 }
 
 // SignUp mocks base method.
-func (m *MockAuth) SignUp(ctx context.Context, input *model.CreateUser) (*model.Tokens, error) {
+func (m *MockAuth) SignUp(ctx context.Context, input *model.CreateUser, device *model.Device) (*model.Tokens, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SignUp", ctx, input)
+	ret := m.ctrl.Call(m, "SignUp", ctx, input, device)
 	ret0, _ := ret[0].(*model.Tokens)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SignUp indicates an expected call of SignUp.
-func (mr *MockAuthMockRecorder) SignUp(ctx, input interface{}) *gomock.Call {
+func (mr *MockAuthMockRecorder) SignUp(ctx, input, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockAuth)(nil).SignUp), ctx, input, device)
+}
+
+// UnlockUser mocks base method.
+func (m *MockAuth) UnlockUser(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockUser", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockUser indicates an expected call of UnlockUser.
+func (mr *MockAuthMockRecorder) UnlockUser(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockUser", reflect.TypeOf((*MockAuth)(nil).UnlockUser), ctx, id)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockAuth) UpdateProfile(ctx context.Context, id string, input *model.UpdateProfile) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, id, input)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockAuthMockRecorder) UpdateProfile(ctx, id, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockAuth)(nil).UpdateProfile), ctx, id, input)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockAuth) VerifyEmail(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockAuthMockRecorder) VerifyEmail(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockAuth)(nil).VerifyEmail), ctx, token)
+}
+
+// VerifyTwoFactor mocks base method.
+func (m *MockAuth) VerifyTwoFactor(ctx context.Context, input *model.VerifyTwoFactor, device *model.Device) (*model.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyTwoFactor", ctx, input, device)
+	ret0, _ := ret[0].(*model.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyTwoFactor indicates an expected call of VerifyTwoFactor.
+func (mr *MockAuthMockRecorder) VerifyTwoFactor(ctx, input, device interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignUp", reflect.TypeOf((*MockAuth)(nil).SignUp), ctx, input)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyTwoFactor", reflect.TypeOf((*MockAuth)(nil).VerifyTwoFactor), ctx, input, device)
 }
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/oidc"
+	"github.com/malkev1ch/first-task/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// OIDCLogin method starts login with OpenID Connect provider and returns URL of the provider
+// the user is redirected to with the state the provider redirects back with. The code verifier
+// of PKCE and the nonce are kept till the callback.
+func (s AuthService) OIDCLogin(ctx context.Context) (string, string, error) {
+	if s.oidc == nil {
+		return "", "", apperror.NotFound("OIDC login isn't configured")
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		secret, err := randomToken()
+		if err != nil {
+			return "", "", err
+		}
+		secrets[i] = secret
+	}
+	state, verifier, nonce := secrets[0], secrets[1], secrets[2]
+
+	authURL, err := s.oidc.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		logrus.Error(err, "service: can't discover OIDC provider")
+		return "", "", fmt.Errorf("service: can't discover OIDC provider - %w", err)
+	}
+	err = s.repo.Auth.CreateOIDCLogin(ctx, &model.OIDCLogin{
+		StateHash:    hashToken(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().UTC().Add(s.oidcLoginTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
+}
+
+// OIDCCallback method exchanges the code the provider redirects back with for the identity of the user
+// and starts new session of the user linked to the identity on the device. The state is checked once,
+// so the callback can't be replayed. Users with two-factor authentication get challenge token like by SignIn.
+func (s AuthService) OIDCCallback(ctx context.Context, input *model.OIDCCallback, device *model.Device) (*model.Tokens, error) {
+	if s.oidc == nil {
+		return nil, apperror.NotFound("OIDC login isn't configured")
+	}
+
+	login, err := s.repo.Auth.ConsumeOIDCLogin(ctx, hashToken(input.State))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.Unauthorized("invalid or expired OIDC login state")
+		}
+		return nil, err
+	}
+
+	identity, err := s.oidc.Exchange(ctx, input.Code, login.CodeVerifier, login.Nonce)
+	switch {
+	case errors.Is(err, oidc.ErrInvalidLogin):
+		logrus.Error(err, "service: OIDC login failed")
+		return nil, apperror.Unauthorized("OIDC login failed")
+
+	case err != nil:
+		logrus.Error(err, "service: can't exchange OIDC code")
+		return nil, fmt.Errorf("service: can't exchange OIDC code - %w", err)
+	}
+
+	user, err := s.identityUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		logrus.Error("service: user is disabled")
+		return nil, apperror.Forbidden("account is disabled")
+	}
+
+	return s.completeSignIn(ctx, user, device)
+}
+
+// identityUser method returns the user the identity is linked to. At the first login the identity
+// is linked to the user with the same email if both the provider and the user verified it,
+// or new user without password is created.
+func (s AuthService) identityUser(ctx context.Context, identity *oidc.Identity) (*model.User, error) {
+	linked, err := s.repo.Auth.GetIdentity(ctx, identity.Issuer, identity.Subject)
+	switch {
+	case err == nil:
+		return s.repo.Auth.GetUser(ctx, linked.UserID)
+
+	case !errors.Is(err, apperror.ErrNotFound):
+		return nil, err
+
+	case identity.Email == "":
+		logrus.Error("service: OIDC provider didn't share email of the user")
+		return nil, apperror.Unauthorized("OIDC provider didn't share email of the user")
+	}
+
+	id, _, err := s.repo.Auth.GetUserHashedPassword(ctx, identity.Email)
+	switch {
+	case err == nil && !identity.EmailVerified:
+		// otherwise whoever registers the email at the provider takes over the account
+		logrus.Error("service: email of OIDC identity isn't verified")
+		return nil, apperror.Conflict("user with given email exists, sign in with password")
+
+	case err == nil:
+		user, err := s.repo.Auth.GetUser(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !user.EmailVerified {
+			// otherwise whoever registers the email here first keeps access to the account of its owner
+			logrus.Error("service: email of the user isn't verified")
+			return nil, apperror.Conflict("user with given email exists, verify the email or sign in with password")
+		}
+
+	case errors.Is(err, apperror.ErrNotFound):
+		id = uuid.New().String()
+		name := identity.Name
+		if name == "" {
+			name = identity.Email
+		}
+		err = s.repo.Auth.CreateUser(ctx, &repository.CreateUserInput{
+			ID: id, UserName: name, Email: identity.Email,
+			Role: model.RoleEditor, EmailVerified: identity.EmailVerified,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+	case err != nil:
+		return nil, err
+	}
+
+	err = s.repo.Auth.CreateIdentity(ctx, &model.Identity{
+		Issuer:    identity.Issuer,
+		Subject:   identity.Subject,
+		UserID:    id,
+		Email:     identity.Email,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.Auth.GetUser(ctx, id)
+}
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"time"
 
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/malkev1ch/first-task/internal/mail"
 	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/password"
 	"github.com/malkev1ch/first-task/internal/rediscache"
 	"github.com/malkev1ch/first-task/internal/repository"
 )
@@ -12,16 +16,53 @@ import (
 
 type Cat interface {
 	Create(ctx context.Context, cat *model.Cat) (string, error)
-	Get(ctx context.Context, id string) (*model.Cat, error)
-	Update(ctx context.Context, id string, input *model.UpdateCat) (*model.Cat, error)
-	Delete(ctx context.Context, id string) error
-	UploadImage(ctx context.Context, id, path string) error
+	Get(ctx context.Context, ownerID, id string) (*model.Cat, error)
+	// Update, Delete and UploadImage change the cat only if it has the version, zero version
+	// changes the cat whatever version it has.
+	Update(ctx context.Context, actorID, ownerID, id string, version int64, input *model.UpdateCat) (*model.Cat, error)
+	Delete(ctx context.Context, actorID, ownerID, id string, version int64) error
+	Restore(ctx context.Context, actorID, ownerID, id string) (*model.Cat, error)
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error)
+	UploadImage(ctx context.Context, actorID, ownerID, id string, version int64, path string) error
+	List(ctx context.Context, input *model.ListCats) (*model.CatsPage, error)
+	History(ctx context.Context, input *model.ListCatChanges) (*model.CatChangesPage, error)
 }
 
 type Auth interface {
-	SignUp(ctx context.Context, input *model.CreateUser) (*model.Tokens, error)
-	SignIn(ctx context.Context, input *model.AuthUser) (*model.Tokens, error)
-	RefreshToken(ctx context.Context, refreshTokenString string) (*model.Tokens, error)
+	SignUp(ctx context.Context, input *model.CreateUser, device *model.Device) (*model.Tokens, error)
+	SignIn(ctx context.Context, input *model.AuthUser, device *model.Device) (*model.Tokens, error)
+	RefreshToken(ctx context.Context, refreshTokenString string, device *model.Device) (*model.Tokens, error)
+	Authenticate(ctx context.Context, accessTokenString string) (*JwtCustomClaims, error)
+	AuthenticateAPIKey(ctx context.Context, key string) (*JwtCustomClaims, error)
+	Logout(ctx context.Context, claims *JwtCustomClaims) error
+	LogoutEverywhere(ctx context.Context, userID string) error
+	JWKS() *model.JWKS
+	ListSessions(ctx context.Context, userID string) ([]*model.Session, error)
+	DeleteSession(ctx context.Context, userID, id string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, input *model.ResetPassword) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, userID string) error
+	EnrollTwoFactor(ctx context.Context, userID string) (*model.TwoFactorEnrollment, error)
+	ConfirmTwoFactor(ctx context.Context, userID, code string) (*model.RecoveryCodes, error)
+	DisableTwoFactor(ctx context.Context, userID, code string) error
+	VerifyTwoFactor(ctx context.Context, input *model.VerifyTwoFactor, device *model.Device) (*model.Tokens, error)
+	CreateAPIKey(ctx context.Context, userID string, input *model.CreateAPIKey) (*model.CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, id string) error
+	ListLockouts(ctx context.Context) ([]*model.Lockout, error)
+	UnlockUser(ctx context.Context, id string) error
+	SetUserRole(ctx context.Context, adminID, id, role string) error
+	GetUser(ctx context.Context, id string) (*model.User, error)
+	UpdateProfile(ctx context.Context, id string, input *model.UpdateProfile) (*model.User, error)
+	ChangePassword(ctx context.Context, id string, input *model.ChangePassword, device *model.Device) (*model.Tokens, error)
+	DeleteAccount(ctx context.Context, id string, input *model.DeleteAccount, device *model.Device) error
+	ListUsers(ctx context.Context, input *model.ListUsers) (*model.UsersPage, error)
+	DisableUser(ctx context.Context, adminID, id string) error
+	EnableUser(ctx context.Context, id string) error
+	ForceLogout(ctx context.Context, id string) error
+	OIDCLogin(ctx context.Context) (string, string, error)
+	OIDCCallback(ctx context.Context, input *model.OIDCCallback, device *model.Device) (*model.Tokens, error)
 }
 
 type Service struct {
@@ -29,9 +70,10 @@ type Service struct {
 	Auth
 }
 
-func NewService(repo *repository.Repository, redis *rediscache.Cache) *Service {
+func NewService(repo *repository.Repository, redis *rediscache.Cache, tokens *TokenManager,
+	hasher password.Hasher, mailer mail.Mailer, cfg *config.Config) *Service {
 	return &Service{
 		Cat:  NewCatService(repo, redis),
-		Auth: NewAuthService(repo),
+		Auth: NewAuthService(repo, tokens, hasher, redis.Cat, redis.Denylist, redis.LoginLimiter, mailer, cfg),
 	}
 }
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/config"
+	"github.com/malkev1ch/first-task/internal/keys"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Types of tokens put into tokenType claim.
+const (
+	TokenTypeAccess    = "access"
+	TokenTypeRefresh   = "refresh"
+	TokenTypeChallenge = "challenge"
+	// TokenTypeAPIKey marks claims of API key, they are never signed into a token
+	TokenTypeAPIKey = "apiKey"
+)
+
+// JwtCustomClaims are custom claims extending default ones.
+// Subject is UUID of the user, Id is unique UUID of the token.
+// Claims of API key have Id of the key and its scopes.
+type JwtCustomClaims struct {
+	Name          string   `json:"name"`
+	Email         string   `json:"email"`
+	Role          string   `json:"role"`
+	EmailVerified bool     `json:"emailVerified"`
+	TokenType     string   `json:"tokenType"`
+	SessionID     string   `json:"sid"`
+	Scopes        []string `json:"scopes,omitempty"`
+	// IssuedAtMicro is the time the token is issued at in microseconds. iat has only seconds,
+	// so it can't tell tokens issued right before revocation of all tokens of the user from the ones after.
+	IssuedAtMicro int64 `json:"iatUs,omitempty"`
+	jwt.StandardClaims
+}
+
+// issuedAt returns the time the token is issued at, only seconds are known for tokens without iatUs.
+func (c *JwtCustomClaims) issuedAt() time.Time {
+	if c.IssuedAtMicro != 0 {
+		return time.UnixMicro(c.IssuedAtMicro)
+	}
+
+	return time.Unix(c.IssuedAt, 0)
+}
+
+// TokenManager type issues and verifies access, refresh and two-factor challenge tokens
+// signed with keys of the provider.
+type TokenManager struct {
+	keys         keys.Provider
+	issuer       string
+	audience     string
+	accessTTL    time.Duration
+	refreshTTL   time.Duration
+	challengeTTL time.Duration
+}
+
+func NewTokenManager(cfg *config.Config, keys keys.Provider) *TokenManager {
+	return &TokenManager{
+		keys:         keys,
+		issuer:       cfg.JWTIssuer,
+		audience:     cfg.JWTAudience,
+		accessTTL:    cfg.AccessTokenTTL,
+		refreshTTL:   cfg.RefreshTokenTTL,
+		challengeTTL: cfg.ChallengeTokenTTL,
+	}
+}
+
+// Generate method generates new token pair of the session with putting name, email, role
+// and email verification state of the user inside payload.
+func (m *TokenManager) Generate(user *model.User, sessionID string) (*model.Tokens, error) {
+	accessToken, err := m.sign(user, sessionID, TokenTypeAccess, m.accessTTL)
+	if err != nil {
+		logrus.Error(err, "service: can't generate access token")
+		return nil, fmt.Errorf("service: can't generate access token - %w", err)
+	}
+
+	refreshToken, err := m.sign(user, sessionID, TokenTypeRefresh, m.refreshTTL)
+	if err != nil {
+		logrus.Error(err, "service: can't generate refresh token")
+		return nil, fmt.Errorf("service: can't generate refresh token - %w", err)
+	}
+
+	return &model.Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Challenge method generates short-lived token proving the user has passed the password
+// check of sign in, the session starts after the second factor is checked too.
+func (m *TokenManager) Challenge(user *model.User, sessionID string) (string, error) {
+	challengeToken, err := m.sign(user, sessionID, TokenTypeChallenge, m.challengeTTL)
+	if err != nil {
+		logrus.Error(err, "service: can't generate challenge token")
+		return "", fmt.Errorf("service: can't generate challenge token - %w", err)
+	}
+
+	return challengeToken, nil
+}
+
+// Parse method verifies signature, lifetime, issuer, audience and type of the token and returns its claims.
+func (m *TokenManager) Parse(tokenString, tokenType string) (*JwtCustomClaims, error) {
+	claims := &JwtCustomClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := m.keys.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		// the method is taken from the key, not from the token, so an attacker
+		// can't make the public key verify a token as HMAC secret
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v of key %s", token.Header["alg"], kid)
+		}
+		return key.Public(), nil
+	})
+	if err != nil || !token.Valid {
+		logrus.Error("service: can't parse ", tokenType, " token - ", err)
+		return nil, apperror.Unauthorized(fmt.Sprintf("invalid or expired %s token", tokenType))
+	}
+
+	switch {
+	case claims.TokenType != tokenType:
+		logrus.Errorf("service: got %s token instead of %s token", claims.TokenType, tokenType)
+		return nil, apperror.Unauthorized(fmt.Sprintf("invalid %s token", tokenType))
+	case !claims.VerifyIssuer(m.issuer, true) || !claims.VerifyAudience(m.audience, true):
+		logrus.Error("service: unexpected issuer or audience of ", tokenType, " token")
+		return nil, apperror.Unauthorized(fmt.Sprintf("invalid %s token", tokenType))
+	case claims.Subject == "" || claims.SessionID == "":
+		logrus.Error("service: ", tokenType, " token without user or session id")
+		return nil, apperror.Unauthorized(fmt.Sprintf("invalid %s token", tokenType))
+	}
+
+	return claims, nil
+}
+
+// AccessTTL method returns lifetime of access tokens.
+func (m *TokenManager) AccessTTL() time.Duration {
+	return m.accessTTL
+}
+
+// RefreshTTL method returns lifetime of refresh tokens.
+func (m *TokenManager) RefreshTTL() time.Duration {
+	return m.refreshTTL
+}
+
+func (m *TokenManager) sign(user *model.User, sessionID, tokenType string, ttl time.Duration) (string, error) {
+	key, err := m.keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(key.Method, &JwtCustomClaims{
+		Name:          user.UserName,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		TokenType:     tokenType,
+		SessionID:     sessionID,
+		IssuedAtMicro: now.UnixMicro(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			Subject:   user.ID,
+			Issuer:    m.issuer,
+			Audience:  m.audience,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+	})
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.Private)
+}
+
+// randomToken returns 256 bits long random token, e.g. reset token sent by email.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		logrus.Error(err, "service: can't generate random token")
+		return "", fmt.Errorf("service: can't generate random token - %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns hex encoded SHA-256 hash of the token, the form refresh and reset tokens are stored in.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
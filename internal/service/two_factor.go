@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/malkev1ch/first-task/internal/apperror"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/totp"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// recoveryCodesNum is the number of recovery codes issued when two-factor authentication is enabled
+	recoveryCodesNum = 10
+	// codeSkew is the number of time steps before and after the current one codes are accepted for
+	codeSkew = 1
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollTwoFactor method generates new TOTP secret of the user. Two-factor authentication
+// is enabled after the user confirms the secret with a code from authenticator app.
+func (s AuthService) EnrollTwoFactor(ctx context.Context, userID string) (*model.TwoFactorEnrollment, error) {
+	user, err := s.repo.Auth.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logrus.Error(err, "service: can't generate TOTP secret")
+		return nil, err
+	}
+	if err := s.repo.Auth.SaveTwoFactor(ctx, &model.TwoFactor{
+		UserID:    user.ID,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor method enables two-factor authentication of the user if the code matches
+// the enrolled secret and returns recovery codes, they can't be got again.
+func (s AuthService) ConfirmTwoFactor(ctx context.Context, userID, code string) (*model.RecoveryCodes, error) {
+	twoFactor, err := s.repo.Auth.GetTwoFactor(ctx, userID)
+	switch {
+	case errors.Is(err, apperror.ErrNotFound):
+		return nil, apperror.Conflict("two-factor authentication isn't enrolled")
+
+	case err != nil:
+		return nil, err
+
+	case twoFactor.Enabled:
+		return nil, apperror.Conflict("two-factor authentication is already enabled")
+	}
+
+	step, ok := totp.Validate(twoFactor.Secret, code, time.Now(), codeSkew)
+	if !ok {
+		logrus.Error("service: incorrect two-factor code")
+		return nil, apperror.Unauthorized("incorrect two-factor code")
+	}
+
+	codes := make([]string, 0, recoveryCodesNum)
+	hashes := make([]string, 0, recoveryCodesNum)
+	for i := 0; i < recoveryCodesNum; i++ {
+		code, err := recoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+	if err := s.repo.Auth.EnableTwoFactor(ctx, userID, hashes, step); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.Conflict("two-factor authentication is already enabled")
+		}
+		return nil, err
+	}
+
+	return &model.RecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor method disables two-factor authentication of the user,
+// the user proves it with a code from authenticator app or a recovery code.
+func (s AuthService) DisableTwoFactor(ctx context.Context, userID, code string) error {
+	twoFactor, err := s.repo.Auth.GetTwoFactor(ctx, userID)
+	switch {
+	case errors.Is(err, apperror.ErrNotFound) || err == nil && !twoFactor.Enabled:
+		return apperror.Conflict("two-factor authentication isn't enabled")
+
+	case err != nil:
+		return err
+	}
+
+	if err := s.checkTwoFactorCode(ctx, twoFactor, code); err != nil {
+		return err
+	}
+
+	return s.repo.Auth.DeleteTwoFactor(ctx, userID)
+}
+
+// VerifyTwoFactor method checks the code of the user the challenge token is issued to and
+// starts new session on the device. Wrong codes count as failed sign in attempts,
+// the challenge token can't be used again after success.
+func (s AuthService) VerifyTwoFactor(ctx context.Context, input *model.VerifyTwoFactor, device *model.Device) (*model.Tokens, error) {
+	claims, err := s.tokens.Parse(input.ChallengeToken, TokenTypeChallenge)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := s.denylist.IsRevoked(ctx, claims.Id, claims.Subject, claims.issuedAt())
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		logrus.Error("service: challenge token is revoked")
+		return nil, apperror.Unauthorized("challenge token is used or revoked")
+	}
+
+	locked, err := s.limiter.Check(ctx, claims.Email, device.IP)
+	if err != nil {
+		return nil, err
+	}
+	if locked > 0 {
+		return nil, apperror.TooManyRequests("too many failed sign in attempts, try again later", locked)
+	}
+
+	twoFactor, err := s.repo.Auth.GetTwoFactor(ctx, claims.Subject)
+	switch {
+	case errors.Is(err, apperror.ErrNotFound) || err == nil && !twoFactor.Enabled:
+		// two-factor authentication is disabled after the challenge token is issued, the user signs in again
+		return nil, apperror.Unauthorized("invalid challenge token")
+
+	case err != nil:
+		return nil, err
+	}
+
+	if err := s.checkTwoFactorCode(ctx, twoFactor, input.Code); err != nil {
+		if errors.Is(err, apperror.ErrUnauthorized) {
+			if err := s.limiter.RecordFailure(ctx, claims.Email, device.IP); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+	if err := s.limiter.Reset(ctx, claims.Email); err != nil {
+		return nil, err
+	}
+	if err := s.denylist.RevokeToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.Auth.GetUser(ctx, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		logrus.Error("service: user is disabled")
+		return nil, apperror.Forbidden("account is disabled")
+	}
+
+	return s.createSession(ctx, user, device)
+}
+
+// checkTwoFactorCode method checks code from authenticator app or recovery code of the user
+// and marks it as used, so every code is accepted once.
+func (s AuthService) checkTwoFactorCode(ctx context.Context, twoFactor *model.TwoFactor, code string) error {
+	if len(code) == totp.Digits && strings.Trim(code, "0123456789") == "" {
+		step, ok := totp.Validate(twoFactor.Secret, code, time.Now(), codeSkew)
+		if !ok {
+			logrus.Error("service: incorrect two-factor code")
+			return apperror.Unauthorized("incorrect two-factor code")
+		}
+		if err := s.repo.Auth.UseTwoFactorStep(ctx, twoFactor.UserID, step); err != nil {
+			if errors.Is(err, apperror.ErrConflict) {
+				return apperror.Unauthorized("two-factor code is already used, wait for the next one")
+			}
+			return err
+		}
+		return nil
+	}
+
+	if err := s.repo.Auth.UseRecoveryCode(ctx, twoFactor.UserID, hashToken(normalizeRecoveryCode(code))); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			logrus.Error("service: incorrect recovery code")
+			return apperror.Unauthorized("incorrect two-factor code")
+		}
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"userID": twoFactor.UserID,
+	}).Warn("service: recovery code is used")
+
+	return nil
+}
+
+// recoveryCode returns random recovery code of 50 bits in form xxxxx-xxxxx.
+func recoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		logrus.Error(err, "service: can't generate recovery code")
+		return "", fmt.Errorf("service: can't generate recovery code - %w", err)
+	}
+	code := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode returns recovery code in the form its hash is computed of,
+// so codes typed with other case or without dash are accepted.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -12,31 +12,45 @@
 //
 // Produces:
 //  - application/json
+//  - application/problem+json
 //
 // SecurityDefinitions:
 //  AdminAuth:
 //   type: apiKey
 //   name: Authorization
 //   in: header
+//  APIKeyAuth:
+//   type: apiKey
+//   name: X-API-Key
+//   in: header
 //
 // swagger:meta
 package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/malkev1ch/first-task/internal/rediscache"
 
 	"github.com/caarlos0/env/v6"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/malkev1ch/first-task/internal/config"
 	"github.com/malkev1ch/first-task/internal/handler"
+	"github.com/malkev1ch/first-task/internal/keys"
+	"github.com/malkev1ch/first-task/internal/mail"
+	"github.com/malkev1ch/first-task/internal/migration"
+	"github.com/malkev1ch/first-task/internal/model"
+	"github.com/malkev1ch/first-task/internal/password"
 	"github.com/malkev1ch/first-task/internal/repository"
 	"github.com/malkev1ch/first-task/internal/service"
 	"github.com/sirupsen/logrus"
@@ -49,12 +63,30 @@ func main() {
 	if err := env.Parse(&cfg); err != nil {
 		logrus.Fatal(err, "wrong config variables")
 	}
-	logrus.Infof("Parsed config - %+v\n", cfg)
-	repo, err := CreateDBConnection(&cfg)
+	logrus.Infof("Parsed config - %+v\n", cfg.Redacted())
+	repo, migrator, err := CreateDBConnection(&cfg)
 	if err != nil {
 		logrus.Fatal(err, "err initializing DB")
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), migrator, os.Args[2:]); err != nil {
+			logrus.Fatal(err, "migration failed")
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "set-role" {
+		if err := runSetRole(context.Background(), repo, os.Args[2:]); err != nil {
+			logrus.Fatal(err, "role assignment failed")
+		}
+		return
+	}
+	if cfg.AutoMigrate && migrator != nil {
+		if err := migrator.Up(context.Background()); err != nil {
+			logrus.Fatal(err, "migration failed")
+		}
+	}
+
 	redisClient := redisConnection(cfg)
 	defer func() {
 		err := redisClient.Close()
@@ -63,8 +95,20 @@ func main() {
 		}
 	}()
 
+	keyProvider, err := createKeyProvider(context.Background(), &cfg)
+	if err != nil {
+		logrus.Fatal(err, "err loading JWT keys")
+	}
+
+	hasher, err := password.NewHasher(&cfg)
+	if err != nil {
+		logrus.Fatal(err, "err creating password hasher")
+	}
+
 	cache := rediscache.NewStreamCache(&cfg, redisClient)
-	services := service.NewService(repo, cache)
+	services := service.NewService(repo, cache, service.NewTokenManager(&cfg, keyProvider), hasher,
+		mail.NewMailer(&cfg), &cfg)
+	go runTrashPurge(context.Background(), services, &cfg)
 	validator := handler.NewValidator()
 	handlers := handler.NewHandler(services, &cfg, validator)
 	router := handler.InitRouter(handlers, &cfg)
@@ -94,7 +138,9 @@ func main() {
 	}
 }
 
-func CreateDBConnection(cfg *config.Config) (*repository.Repository, error) {
+// CreateDBConnection connects to the database chosen by config and returns repository
+// with migrator of the database. Migrator is nil for in-memory repository.
+func CreateDBConnection(cfg *config.Config) (*repository.Repository, *migration.Migrator, error) {
 	switch cfg.CurrentDB {
 	case "mongo":
 		client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(cfg.MongoURL))
@@ -108,7 +154,7 @@ func CreateDBConnection(cfg *config.Config) (*repository.Repository, error) {
 				"status": "successfully connected to mongodb database.",
 			}).Info("mongodb repository info.")
 		}
-		return repository.NewRepositoryMongo(client), err
+		return repository.NewRepositoryMongo(client), migration.NewMigrator(migration.NewMongo(client)), err
 	case "postgres":
 		conn, err := pgxpool.Connect(context.Background(), cfg.PostgresURL)
 		if err != nil {
@@ -121,15 +167,123 @@ func CreateDBConnection(cfg *config.Config) (*repository.Repository, error) {
 				"status": "successfully connected to postgres database.",
 			}).Info("postgres repository info.")
 		}
-		return repository.NewRepositoryPostgres(conn), err
+		driver, err := migration.NewPostgres(conn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repository.NewRepositoryPostgres(conn), migration.NewMigrator(driver), nil
+	case "memory":
+		logrus.WithFields(logrus.Fields{
+			"status": "data is stored in memory and will be lost on shutdown.",
+		}).Info("memory repository info.")
+		return repository.NewRepositoryMemory(), nil, nil
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"status": "database connection failed.",
 		"err":    "invalid config",
-	}).Info("repository info")
+	}).Error("repository info")
+
+	return nil, nil, fmt.Errorf("unknown CURRENT_DB %q, should be one of postgres, mongo, memory", cfg.CurrentDB)
+}
+
+// runMigrate runs migrate subcommand: up applies pending migrations, down reverts the last one,
+// status prints state of every migration.
+func runMigrate(ctx context.Context, migrator *migration.Migrator, args []string) error {
+	if migrator == nil {
+		return errors.New("in-memory repository has no schema to migrate")
+	}
+	if len(args) != 1 {
+		return errors.New("usage: migrate up|down|status")
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+
+	return fmt.Errorf("unknown migrate command %q, usage: migrate up|down|status", args[0])
+}
+
+// runSetRole runs set-role subcommand that assigns role to the user with given email,
+// e.g. to create the first admin.
+func runSetRole(ctx context.Context, repo *repository.Repository, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: set-role <email> admin|editor|viewer")
+	}
+	email, role := args[0], args[1]
+	if role != model.RoleAdmin && role != model.RoleEditor && role != model.RoleViewer {
+		return fmt.Errorf("unknown role %q, usage: set-role <email> admin|editor|viewer", role)
+	}
+
+	id, _, err := repo.Auth.GetUserHashedPassword(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	return repo.Auth.UpdateUserRole(ctx, id, role)
+}
 
-	return nil, nil
+// createKeyProvider returns provider of keys listed in JWT_KEYS_FILE that reloads them in background.
+// Without the file it returns provider of a key generated at start, tokens signed with it
+// can't be verified after restart and by other instances of the application.
+func createKeyProvider(ctx context.Context, cfg *config.Config) (keys.Provider, error) {
+	if cfg.JWTKeysFile == "" {
+		logrus.Warn("JWT_KEYS_FILE isn't set, tokens are signed with a key generated at start")
+		key, err := keys.GenerateEd25519(uuid.New().String(), time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return keys.NewSet(key), nil
+	}
+
+	provider, err := keys.NewFileProvider(cfg.JWTKeysFile)
+	if err != nil {
+		return nil, err
+	}
+	go provider.Run(ctx, cfg.JWTKeysReload)
+
+	return provider, nil
+}
+
+// runTrashPurge deletes cats kept in trash longer than TRASH_RETENTION for good
+// every TRASH_PURGE_INTERVAL until the context is done.
+func runTrashPurge(ctx context.Context, services *service.Service, cfg *config.Config) {
+	ticker := time.NewTicker(cfg.TrashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// cats can be purged even if the purge fails partly
+			purged, err := services.PurgeTrash(ctx, time.Now().UTC().Add(-cfg.TrashRetention))
+			if err != nil {
+				logrus.Error("can't purge cats in trash - ", err)
+			}
+			if purged > 0 {
+				logrus.Infof("purged %d cats from trash", purged)
+			}
+		}
+	}
 }
 
 func redisConnection(cfg config.Config) *redis.Client {
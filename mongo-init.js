@@ -1,2 +0,0 @@
-db = db.getSiblingDB('mongo_database');
-db.createCollection('cats', {capped: false});
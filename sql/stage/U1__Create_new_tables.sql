@@ -1,2 +0,0 @@
-DROP TABLE IF EXISTS cats;
-DROP TABLE IF EXISTS users;
@@ -31,12 +31,12 @@ func TestCreateCat(t *testing.T) {
 			expectedStatusCode: http.StatusCreated,
 		},
 		{
-			name: "Bad request",
+			name: "Unprocessable entity",
 			requestBody: &model.CreateCat{
 				DateBirth:  time.Date(2022, 1, 1, 1, 1, 1, 1, time.UTC),
 				Vaccinated: false,
 			},
-			expectedStatusCode: http.StatusBadRequest,
+			expectedStatusCode: http.StatusUnprocessableEntity,
 		},
 	}
 
@@ -1,12 +1,14 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/malkev1ch/first-task/internal/migration"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	log "github.com/sirupsen/logrus"
@@ -43,12 +45,30 @@ func TestMain(m *testing.M) {
 	postgresHostAndPort := postgres.GetHostPort("5432/tcp")
 
 	// Start migration
-	flywayPath := fmt.Sprintf("-url=jdbc:postgresql://%s/postgres", postgresHostAndPort)
-	flywayConfFile := "-configFiles=/home/andreimalkevich/first-task/sql/dockertest/flyway.conf"
-	cmd := exec.Command("flyway", flywayPath, flywayConfFile, "migrate")
-	if err := cmd.Run(); err != nil {
-		log.Fatalf("Command finished with error: %v", err)
+	var db *pgxpool.Pool
+	pool.MaxWait = 120 * time.Second
+	if err = pool.Retry(func() error {
+		db, err = pgxpool.Connect(context.Background(),
+			fmt.Sprintf("postgres://postgres:qwerty@%s/postgres?sslmode=disable", postgresHostAndPort))
+		if err != nil {
+			return err
+		}
+		return db.Ping(context.Background())
+	}); err != nil {
+		log.Fatalf("Could not connect to postgres: %s", err)
 	}
+	driver, err := migration.NewPostgres(db)
+	if err != nil {
+		log.Fatalf("Could not load migrations: %s", err)
+	}
+	if err := migration.NewMigrator(driver).Up(context.Background()); err != nil {
+		log.Fatalf("Could not migrate postgres: %s", err)
+	}
+	if _, err := db.Exec(context.Background(), `INSERT INTO cats(id, name, date_birth, vaccinated)
+		VALUES ('9d9044a6-d8a8-4e8c-9132-e583d2ebd6c4', 'Some name', '2018-09-22', FALSE)`); err != nil {
+		log.Fatalf("Could not insert test data: %s", err)
+	}
+	db.Close()
 
 	postgres.Expire(300) // Tell docker to hard kill the container in 300 seconds
 	log.Info("Created postgres container successfully")
@@ -85,7 +105,7 @@ func TestMain(m *testing.M) {
 	redisURL := fmt.Sprintf("redis://:@%s:6379/1", redisIPInNetworkBridge)
 
 	// pulls an image, creates a container based on it and runs it
-	golang, err := pool.BuildAndRunWithOptions("/home/andreimalkevich/first-task/Dockerfile",
+	golang, err := pool.BuildAndRunWithOptions("../Dockerfile",
 		&dockertest.RunOptions{
 			Name: "test-handler",
 			PortBindings: map[docker.Port][]docker.PortBinding{